@@ -311,6 +311,9 @@ func (h authHandler) SignInWithGoogle(c *gin.Context) {
 				})
 				return
 			}
+			if err = h.app.Services.AttachShareInvitations(user); err != nil {
+				h.app.Logger.Err(err).Msg("Could not attach the pipes shared with the email of the user")
+			}
 		} else {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"message": "Error occurred while trying to register user",
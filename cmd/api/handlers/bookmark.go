@@ -16,6 +16,10 @@ type BookmarkHandler interface {
 	GetBookmarks(c *gin.Context)
 	CreateBookmark(c *gin.Context)
 	GetBookmark(c *gin.Context)
+	GetBookmarksByState(c *gin.Context)
+	UpdateBookmark(c *gin.Context)
+	UpdateBookmarkState(c *gin.Context)
+	MoveBookmarks(c *gin.Context)
 	DeleteBookmark(c *gin.Context)
 }
 
@@ -30,6 +34,7 @@ func NewBookmarkHandler(app internal.Application) BookmarkHandler {
 func (h bookmarkHandler) CreateBookmark(c *gin.Context) {
 	bmRequest := struct {
 		Url   string  `json:"url" binding:"required"`
+		Title string  `json:"title"`
 		Tags  string  `json:"tags"`
 		Pipes []int64 `json:"pipes"`
 	}{}
@@ -42,20 +47,24 @@ func (h bookmarkHandler) CreateBookmark(c *gin.Context) {
 	var detectedPlatform string
 	detectedPlatform, _ = h.app.Services.GetPlatformFromLink(bmRequest.Url)
 	var bookmark models.Bookmark
+	userId := c.GetInt64(middlewares.KeyUserId)
 
 	for _, pid := range bmRequest.Pipes {
-		var err error
-		if _, err = h.app.Services.UserOwnsPipe(pid, c.GetInt64(middlewares.KeyUserId)); err != nil {
+		// bookmarks added to a pipe shared with the user belong to the owner of the pipe
+		access, err := h.app.Services.AuthorizePipe(pid, userId, models.PipeRoleContributor)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"message": err.Error(),
 			})
 			return
 		}
 		bookmark = models.Bookmark{
-			UserID:   c.GetInt64(middlewares.KeyUserId),
+			UserID:   access.OwnerID,
 			PipeID:   pid,
 			Platform: detectedPlatform,
 			Url:      bmRequest.Url,
+			Title:    strings.TrimSpace(bmRequest.Title),
+			AddedBy:  &userId,
 		}
 		bookmark, err = h.app.Repositories.Bookmark.CreateBookmark(bookmark)
 		if err != nil {
@@ -65,6 +74,12 @@ func (h bookmarkHandler) CreateBookmark(c *gin.Context) {
 				})
 				return
 			}
+			if err == postgres.ErrSmartPipe {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"message": "Bookmarks can not be added to a smart pipe",
+				})
+				return
+			}
 			h.app.Logger.Err(err).Msg(err.Error())
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"message": "An error occurred when trying to create your bookmark",
@@ -89,6 +104,16 @@ func (h bookmarkHandler) CreateBookmark(c *gin.Context) {
 
 		// don't really care if there's any error for now
 		bookmark, _ = h.app.Repositories.Bookmark.ParseTags(bookmark)
+
+		h.app.Services.NotifyPipeFollowers(bookmark, userId)
+		if access.OwnerID == userId {
+			h.app.Services.RecordActivity(models.Activity{
+				ActorID:    userId,
+				Verb:       models.ActivityBookmarkAdded,
+				PipeID:     bookmark.PipeID,
+				BookmarkID: &bookmark.ID,
+			})
+		}
 	}
 
 	// parse the tags as part of the bookmarks and send it back
@@ -99,8 +124,10 @@ func (h bookmarkHandler) CreateBookmark(c *gin.Context) {
 			"bookmark": map[string]interface{}{
 				"id":        bookmark.ID,
 				"url":       bookmark.Url,
+				"title":     bookmark.Title,
 				"platform":  bookmark.Platform,
 				"tags":      bookmark.Tags,
+				"added_by":  bookmark.AddedBy,
 				"createdAt": bookmark.CreatedAt,
 			},
 		},
@@ -135,11 +162,20 @@ func (h bookmarkHandler) GetBookmark(c *gin.Context) {
 		"message": "bookmarked fetched successfully",
 		"data": map[string]interface{}{
 			"bookmark": map[string]interface{}{
-				"id":        bookmark.ID,
-				"url":       bookmark.Url,
-				"platform":  bookmark.Platform,
-				"createdAt": bookmark.CreatedAt,
-				"tags":      bookmark.Tags,
+				"id":          bookmark.ID,
+				"url":         bookmark.Url,
+				"title":       bookmark.Title,
+				"position":    bookmark.Position,
+				"platform":    bookmark.Platform,
+				"createdAt":   bookmark.CreatedAt,
+				"tags":        bookmark.Tags,
+				"is_read":     bookmark.IsRead,
+				"read_at":     bookmark.ReadAt,
+				"is_starred":  bookmark.IsStarred,
+				"starred_at":  bookmark.StarredAt,
+				"is_archived": bookmark.IsArchived,
+				"archived_at": bookmark.ArchivedAt,
+				"added_by":    bookmark.AddedBy,
 			},
 		},
 	})
@@ -154,8 +190,34 @@ func (h bookmarkHandler) GetBookmarks(c *gin.Context) {
 		})
 		return
 	}
-	bookmarks, err := h.app.Repositories.Bookmark.GetBookmarks(userId, pipeId)
+	page, err := pageFilter(c)
 	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	filter := models.BookmarkFilter{Filter: page, State: c.Query("state"), Sort: c.Query("sort"), Tag: c.Query("tag")}
+	if !models.ValidBookmarkState(filter.State) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid state. valid states are: *unread*, *read*, *starred*, *archived* and *all*",
+		})
+		return
+	}
+	if !models.ValidBookmarkSort(filter.Sort) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid sort. valid sort options are: *manual*, *newest*, *oldest*, *title* and *platform*",
+		})
+		return
+	}
+	bookmarks, pagination, err := h.app.Repositories.Bookmark.GetBookmarks(userId, pipeId, filter)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 			"message": "Could not retrieve bookmarks! Please try again soon",
 		})
@@ -165,10 +227,261 @@ func (h bookmarkHandler) GetBookmarks(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Pipes fetched successfully",
 		"data": map[string]interface{}{
-			"bookmarks": bookmarks,
+			"bookmarks":  bookmarks,
+			"pagination": pagination,
+		},
+	})
+}
+func (h bookmarkHandler) GetBookmarksByState(c *gin.Context) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	filter := models.BookmarkFilter{
+		Filter: page,
+		State:  c.DefaultQuery("state", models.BookmarkStateUnread),
+		Sort:   c.DefaultQuery("sort", models.SortNewest),
+		Tag:    c.Query("tag"),
+	}
+	if !models.ValidBookmarkState(filter.State) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid state. valid states are: *unread*, *read*, *starred*, *archived* and *all*",
+		})
+		return
+	}
+	if !models.ValidBookmarkSort(filter.Sort) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid sort. valid sort options are: *manual*, *newest*, *oldest*, *title* and *platform*",
+		})
+		return
+	}
+
+	bookmarks, pagination, err := h.app.Repositories.Bookmark.GetBookmarksByState(c.GetInt64(middlewares.KeyUserId), filter)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Could not retrieve bookmarks! Please try again soon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmarks fetched successfully",
+		"data": map[string]interface{}{
+			"state":      filter.State,
+			"bookmarks":  bookmarks,
+			"pagination": pagination,
+		},
+	})
+}
+
+func (h bookmarkHandler) UpdateBookmark(c *gin.Context) {
+	req := struct {
+		Title  *string `json:"title"`
+		PipeID *int64  `json:"pipe_id"`
+		Tags   *string `json:"tags"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+		})
+		return
+	}
+	if req.Title == nil && req.PipeID == nil && req.Tags == nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify at least one of *title*, *pipe_id* or *tags*",
+		})
+		return
+	}
+
+	userId := c.GetInt64(middlewares.KeyUserId)
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return
+	}
+
+	current, ok := h.editableBookmark(c, bmId, userId)
+	if !ok {
+		return
+	}
+
+	update := models.BookmarkUpdate{PipeID: req.PipeID}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		update.Title = &title
+	}
+	if req.PipeID != nil {
+		access, err := h.app.Services.AuthorizePipe(*req.PipeID, userId, models.PipeRoleContributor)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if access.OwnerID != current.UserID {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Bookmarks can only be moved between pipes of the same owner",
+			})
+			return
+		}
+	}
+	if req.Tags != nil {
+		// an empty tags string removes every tag of the bookmark
+		update.Tags = []string{}
+		for _, tag := range strings.Split(*req.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				update.Tags = append(update.Tags, tag)
+			}
+		}
+	}
+
+	bookmark, err := h.app.Repositories.Bookmark.UpdateBookmark(bmId, current.UserID, userId, update)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found",
+			})
+			return
+		}
+		if err == postgres.ErrSmartPipe {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Bookmarks can not be moved to a smart pipe",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to update bookmark",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmark updated successfully",
+		"data": map[string]interface{}{
+			"bookmark": bookmark,
+		},
+	})
+}
+
+func (h bookmarkHandler) UpdateBookmarkState(c *gin.Context) {
+	var req models.BookmarkStateUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+		})
+		return
+	}
+	if req.Read == nil && req.Starred == nil && req.Archived == nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify at least one of *read*, *starred* or *archived*",
+		})
+		return
+	}
+
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return
+	}
+
+	bookmark, err := h.app.Repositories.Bookmark.UpdateBookmarkState(bmId, c.GetInt64(middlewares.KeyUserId), req)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to update bookmark",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmark updated successfully",
+		"data": map[string]interface{}{
+			"bookmark": bookmark,
+		},
+	})
+}
+
+func (h bookmarkHandler) MoveBookmarks(c *gin.Context) {
+	req := struct {
+		Moves []models.PositionMove `json:"moves" binding:"required,dive"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *moves* to make",
+		})
+		return
+	}
+
+	userId := c.GetInt64(middlewares.KeyUserId)
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+	access, err := h.app.Services.AuthorizePipe(pipeId, userId, models.PipeRoleEditor)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err = h.app.Repositories.Bookmark.MoveBookmarks(access.OwnerID, userId, pipeId, req.Moves); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "One or more of the bookmarks could not be found in this pipe",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to reorder bookmarks",
+		})
+		return
+	}
+
+	bookmarks, pagination, err := h.app.Repositories.Bookmark.GetBookmarks(userId, pipeId, models.BookmarkFilter{
+		State: models.BookmarkStateAll,
+		Sort:  models.SortManual,
+	})
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmarks reordered successfully",
+		"data": map[string]interface{}{
+			"bookmarks":  bookmarks,
+			"pagination": pagination,
 		},
 	})
 }
+
 func (h bookmarkHandler) DeleteBookmark(c *gin.Context) {
 	userId := c.GetInt64(middlewares.KeyUserId)
 	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
@@ -180,8 +493,19 @@ func (h bookmarkHandler) DeleteBookmark(c *gin.Context) {
 		return
 	}
 
-	_, err = h.app.Repositories.Bookmark.DeleteBookmark(bmId, userId)
+	bookmark, ok := h.editableBookmark(c, bmId, userId)
+	if !ok {
+		return
+	}
+
+	_, err = h.app.Repositories.Bookmark.DeleteBookmark(bmId, bookmark.UserID)
 	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found",
+			})
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "An error occurred while trying to delete bookmark",
 		})
@@ -189,6 +513,32 @@ func (h bookmarkHandler) DeleteBookmark(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Bookmark deleted successfully",
+		"message": "Bookmark moved to trash successfully",
 	})
 }
+
+// editableBookmark retrieves a bookmark the user can edit, aborting the request when
+// the bookmark can't be found or the user is not allowed to edit it
+func (h bookmarkHandler) editableBookmark(c *gin.Context, bmId, userId int64) (models.Bookmark, bool) {
+	bookmark, err := h.app.Repositories.Bookmark.GetBookmark(bmId, userId)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found",
+			})
+			return bookmark, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve bookmark",
+		})
+		return bookmark, false
+	}
+	if err := h.app.Services.CanEditBookmark(bookmark, userId); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return bookmark, false
+	}
+	return bookmark, true
+}
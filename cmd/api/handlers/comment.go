@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type CommentHandler interface {
+	GetComments(c *gin.Context)
+	CreateComment(c *gin.Context)
+	UpdateComment(c *gin.Context)
+	DeleteComment(c *gin.Context)
+	GetReactions(c *gin.Context)
+	AddReaction(c *gin.Context)
+	RemoveReaction(c *gin.Context)
+}
+
+type commentHandler struct {
+	app internal.Application
+}
+
+func NewCommentHandler(app internal.Application) CommentHandler {
+	return commentHandler{app: app}
+}
+
+// bookmark retrieves the bookmark a request is about along with the access of the user to its pipe,
+// aborting the request when the user can't see the bookmark
+func (h commentHandler) bookmark(c *gin.Context) (models.Bookmark, models.PipeAccess, bool) {
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return models.Bookmark{}, models.PipeAccess{}, false
+	}
+	userId := c.GetInt64(middlewares.KeyUserId)
+	bookmark, err := h.app.Repositories.Bookmark.GetBookmark(bmId, userId)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found",
+			})
+			return bookmark, models.PipeAccess{}, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve bookmark",
+		})
+		return bookmark, models.PipeAccess{}, false
+	}
+	access, err := h.app.Services.AuthorizePipe(bookmark.PipeID, userId, models.PipeRoleViewer)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return bookmark, access, false
+	}
+	return bookmark, access, true
+}
+
+// comment retrieves the comment on the bookmark a request is about, aborting the request when
+// the comment can't be found
+func (h commentHandler) comment(c *gin.Context, bookmark models.Bookmark) (models.BookmarkComment, bool) {
+	commentId, err := strconv.ParseInt(c.Param("commentId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid comment ID",
+		})
+		return models.BookmarkComment{}, false
+	}
+	comment, err := h.app.Repositories.Comment.GetComment(commentId)
+	if err == nil && comment.BookmarkID != bookmark.ID {
+		err = postgres.ErrNoRecord
+	}
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Comment not found",
+			})
+			return comment, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve comment",
+		})
+		return comment, false
+	}
+	return comment, true
+}
+
+// commentBody reads the body of a comment from a request, aborting the request when it's empty or too long
+func commentBody(c *gin.Context) (string, bool) {
+	req := struct {
+		Body string `json:"body" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *body* of the comment",
+		})
+		return "", false
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" || utf8.RuneCountInString(body) > models.MaxCommentLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("A comment must hold between 1 and %d characters", models.MaxCommentLength),
+		})
+		return "", false
+	}
+	return body, true
+}
+
+// GetComments lists the comments on a bookmark, from the oldest
+func (h commentHandler) GetComments(c *gin.Context) {
+	bookmark, _, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	comments, pagination, err := h.app.Repositories.Comment.GetComments(bookmark.ID, page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve comments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comments fetched successfully",
+		"data": map[string]interface{}{
+			"comments":   comments,
+			"pagination": pagination,
+		},
+	})
+}
+
+// CreateComment adds a comment to a bookmark, or a reply to one of its comments, and notifies
+// the users it mentions
+func (h commentHandler) CreateComment(c *gin.Context) {
+	bookmark, _, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+	// replies are posted to the comment they reply to
+	var parentId *int64
+	if parent := c.Param("commentId"); parent != "" {
+		id, err := strconv.ParseInt(parent, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid comment ID",
+			})
+			return
+		}
+		parentId = &id
+	}
+	body, ok := commentBody(c)
+	if !ok {
+		return
+	}
+
+	comment, err := h.app.Repositories.Comment.CreateComment(models.BookmarkComment{
+		BookmarkID: bookmark.ID,
+		UserID:     c.GetInt64(middlewares.KeyUserId),
+		ParentID:   parentId,
+		Body:       body,
+	})
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "The comment you are replying to was not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to add comment",
+		})
+		return
+	}
+	h.app.Services.NotifyCommentMentions(bookmark, comment, "")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Comment added successfully",
+		"data": map[string]interface{}{
+			"comment": comment,
+		},
+	})
+}
+
+// UpdateComment changes the body of a comment written by the user and notifies the users
+// mentioned for the first time
+func (h commentHandler) UpdateComment(c *gin.Context) {
+	bookmark, _, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+	comment, ok := h.comment(c, bookmark)
+	if !ok {
+		return
+	}
+	userId := c.GetInt64(middlewares.KeyUserId)
+	if comment.UserID != userId {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "You can only edit your own comments",
+		})
+		return
+	}
+	body, ok := commentBody(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.app.Repositories.Comment.UpdateComment(comment.ID, userId, body)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Comment not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to update comment",
+		})
+		return
+	}
+	h.app.Services.NotifyCommentMentions(bookmark, updated, comment.Body)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment updated successfully",
+		"data": map[string]interface{}{
+			"comment": updated,
+		},
+	})
+}
+
+// DeleteComment deletes a comment. Users delete their own comments, while the owners and
+// co-owners of a pipe moderate the comments on its bookmarks
+func (h commentHandler) DeleteComment(c *gin.Context) {
+	bookmark, access, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+	comment, ok := h.comment(c, bookmark)
+	if !ok {
+		return
+	}
+	if comment.UserID != c.GetInt64(middlewares.KeyUserId) && !access.Allows(models.PipeRoleCoOwner) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "Only the author of a comment or the owner of the pipe can delete it",
+		})
+		return
+	}
+
+	if err := h.app.Repositories.Comment.DeleteComment(comment.ID); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Comment not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to delete comment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment deleted successfully",
+	})
+}
+
+// GetReactions counts the reactions to a bookmark by emoji
+func (h commentHandler) GetReactions(c *gin.Context) {
+	bookmark, _, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+	reactions, err := h.app.Repositories.Comment.GetReactions(bookmark.ID, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve reactions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reactions fetched successfully",
+		"data": map[string]interface{}{
+			"reactions": reactions,
+		},
+	})
+}
+
+// AddReaction makes the user react to a bookmark with an emoji
+func (h commentHandler) AddReaction(c *gin.Context) {
+	bookmark, _, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+	req := struct {
+		Emoji string `json:"emoji" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidReaction(req.Emoji) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please react with an *emoji*",
+		})
+		return
+	}
+
+	userId := c.GetInt64(middlewares.KeyUserId)
+	if err := h.app.Repositories.Comment.AddReaction(bookmark.ID, userId, req.Emoji); err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to add reaction",
+		})
+		return
+	}
+	reactions, err := h.app.Repositories.Comment.GetReactions(bookmark.ID, userId)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve reactions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reaction added successfully",
+		"data": map[string]interface{}{
+			"reactions": reactions,
+		},
+	})
+}
+
+// RemoveReaction takes back a reaction of the user to a bookmark
+func (h commentHandler) RemoveReaction(c *gin.Context) {
+	bookmark, _, ok := h.bookmark(c)
+	if !ok {
+		return
+	}
+
+	userId := c.GetInt64(middlewares.KeyUserId)
+	if err := h.app.Repositories.Comment.RemoveReaction(bookmark.ID, userId, c.Param("emoji")); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "You haven't reacted with this emoji",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to remove reaction",
+		})
+		return
+	}
+	reactions, err := h.app.Repositories.Comment.GetReactions(bookmark.ID, userId)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve reactions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reaction removed successfully",
+		"data": map[string]interface{}{
+			"reactions": reactions,
+		},
+	})
+}
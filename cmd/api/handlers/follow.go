@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+)
+
+type FollowHandler interface {
+	FollowPipe(c *gin.Context)
+	UpdatePipeFollow(c *gin.Context)
+	UnfollowPipe(c *gin.Context)
+	GetFollowedPipes(c *gin.Context)
+	GetFollowingFeed(c *gin.Context)
+	FollowUser(c *gin.Context)
+	UnfollowUser(c *gin.Context)
+	BlockUser(c *gin.Context)
+	UnblockUser(c *gin.Context)
+	GetFollowers(c *gin.Context)
+	GetFollowing(c *gin.Context)
+	GetBlockedUsers(c *gin.Context)
+	GetActivityFeed(c *gin.Context)
+}
+
+type followHandler struct {
+	app internal.Application
+}
+
+func NewFollowHandler(app internal.Application) FollowHandler {
+	return followHandler{app: app}
+}
+
+// followRequest reads the pipe and the way to hear about its bookmarks a follow request is about.
+// notify is optional unless required is set
+func (h followHandler) followRequest(c *gin.Context, required bool) (int64, string, bool) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return 0, "", false
+	}
+	req := struct {
+		Notify string `json:"notify"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return 0, "", false
+		}
+	}
+	if (required || req.Notify != "") && !models.ValidFollowNotify(req.Notify) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid notify. valid values are: *instant*, *digest* and *mute*",
+		})
+		return 0, "", false
+	}
+	return pipeId, req.Notify, true
+}
+
+// FollowPipe makes the user follow a pipe they can see, or changes how they hear
+// about its bookmarks when they already follow it
+func (h followHandler) FollowPipe(c *gin.Context) {
+	pipeId, notify, ok := h.followRequest(c, false)
+	if !ok {
+		return
+	}
+	follow, err := h.app.Repositories.Follow.FollowPipe(c.GetInt64(middlewares.KeyUserId), pipeId, notify)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found or it can't be followed",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to follow this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe followed successfully",
+		"data": map[string]interface{}{
+			"follow": follow,
+		},
+	})
+}
+
+// UpdatePipeFollow changes how the user hears about the bookmarks of a pipe they follow
+func (h followHandler) UpdatePipeFollow(c *gin.Context) {
+	pipeId, notify, ok := h.followRequest(c, true)
+	if !ok {
+		return
+	}
+	follow, err := h.app.Repositories.Follow.UpdatePipeFollow(c.GetInt64(middlewares.KeyUserId), pipeId, notify)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "You don't follow this pipe",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to update this follow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Follow updated successfully",
+		"data": map[string]interface{}{
+			"follow": follow,
+		},
+	})
+}
+
+// UnfollowPipe stops the user from following a pipe
+func (h followHandler) UnfollowPipe(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+	if err := h.app.Repositories.Follow.UnfollowPipe(c.GetInt64(middlewares.KeyUserId), pipeId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "You don't follow this pipe",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to unfollow this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe unfollowed successfully",
+	})
+}
+
+// GetFollowedPipes lists the pipes the user follows
+func (h followHandler) GetFollowedPipes(c *gin.Context) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	pipes, pagination, err := h.app.Repositories.Follow.GetFollowedPipes(c.GetInt64(middlewares.KeyUserId), page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve the pipes you follow",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Followed pipes fetched successfully",
+		"data": map[string]interface{}{
+			"pipes":      pipes,
+			"pagination": pagination,
+		},
+	})
+}
+
+// GetFollowingFeed lists the bookmarks recently added to the pipes the user follows
+func (h followHandler) GetFollowingFeed(c *gin.Context) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	bookmarks, pagination, err := h.app.Repositories.Follow.GetFollowingFeed(c.GetInt64(middlewares.KeyUserId), page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve your feed",
+		})
+		return
+	}
+	for i := range bookmarks {
+		// don't really care if there's any error for now
+		bookmarks[i], _ = h.app.Repositories.Bookmark.ParseTags(bookmarks[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Feed fetched successfully",
+		"data": map[string]interface{}{
+			"bookmarks":  bookmarks,
+			"pagination": pagination,
+		},
+	})
+}
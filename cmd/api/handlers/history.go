@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+)
+
+type HistoryHandler interface {
+	GetHistory(c *gin.Context)
+	GetPipeHistory(c *gin.Context)
+	GetBookmarkHistory(c *gin.Context)
+	Undo(c *gin.Context)
+}
+
+type historyHandler struct {
+	app internal.Application
+}
+
+func NewHistoryHandler(app internal.Application) HistoryHandler {
+	return historyHandler{app: app}
+}
+
+func (h historyHandler) GetHistory(c *gin.Context) {
+	h.getHistory(c, models.HistoryFilter{})
+}
+
+func (h historyHandler) GetPipeHistory(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+	h.getHistory(c, models.HistoryFilter{EntityType: models.HistoryEntityPipe, EntityID: pipeId})
+}
+
+func (h historyHandler) GetBookmarkHistory(c *gin.Context) {
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return
+	}
+	h.getHistory(c, models.HistoryFilter{EntityType: models.HistoryEntityBookmark, EntityID: bmId})
+}
+
+// getHistory responds with a page of the history of the user, narrowed down by filter
+func (h historyHandler) getHistory(c *gin.Context, filter models.HistoryFilter) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	filter.Filter = page
+
+	operations, pagination, err := h.app.Repositories.History.GetHistory(c.GetInt64(middlewares.KeyUserId), filter)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to fetch history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "History fetched successfully",
+		"data": map[string]interface{}{
+			"history":    operations,
+			"pagination": pagination,
+		},
+	})
+}
+
+func (h historyHandler) Undo(c *gin.Context) {
+	req := struct {
+		Count int `json:"count"`
+	}{}
+	// the body is optional; a missing count undoes the last operation
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid request body",
+			})
+			return
+		}
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 || req.Count > models.MaxUndoCount {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("count must be between 1 and %d", models.MaxUndoCount),
+		})
+		return
+	}
+
+	operations, err := h.app.Repositories.History.Undo(c.GetInt64(middlewares.KeyUserId), req.Count)
+	if err != nil {
+		if err == postgres.ErrRecordExists {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "A pipe can not be renamed back because another pipe already has its old name",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to undo changes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%d operation(s) undone successfully", len(operations)),
+		"data": map[string]interface{}{
+			"undone": operations,
+		},
+	})
+}
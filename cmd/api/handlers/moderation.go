@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxReportDetailsLength is the number of characters users can explain a report with
+const maxReportDetailsLength = 1000
+
+type ModerationHandler interface {
+	CreateReport(c *gin.Context)
+	GetReports(c *gin.Context)
+	GetReport(c *gin.Context)
+	ResolveReport(c *gin.Context)
+	UnhidePipe(c *gin.Context)
+	UnsuspendUser(c *gin.Context)
+}
+
+type moderationHandler struct {
+	app internal.Application
+}
+
+func NewModerationHandler(app internal.Application) ModerationHandler {
+	return moderationHandler{app: app}
+}
+
+// CreateReport reports a pipe, a bookmark or a comment the user can see, or another user, to moderators
+func (h moderationHandler) CreateReport(c *gin.Context) {
+	req := struct {
+		TargetType string `json:"target_type" binding:"required"`
+		TargetID   int64  `json:"target_id" binding:"required"`
+		Reason     string `json:"reason" binding:"required"`
+		Details    string `json:"details"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *target_type*, the *target_id* and the *reason* of the report",
+		})
+		return
+	}
+	if !models.ValidReportTarget(req.TargetType) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid target type. valid types are: *pipe*, *bookmark*, *comment* and *user*",
+		})
+		return
+	}
+	if !models.ValidReportReason(req.Reason) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid reason. valid reasons are: *spam*, *harassment*, *inappropriate* and *other*",
+		})
+		return
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > maxReportDetailsLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("The details of a report can not be longer than %d characters", maxReportDetailsLength),
+		})
+		return
+	}
+
+	report, err := h.app.Repositories.Moderation.CreateReport(models.Report{
+		ReporterID: c.GetInt64(middlewares.KeyUserId),
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		Details:    details,
+	})
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "There's nothing you can report with this type and id",
+			})
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "You already reported this. Our moderators will look into it",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to create report",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Report sent successfully",
+		"data": map[string]interface{}{
+			"report": report,
+		},
+	})
+}
+
+// GetReports lists the reports with a status, which are the open ones by default, from the oldest
+func (h moderationHandler) GetReports(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReportStatusOpen)
+	if !models.ValidReportStatus(status) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid status. valid statuses are: *open*, *resolved* and *dismissed*",
+		})
+		return
+	}
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	reports, pagination, err := h.app.Repositories.Moderation.GetReports(status, page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve reports",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reports fetched successfully",
+		"data": map[string]interface{}{
+			"reports":    reports,
+			"pagination": pagination,
+		},
+	})
+}
+
+// report retrieves the report a request is about, aborting the request when it can't be found
+func (h moderationHandler) report(c *gin.Context) (models.Report, bool) {
+	reportId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid report ID",
+		})
+		return models.Report{}, false
+	}
+	report, err := h.app.Repositories.Moderation.GetReport(reportId)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Report not found",
+			})
+			return report, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve report",
+		})
+		return report, false
+	}
+	return report, true
+}
+
+// GetReport retrieves a report
+func (h moderationHandler) GetReport(c *gin.Context) {
+	report, ok := h.report(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report fetched successfully",
+		"data": map[string]interface{}{
+			"report": report,
+		},
+	})
+}
+
+// ResolveReport takes an action on what an open report is about, which closes every open report on it
+func (h moderationHandler) ResolveReport(c *gin.Context) {
+	report, ok := h.report(c)
+	if !ok {
+		return
+	}
+	req := struct {
+		Action string `json:"action" binding:"required"`
+		Note   string `json:"note"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *action* to take",
+		})
+		return
+	}
+	if !models.ValidModerationAction(report.TargetType, req.Action) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "This action can't be taken on a " + report.TargetType,
+		})
+		return
+	}
+	if report.Status != models.ReportStatusOpen {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"message": "This report has already been closed",
+		})
+		return
+	}
+
+	report, err := h.app.Services.ModerateReport(report, c.GetInt64(middlewares.KeyUserId), req.Action, strings.TrimSpace(req.Note))
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to resolve report",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report closed successfully",
+		"data": map[string]interface{}{
+			"report": report,
+		},
+	})
+}
+
+// UnhidePipe lets the owner of a pipe moderators hid publish it again
+func (h moderationHandler) UnhidePipe(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+	if err := h.app.Repositories.Moderation.UnhidePipe(pipeId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found or it's not hidden",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to unhide pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe unhidden successfully",
+	})
+}
+
+// UnsuspendUser lifts the suspension of a user
+func (h moderationHandler) UnsuspendUser(c *gin.Context) {
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid user ID",
+		})
+		return
+	}
+	if err := h.app.Repositories.Moderation.UnsuspendUser(userId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "User not found or they are not suspended",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to lift the suspension",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Suspension lifted successfully",
+	})
+}
+
+// pipeHidden reports whether moderators hid a pipe, aborting the request when they did or when it
+// can't be told. Hidden pipes can't be made public or unlisted, or shared through a public link
+func pipeHidden(app internal.Application, c *gin.Context, pipeId int64) bool {
+	hidden, err := app.Repositories.Moderation.IsPipeHidden(pipeId)
+	if err != nil {
+		app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve pipe",
+		})
+		return true
+	}
+	if hidden {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"message": "This pipe was hidden by moderators and can't be published",
+		})
+		return true
+	}
+	return false
+}
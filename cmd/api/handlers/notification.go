@@ -74,8 +74,21 @@ func (h notificationHandler) GetNotification(c *gin.Context) {
 
 func (h notificationHandler) GetNotifications(c *gin.Context) {
 	userId := c.GetInt64(middlewares.KeyUserId)
-	notifications, err := h.app.Repositories.Notification.GetNotifications(userId)
+	filter, err := pageFilter(c)
 	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	notifications, pagination, err := h.app.Repositories.Notification.GetNotifications(userId, filter)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "Could not retrieve notifications! Please try again soon",
 		})
@@ -86,6 +99,7 @@ func (h notificationHandler) GetNotifications(c *gin.Context) {
 		"message": "Notifications fetched",
 		"data": map[string]interface{}{
 			"notifications": notifications,
+			"pagination":    pagination,
 		},
 	})
 }
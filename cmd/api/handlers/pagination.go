@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"strconv"
+)
+
+// pageFilter reads the pagination options of a list from the limit and cursor query params
+func pageFilter(c *gin.Context) (models.Filter, error) {
+	filter := models.Filter{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			return filter, fmt.Errorf("limit must be a positive number")
+		}
+		filter.Limit = parsed
+	}
+	return filter, nil
+}
@@ -23,6 +23,11 @@ type PipeHandler interface {
 	UpdatePipe(c *gin.Context)
 	DeletePipe(c *gin.Context)
 	GetPipes(c *gin.Context)
+	MovePipes(c *gin.Context)
+	NestPipe(c *gin.Context)
+	ForkPipe(c *gin.Context)
+	MergePipes(c *gin.Context)
+	SplitPipe(c *gin.Context)
 }
 
 type pipeHandler struct {
@@ -35,7 +40,14 @@ func NewPipeHandler(app internal.Application) PipeHandler {
 
 func (h pipeHandler) CreatePipe(c *gin.Context) {
 	req := struct {
-		Name string `form:"name" json:"name" binding:"required"`
+		Name        string             `form:"name" json:"name" binding:"required"`
+		ParentID    *int64             `form:"parent_id" json:"parent_id"`
+		Type        string             `form:"type" json:"type"`
+		Query       *models.SmartQuery `form:"-" json:"query"`
+		Description string             `form:"description" json:"description"`
+		Icon        string             `form:"icon" json:"icon"`
+		Color       string             `form:"color" json:"color"`
+		Visibility  string             `form:"visibility" json:"visibility"`
 	}{}
 
 	if err := c.Bind(&req); err != nil {
@@ -46,10 +58,57 @@ func (h pipeHandler) CreatePipe(c *gin.Context) {
 		return
 	}
 	authenticatedUser := middlewares.GetLoggedInUser(c)
+	pipe := models.Pipe{
+		UserID:      authenticatedUser.ID,
+		ParentID:    req.ParentID,
+		Name:        strings.TrimSpace(strings.ToLower(req.Name)),
+		DisplayName: strings.TrimSpace(req.Name),
+		Type:        req.Type,
+		Query:       req.Query,
+		Description: strings.TrimSpace(req.Description),
+		Icon:        strings.TrimSpace(req.Icon),
+		Color:       strings.TrimSpace(req.Color),
+		Visibility:  req.Visibility,
+	}
+	if err := h.app.Services.ValidatePipeMetadata(pipe); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	switch req.Type {
+	case "", models.PipeTypeStandard:
+		if req.Query != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Only smart pipes can have a query",
+			})
+			return
+		}
+	case models.PipeTypeSmart:
+		if err := h.app.Services.ValidateSmartQuery(req.Query, authenticatedUser.ID); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+	default:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid type. valid pipe types are: *standard* and *smart*",
+		})
+		return
+	}
+	if req.ParentID != nil {
+		if _, err := h.app.Services.UserOwnsPipe(*req.ParentID, authenticatedUser.ID); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 
 	// TODO: refine db call to remove extra step of first checking if pipe
 	//		 already exists
-	pipeAlreadyExists, err := h.app.Repositories.Pipe.PipeAlreadyExists(req.Name, authenticatedUser.ID)
+	pipeAlreadyExists, err := h.app.Repositories.Pipe.PipeAlreadyExists(pipe.Name, authenticatedUser.ID)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 			"message": "An error occurred during validation",
@@ -95,12 +154,7 @@ func (h pipeHandler) CreatePipe(c *gin.Context) {
 		}
 	}
 
-	pipe := models.Pipe{
-		UserID:     authenticatedUser.ID,
-		Name:       strings.TrimSpace(strings.ToLower(req.Name)),
-		CoverPhoto: photoUrl,
-	}
-
+	pipe.CoverPhoto = photoUrl
 	pipe, err = h.app.Repositories.Pipe.CreatePipe(pipe)
 	if err != nil {
 		h.app.Logger.Err(err).Msg(err.Error())
@@ -122,6 +176,11 @@ func (h pipeHandler) CreatePipe(c *gin.Context) {
 		})
 		return
 	}
+	h.app.Services.RecordActivity(models.Activity{
+		ActorID: pipe.UserID,
+		Verb:    models.ActivityPipeCreated,
+		PipeID:  pipe.ID,
+	})
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Pipe created successfully",
@@ -168,8 +227,28 @@ func (h pipeHandler) GetPipe(c *gin.Context) {
 
 func (h pipeHandler) GetPipes(c *gin.Context) {
 	userID := c.GetInt64(middlewares.KeyUserId)
-	pipes, err := h.app.Repositories.Pipe.GetPipes(userID)
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	filter := models.PipeFilter{Filter: page, Sort: c.Query("sort"), Tree: c.Query("tree") == "true"}
+	if !models.ValidPipeSort(filter.Sort) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid sort. valid sort options are: *manual*, *newest*, *oldest* and *title*",
+		})
+		return
+	}
+	pipes, pagination, err := h.app.Repositories.Pipe.GetPipes(userID, filter)
 	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
 		h.app.Logger.Err(err).Msg(err.Error())
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "An error occurred while trying to fetch pipes",
@@ -182,13 +261,21 @@ func (h pipeHandler) GetPipes(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "pipes fetched successfully",
 		"data": map[string]interface{}{
-			"pipes": pipes,
+			"pipes":      pipes,
+			"pagination": pagination,
 		},
 	})
 }
 func (h pipeHandler) UpdatePipe(c *gin.Context) {
 	req := struct {
-		Name string `form:"name" json:"name,omitempty"`
+		Name        string             `form:"name" json:"name,omitempty"`
+		Query       *models.SmartQuery `form:"-" json:"query,omitempty"`
+		Description *string            `form:"description" json:"description,omitempty"`
+		Icon        *string            `form:"icon" json:"icon,omitempty"`
+		Color       *string            `form:"color" json:"color,omitempty"`
+		Visibility  string             `form:"visibility" json:"visibility,omitempty"`
+		// SyncUpstream can only be switched on for forks
+		SyncUpstream *bool `form:"sync_upstream" json:"sync_upstream,omitempty"`
 	}{}
 
 	if err := c.ShouldBind(&req); err != nil {
@@ -211,7 +298,22 @@ func (h pipeHandler) UpdatePipe(c *gin.Context) {
 		return
 	}
 
-	pipe, err = h.app.Repositories.Pipe.GetPipe(pipeId, c.GetInt64(middlewares.KeyUserId))
+	// editors update the pipe on behalf of its owner
+	access, err := h.app.Services.AuthorizePipe(pipeId, c.GetInt64(middlewares.KeyUserId), models.PipeRoleEditor)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// who can see the pipe and where its bookmarks come from are only decided by its owner and co-owners
+	if (req.Visibility != "" || req.SyncUpstream != nil) && !access.Allows(models.PipeRoleCoOwner) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": fmt.Sprintf("you need the *%s* role or a higher one on this pipe to change its visibility or syncing", models.PipeRoleCoOwner),
+		})
+		return
+	}
+	pipe, err = h.app.Repositories.Pipe.GetPipe(pipeId, access.OwnerID)
 	if err != nil {
 		if err == postgres.ErrNoRecord {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
@@ -240,6 +342,22 @@ func (h pipeHandler) UpdatePipe(c *gin.Context) {
 	}
 
 	_ = json.Unmarshal(updatedBodyBytes, &pipe)
+	if req.Query != nil {
+		// a new query replaces the definition of the pipe instead of being merged into it
+		if pipe.Type != models.PipeTypeSmart {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Only smart pipes can have a query",
+			})
+			return
+		}
+		if err := h.app.Services.ValidateSmartQuery(req.Query, access.OwnerID); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		pipe.Query = req.Query
+	}
 
 	file, _, err := c.Request.FormFile("cover_photo")
 	if err != nil {
@@ -272,8 +390,23 @@ func (h pipeHandler) UpdatePipe(c *gin.Context) {
 		pipe.CoverPhoto = photoUrl
 	}
 
+	if req.Name != "" {
+		pipe.DisplayName = strings.TrimSpace(req.Name)
+	}
 	pipe.Name = strings.TrimSpace(strings.ToLower(pipe.Name))
-	pipe, err = h.app.Repositories.Pipe.UpdatePipe(c.GetInt64(middlewares.KeyUserId), pipeId, pipe)
+	pipe.Description = strings.TrimSpace(pipe.Description)
+	pipe.Icon = strings.TrimSpace(pipe.Icon)
+	pipe.Color = strings.TrimSpace(pipe.Color)
+	if err := h.app.Services.ValidatePipeMetadata(pipe); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if req.Visibility != "" && req.Visibility != models.PipeVisibilityPrivate && pipeHidden(h.app, c, pipeId) {
+		return
+	}
+	pipe, err = h.app.Repositories.Pipe.UpdatePipe(access.OwnerID, c.GetInt64(middlewares.KeyUserId), pipeId, pipe)
 	if err != nil {
 		if err == postgres.ErrRecordExists {
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
@@ -299,6 +432,93 @@ func (h pipeHandler) UpdatePipe(c *gin.Context) {
 
 }
 
+func (h pipeHandler) MovePipes(c *gin.Context) {
+	req := struct {
+		Moves []models.PositionMove `json:"moves" binding:"required,dive"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *moves* to make",
+		})
+		return
+	}
+
+	userID := c.GetInt64(middlewares.KeyUserId)
+	if err := h.app.Repositories.Pipe.MovePipes(userID, req.Moves); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "One or more of the pipes could not be found in your collection",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to reorder pipes",
+		})
+		return
+	}
+
+	pipes, pagination, err := h.app.Repositories.Pipe.GetPipes(userID, models.PipeFilter{Sort: models.SortManual})
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipes reordered successfully",
+		"data": map[string]interface{}{
+			"pipes":      pipes,
+			"pagination": pagination,
+		},
+	})
+}
+
+func (h pipeHandler) NestPipe(c *gin.Context) {
+	req := struct {
+		ParentID *int64 `json:"parent_id"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+
+	pipe, err := h.app.Repositories.Pipe.NestPipe(c.GetInt64(middlewares.KeyUserId), pipeId, req.ParentID)
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe or parent pipe not found in your collection",
+			})
+		case postgres.ErrPipeCycle:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "A pipe can not be moved into itself or into one of its own nested pipes",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to move pipe",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe moved successfully",
+		"data": map[string]interface{}{
+			"pipe": pipe,
+		},
+	})
+}
+
 func (h pipeHandler) DeletePipe(c *gin.Context) {
 	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -310,6 +530,12 @@ func (h pipeHandler) DeletePipe(c *gin.Context) {
 	}
 	_, err = h.app.Repositories.Pipe.DeletePipe(c.GetInt64(middlewares.KeyUserId), pipeId)
 	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found",
+			})
+			return
+		}
 		h.app.Logger.Err(err).Msg(err.Error())
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "An error occurred while trying to delete pipe!",
@@ -318,7 +544,187 @@ func (h pipeHandler) DeletePipe(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Pipe deleted successfully",
+		"message": "Pipe moved to trash successfully",
+	})
+
+}
+
+func (h pipeHandler) ForkPipe(c *gin.Context) {
+	req := struct {
+		Name         string `json:"name"`
+		SyncUpstream bool   `json:"sync_upstream"`
+	}{}
+	// the body is optional; a fork without a name takes the name of the forked pipe
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid request body",
+			})
+			return
+		}
+	}
+
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+
+	fork := models.PipeFork{
+		Name:         strings.TrimSpace(strings.ToLower(req.Name)),
+		DisplayName:  strings.TrimSpace(req.Name),
+		SyncUpstream: req.SyncUpstream,
+	}
+	pipe, err := h.app.Repositories.Pipe.ForkPipe(c.GetInt64(middlewares.KeyUserId), pipeId, fork)
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found",
+			})
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "You already have a pipe with this name. Please give the fork another *name*",
+			})
+		case postgres.ErrForkNotAllowed:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to fork pipe",
+			})
+		}
+		return
+	}
+	h.app.Services.RecordActivity(models.Activity{
+		ActorID:      pipe.UserID,
+		Verb:         models.ActivityPipeForked,
+		PipeID:       pipe.ID,
+		SourcePipeID: pipe.ForkedFrom,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Pipe forked successfully",
+		"data": map[string]interface{}{
+			"pipe": pipe,
+		},
+	})
+}
+
+func (h pipeHandler) MergePipes(c *gin.Context) {
+	req := struct {
+		PipeIDs   []int64 `json:"pipe_ids" binding:"required,min=1"`
+		CoverFrom int64   `json:"cover_from"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *pipe_ids* of the pipes to merge",
+		})
+		return
+	}
+
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+	for _, id := range req.PipeIDs {
+		if id == pipeId {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "A pipe can not be merged into itself",
+			})
+			return
+		}
+	}
+
+	merge := models.PipeMerge{SourceIDs: req.PipeIDs, CoverFromID: req.CoverFrom}
+	pipe, err := h.app.Repositories.Pipe.MergePipes(c.GetInt64(middlewares.KeyUserId), pipeId, merge)
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "One or more of the pipes could not be found in your collection",
+			})
+		case postgres.ErrSmartPipe:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Smart pipes can not be merged",
+			})
+		case postgres.ErrPipeCycle:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "A pipe can not be merged into one of its own nested pipes",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to merge pipes",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipes merged successfully",
+		"data": map[string]interface{}{
+			"pipe": pipe,
+		},
 	})
+}
 
+func (h pipeHandler) SplitPipe(c *gin.Context) {
+	req := struct {
+		By     string   `json:"by" binding:"required"`
+		Values []string `json:"values"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.By != models.PipeSplitByTag && req.By != models.PipeSplitByPlatform) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify what to split the pipe *by*. valid options are: *tag* and *platform*",
+		})
+		return
+	}
+
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+
+	split := models.PipeSplit{By: req.By, Values: req.Values}
+	pipes, err := h.app.Repositories.Pipe.SplitPipe(c.GetInt64(middlewares.KeyUserId), pipeId, split)
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found",
+			})
+		case postgres.ErrSmartPipe:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Smart pipes can not be split",
+			})
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "You already have a pipe with the name of one of the new pipes",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to split pipe",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Pipe split into %d pipe(s) successfully", len(pipes)),
+		"data": map[string]interface{}{
+			"pipes": pipes,
+		},
+	})
 }
@@ -6,17 +6,31 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
 	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/cmd/api/services"
 	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
 	"github.com/mypipeapp/mypipeapi/db/models"
 	"net/http"
+	"net/mail"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type PipeShareHandler interface {
 	SharePipe(c *gin.Context)
 	PreviewPipe(c *gin.Context)
 	AddPipe(c *gin.Context)
+	UpdateCollaboratorRole(c *gin.Context)
+	GetPipeShares(c *gin.Context)
+	RemoveShareAccessFromPipe(c *gin.Context)
+	LeavePipe(c *gin.Context)
+	RotatePublicShareCode(c *gin.Context)
+	DisablePublicShare(c *gin.Context)
+	GetReceivedShares(c *gin.Context)
+	AcceptShare(c *gin.Context)
+	DeclineShare(c *gin.Context)
+	OpenSharedBookmark(c *gin.Context)
+	GetShareLinkStats(c *gin.Context)
 }
 
 type pipeShareHandler struct {
@@ -39,6 +53,12 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 	// Validate inputs integrity
 	req := struct {
 		Username string `form:"username" json:"username"`
+		// Email shares a private pipe with the account of an email, or invites the email to sign up
+		Email string `form:"email" json:"email"`
+		// Role is the role of the receiver of a private share, who is a viewer by default
+		Role string `form:"role" json:"role"`
+		// the limits of a public share link, which replace the limits of an existing link
+		models.ShareLinkSettings
 	}{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
@@ -51,11 +71,18 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 	sharerId := c.GetInt64(middlewares.KeyUserId)
 	id, _ := strconv.Atoi(c.Param("id"))
 	pipeId := int64(id)
-	_, err := h.app.Repositories.Pipe.GetPipe(pipeId, sharerId)
+	// co-owners share the pipe on behalf of its owner
+	access, err := h.app.Services.AuthorizePipe(pipeId, sharerId, models.PipeRoleCoOwner)
 	if err != nil {
 		h.app.Logger.Err(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"message": "Invalid pipe ID",
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if req.Role != "" && !models.ValidPipeRole(req.Role) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid role. valid roles are: *viewer*, *contributor*, *editor* and *co-owner*",
 		})
 		return
 	}
@@ -66,16 +93,28 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 			Algorithm used for public pipe share logic
 			---------------------------------------------------------------------
 			Check if the user has previously shared this pipe publicly
-			---| If they have, return the code for the previous pipe share record
+			---| If they have, return the code for the previous pipe share record, with the new limits if any were given
 			---| If they haven't, create another record for a public pipe share record and return the code
 		*/
+		if pipeHidden(h.app, c, pipeId) {
+			return
+		}
+		if err := h.app.Services.ValidateShareLinkSettings(req.ShareLinkSettings); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
 		var publicPipeShareRecord models.SharedPipe
 		publicPipeShareRecord, err := h.app.Repositories.PipeShare.GetSharedPipe(pipeId, models.PipeShareTypePublic)
+		if err == nil && !req.ShareLinkSettings.IsEmpty() {
+			publicPipeShareRecord, err = h.app.Services.UpdateShareLinkSettings(publicPipeShareRecord, req.ShareLinkSettings)
+		}
 		if err != nil {
 			if err == postgres.ErrNoRecord {
 				// This means no public pipe share record was found for this pipe
 				// We can proceed to create a new public pipe share record at this point
-				publicPipeShareRecord, err = h.app.Services.SharePipePublicly(pipeId, c.GetInt64(middlewares.KeyUserId))
+				publicPipeShareRecord, err = h.app.Services.SharePipePublicly(pipeId, access.OwnerID, req.ShareLinkSettings)
 				if err != nil {
 					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 						"message": "Our system encountered an error while trying to create a public share link",
@@ -99,6 +138,7 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 			"message": "Public pipe share link generated successfully",
 			"data": map[string]interface{}{
 				"share_code": publicPipeShareRecord.Code,
+				"share_link": publicPipeShareRecord,
 			},
 		})
 		return
@@ -116,20 +156,57 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 			Check if this pipe has already been shared with the designated req.Username privately prior to now
 			---| If it has, return an error message
 			---| If it hasn't, create a shared_pipe_receivers record for the designated req.Username
+
+			A pipe shared with an email is shared with the account of the email. When there's no such
+			account, the email is invited instead and the share is attached to the account later
 		*/
-		if strings.TrimSpace(req.Username) == "" {
+		var receiver models.User
+		var err error
+		switch {
+		case strings.TrimSpace(req.Username) != "":
+			receiver, err = h.app.Repositories.User.GetUserByUsername(req.Username)
+		case strings.TrimSpace(req.Email) != "":
+			if _, err := mail.ParseAddress(req.Email); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"message": "Please specify a valid email address",
+				})
+				return
+			}
+			receiver, err = h.app.Repositories.User.GetUserByEmail(strings.TrimSpace(req.Email))
+			if err == postgres.ErrNoRecord {
+				h.inviteByEmail(c, pipeId, access.OwnerID, sharerId, strings.TrimSpace(req.Email), req.Role)
+				return
+			}
+		default:
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 				"message": "For private pipe share, please specify with whom you want to share the pipe with",
 			})
 			return
 		}
-		receiver, err := h.app.Repositories.User.GetUserByUsername(req.Username)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 				"message": "User not found",
 			})
 			return
 		}
+		// users who blocked the sharer or the owner the sharer shares on behalf of don't receive their pipes
+		blocked, err := h.app.Repositories.Follow.IsBlocked(receiver.ID, sharerId)
+		if err == nil && !blocked && access.OwnerID != sharerId {
+			blocked, err = h.app.Repositories.Follow.IsBlocked(receiver.ID, access.OwnerID)
+		}
+		if err != nil {
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Our system encountered an error while trying to create a private share",
+			})
+			return
+		}
+		if blocked {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "You can't share pipes with this user",
+			})
+			return
+		}
 		var pipeHasPublicShareRecord bool
 		publicPipeShareRecord, err := h.app.Repositories.PipeShare.GetSharedPipe(pipeId, models.PipeShareTypePublic)
 		if err != nil {
@@ -158,6 +235,7 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 						"message": "Our system encountered an error while trying to create a private share",
 						"err":     err.Error(),
 					})
+					return
 				}
 			}
 			if userHasPreviouslyReceivedPipeThroughPublicShareLink {
@@ -169,21 +247,22 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 		}
 
 		// Check if this pipe has already been shared with the designated req.Username privately prior to now
-		_, err = h.app.Repositories.PipeShare.GetReceivedPipeRecord(pipeId, receiver.ID)
+		receiverRecord, err := h.app.Repositories.PipeShare.GetReceivedPipeRecord(pipeId, receiver.ID)
 		if err != nil {
 			if err == postgres.ErrNoRecord {
 				// This means this pipe has not been shared privately with this user before now
 				// Go ahead to create a new private share record
 				newPrivatePipeShareRecord := models.SharedPipe{
 					PipeID:   pipeId,
-					SharerID: c.GetInt64(middlewares.KeyUserId),
+					SharerID: access.OwnerID,
 				}
-				newPrivatePipeShareRecord, err = h.app.Services.SharePipePrivately(newPrivatePipeShareRecord, receiver.Username)
+				newPrivatePipeShareRecord, err = h.app.Services.SharePipePrivately(newPrivatePipeShareRecord, receiver.Username, req.Role)
 				if err != nil {
 					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 						"message": "Our system encountered an error while trying to create a private share",
 						"err":     err.Error(),
 					})
+					return
 				}
 
 				err = h.app.Services.CreatePrivatePipeShareNotification(newPrivatePipeShareRecord.Code, newPrivatePipeShareRecord.PipeID, newPrivatePipeShareRecord.SharerID, receiver.ID)
@@ -199,7 +278,28 @@ func (h pipeShareHandler) SharePipe(c *gin.Context) {
 					"message": "Our system encountered an error while trying to create a private share",
 					"err":     err.Error(),
 				})
+				return
+			}
+		}
+
+		// An invitation that was declined or has expired can be sent again
+		if status := receiverRecord.Status(time.Now()); status == models.ShareStatusDeclined || status == models.ShareStatusExpired {
+			receiverRecord, err = h.app.Repositories.PipeShare.RenewPrivateShare(receiverRecord, time.Now().Add(models.PrivateShareTTL))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"message": "Our system encountered an error while trying to create a private share",
+					"err":     err.Error(),
+				})
+				return
+			}
+			err = h.app.Services.CreatePrivatePipeShareNotification(receiverRecord.Code, receiverRecord.SharedPipeId, receiverRecord.SharerId, receiver.ID)
+			if err != nil {
+				h.app.Logger.Err(err).Msg("Error occurred during push notification")
 			}
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Pipe has been successfully shared with " + receiver.Username + " again",
+			})
+			return
 		}
 
 		// At this point, it means this pipe has already been shared with req.Username
@@ -236,6 +336,11 @@ func (h pipeShareHandler) PreviewPipe(c *gin.Context) {
 		return
 	}
 
+	if err := h.app.Services.CheckShareLink(pipeToAdd, sharePassword(c)); err != nil {
+		abortWithShareLinkError(c, err)
+		return
+	}
+
 	/*
 		Algorithm used to determine if a user can preview a pipe
 		---------------------------------------------------------------------
@@ -243,7 +348,7 @@ func (h pipeShareHandler) PreviewPipe(c *gin.Context) {
 		only the user it was shared with initially can view it
 	*/
 	if pipeToAdd.Type == models.PipeShareTypePrivate {
-		_, err = h.app.Repositories.PipeShare.GetReceivedPipeRecordByCode(code, authenticatedUser.ID)
+		receiverRecord, err := h.app.Repositories.PipeShare.GetReceivedPipeRecordByCode(code, authenticatedUser.ID)
 		if err != nil {
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -256,7 +361,10 @@ func (h pipeShareHandler) PreviewPipe(c *gin.Context) {
 			})
 			return
 		}
-
+		if err := h.app.Services.CheckShareInvitation(receiverRecord); err != nil {
+			abortWithShareLinkError(c, err)
+			return
+		}
 	}
 
 	// At this point, this pipe can be previewed successfully
@@ -274,6 +382,7 @@ func (h pipeShareHandler) PreviewPipe(c *gin.Context) {
 		})
 		return
 	}
+	h.app.Services.RecordShareLinkEvent(pipeToAdd, models.ShareEventView, nil, shareReferrer(c), c.Request.UserAgent())
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Preview Successful",
@@ -281,6 +390,7 @@ func (h pipeShareHandler) PreviewPipe(c *gin.Context) {
 			"sharer": sharer,
 			"fullPipeData": map[string]interface{}{
 				"pipe":      pipeAndR.Pipe,
+				"children":  pipeAndR.Children,
 				"bookmarks": pipeAndR.Bookmarks,
 			},
 		},
@@ -317,6 +427,10 @@ func (h pipeShareHandler) AddPipe(c *gin.Context) {
 		})
 		return
 	}
+	if err := h.app.Services.CheckShareLink(pipeToAdd, sharePassword(c)); err != nil {
+		abortWithShareLinkError(c, err)
+		return
+	}
 
 	/*
 		Algorithm used for private pipe share logic
@@ -339,12 +453,22 @@ func (h pipeShareHandler) AddPipe(c *gin.Context) {
 		receiverRecord, err := h.app.Repositories.PipeShare.GetReceivedPipeRecord(pipeToAdd.PipeID, c.GetInt64(middlewares.KeyUserId))
 		if err != nil {
 			if err == postgres.ErrNoRecord {
+				// the link may have run out since it was checked
+				if err = h.app.Repositories.PipeShare.RedeemSharedPipe(pipeToAdd.ID); err != nil {
+					if err == postgres.ErrNoRecord {
+						err = services.ErrShareLinkExhausted
+					}
+					abortWithShareLinkError(c, err)
+					return
+				}
 				newReceiverRecord := models.SharedPipeReceiver{
 					IsAccepted:   true,
 					SharedPipeId: pipeToAdd.PipeID,
 					Code:         pipeToAdd.Code,
 					SharerId:     pipeToAdd.SharerID,
 					ReceiverID:   c.GetInt64(middlewares.KeyUserId),
+					// a pipe added through a read-only link can be read but not forked
+					Permission: pipeToAdd.Permission,
 				}
 				newReceiverRecord, err = h.app.Repositories.PipeShare.CreatePipeReceiver(newReceiverRecord)
 				if err != nil {
@@ -354,6 +478,7 @@ func (h pipeShareHandler) AddPipe(c *gin.Context) {
 					})
 					return
 				}
+				h.app.Services.RecordShareLinkEvent(pipeToAdd, models.ShareEventRedemption, nil, shareReferrer(c), c.Request.UserAgent())
 				c.JSON(http.StatusOK, gin.H{
 					"message": "Pipe has been added to your collection successfully",
 				})
@@ -403,6 +528,10 @@ func (h pipeShareHandler) AddPipe(c *gin.Context) {
 			}
 		}
 		if !receiverRecord.IsAccepted {
+			if err := h.app.Services.CheckShareInvitation(receiverRecord); err != nil {
+				abortWithShareLinkError(c, err)
+				return
+			}
 			receiverRecord, err = h.app.Repositories.PipeShare.AcceptPrivateShare(receiverRecord)
 			if err != nil {
 				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -411,7 +540,7 @@ func (h pipeShareHandler) AddPipe(c *gin.Context) {
 				})
 				return
 			}
-
+			h.app.Services.RecordShareLinkEvent(pipeToAdd, models.ShareEventRedemption, nil, shareReferrer(c), c.Request.UserAgent())
 		}
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Pipe has been added to your collection successfully",
@@ -428,5 +557,525 @@ func (h pipeShareHandler) AddPipe(c *gin.Context) {
 
 }
 
-func (h pipeShareHandler) RemoveShareAccessFromPipe(c *gin.Context) {}
+// UpdateCollaboratorRole lets the owner of a pipe change the role of a user the pipe was shared with
+func (h pipeShareHandler) UpdateCollaboratorRole(c *gin.Context) {
+	req := struct {
+		Role string `json:"role" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidPipeRole(req.Role) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify a valid *role*. valid roles are: *viewer*, *contributor*, *editor* and *co-owner*",
+		})
+		return
+	}
+
+	pipeId, ok := h.authorizedPipe(c, models.PipeRoleOwner)
+	if !ok {
+		return
+	}
+	receiverId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	receiver, err := h.app.Repositories.PipeShare.UpdateReceiverRole(pipeId, receiverId, req.Role)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "This pipe has not been shared with this user",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to change the role of this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"data": map[string]interface{}{
+			"collaborator": receiver,
+		},
+	})
+}
+
+// GetPipeShares lists the public share code of a pipe and every user the pipe was shared with
+func (h pipeShareHandler) GetPipeShares(c *gin.Context) {
+	pipeId, ok := h.authorizedPipe(c, models.PipeRoleCoOwner)
+	if !ok {
+		return
+	}
+
+	var publicCode *string
+	publicShare, err := h.app.Repositories.PipeShare.GetSharedPipe(pipeId, models.PipeShareTypePublic)
+	switch err {
+	case nil:
+		publicCode = &publicShare.Code
+	case postgres.ErrNoRecord:
+	default:
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve the shares of this pipe",
+		})
+		return
+	}
+	collaborators, err := h.app.Repositories.PipeShare.GetPipeCollaborators(pipeId)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve the shares of this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe shares fetched successfully",
+		"data": map[string]interface{}{
+			"public_code":   publicCode,
+			"collaborators": collaborators,
+		},
+	})
+}
+
+// RemoveShareAccessFromPipe lets the owner of a pipe revoke the access of a user the pipe was shared with
+func (h pipeShareHandler) RemoveShareAccessFromPipe(c *gin.Context) {
+	pipeId, ok := h.authorizedPipe(c, models.PipeRoleOwner)
+	if !ok {
+		return
+	}
+	receiverId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	if _, err := h.app.Services.RevokePipeShare(pipeId, c.GetInt64(middlewares.KeyUserId), receiverId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "This pipe has not been shared with this user",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to revoke access to this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access to pipe revoked successfully",
+	})
+}
+
+// LeavePipe lets a user remove a pipe shared with them from their collection
+func (h pipeShareHandler) LeavePipe(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+
+	if _, err := h.app.Repositories.PipeShare.DeletePipeReceiver(pipeId, c.GetInt64(middlewares.KeyUserId)); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "This pipe has not been shared with you",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to leave this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe removed from your collection successfully",
+	})
+}
+
+// RotatePublicShareCode replaces the public share code of a pipe, so that the old link stops working
+func (h pipeShareHandler) RotatePublicShareCode(c *gin.Context) {
+	pipeId, ok := h.authorizedPipe(c, models.PipeRoleOwner)
+	if !ok {
+		return
+	}
+
+	sharedPipe, err := h.app.Services.RotatePublicShareCode(pipeId)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "This pipe has not been shared publicly",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Our system encountered an error while trying to create a public share link",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Public pipe share link regenerated successfully",
+		"data": map[string]interface{}{
+			"share_code": sharedPipe.Code,
+		},
+	})
+}
+
+// DisablePublicShare stops the public share link of a pipe from working. The users who added
+// the pipe through it keep their access until it is revoked
+func (h pipeShareHandler) DisablePublicShare(c *gin.Context) {
+	pipeId, ok := h.authorizedPipe(c, models.PipeRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := h.app.Repositories.PipeShare.DeleteSharedPipe(pipeId, models.PipeShareTypePublic); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "This pipe has not been shared publicly",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to disable the public share link",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Public pipe share link disabled successfully",
+	})
+}
+
+// authorizedPipe reads the id of the pipe of the request and checks that the user has at least
+// the given role on it, aborting the request when they don't
+func (h pipeShareHandler) authorizedPipe(c *gin.Context, role string) (int64, bool) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return 0, false
+	}
+	if _, err := h.app.Services.AuthorizePipe(pipeId, c.GetInt64(middlewares.KeyUserId), role); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+		return 0, false
+	}
+	return pipeId, true
+}
+
 func (h pipeShareHandler) ChangePipeShareAccessType(c *gin.Context) {}
+
+// inviteByEmail shares a pipe of ownerId with an email that doesn't belong to any account yet
+func (h pipeShareHandler) inviteByEmail(c *gin.Context, pipeId, ownerId, sharerId int64, email, role string) {
+	invitation, err := h.app.Services.SharePipeByEmail(pipeId, ownerId, sharerId, email, role)
+	if err != nil {
+		if err == postgres.ErrRecordExists {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "This pipe has been previously shared with " + email,
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Our system encountered an error while trying to create a private share",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "An invitation to this pipe has been sent to " + email,
+		"data": map[string]interface{}{
+			"invitation": invitation,
+		},
+	})
+}
+
+// GetReceivedShares lists the pipes shared with the user, optionally narrowed down to a status
+func (h pipeShareHandler) GetReceivedShares(c *gin.Context) {
+	status := c.Query("status")
+	if !models.ValidShareStatus(status) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid status. valid statuses are: *pending*, *accepted*, *declined* and *expired*",
+		})
+		return
+	}
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	shares, pagination, err := h.app.Repositories.PipeShare.GetReceivedShares(c.GetInt64(middlewares.KeyUserId), status, page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve the pipes shared with you",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shared pipes fetched successfully",
+		"data": map[string]interface{}{
+			"shares":     shares,
+			"pagination": pagination,
+		},
+	})
+}
+
+// AcceptShare accepts a pipe shared with the user, adding it to their collection
+func (h pipeShareHandler) AcceptShare(c *gin.Context) {
+	receiver, ok := h.receivedShare(c)
+	if !ok {
+		return
+	}
+	if !receiver.IsAccepted {
+		if err := h.app.Services.CheckShareInvitation(receiver); err != nil {
+			abortWithShareLinkError(c, err)
+			return
+		}
+		var err error
+		if receiver, err = h.app.Repositories.PipeShare.AcceptPrivateShare(receiver); err != nil {
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Our system encountered an error while trying to add pipe your collection. Try again soon!",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe has been added to your collection successfully",
+		"data": map[string]interface{}{
+			"share": receiver,
+		},
+	})
+}
+
+// DeclineShare declines a pipe shared with the user that they have not accepted
+func (h pipeShareHandler) DeclineShare(c *gin.Context) {
+	receiver, ok := h.receivedShare(c)
+	if !ok {
+		return
+	}
+	if receiver.IsAccepted {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "This pipe is already in your collection, leave it instead",
+		})
+		return
+	}
+	if receiver.Status(time.Now()) == models.ShareStatusExpired {
+		abortWithShareLinkError(c, services.ErrShareInvitationExpired)
+		return
+	}
+
+	receiver, err := h.app.Repositories.PipeShare.DeclinePrivateShare(receiver)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to decline this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe declined successfully",
+		"data": map[string]interface{}{
+			"share": receiver,
+		},
+	})
+}
+
+// receivedShare retrieves the pipe shared with the user the request refers to,
+// aborting the request when it can't be found
+func (h pipeShareHandler) receivedShare(c *gin.Context) (models.SharedPipeReceiver, bool) {
+	shareId, err := strconv.ParseInt(c.Param("shareId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid share ID",
+		})
+		return models.SharedPipeReceiver{}, false
+	}
+	receiver, err := h.app.Repositories.PipeShare.GetReceivedShare(shareId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Shared pipe not found",
+			})
+			return receiver, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve this shared pipe",
+		})
+		return receiver, false
+	}
+	return receiver, true
+}
+
+// OpenSharedBookmark records that a bookmark of a shared pipe was opened from its preview
+// and returns the url of the bookmark to open
+func (h pipeShareHandler) OpenSharedBookmark(c *gin.Context) {
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid bookmark ID",
+		})
+		return
+	}
+	share, err := h.app.Repositories.PipeShare.GetSharedPipeByCode(c.Query("code"))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Shared pipe not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to open this bookmark",
+		})
+		return
+	}
+	if err := h.app.Services.CheckShareLink(share, sharePassword(c)); err != nil {
+		abortWithShareLinkError(c, err)
+		return
+	}
+
+	bookmark, err := h.app.Repositories.Bookmark.GetBookmark(bmId, share.SharerID)
+	if err != nil || bookmark.PipeID != share.PipeID {
+		if err == nil || err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found in this pipe",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to open this bookmark",
+		})
+		return
+	}
+	h.app.Services.RecordShareLinkEvent(share, models.ShareEventClick, &bookmark.ID, shareReferrer(c), c.Request.UserAgent())
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmark opened successfully",
+		"data": map[string]interface{}{
+			"url": bookmark.Url,
+		},
+	})
+}
+
+// GetShareLinkStats returns the daily stats of the codes a pipe was shared with to its owner.
+// The days are given as YYYY-MM-DD in the from and to query parameters
+func (h pipeShareHandler) GetShareLinkStats(c *gin.Context) {
+	pipeId, ok := h.authorizedPipe(c, models.PipeRoleOwner)
+	if !ok {
+		return
+	}
+	var days [2]*time.Time
+	for i, param := range []string{"from", "to"} {
+		if value := c.Query(param); value != "" {
+			day, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"message": "*" + param + "* must be a day formatted as YYYY-MM-DD",
+				})
+				return
+			}
+			days[i] = &day
+		}
+	}
+
+	from, to, err := h.app.Services.ShareStatsRange(days[0], days[1])
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	stats, err := h.app.Repositories.PipeShare.GetShareLinkStats(pipeId, from, to)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve the stats of this pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share link stats fetched successfully",
+		"data": map[string]interface{}{
+			"stats": stats,
+		},
+	})
+}
+
+// shareReferrer reads where a share event came from, which clients can report in the ref query
+// parameter when the request has no referrer
+func shareReferrer(c *gin.Context) string {
+	if ref := c.Query("ref"); ref != "" {
+		return ref
+	}
+	return c.Request.Referer()
+}
+
+// sharePassword reads the password given to open a share link, from the
+// X-Share-Password header or else the password field of a JSON body. It is never
+// read from the url, which ends up in access logs and the browser history
+func sharePassword(c *gin.Context) string {
+	if password := c.GetHeader("X-Share-Password"); password != "" {
+		return password
+	}
+	req := struct {
+		Password string `json:"password"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return ""
+	}
+	return req.Password
+}
+
+// abortWithShareLinkError aborts a request made with a share link that can't be used
+func abortWithShareLinkError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrShareLinkExpired, services.ErrShareLinkExhausted, services.ErrShareInvitationExpired:
+		c.AbortWithStatusJSON(http.StatusGone, gin.H{
+			"message": err.Error(),
+		})
+	case services.ErrShareLinkPassword:
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Our system encountered an error while trying to add pipe your collection. Try again soon!",
+		})
+	}
+}
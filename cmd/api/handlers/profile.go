@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+)
+
+type ProfileHandler interface {
+	PublicProfile(c *gin.Context)
+	PublicPipe(c *gin.Context)
+	Explore(c *gin.Context)
+	GetProfileSettings(c *gin.Context)
+	UpdateProfileSettings(c *gin.Context)
+}
+
+type profileHandler struct {
+	app internal.Application
+}
+
+func NewProfileHandler(app internal.Application) ProfileHandler {
+	return profileHandler{app: app}
+}
+
+// PublicProfile returns the public profile of a user along with a page of their public pipes.
+// Profiles that were not made public are not found
+func (h profileHandler) PublicProfile(c *gin.Context) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	profile, err := h.app.Repositories.User.GetPublicProfile(c.Param("username"))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Profile not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve this profile",
+		})
+		return
+	}
+
+	pipes, pagination, err := h.app.Repositories.Pipe.GetPublicPipes(profile.ID, page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve the pipes of this profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile fetched successfully",
+		"data": map[string]interface{}{
+			"profile":    profile,
+			"pipes":      pipes,
+			"pagination": pagination,
+		},
+	})
+}
+
+// PublicPipe returns a pipe of a user that anyone can see, along with its bookmarks
+func (h profileHandler) PublicPipe(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+	owner, err := h.app.Repositories.User.GetUserByUsername(c.Param("username"))
+	if err == nil {
+		var pipeAndR models.PipeAndResource
+		if pipeAndR, err = h.app.Repositories.Pipe.GetPublicPipe(owner.ID, pipeId); err == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Pipe fetched successfully",
+				"data": map[string]interface{}{
+					"pipe":      pipeAndR.Pipe,
+					"children":  pipeAndR.Children,
+					"bookmarks": pipeAndR.Bookmarks,
+				},
+			})
+			return
+		}
+	}
+
+	if err == postgres.ErrNoRecord {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"message": "Pipe not found",
+		})
+		return
+	}
+	h.app.Logger.Err(err).Msg(err.Error())
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+		"message": "An error occurred while trying to retrieve this pipe",
+	})
+}
+
+// Explore lists the public pipes of the public profiles that show in explore, either
+// from the most recently active or from the most popular
+func (h profileHandler) Explore(c *gin.Context) {
+	sort := c.Query("sort")
+	if !models.ValidExploreSort(sort) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid sort. valid sorts are: *recent* and *popular*",
+		})
+		return
+	}
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	pipes, pagination, err := h.app.Repositories.Pipe.ExplorePipes(sort, page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve pipes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipes fetched successfully",
+		"data": map[string]interface{}{
+			"pipes":      pipes,
+			"pagination": pagination,
+		},
+	})
+}
+
+// GetProfileSettings returns what the user lets other people see of them
+func (h profileHandler) GetProfileSettings(c *gin.Context) {
+	settings, err := h.app.Repositories.User.GetProfileSettings(c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve your profile settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile settings fetched successfully",
+		"data": map[string]interface{}{
+			"settings": settings,
+		},
+	})
+}
+
+// UpdateProfileSettings changes what the user lets other people see of them. Settings left
+// out of the request keep their value
+func (h profileHandler) UpdateProfileSettings(c *gin.Context) {
+	req := struct {
+		PublicProfile *bool `json:"public_profile"`
+		ShowInExplore *bool `json:"show_in_explore"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userId := c.GetInt64(middlewares.KeyUserId)
+	settings, err := h.app.Repositories.User.GetProfileSettings(userId)
+	if err == nil {
+		if req.PublicProfile != nil {
+			settings.PublicProfile = *req.PublicProfile
+		}
+		if req.ShowInExplore != nil {
+			settings.ShowInExplore = *req.ShowInExplore
+		}
+		settings, err = h.app.Repositories.User.UpdateProfileSettings(userId, settings)
+	}
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to update your profile settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile settings updated successfully",
+		"data": map[string]interface{}{
+			"settings": settings,
+		},
+	})
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/cmd/api/services"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// maxReminderNoteLength is the number of characters the note of a reminder can have
+const maxReminderNoteLength = 255
+
+type ReminderHandler interface {
+	CreateReminder(c *gin.Context)
+	GetReminders(c *gin.Context)
+	SnoozeReminder(c *gin.Context)
+	DeleteReminder(c *gin.Context)
+	UpdateRediscoverSetting(c *gin.Context)
+}
+
+type reminderHandler struct {
+	app internal.Application
+}
+
+func NewReminderHandler(app internal.Application) ReminderHandler {
+	return reminderHandler{app: app}
+}
+
+// reminderTimeRequest is the part of a request body that describes when a reminder should fire.
+// Either an exact time or an offset from now can be specified
+type reminderTimeRequest struct {
+	RemindAt *time.Time `json:"remind_at"`
+	InHours  int        `json:"in_hours"`
+	InDays   int        `json:"in_days"`
+	InMonths int        `json:"in_months"`
+}
+
+func (h reminderHandler) CreateReminder(c *gin.Context) {
+	req := struct {
+		reminderTimeRequest
+		Note string `json:"note"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Pipe ID",
+		})
+		return
+	}
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return
+	}
+	if utf8.RuneCountInString(req.Note) > maxReminderNoteLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("The note of a reminder can not be longer than %d characters", maxReminderNoteLength),
+		})
+		return
+	}
+
+	remindAt, err := services.ResolveReminderTime(time.Now(), req.RemindAt, req.InHours, req.InDays, req.InMonths)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify a reminder time in the future",
+		})
+		return
+	}
+
+	userId := c.GetInt64(middlewares.KeyUserId)
+	bookmark, err := h.app.Repositories.Bookmark.GetBookmark(bmId, userId)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to create reminder",
+		})
+		return
+	}
+	if bookmark.PipeID != pipeId {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"message": "Bookmark not found in this pipe",
+		})
+		return
+	}
+
+	reminder, err := h.app.Repositories.Reminder.CreateReminder(models.Reminder{
+		UserID:     userId,
+		BookmarkID: bmId,
+		Note:       req.Note,
+		RemindAt:   remindAt,
+	})
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to create reminder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Reminder created successfully",
+		"data": map[string]interface{}{
+			"reminder": reminder,
+		},
+	})
+}
+
+func (h reminderHandler) GetReminders(c *gin.Context) {
+	reminders, err := h.app.Repositories.Reminder.GetReminders(c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Could not retrieve reminders! Please try again soon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reminders fetched successfully",
+		"data": map[string]interface{}{
+			"reminders": reminders,
+		},
+	})
+}
+
+func (h reminderHandler) SnoozeReminder(c *gin.Context) {
+	var req reminderTimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	reminderId, err := strconv.ParseInt(c.Param("reminderId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Reminder ID",
+		})
+		return
+	}
+
+	until, err := services.ResolveReminderTime(time.Now(), req.RemindAt, req.InHours, req.InDays, req.InMonths)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify a snooze time in the future",
+		})
+		return
+	}
+
+	reminder, err := h.app.Repositories.Reminder.SnoozeReminder(reminderId, c.GetInt64(middlewares.KeyUserId), until)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Reminder not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to snooze reminder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reminder snoozed successfully",
+		"data": map[string]interface{}{
+			"reminder": reminder,
+		},
+	})
+}
+
+func (h reminderHandler) DeleteReminder(c *gin.Context) {
+	reminderId, err := strconv.ParseInt(c.Param("reminderId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Reminder ID",
+		})
+		return
+	}
+
+	_, err = h.app.Repositories.Reminder.DeleteReminder(reminderId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Reminder not found",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to delete reminder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reminder deleted successfully",
+	})
+}
+
+func (h reminderHandler) UpdateRediscoverSetting(c *gin.Context) {
+	req := struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify whether rediscover mode should be *enabled*",
+		})
+		return
+	}
+
+	err := h.app.Repositories.Reminder.UpdateRediscoverSetting(c.GetInt64(middlewares.KeyUserId), *req.Enabled)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to update rediscover mode",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rediscover mode updated successfully",
+		"data": map[string]interface{}{
+			"enabled": *req.Enabled,
+		},
+	})
+}
@@ -38,10 +38,24 @@ func (h searchHandler) Search(c *gin.Context) {
 		return
 	}
 
+	filter, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
 	switch req.Type {
 	case models.SearchTypePipes:
-		pipes, err := h.app.Repositories.Search.SearchThroughPipes(req.Name, c.GetInt64(middlewares.KeyUserId))
+		pipes, pagination, err := h.app.Repositories.Search.SearchThroughPipes(req.Name, c.GetInt64(middlewares.KeyUserId), filter)
 		if err != nil {
+			if err == postgres.ErrInvalidCursor {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"message": "Invalid cursor",
+				})
+				return
+			}
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 					"message": fmt.Sprintf("no results found for %v", req.Name),
@@ -60,15 +74,22 @@ func (h searchHandler) Search(c *gin.Context) {
 			"type": "pipe",
 			"data": map[string]interface{}{
 				"pipes": map[string]interface{}{
-					"result": pipes,
-					"total":  len(pipes),
+					"result":     pipes,
+					"total":      len(pipes),
+					"pagination": pagination,
 				},
 			},
 		})
 
 	case models.SearchTypeTags:
-		bookmarks, err := h.app.Repositories.Search.SearchThroughTags(req.Name, c.GetInt64(middlewares.KeyUserId))
+		bookmarks, pagination, err := h.app.Repositories.Search.SearchThroughTags(req.Name, c.GetInt64(middlewares.KeyUserId), filter)
 		if err != nil {
+			if err == postgres.ErrInvalidCursor {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"message": "Invalid cursor",
+				})
+				return
+			}
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 					"message": fmt.Sprintf("no results found for %v", req.Name),
@@ -87,15 +108,22 @@ func (h searchHandler) Search(c *gin.Context) {
 			"type": "bookmark",
 			"data": map[string]interface{}{
 				"bookmarks": map[string]interface{}{
-					"result": bookmarks,
-					"total":  len(bookmarks),
+					"result":     bookmarks,
+					"total":      len(bookmarks),
+					"pagination": pagination,
 				},
 			},
 		})
 
 	case models.SearchTypePlatform:
-		bookmarks, err := h.app.Repositories.Search.SearchThroughPlatform(req.Name, c.GetInt64(middlewares.KeyUserId))
+		bookmarks, pagination, err := h.app.Repositories.Search.SearchThroughPlatform(req.Name, c.GetInt64(middlewares.KeyUserId), filter)
 		if err != nil {
+			if err == postgres.ErrInvalidCursor {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"message": "Invalid cursor",
+				})
+				return
+			}
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
 					"message": fmt.Sprintf("no results found for %v", req.Name),
@@ -114,13 +142,17 @@ func (h searchHandler) Search(c *gin.Context) {
 			"type": "bookmark",
 			"data": map[string]interface{}{
 				"bookmarks": map[string]interface{}{
-					"result": bookmarks,
-					"total":  len(bookmarks),
+					"result":     bookmarks,
+					"total":      len(bookmarks),
+					"pagination": pagination,
 				},
 			},
 		})
 	case models.SearchTypeAll:
-		bookmarks, err := h.app.Repositories.Search.SearchThroughTags(req.Name, c.GetInt64(middlewares.KeyUserId))
+		// every kind of result is sorted on its own, so only the first page of each can be
+		// returned here. The next pages are fetched by searching for that kind of result
+		filter.Cursor = ""
+		bookmarks, bookmarksPagination, err := h.app.Repositories.Search.SearchThroughTags(req.Name, c.GetInt64(middlewares.KeyUserId), filter)
 		if err != nil {
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
@@ -135,7 +167,7 @@ func (h searchHandler) Search(c *gin.Context) {
 			})
 			return
 		}
-		pipes, err := h.app.Repositories.Search.SearchThroughPipes(req.Name, c.GetInt64(middlewares.KeyUserId))
+		pipes, pipesPagination, err := h.app.Repositories.Search.SearchThroughPipes(req.Name, c.GetInt64(middlewares.KeyUserId), filter)
 		if err != nil {
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
@@ -151,7 +183,7 @@ func (h searchHandler) Search(c *gin.Context) {
 			return
 		}
 
-		platform, err := h.app.Repositories.Search.SearchThroughPlatform(req.Name, c.GetInt64(middlewares.KeyUserId))
+		platform, platformPagination, err := h.app.Repositories.Search.SearchThroughPlatform(req.Name, c.GetInt64(middlewares.KeyUserId), filter)
 		if err != nil {
 			if err == postgres.ErrNoRecord {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
@@ -171,16 +203,19 @@ func (h searchHandler) Search(c *gin.Context) {
 			"type": "all",
 			"data": map[string]interface{}{
 				"pipes": map[string]interface{}{
-					"result": pipes,
-					"total":  len(pipes),
+					"result":     pipes,
+					"total":      len(pipes),
+					"pagination": pipesPagination,
 				},
 				"bookmarks": map[string]interface{}{
-					"result": bookmarks,
-					"total":  len(bookmarks),
+					"result":     bookmarks,
+					"total":      len(bookmarks),
+					"pagination": bookmarksPagination,
 				},
 				"platform": map[string]interface{}{
-					"result": platform,
-					"total":  len(platform),
+					"result":     platform,
+					"total":      len(platform),
+					"pagination": platformPagination,
 				},
 				"total": len(bookmarks) + len(pipes) + len(platform),
 			},
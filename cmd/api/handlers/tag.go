@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/cmd/api/services"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type TagHandler interface {
+	GetTags(c *gin.Context)
+	CreateTag(c *gin.Context)
+	GetTag(c *gin.Context)
+	GetTagTree(c *gin.Context)
+	RenameTag(c *gin.Context)
+	MoveTag(c *gin.Context)
+	MergeTags(c *gin.Context)
+	DeleteTag(c *gin.Context)
+	AutocompleteTags(c *gin.Context)
+	SuggestTags(c *gin.Context)
+}
+
+type tagHandler struct {
+	app internal.Application
+}
+
+func NewTagHandler(app internal.Application) TagHandler {
+	return tagHandler{app: app}
+}
+
+// tag retrieves the tag of the user a request is about, aborting the request when it can't be found
+func (h tagHandler) tag(c *gin.Context) (models.Tag, bool) {
+	tagId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid tag ID",
+		})
+		return models.Tag{}, false
+	}
+	tag, err := h.app.Repositories.Tag.GetTag(tagId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Tag not found",
+			})
+			return tag, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve tag",
+		})
+		return tag, false
+	}
+	return tag, true
+}
+
+// tagName reads the name of a tag from the body of a request, aborting the request when it's not a valid name
+func (h tagHandler) tagName(c *gin.Context) (string, bool) {
+	req := struct {
+		Name string `json:"name"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *name* of the tag",
+		})
+		return "", false
+	}
+	name := models.NormalizeTagName(req.Name)
+	if err := h.app.Services.ValidateTagName(name); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return "", false
+	}
+	return name, true
+}
+
+// GetTags lists the tags of the user along with the number of bookmarks they are on,
+// either by name or from the most used
+func (h tagHandler) GetTags(c *gin.Context) {
+	sort := c.Query("sort")
+	if !models.ValidTagSort(sort) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid sort. valid sort options are: *name* and *usage*",
+		})
+		return
+	}
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tags, pagination, err := h.app.Repositories.Tag.GetTags(c.GetInt64(middlewares.KeyUserId), sort, page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags fetched successfully",
+		"data": map[string]interface{}{
+			"tags":       tags,
+			"pagination": pagination,
+		},
+	})
+}
+
+// CreateTag creates a tag the user can put on their bookmarks. A name like lang/go creates the tag
+// under lang, which is created as well when the user doesn't have it
+func (h tagHandler) CreateTag(c *gin.Context) {
+	name, ok := h.tagName(c)
+	if !ok {
+		return
+	}
+
+	tag, err := h.app.Repositories.Tag.CreateTag(models.Tag{UserID: c.GetInt64(middlewares.KeyUserId), Name: name})
+	if err != nil {
+		switch err {
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "You already have a tag with this name",
+			})
+		case postgres.ErrTagNameTooLong:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": fmt.Sprintf("The name of a tag can not be longer than %d characters", models.MaxTagNameLength),
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to create tag",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tag created successfully",
+		"data": map[string]interface{}{
+			"tag": tag,
+		},
+	})
+}
+
+// GetTag retrieves a tag of the user
+func (h tagHandler) GetTag(c *gin.Context) {
+	tag, ok := h.tag(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag fetched successfully",
+		"data": map[string]interface{}{
+			"tag": tag,
+		},
+	})
+}
+
+// GetTagTree lists every tag of the user as a tree, with the tags under each tag as its children
+func (h tagHandler) GetTagTree(c *gin.Context) {
+	tags, err := h.app.Repositories.Tag.GetTagTree(c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags fetched successfully",
+		"data": map[string]interface{}{
+			"tags": tags,
+		},
+	})
+}
+
+// RenameTag renames a tag of the user, which renames it on every bookmark it's on along with the tags
+// under it. The tag stays under the same tag, moving it is done with MoveTag
+func (h tagHandler) RenameTag(c *gin.Context) {
+	tag, ok := h.tag(c)
+	if !ok {
+		return
+	}
+	name, ok := h.tagName(c)
+	if !ok {
+		return
+	}
+	if strings.Contains(name, models.TagSeparator) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "The new name of a tag can not have a " + models.TagSeparator + ". Move the tag to put it under another tag",
+		})
+		return
+	}
+
+	tag, err := h.app.Repositories.Tag.RenameTag(tag.Id, tag.UserID, name)
+	if err != nil {
+		switch err {
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "You already have a tag with this name. Merge the tags instead",
+			})
+		case postgres.ErrTagNameTooLong:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "The name would make the name of a tag under it too long",
+			})
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Tag not found",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to rename tag",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag renamed successfully",
+		"data": map[string]interface{}{
+			"tag": tag,
+		},
+	})
+}
+
+// MoveTag puts a tag of the user under another of their tags in parent_id, or at the top when parent_id
+// is null. The tags under it move along with it
+func (h tagHandler) MoveTag(c *gin.Context) {
+	tag, ok := h.tag(c)
+	if !ok {
+		return
+	}
+	req := struct {
+		ParentID *int64 `json:"parent_id"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *parent_id* of the tag to move the tag under, or null to move it to the top",
+		})
+		return
+	}
+
+	tag, err := h.app.Repositories.Tag.MoveTag(tag.Id, tag.UserID, req.ParentID)
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Tag not found",
+			})
+		case postgres.ErrTagCycle:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "A tag can not be moved under itself or under one of the tags under it",
+			})
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "There's already a tag with this name there. Merge the tags instead",
+			})
+		case postgres.ErrTagNameTooLong:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Moving the tag there would make its name or the name of a tag under it too long",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to move tag",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag moved successfully",
+		"data": map[string]interface{}{
+			"tag": tag,
+		},
+	})
+}
+
+// MergeTags merges tags of the user into the tag of the request, which takes their place on
+// their bookmarks. The merged tags are deleted and the tags under them move under the tag of the request
+func (h tagHandler) MergeTags(c *gin.Context) {
+	tag, ok := h.tag(c)
+	if !ok {
+		return
+	}
+	req := struct {
+		SourceIDs []int64 `json:"source_ids" binding:"required"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.SourceIDs) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Please specify the *source_ids* of the tags to merge",
+		})
+		return
+	}
+	var sourceIds []int64
+	seen := map[int64]bool{}
+	for _, id := range req.SourceIDs {
+		if id == tag.Id {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "A tag can not be merged into itself",
+			})
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			sourceIds = append(sourceIds, id)
+		}
+	}
+
+	tag, err := h.app.Repositories.Tag.MergeTags(tag.UserID, tag.Id, sourceIds)
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "One or more of the tags to merge could not be found",
+			})
+		case postgres.ErrTagCycle:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "A tag can not be merged into a tag under it",
+			})
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "A tag under the merged tags has the same name as a tag under this tag",
+			})
+		case postgres.ErrTagNameTooLong:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Merging would make the name of a tag under this tag too long",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to merge tags",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags merged successfully",
+		"data": map[string]interface{}{
+			"tag": tag,
+		},
+	})
+}
+
+// DeleteTag deletes a tag of the user, along with the tags under it, and takes them off their bookmarks
+func (h tagHandler) DeleteTag(c *gin.Context) {
+	tagId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid tag ID",
+		})
+		return
+	}
+	if err := h.app.Repositories.Tag.DeleteTag(tagId, c.GetInt64(middlewares.KeyUserId)); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Tag not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to delete tag",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag deleted successfully",
+	})
+}
+
+// suggestionLimit reads how many tags to autocomplete or suggest from the request, aborting the
+// request when it's not a positive number
+func (h tagHandler) suggestionLimit(c *gin.Context) (int, bool) {
+	limit := models.DefaultTagSuggestions
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "limit must be a positive number",
+			})
+			return 0, false
+		}
+		limit = parsed
+	}
+	if limit > models.MaxTagSuggestions {
+		limit = models.MaxTagSuggestions
+	}
+	return limit, true
+}
+
+// AutocompleteTags lists the tags of the user that match what they typed so far in q, the best matches
+// and the tags they use the most and the most recently first
+func (h tagHandler) AutocompleteTags(c *gin.Context) {
+	limit, ok := h.suggestionLimit(c)
+	if !ok {
+		return
+	}
+	if len(c.Query("q")) > models.MaxTagNameLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": fmt.Sprintf("q can not be longer than %d characters", models.MaxTagNameLength),
+		})
+		return
+	}
+
+	tags, err := h.app.Repositories.Tag.AutocompleteTags(c.GetInt64(middlewares.KeyUserId), c.Query("q"), limit)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags fetched successfully",
+		"data": map[string]interface{}{
+			"tags": tags,
+		},
+	})
+}
+
+// SuggestTags suggests tags for the link in url, out of the tags the user put on links of the same site,
+// the tags found in the title and description parsed from the link and the name of its site
+func (h tagHandler) SuggestTags(c *gin.Context) {
+	limit, ok := h.suggestionLimit(c)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.app.Services.SuggestTags(
+		c.GetInt64(middlewares.KeyUserId),
+		c.Query("url"),
+		c.Query("title"),
+		c.Query("description"),
+		limit,
+	)
+	if err != nil {
+		if err == services.ErrInvalidLink {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Please specify a valid *url*",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to suggest tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags suggested successfully",
+		"data": map[string]interface{}{
+			"suggestions": suggestions,
+		},
+	})
+}
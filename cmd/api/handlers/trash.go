@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TrashHandler interface {
+	GetTrash(c *gin.Context)
+	RestorePipe(c *gin.Context)
+	RestoreBookmark(c *gin.Context)
+	PurgePipe(c *gin.Context)
+	PurgeBookmark(c *gin.Context)
+	EmptyTrash(c *gin.Context)
+}
+
+type trashHandler struct {
+	app internal.Application
+}
+
+func NewTrashHandler(app internal.Application) TrashHandler {
+	return trashHandler{app: app}
+}
+
+func (h trashHandler) GetTrash(c *gin.Context) {
+	userId := c.GetInt64(middlewares.KeyUserId)
+	pipes, err := h.app.Repositories.Trash.GetTrashedPipes(userId)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to fetch trash",
+		})
+		return
+	}
+
+	bookmarks, err := h.app.Repositories.Trash.GetTrashedBookmarks(userId)
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to fetch trash",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trash fetched successfully",
+		"data": map[string]interface{}{
+			"trash": models.Trash{
+				Pipes:     pipes,
+				Bookmarks: bookmarks,
+			},
+			"retention_days": int(h.app.Services.Scheduler.TrashRetention / (24 * time.Hour)),
+		},
+	})
+}
+
+func (h trashHandler) RestorePipe(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+
+	pipe, err := h.app.Repositories.Trash.RestorePipe(pipeId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found in trash",
+			})
+		case postgres.ErrRecordExists:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "You already have a pipe with the same name. Please rename it before restoring this one",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to restore pipe",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe restored successfully",
+		"data": map[string]interface{}{
+			"pipe": pipe,
+		},
+	})
+}
+
+func (h trashHandler) RestoreBookmark(c *gin.Context) {
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return
+	}
+
+	bookmark, err := h.app.Repositories.Trash.RestoreBookmark(bmId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		switch err {
+		case postgres.ErrNoRecord:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found in trash",
+			})
+		case postgres.ErrPipeInTrash:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": "The pipe this bookmark belongs to is in the trash. Please restore the pipe instead",
+			})
+		default:
+			h.app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "An error occurred while trying to restore bookmark",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmark restored successfully",
+		"data": map[string]interface{}{
+			"bookmark": bookmark,
+		},
+	})
+}
+
+func (h trashHandler) PurgePipe(c *gin.Context) {
+	pipeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid pipe ID",
+		})
+		return
+	}
+
+	_, err = h.app.Repositories.Trash.PurgePipe(pipeId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Pipe not found in trash",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to delete pipe",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pipe deleted permanently",
+	})
+}
+
+func (h trashHandler) PurgeBookmark(c *gin.Context) {
+	bmId, err := strconv.ParseInt(c.Param("bmId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid Bookmark ID",
+		})
+		return
+	}
+
+	_, err = h.app.Repositories.Trash.PurgeBookmark(bmId, c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Bookmark not found in trash",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to delete bookmark",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bookmark deleted permanently",
+	})
+}
+
+func (h trashHandler) EmptyTrash(c *gin.Context) {
+	purged, err := h.app.Repositories.Trash.EmptyTrash(c.GetInt64(middlewares.KeyUserId))
+	if err != nil {
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to empty trash",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trash emptied successfully",
+		"data": map[string]interface{}{
+			"deleted": purged,
+		},
+	})
+}
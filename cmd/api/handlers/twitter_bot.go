@@ -77,7 +77,11 @@ func (h twitterBotHandler) BotAddToPipe(c *gin.Context) {
 				return
 			}
 		}
-		_, err = h.app.Repositories.Bookmark.CreateBookmark(models.Bookmark{
+		if pipe.Type == models.PipeTypeSmart {
+			h.app.Logger.Info().Msg(fmt.Sprintf("pipe %s is a smart pipe, moving on...", name))
+			continue
+		}
+		bookmark, err := h.app.Repositories.Bookmark.CreateBookmark(models.Bookmark{
 			UserID:   user.ID,
 			PipeID:   pipe.ID,
 			Platform: "twitter",
@@ -90,6 +94,12 @@ func (h twitterBotHandler) BotAddToPipe(c *gin.Context) {
 			})
 			return
 		}
+		h.app.Services.RecordActivity(models.Activity{
+			ActorID:    user.ID,
+			Verb:       models.ActivityBookmarkAdded,
+			PipeID:     pipe.ID,
+			BookmarkID: &bookmark.ID,
+		})
 		encounteredError = false
 	}
 
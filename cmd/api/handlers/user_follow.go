@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/http"
+)
+
+// otherUser reads the user a follow or block request is about, who can't be the user making the request.
+// Users can only be followed when their profile is public
+func (h followHandler) otherUser(c *gin.Context, publicOnly bool) (int64, bool) {
+	var (
+		userId int64
+		err    error
+	)
+	if publicOnly {
+		var profile models.PublicProfile
+		profile, err = h.app.Repositories.User.GetPublicProfile(c.Param("username"))
+		userId = profile.ID
+	} else {
+		var user models.User
+		user, err = h.app.Repositories.User.GetUserByUsername(c.Param("username"))
+		userId = user.ID
+	}
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "User not found",
+			})
+			return 0, false
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve this user",
+		})
+		return 0, false
+	}
+	if userId == c.GetInt64(middlewares.KeyUserId) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "You can't follow or block yourself",
+		})
+		return 0, false
+	}
+	return userId, true
+}
+
+// FollowUser makes the user follow another user with a public profile
+func (h followHandler) FollowUser(c *gin.Context) {
+	followeeId, ok := h.otherUser(c, true)
+	if !ok {
+		return
+	}
+	if err := h.app.Repositories.Follow.FollowUser(c.GetInt64(middlewares.KeyUserId), followeeId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "User not found or they can't be followed",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to follow this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User followed successfully",
+	})
+}
+
+// UnfollowUser stops the user from following another user
+func (h followHandler) UnfollowUser(c *gin.Context) {
+	followeeId, ok := h.otherUser(c, false)
+	if !ok {
+		return
+	}
+	if err := h.app.Repositories.Follow.UnfollowUser(c.GetInt64(middlewares.KeyUserId), followeeId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "You don't follow this user",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to unfollow this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unfollowed successfully",
+	})
+}
+
+// BlockUser blocks another user, which removes the follows between them and the user
+func (h followHandler) BlockUser(c *gin.Context) {
+	blockedId, ok := h.otherUser(c, false)
+	if !ok {
+		return
+	}
+	if err := h.app.Repositories.Follow.BlockUser(c.GetInt64(middlewares.KeyUserId), blockedId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "User not found",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to block this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User blocked successfully",
+	})
+}
+
+// UnblockUser lifts the block the user put on another user
+func (h followHandler) UnblockUser(c *gin.Context) {
+	blockedId, ok := h.otherUser(c, false)
+	if !ok {
+		return
+	}
+	if err := h.app.Repositories.Follow.UnblockUser(c.GetInt64(middlewares.KeyUserId), blockedId); err != nil {
+		if err == postgres.ErrNoRecord {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "You haven't blocked this user",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to unblock this user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unblocked successfully",
+	})
+}
+
+// listConnections responds with a page of the users list returns for the user. what names the
+// users in the messages of the response
+func (h followHandler) listConnections(c *gin.Context, what string, list func(int64, models.Filter) ([]models.UserConnection, models.Pagination, error)) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	users, pagination, err := list(c.GetInt64(middlewares.KeyUserId), page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve " + what,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Users fetched successfully",
+		"data": map[string]interface{}{
+			"users":      users,
+			"pagination": pagination,
+		},
+	})
+}
+
+// GetFollowers lists the users following the user
+func (h followHandler) GetFollowers(c *gin.Context) {
+	h.listConnections(c, "your followers", h.app.Repositories.Follow.GetFollowers)
+}
+
+// GetFollowing lists the users the user follows
+func (h followHandler) GetFollowing(c *gin.Context) {
+	h.listConnections(c, "the users you follow", h.app.Repositories.Follow.GetFollowing)
+}
+
+// GetBlockedUsers lists the users the user blocked
+func (h followHandler) GetBlockedUsers(c *gin.Context) {
+	h.listConnections(c, "the users you blocked", h.app.Repositories.Follow.GetBlockedUsers)
+}
+
+// GetActivityFeed lists what the users the user follows recently did on their public pipes
+func (h followHandler) GetActivityFeed(c *gin.Context) {
+	page, err := pageFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	activities, pagination, err := h.app.Repositories.Activity.GetActivityFeed(c.GetInt64(middlewares.KeyUserId), page)
+	if err != nil {
+		if err == postgres.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		h.app.Logger.Err(err).Msg(err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "An error occurred while trying to retrieve your activity feed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Activity feed fetched successfully",
+		"data": map[string]interface{}{
+			"activities": activities,
+			"pagination": pagination,
+		},
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/rs/zerolog"
 	"os"
 	"strconv"
+	"time"
 )
 
 func main() {
@@ -87,6 +88,26 @@ func initJWTConfig() (services.JWTConfig, error) {
 	return cfg, nil
 }
 
+func initSchedulerConfig() services.SchedulerConfig {
+	cfg := services.SchedulerConfig{
+		Interval:         time.Minute,
+		RediscoverMinAge: 30 * 24 * time.Hour,
+		TrashRetention:   30 * 24 * time.Hour,
+	}
+
+	if interval, err := strconv.Atoi(os.Getenv("SCHEDULER_INTERVAL_SECONDS")); err == nil {
+		cfg.Interval = time.Duration(interval) * time.Second
+	}
+	if minAge, err := strconv.Atoi(os.Getenv("REDISCOVER_MIN_AGE_DAYS")); err == nil {
+		cfg.RediscoverMinAge = time.Duration(minAge) * 24 * time.Hour
+	}
+	if retention, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil {
+		cfg.TrashRetention = time.Duration(retention) * 24 * time.Hour
+	}
+
+	return cfg
+}
+
 func initMailer() *mailer.Mailer {
 	logger := zerolog.New(os.Stderr).With().Caller().Timestamp().Logger()
 	var mailerP *mailer.Mailer
@@ -73,6 +73,13 @@ func AuthRequired(app internal.Application, jwtSecret string) gin.HandlerFunc {
 				return
 			}
 
+			if loggedInUser.SuspendedAt != nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"message": "Your account has been suspended",
+				})
+				return
+			}
+
 			if loggedInUser.ID == userId {
 				c.Set(KeyUsername, username)
 				c.Set(KeyUserId, userId)
@@ -93,3 +100,24 @@ func AuthRequired(app internal.Application, jwtSecret string) gin.HandlerFunc {
 
 	}
 }
+
+// AdminRequired lets only admins through. It must come after AuthRequired
+func AdminRequired(app internal.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := app.Repositories.User.GetUserById(c.GetInt64(KeyUserId))
+		if err != nil {
+			app.Logger.Err(err).Msg(err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "Authentication error",
+			})
+			return
+		}
+		if !user.IsAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "You are not allowed to perform this operation",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+)
+
+func setupBookmarkRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewBookmarkHandler(app)
+	bookmark := routeGroup.Group("/bookmarks")
+	bookmark.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
+	bookmark.GET("/", h.GetBookmarksByState)
+}
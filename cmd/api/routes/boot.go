@@ -18,8 +18,15 @@ func BootRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
 	setupAuthRoutes(app, routeGroup)
 	setupUserRoutes(app, routeGroup)
 	setupPipeRoutes(app, routeGroup)
+	setupBookmarkRoutes(app, routeGroup)
+	setupTagRoutes(app, routeGroup)
+	setupReminderRoutes(app, routeGroup)
+	setupTrashRoutes(app, routeGroup)
+	setupHistoryRoutes(app, routeGroup)
 	setupNotificationRoutes(app, routeGroup)
 	setupTwitterBotRoutes(app, routeGroup)
 	setupParserRoutes(app, routeGroup)
 	setupSearchRoutes(app, routeGroup)
+	setupProfileRoutes(app, routeGroup)
+	setupModerationRoutes(app, routeGroup)
 }
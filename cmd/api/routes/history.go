@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+)
+
+func setupHistoryRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewHistoryHandler(app)
+	history := routeGroup.Group("/history")
+	history.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
+	history.GET("/", h.GetHistory)
+	history.GET("/pipes/:id", h.GetPipeHistory)
+	history.GET("/bookmarks/:bmId", h.GetBookmarkHistory)
+	history.POST("/undo", h.Undo)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+)
+
+// setupModerationRoutes registers the routes users report content through and the admin routes
+// moderators work the reports through
+func setupModerationRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewModerationHandler(app)
+
+	reports := routeGroup.Group("/reports")
+	reports.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
+	reports.POST("/", h.CreateReport)
+
+	admin := routeGroup.Group("/admin")
+	admin.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key), middlewares.AdminRequired(app))
+	admin.GET("/reports", h.GetReports)
+	admin.GET("/reports/:id", h.GetReport)
+	admin.POST("/reports/:id/resolve", h.ResolveReport)
+	admin.DELETE("/pipes/:id/hidden", h.UnhidePipe)
+	admin.DELETE("/users/:id/suspension", h.UnsuspendUser)
+}
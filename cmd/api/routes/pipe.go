@@ -11,6 +11,9 @@ func setupPipeRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
 	h := handlers.NewPipeHandler(app)
 	bookmarkH := handlers.NewBookmarkHandler(app)
 	pipeShareH := handlers.NewPipeShareHandler(app)
+	reminderH := handlers.NewReminderHandler(app)
+	followH := handlers.NewFollowHandler(app)
+	commentH := handlers.NewCommentHandler(app)
 
 	pipe := routeGroup.Group("/pipe")
 	pipe.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
@@ -19,13 +22,46 @@ func setupPipeRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
 	pipe.GET("/:id", h.GetPipe)
 	pipe.POST("/bookmark", bookmarkH.CreateBookmark)
 	pipe.POST("/:id/share", pipeShareH.SharePipe)
+	pipe.GET("/:id/shares", pipeShareH.GetPipeShares)
+	pipe.PUT("/:id/share/public", pipeShareH.RotatePublicShareCode)
+	pipe.DELETE("/:id/share/public", pipeShareH.DisablePublicShare)
+	pipe.GET("/:id/share/stats", pipeShareH.GetShareLinkStats)
+	pipe.POST("/:id/leave", pipeShareH.LeavePipe)
+	pipe.PUT("/:id/collaborators/:userId", pipeShareH.UpdateCollaboratorRole)
+	pipe.DELETE("/:id/collaborators/:userId", pipeShareH.RemoveShareAccessFromPipe)
+	pipe.POST("/:id/follow", followH.FollowPipe)
+	pipe.PUT("/:id/follow", followH.UpdatePipeFollow)
+	pipe.DELETE("/:id/follow", followH.UnfollowPipe)
 	pipe.PUT("/:id", h.UpdatePipe)
+	pipe.PUT("/order", h.MovePipes)
+	pipe.PUT("/:id/parent", h.NestPipe)
+	pipe.POST("/:id/fork", h.ForkPipe)
+	pipe.POST("/:id/merge", h.MergePipes)
+	pipe.POST("/:id/split", h.SplitPipe)
 	pipe.DELETE("/:id", h.DeletePipe)
 	pipe.GET("/all", h.GetPipes)
 	pipe.GET("/preview", pipeShareH.PreviewPipe)
+	pipe.POST("/preview/bookmark/:bmId/open", pipeShareH.OpenSharedBookmark)
 	pipe.POST("/add-pipe", pipeShareH.AddPipe)
+	pipe.GET("/shared-with-me", pipeShareH.GetReceivedShares)
+	pipe.POST("/shared-with-me/:shareId/accept", pipeShareH.AcceptShare)
+	pipe.POST("/shared-with-me/:shareId/decline", pipeShareH.DeclineShare)
+	pipe.GET("/following", followH.GetFollowedPipes)
+	pipe.GET("/following/bookmarks", followH.GetFollowingFeed)
 
 	pipe.GET("/:id/bookmarks", bookmarkH.GetBookmarks)
+	pipe.PUT("/:id/bookmarks/order", bookmarkH.MoveBookmarks)
 	pipe.GET("/:id/bookmark/:bmId", bookmarkH.GetBookmark)
+	pipe.PATCH("/:id/bookmark/:bmId", bookmarkH.UpdateBookmark)
+	pipe.PATCH("/:id/bookmark/:bmId/state", bookmarkH.UpdateBookmarkState)
+	pipe.POST("/:id/bookmark/:bmId/reminders", reminderH.CreateReminder)
 	pipe.DELETE("/:id/bookmark/:bmId", bookmarkH.DeleteBookmark)
+	pipe.GET("/:id/bookmark/:bmId/comments", commentH.GetComments)
+	pipe.POST("/:id/bookmark/:bmId/comments", commentH.CreateComment)
+	pipe.POST("/:id/bookmark/:bmId/comments/:commentId/replies", commentH.CreateComment)
+	pipe.PATCH("/:id/bookmark/:bmId/comments/:commentId", commentH.UpdateComment)
+	pipe.DELETE("/:id/bookmark/:bmId/comments/:commentId", commentH.DeleteComment)
+	pipe.GET("/:id/bookmark/:bmId/reactions", commentH.GetReactions)
+	pipe.POST("/:id/bookmark/:bmId/reactions", commentH.AddReaction)
+	pipe.DELETE("/:id/bookmark/:bmId/reactions/:emoji", commentH.RemoveReaction)
 }
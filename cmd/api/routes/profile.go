@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+)
+
+// setupProfileRoutes registers the routes anyone can read public profiles and pipes through
+func setupProfileRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewProfileHandler(app)
+
+	routeGroup.GET("/u/:username", h.PublicProfile)
+	routeGroup.GET("/u/:username/pipes/:id", h.PublicPipe)
+	routeGroup.GET("/explore", h.Explore)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+)
+
+func setupReminderRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewReminderHandler(app)
+	reminder := routeGroup.Group("/reminders")
+	reminder.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
+	reminder.GET("/", h.GetReminders)
+	reminder.PUT("/rediscover", h.UpdateRediscoverSetting)
+	reminder.POST("/:reminderId/snooze", h.SnoozeReminder)
+	reminder.DELETE("/:reminderId", h.DeleteReminder)
+}
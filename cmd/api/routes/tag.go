@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+)
+
+func setupTagRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewTagHandler(app)
+	tag := routeGroup.Group("/tags")
+	tag.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
+	tag.GET("/", h.GetTags)
+	tag.POST("/", h.CreateTag)
+	tag.GET("/tree", h.GetTagTree)
+	tag.GET("/autocomplete", h.AutocompleteTags)
+	tag.GET("/suggestions", h.SuggestTags)
+	tag.GET("/:id", h.GetTag)
+	tag.PATCH("/:id", h.RenameTag)
+	tag.DELETE("/:id", h.DeleteTag)
+	tag.POST("/:id/merge", h.MergeTags)
+	tag.POST("/:id/move", h.MoveTag)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mypipeapp/mypipeapi/cmd/api/handlers"
+	"github.com/mypipeapp/mypipeapi/cmd/api/internal"
+	"github.com/mypipeapp/mypipeapi/cmd/api/middlewares"
+)
+
+func setupTrashRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
+	h := handlers.NewTrashHandler(app)
+	trash := routeGroup.Group("/trash")
+	trash.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
+	trash.GET("/", h.GetTrash)
+	trash.DELETE("/", h.EmptyTrash)
+	trash.POST("/pipes/:id/restore", h.RestorePipe)
+	trash.DELETE("/pipes/:id", h.PurgePipe)
+	trash.POST("/bookmarks/:bmId/restore", h.RestoreBookmark)
+	trash.DELETE("/bookmarks/:bmId", h.PurgeBookmark)
+}
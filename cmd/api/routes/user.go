@@ -9,6 +9,8 @@ import (
 
 func setupUserRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
 	h := handlers.NewUserHandler(app)
+	profileH := handlers.NewProfileHandler(app)
+	followH := handlers.NewFollowHandler(app)
 
 	user := routeGroup.Group("/user")
 	user.Use(middlewares.AuthRequired(app, app.Services.JWTConfig.Key))
@@ -16,4 +18,14 @@ func setupUserRoutes(app internal.Application, routeGroup *gin.RouterGroup) {
 	user.PATCH("/profile", h.EditProfile)
 	user.PATCH("/profile/change-password", h.ChangePassword)
 	user.POST("/profile/cover-photo", h.UploadCoverPhoto)
+	user.GET("/profile/settings", profileH.GetProfileSettings)
+	user.PUT("/profile/settings", profileH.UpdateProfileSettings)
+	user.GET("/followers", followH.GetFollowers)
+	user.GET("/following", followH.GetFollowing)
+	user.POST("/following/:username", followH.FollowUser)
+	user.DELETE("/following/:username", followH.UnfollowUser)
+	user.GET("/blocked", followH.GetBlockedUsers)
+	user.POST("/blocked/:username", followH.BlockUser)
+	user.DELETE("/blocked/:username", followH.UnblockUser)
+	user.GET("/feed", followH.GetActivityFeed)
 }
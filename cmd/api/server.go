@@ -32,12 +32,20 @@ func serveApp(db *sql.DB, logger zerolog.Logger) {
 		PasswordReset:       postgres.NewPasswordResetActions(db, logger),
 		Tag:                 postgres.NewTagActions(db, logger),
 		Search:              postgres.NewSearchActions(db, logger),
+		Reminder:            postgres.NewReminderActions(db, logger),
+		Trash:               postgres.NewTrashActions(db, logger),
+		History:             postgres.NewHistoryActions(db, logger),
+		Follow:              postgres.NewFollowActions(db, logger),
+		Activity:            postgres.NewActivityActions(db, logger),
+		Comment:             postgres.NewCommentActions(db, logger),
+		Moderation:          postgres.NewModerationActions(db, logger),
 	}
 
 	jwtConfig, err := initJWTConfig()
 	if err != nil {
 		logger.Err(err).Msg("jwt config")
 	}
+	schedulerConfig := initSchedulerConfig()
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	mailerP := initMailer()
 	defer stop()
@@ -51,9 +59,13 @@ func serveApp(db *sql.DB, logger zerolog.Logger) {
 			Logger:       logger,
 			JWTConfig:    jwtConfig,
 			Mailer:       mailerP,
+			Scheduler:    schedulerConfig,
 		},
 	}
 
+	// run background jobs such as reminders alongside the api
+	go app.Services.RunScheduler(ctx)
+
 	// setup router
 	router := gin.Default()
 	router.Use(cors.Default())
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"regexp"
+	"strings"
+)
+
+// maxCommentMentions is the number of users a comment can notify by mentioning them
+const maxCommentMentions = 10
+
+// mentionRegex matches the @username mentions of a comment, leaving out email addresses
+var mentionRegex = regexp.MustCompile(`(?:^|[^\w@.])@(\w[\w.-]*)`)
+
+// commentMentions returns the usernames mentioned in a comment, once each and in the order
+// they are first mentioned
+func commentMentions(body string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range mentionRegex.FindAllStringSubmatch(body, -1) {
+		// mentions at the end of a sentence are followed by punctuation
+		username := strings.TrimRight(match[1], ".-")
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// NotifyCommentMentions notifies the users mentioned in a comment who can see the pipe of the bookmark
+// it's on. Users already mentioned in previousBody, the body of the comment before it was edited, are
+// not notified again and authors are never notified of their own mentions
+func (s Services) NotifyCommentMentions(bookmark models.Bookmark, comment models.BookmarkComment, previousBody string) {
+	mentioned := map[string]bool{}
+	for _, username := range commentMentions(previousBody) {
+		mentioned[username] = true
+	}
+
+	var notified int
+	for _, username := range commentMentions(comment.Body) {
+		if notified == maxCommentMentions {
+			break
+		}
+		if mentioned[username] || username == comment.Username {
+			continue
+		}
+		user, err := s.Repositories.User.GetUserByUsername(username)
+		if err != nil {
+			continue
+		}
+		if _, err := s.AuthorizePipe(bookmark.PipeID, user.ID, models.PipeRoleViewer); err != nil {
+			continue
+		}
+		notified++
+		message := comment.Username + " mentioned you in a comment on " + bookmark.Url
+		err = s.NotifyUser(user.ID, "New mention", message, models.MDCommentMention{Bookmark: bookmark, Comment: comment})
+		if err != nil {
+			s.Logger.Err(err).Msg(fmt.Sprintf("could not notify user %d of a mention", user.ID))
+		}
+	}
+}
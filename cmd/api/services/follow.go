@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+// NotifyPipeFollowers notifies the users who follow the pipe of a bookmark as bookmarks are added
+// that the bookmark was added, leaving out the user who added it
+func (s Services) NotifyPipeFollowers(bookmark models.Bookmark, addedBy int64) {
+	followers, err := s.Repositories.Follow.GetPipeFollowers(bookmark.PipeID, models.FollowNotifyInstant)
+	if err != nil {
+		s.Logger.Err(err).Msg(fmt.Sprintf("could not retrieve the followers of pipe %d", bookmark.PipeID))
+		return
+	}
+	if len(followers) == 0 {
+		return
+	}
+	pipe, err := s.Repositories.Pipe.GetPipe(bookmark.PipeID, bookmark.UserID)
+	if err != nil {
+		s.Logger.Err(err).Msg(fmt.Sprintf("could not retrieve pipe %d", bookmark.PipeID))
+		return
+	}
+
+	message := "New bookmark in " + pipe.DisplayName + ": " + bookmark.Url
+	for _, userId := range followers {
+		if userId == addedBy {
+			continue
+		}
+		err := s.NotifyUser(userId, "New bookmark", message, models.MDPipeBookmarkAdded{Pipe: pipe, Bookmark: bookmark})
+		if err != nil {
+			s.Logger.Err(err).Msg(fmt.Sprintf("could not notify user %d of a new bookmark", userId))
+		}
+	}
+}
+
+// SendFollowDigests sends the users who get a daily digest of the pipes they follow one notification
+// counting the bookmarks added to those pipes since their last digest
+func (s Services) SendFollowDigests(now time.Time) error {
+	digests, err := s.Repositories.Follow.ClaimFollowDigests(now.Add(-24*time.Hour), schedulerBatchSize)
+	if err != nil {
+		return err
+	}
+
+	byUser := map[int64][]models.FollowDigest{}
+	var userIds []int64
+	for _, digest := range digests {
+		if _, ok := byUser[digest.UserID]; !ok {
+			userIds = append(userIds, digest.UserID)
+		}
+		byUser[digest.UserID] = append(byUser[digest.UserID], digest)
+	}
+	for _, userId := range userIds {
+		pipes := byUser[userId]
+		var count int
+		for _, pipe := range pipes {
+			count += pipe.Bookmarks
+		}
+		message := fmt.Sprintf("%d new bookmarks in %d pipes you follow", count, len(pipes))
+		if len(pipes) == 1 {
+			message = fmt.Sprintf("%d new bookmarks in %s", count, pipes[0].PipeName)
+		}
+		err := s.NotifyUser(userId, "Pipes you follow", message, models.MDFollowDigest{Pipes: pipes})
+		if err != nil {
+			s.Logger.Err(err).Msg(fmt.Sprintf("could not send the follow digest of user %d", userId))
+		}
+	}
+	return nil
+}
+
+// RecordActivity records something a user did on one of their pipes so that it shows up in the
+// activity feed of their followers. Failing to record it doesn't fail what the user did
+func (s Services) RecordActivity(activity models.Activity) {
+	if _, err := s.Repositories.Activity.RecordActivity(activity); err != nil {
+		s.Logger.Err(err).Msg(fmt.Sprintf("could not record the %s activity of user %d", activity.Verb, activity.ActorID))
+	}
+}
@@ -3,6 +3,7 @@ package mailer
 import (
 	"fmt"
 	"github.com/jordan-wright/email"
+	"html"
 	"net/smtp"
 )
 
@@ -53,3 +54,18 @@ func (m *Mailer) SendPasswordResetToken(mailTo []string, token string) error {
 	}
 	return nil
 }
+
+// SendPipeShareInvitation invites someone without an account to a pipe shared with their email
+func (m *Mailer) SendPipeShareInvitation(mailTo []string, sharer, pipeName string) error {
+	m.Transporter.HTML = []byte(fmt.Sprintf(
+		"<h2>%v shared the pipe %v with you</h2><p>Sign up to MyPipe with this email address to find it among the pipes shared with you</p>",
+		html.EscapeString(sharer), html.EscapeString(pipeName),
+	))
+	m.Transporter.Subject = sharer + " shared a pipe with you on MyPipe"
+	m.Transporter.To = mailTo
+	err := m.Transporter.Send(m.Addr, m.Auth)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+)
+
+// ModerateReport takes an action on what a report is about and closes every open report on it.
+// The action must be one that can be taken on reports of the type of the report
+func (s Services) ModerateReport(report models.Report, moderatorId int64, action, note string) (models.Report, error) {
+	var err error
+	switch action {
+	case models.ModerationHide:
+		if report.TargetType == models.ReportTargetComment {
+			err = s.Repositories.Comment.DeleteComment(report.TargetID)
+		} else if report.PipeID != nil {
+			err = s.Repositories.Moderation.HidePipe(*report.PipeID)
+		}
+	case models.ModerationUnpublish:
+		if report.PipeID != nil {
+			err = s.Repositories.Moderation.UnpublishPipe(*report.PipeID)
+		}
+	case models.ModerationSuspend:
+		err = s.Repositories.Moderation.SuspendUser(report.TargetUserID)
+	}
+	// what was reported may have been deleted since, in which case there's nothing left to act on
+	if err != nil && err != postgres.ErrNoRecord {
+		return report, err
+	}
+	return s.Repositories.Moderation.ResolveReports(report, moderatorId, action, note)
+}
@@ -58,6 +58,32 @@ func (s Services) CreatePrivatePipeShareNotification(sharedPipeCode string, shar
 	return nil
 }
 
+// NotifyUser records a notification for a user and tries to deliver it to their devices as a
+// push notification. Failing to send the push notification is logged but not returned
+func (s Services) NotifyUser(userId int64, title, message string, metadata interface{}) error {
+	var mdToJson []byte
+	if metadata != nil {
+		mdToJson, _ = json.Marshal(metadata)
+	}
+	_, err := s.Repositories.Notification.CreateNotification(userId, message, string(mdToJson))
+	if err != nil {
+		return err
+	}
+
+	userDeviceTokens, err := s.Repositories.User.GetUserDeviceTokens(userId)
+	if err != nil {
+		s.Logger.Err(err).Msg("An error occurred while fetching user device tokens")
+		return nil
+	}
+	if len(userDeviceTokens) == 0 {
+		return nil
+	}
+	if pnErr := s.SendPushNotification(title, message, userDeviceTokens); pnErr != nil {
+		s.Logger.Err(pnErr).Msg("An error occurred while sending push notification")
+	}
+	return nil
+}
+
 func (s Services) SendPushNotification(title, message string, deviceTokens []string) error {
 	serviceAccountKeyFilePath, err := filepath.Abs("./fbServiceAccount.json")
 	if err != nil {
@@ -3,10 +3,19 @@ package services
 import (
 	"fmt"
 	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
+const (
+	maxPipeDescriptionLength = 5000
+	maxPipeIconLength        = 32
+)
+
+var pipeColorRegex = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
+
 func (s Services) PipeExists(pipeId, userId int64) (bool, error) {
 	pipe, err := s.Repositories.Pipe.GetPipe(pipeId, userId)
 	if err != nil {
@@ -32,6 +41,59 @@ func (s Services) UserOwnsPipe(pipeId, userId int64) (bool, error) {
 	return true, nil
 }
 
+// ValidateSmartQuery checks that a smart pipe query of a user selects something and only
+// draws bookmarks from the pipes of that user
+func (s Services) ValidateSmartQuery(query *models.SmartQuery, userId int64) error {
+	if query == nil || query.IsEmpty() {
+		return fmt.Errorf("a smart pipe needs a query with at least one criterion")
+	}
+	if !models.ValidBookmarkState(query.State) {
+		return fmt.Errorf("invalid state. valid states are: *unread*, *read*, *starred*, *archived* and *all*")
+	}
+	if query.After != nil && query.Before != nil && !query.After.Before(*query.Before) {
+		return fmt.Errorf("*after* must be earlier than *before*")
+	}
+	if query.LastDays < 0 {
+		return fmt.Errorf("*last_days* can not be negative")
+	}
+	for _, pipeId := range query.PipeIDs {
+		if _, err := s.UserOwnsPipe(pipeId, userId); err != nil {
+			return fmt.Errorf("pipe %d could not be found in your collection", pipeId)
+		}
+	}
+	return nil
+}
+
+// ValidatePipeMetadata checks the description, icon, colour and visibility of a pipe.
+// Each of them can be left empty
+func (s Services) ValidatePipeMetadata(pipe models.Pipe) error {
+	if utf8.RuneCountInString(pipe.Description) > maxPipeDescriptionLength {
+		return fmt.Errorf("description can not be longer than %d characters", maxPipeDescriptionLength)
+	}
+	if utf8.RuneCountInString(pipe.Icon) > maxPipeIconLength {
+		return fmt.Errorf("icon can not be longer than %d characters", maxPipeIconLength)
+	}
+	if pipe.Color != "" && !pipeColorRegex.MatchString(pipe.Color) {
+		return fmt.Errorf("color must be a hex colour such as *#1da1f2*")
+	}
+	if pipe.Visibility != "" && !models.ValidPipeVisibility(pipe.Visibility) {
+		return fmt.Errorf("invalid visibility. valid visibilities are: *private*, *unlisted* and *public*")
+	}
+	return nil
+}
+
+// SyncForkedPipes copies the bookmarks added to forked pipes into the forks that keep in sync with them
+func (s Services) SyncForkedPipes() error {
+	copied, err := s.Repositories.Pipe.SyncForks(schedulerBatchSize)
+	if err != nil {
+		return err
+	}
+	if copied > 0 {
+		s.Logger.Info().Msg(fmt.Sprintf("copied %d bookmarks into forked pipes", copied))
+	}
+	return nil
+}
+
 func (s Services) GetPlatformFromLink(link string) (string, error) {
 	linkSplit := strings.Split(link, "://")[1]
 	r, _ := regexp.Compile("^((?:https?:)?\\/\\/)?((?:www|m)\\.)?((?:youtube(-nocookie)?\\.com|youtu.be))(\\/(?:[\\w\\-]+\\?v=|embed\\/|v\\/)?)([\\w\\-]+)(\\S+)?$")
@@ -5,14 +5,24 @@ import (
 	"github.com/mypipeapp/mypipeapi/cmd/api/helpers"
 	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
 	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
 )
 
-func (s Services) SharePipePublicly(pipeId, sharerId int64) (models.SharedPipe, error) {
+var (
+	ErrShareLinkExpired   = fmt.Errorf("this share link has expired")
+	ErrShareLinkExhausted = fmt.Errorf("this share link has been used as many times as it allows")
+	ErrShareLinkPassword  = fmt.Errorf("this share link needs a valid password")
+
+	ErrShareInvitationExpired = fmt.Errorf("this invitation has expired, ask for the pipe to be shared with you again")
+)
+
+// SharePipePublicly creates the public share link of a pipe of ownerId with the given limits
+func (s Services) SharePipePublicly(pipeId, ownerId int64, settings models.ShareLinkSettings) (models.SharedPipe, error) {
 	var sharedPipeRecord models.SharedPipe
 	var pipeToBeShared models.Pipe
 	var err error
 
-	pipeToBeShared, err = s.Repositories.Pipe.GetPipe(pipeId, sharerId)
+	pipeToBeShared, err = s.Repositories.Pipe.GetPipe(pipeId, ownerId)
 	if err != nil {
 		return sharedPipeRecord, err
 	}
@@ -20,15 +30,20 @@ func (s Services) SharePipePublicly(pipeId, sharerId int64) (models.SharedPipe,
 	sharedPipeRecord.SharerID = pipeToBeShared.UserID
 	sharedPipeRecord.Type = "public"
 	sharedPipeRecord.Code = helpers.RandomToken(15)
+	if err = applyShareLinkSettings(&sharedPipeRecord, settings); err != nil {
+		return sharedPipeRecord, err
+	}
 	// Parse an empty string to the receiver since it's a public pipe sharer
-	sharedPipeRecord, err = s.Repositories.PipeShare.CreatePipeShareRecord(sharedPipeRecord, "")
+	sharedPipeRecord, err = s.Repositories.PipeShare.CreatePipeShareRecord(sharedPipeRecord, "", "")
 	if err != nil {
 		return sharedPipeRecord, err
 	}
 	return sharedPipeRecord, nil
 }
 
-func (s Services) SharePipePrivately(shareRecord models.SharedPipe, shareTo string) (models.SharedPipe, error) {
+// SharePipePrivately shares a pipe with a user. The SharerID of shareRecord is the owner of the pipe, which
+// co-owners share on behalf of
+func (s Services) SharePipePrivately(shareRecord models.SharedPipe, shareTo string, role string) (models.SharedPipe, error) {
 	var sharedPipeRecord models.SharedPipe
 	var pipeToBeShared models.Pipe
 	var err error
@@ -42,24 +57,148 @@ func (s Services) SharePipePrivately(shareRecord models.SharedPipe, shareTo stri
 	sharedPipeRecord.Type = "private"
 	sharedPipeRecord.Code = helpers.RandomToken(15)
 	// Parse an empty string to the receiver since it's a public pipe sharer
-	sharedPipeRecord, err = s.Repositories.PipeShare.CreatePipeShareRecord(sharedPipeRecord, shareTo)
+	sharedPipeRecord, err = s.Repositories.PipeShare.CreatePipeShareRecord(sharedPipeRecord, shareTo, role)
 	if err != nil {
 		return sharedPipeRecord, err
 	}
 	return sharedPipeRecord, nil
 }
 
+// SharePipeByEmail invites someone without an account to a pipe of ownerId by email on behalf of sharerId,
+// who is the owner or a co-owner. The invitation becomes a private share once an account signs up with or
+// verifies the email
+func (s Services) SharePipeByEmail(pipeId, ownerId, sharerId int64, email, role string) (models.PipeShareInvitation, error) {
+	pipeToBeShared, err := s.Repositories.Pipe.GetPipe(pipeId, ownerId)
+	if err != nil {
+		return models.PipeShareInvitation{}, err
+	}
+	sharer, err := s.Repositories.User.GetUserById(sharerId)
+	if err != nil {
+		return models.PipeShareInvitation{}, err
+	}
+	invitation, err := s.Repositories.PipeShare.CreateShareInvitation(models.PipeShareInvitation{
+		SharerID:  pipeToBeShared.UserID,
+		PipeID:    pipeToBeShared.ID,
+		Email:     email,
+		Code:      helpers.RandomToken(15),
+		Role:      role,
+		ExpiresAt: time.Now().Add(models.PrivateShareTTL),
+	})
+	if err != nil {
+		return invitation, err
+	}
+	if err := s.Mailer.SendPipeShareInvitation([]string{invitation.Email}, sharer.Username, pipeToBeShared.Name); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while trying to send pipe share invitation")
+	}
+	return invitation, nil
+}
+
+// AttachShareInvitations turns the pipes shared with the email of a user before they had an account
+// into private shares of the user, notifying them of each one
+func (s Services) AttachShareInvitations(user models.User) error {
+	if !user.EmailVerified || user.Email == "" {
+		return nil
+	}
+	receivers, err := s.Repositories.PipeShare.AttachShareInvitations(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	for _, receiver := range receivers {
+		err := s.CreatePrivatePipeShareNotification(receiver.Code, receiver.SharedPipeId, receiver.SharerId, user.ID)
+		if err != nil {
+			s.Logger.Err(err).Msg("Error occurred during push notification")
+		}
+	}
+	return nil
+}
+
+// ValidateShareLinkSettings checks the limits given to a share link
+func (s Services) ValidateShareLinkSettings(settings models.ShareLinkSettings) error {
+	if settings.ExpiresAt != nil && !settings.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("*expires_at* must be in the future")
+	}
+	if settings.MaxRedemptions != nil && *settings.MaxRedemptions < 1 {
+		return fmt.Errorf("*max_redemptions* must be at least 1")
+	}
+	if settings.Permission != "" && !models.ValidSharePermission(settings.Permission) {
+		return fmt.Errorf("invalid permission. valid permissions are: *read-only* and *fork*")
+	}
+	return nil
+}
+
+// UpdateShareLinkSettings replaces every limit of a share link with the given ones
+func (s Services) UpdateShareLinkSettings(share models.SharedPipe, settings models.ShareLinkSettings) (models.SharedPipe, error) {
+	if err := applyShareLinkSettings(&share, settings); err != nil {
+		return share, err
+	}
+	return s.Repositories.PipeShare.UpdateShareLink(share)
+}
+
+// applyShareLinkSettings sets the limits of a share link, hashing its password
+func applyShareLinkSettings(share *models.SharedPipe, settings models.ShareLinkSettings) error {
+	share.ExpiresAt = settings.ExpiresAt
+	share.MaxRedemptions = settings.MaxRedemptions
+	share.Permission = settings.Permission
+	share.PasswordHash = ""
+	if settings.Password != "" {
+		hash, err := helpers.HashPassword(settings.Password)
+		if err != nil {
+			return err
+		}
+		share.PasswordHash = hash
+	}
+	return nil
+}
+
+// CheckShareLink checks that a share link can still be used with the given password, either to
+// preview its pipe or to add it to a collection. Read-only links can be added too; the users who
+// add them just can't fork the pipe
+func (s Services) CheckShareLink(share models.SharedPipe, password string) error {
+	if share.ExpiresAt != nil && !share.ExpiresAt.After(time.Now()) {
+		return ErrShareLinkExpired
+	}
+	if share.MaxRedemptions != nil && share.Redemptions >= *share.MaxRedemptions {
+		return ErrShareLinkExhausted
+	}
+	if share.HasPassword() {
+		if ok, _ := helpers.VerifyPassword(password, share.PasswordHash, ""); !ok {
+			return ErrShareLinkPassword
+		}
+	}
+	return nil
+}
+
+// CheckShareInvitation checks that a private share can still be accepted. Declined shares
+// can be accepted until they expire
+func (s Services) CheckShareInvitation(receiver models.SharedPipeReceiver) error {
+	if receiver.Status(time.Now()) == models.ShareStatusExpired {
+		return ErrShareInvitationExpired
+	}
+	return nil
+}
+
+// CanPreviewAndCanAdd checks that a user can preview and add the pipe shared with a code. Private pipes
+// can only be previewed and added by the user they were shared with, until their invitation expires
 func (s Services) CanPreviewAndCanAdd(code string, userId int64) (bool, error) {
 	pipeToAdd, err := s.Repositories.PipeShare.GetSharedPipeByCode(code)
 	if err != nil {
 		return false, err
 	}
-	if pipeToAdd.Type == "private" {
-		return false, fmt.Errorf("you can't view pipe because it's a private pipe")
-	}
 	if pipeToAdd.SharerID == userId {
 		return false, postgres.ErrCannotSharePipeToSelf
 	}
+	if pipeToAdd.Type == models.PipeShareTypePrivate {
+		receiver, err := s.Repositories.PipeShare.GetReceivedPipeRecordByCode(code, userId)
+		if err != nil {
+			if err == postgres.ErrNoRecord {
+				return false, fmt.Errorf("you can't view pipe because it's a private pipe")
+			}
+			return false, err
+		}
+		if err := s.CheckShareInvitation(receiver); err != nil {
+			return false, err
+		}
+	}
 	return true, nil
 }
 
@@ -73,3 +212,62 @@ func (s Services) AddPipeToCollection(pipeToAdd models.SharedPipe, userId int64)
 	}
 	return receiverInfo, nil
 }
+
+// RotatePublicShareCode replaces the public share code of a pipe with a new one
+func (s Services) RotatePublicShareCode(pipeId int64) (models.SharedPipe, error) {
+	return s.Repositories.PipeShare.UpdateSharedPipeCode(pipeId, models.PipeShareTypePublic, helpers.RandomToken(15))
+}
+
+// RevokePipeShare removes a user a pipe of the owner was shared with and lets them know they lost access to it
+func (s Services) RevokePipeShare(pipeId, ownerId, receiverId int64) (models.SharedPipeReceiver, error) {
+	pipe, err := s.Repositories.Pipe.GetPipe(pipeId, ownerId)
+	if err != nil {
+		return models.SharedPipeReceiver{}, err
+	}
+	receiver, err := s.Repositories.PipeShare.DeletePipeReceiver(pipeId, receiverId)
+	if err != nil {
+		return receiver, err
+	}
+
+	owner, err := s.Repositories.User.GetUserById(ownerId)
+	if err != nil {
+		s.Logger.Err(err).Msg("An error occurred while fetching the owner of a revoked pipe")
+		return receiver, nil
+	}
+	metadata := models.MDPipeShareRevoked{Owner: owner, Pipe: pipe}
+	message := owner.Username + " stopped sharing " + pipe.DisplayName + " with you"
+	if err := s.NotifyUser(receiverId, "Pipe share", message, metadata); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while notifying user of a revoked pipe share")
+	}
+	return receiver, nil
+}
+
+// AuthorizePipe checks that a user owns a pipe or collaborates on it with at least the given role
+// and returns their access to the pipe
+func (s Services) AuthorizePipe(pipeId, userId int64, role string) (models.PipeAccess, error) {
+	access, err := s.Repositories.PipeShare.GetPipeAccess(pipeId, userId)
+	if err != nil {
+		if err == postgres.ErrNoRecord {
+			return access, fmt.Errorf("pipe does not belong to this user")
+		}
+		return access, err
+	}
+	if !access.Allows(role) {
+		return access, fmt.Errorf("you need the *%s* role or a higher one on this pipe to do this", role)
+	}
+	return access, nil
+}
+
+// CanEditBookmark checks that a user can edit or delete a bookmark. Owners can edit their bookmarks,
+// editors every bookmark of the pipes they collaborate on and contributors the bookmarks they added
+func (s Services) CanEditBookmark(bookmark models.Bookmark, userId int64) error {
+	if bookmark.UserID == userId {
+		return nil
+	}
+	role := models.PipeRoleEditor
+	if bookmark.AddedBy != nil && *bookmark.AddedBy == userId {
+		role = models.PipeRoleContributor
+	}
+	_, err := s.AuthorizePipe(bookmark.PipeID, userId, role)
+	return err
+}
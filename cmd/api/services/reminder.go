@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"github.com/mypipeapp/mypipeapi/db/actions/postgres"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+var (
+	ErrReminderInThePast = fmt.Errorf("reminder time must be in the future")
+)
+
+// ResolveReminderTime works out when a reminder should fire. An explicit time takes precedence,
+// otherwise the offsets are added to now
+func ResolveReminderTime(now time.Time, remindAt *time.Time, inHours, inDays, inMonths int) (time.Time, error) {
+	var at time.Time
+	if remindAt != nil {
+		at = *remindAt
+	} else {
+		at = now.Add(time.Duration(inHours)*time.Hour).AddDate(0, inMonths, inDays)
+	}
+	if !at.After(now) {
+		return at, ErrReminderInThePast
+	}
+	return at, nil
+}
+
+// SendDueReminders delivers every reminder that is due at the given time through the notification pipeline.
+// Reminders that can't be delivered are released, so that they are tried again on the next run
+func (s Services) SendDueReminders(now time.Time) error {
+	reminders, err := s.Repositories.Reminder.ClaimDueReminders(now, schedulerBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		if err := s.sendReminder(reminder); err != nil {
+			s.Logger.Err(err).Msg(fmt.Sprintf("could not send reminder %d", reminder.ID))
+			if err := s.Repositories.Reminder.ReleaseReminder(reminder.ID); err != nil {
+				s.Logger.Err(err).Msg(fmt.Sprintf("could not release reminder %d", reminder.ID))
+			}
+		}
+	}
+	return nil
+}
+
+// sendReminder notifies the user of a reminder about the bookmark it's on
+func (s Services) sendReminder(reminder models.Reminder) error {
+	bookmark, err := s.Repositories.Bookmark.GetBookmark(reminder.BookmarkID, reminder.UserID)
+	if err != nil {
+		return err
+	}
+
+	message := "Reminder: " + bookmark.Url
+	if reminder.Note != "" {
+		message = "Reminder: " + reminder.Note + " - " + bookmark.Url
+	}
+	return s.NotifyUser(reminder.UserID, "Bookmark reminder", message, models.MDBookmarkReminder{
+		ReminderID: reminder.ID,
+		Note:       reminder.Note,
+		Bookmark:   bookmark,
+	})
+}
+
+// SendRediscoveries resurfaces an old unread bookmark for every user with rediscover mode turned on
+// who has not been sent one in the last day
+func (s Services) SendRediscoveries(now time.Time) error {
+	userIDs, err := s.Repositories.Reminder.ClaimRediscoveryUsers(now.Add(-24*time.Hour), schedulerBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		bookmark, err := s.Repositories.Reminder.GetRediscoverBookmark(userID, now.Add(-s.Scheduler.RediscoverMinAge))
+		if err != nil {
+			if err != postgres.ErrNoRecord {
+				s.Logger.Err(err).Msg(fmt.Sprintf("could not pick a bookmark to rediscover for user %d", userID))
+			}
+			continue
+		}
+
+		bookmark, _ = s.Repositories.Bookmark.ParseTags(bookmark)
+		message := "Rediscover something you saved: " + bookmark.Url
+		err = s.NotifyUser(userID, "Rediscover", message, models.MDBookmarkReminder{Bookmark: bookmark})
+		if err != nil {
+			s.Logger.Err(err).Msg(fmt.Sprintf("could not send rediscovered bookmark to user %d", userID))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// schedulerBatchSize is the maximum number of items a job processes on every tick
+	schedulerBatchSize = 100
+)
+
+type SchedulerConfig struct {
+	// Interval is how often the scheduled jobs run
+	Interval time.Duration
+	// RediscoverMinAge is how old a bookmark must be before it can be rediscovered
+	RediscoverMinAge time.Duration
+	// TrashRetention is how long deleted pipes and bookmarks are kept in the trash.
+	// Items are kept forever when it is not set
+	TrashRetention time.Duration
+}
+
+// RunScheduler runs the background jobs of the api on every tick of the configured
+// interval until ctx is cancelled
+func (s Services) RunScheduler(ctx context.Context) {
+	if s.Scheduler.Interval <= 0 {
+		s.Logger.Info().Msg("scheduler interval not set, background jobs will not run")
+		return
+	}
+
+	ticker := time.NewTicker(s.Scheduler.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.Logger.Info().Msg("stopping scheduler")
+			return
+		case now := <-ticker.C:
+			s.runScheduledJobs(now)
+		}
+	}
+}
+
+func (s Services) runScheduledJobs(now time.Time) {
+	if err := s.SendDueReminders(now); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while sending due reminders")
+	}
+	if err := s.SendRediscoveries(now); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while sending rediscovered bookmarks")
+	}
+	if err := s.PurgeExpiredTrash(now); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while purging the trash")
+	}
+	if err := s.SyncForkedPipes(); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while syncing forked pipes")
+	}
+	if err := s.SendFollowDigests(now); err != nil {
+		s.Logger.Err(err).Msg("An error occurred while sending follow digests")
+	}
+}
@@ -11,4 +11,5 @@ type Services struct {
 	Repositories repository.Repositories
 	Logger       zerolog.Logger
 	JWTConfig    JWTConfig
+	Scheduler    SchedulerConfig
 }
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxShareStatsDays is the number of days share link stats can be asked for at once
+const maxShareStatsDays = 366
+
+// RecordShareLinkEvent records an event for the code a pipe was shared with. Only the host of the
+// referrer and the type of the client are kept. Failing to record an event never fails the request
+// it was recorded for, so errors are only logged
+func (s Services) RecordShareLinkEvent(share models.SharedPipe, event string, bookmarkId *int64, referrer, userAgent string) {
+	_, err := s.Repositories.PipeShare.RecordShareLinkEvent(models.ShareLinkEvent{
+		SharedPipeID: share.ID,
+		PipeID:       share.PipeID,
+		Code:         share.Code,
+		Event:        event,
+		BookmarkID:   bookmarkId,
+		Referrer:     ReferrerSource(referrer),
+		ClientType:   ClientType(userAgent),
+	})
+	if err != nil {
+		s.Logger.Err(err).Msg("Could not record share link event")
+	}
+}
+
+// ShareStatsRange returns the days share link stats are fetched for, which are the last 30 days
+// unless other days are given
+func (s Services) ShareStatsRange(from, to *time.Time) (time.Time, time.Time, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.AddDate(0, 0, -29)
+	if from != nil {
+		start = *from
+	}
+	if start.After(end) {
+		return start, end, fmt.Errorf("*from* must not be after *to*")
+	}
+	if end.Sub(start) > maxShareStatsDays*24*time.Hour {
+		return start, end, fmt.Errorf("stats can only be fetched for %d days at once", maxShareStatsDays)
+	}
+	return start, end, nil
+}
+
+// ReferrerSource returns what is kept of the referrer of a share event: the host of a referrer url,
+// or the name of the source the client reported, like "twitter"
+func ReferrerSource(referrer string) string {
+	referrer = strings.ToLower(strings.TrimSpace(referrer))
+	if u, err := url.Parse(referrer); err == nil && u.Host != "" {
+		referrer = u.Hostname()
+	}
+	referrer = strings.TrimPrefix(referrer, "www.")
+	if len(referrer) > 255 {
+		referrer = referrer[:255]
+	}
+	return referrer
+}
+
+// ClientType returns the coarse type of the client a user agent belongs to
+func ClientType(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return models.ClientTypeOther
+	case strings.Contains(ua, "bot"), strings.Contains(ua, "crawler"), strings.Contains(ua, "spider"):
+		return models.ClientTypeBot
+	case strings.Contains(ua, "android"), strings.Contains(ua, "okhttp"):
+		return models.ClientTypeAndroid
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "cfnetwork"):
+		return models.ClientTypeIOS
+	case strings.Contains(ua, "mozilla"):
+		return models.ClientTypeWeb
+	}
+	return models.ClientTypeOther
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidLink is returned when tags are suggested for something that isn't a link
+var ErrInvalidLink = errors.New("please specify a valid *url*")
+
+// ValidateTagName checks the name of a tag. Tags are sent to bookmarks as a comma separated list,
+// so a name can't have a comma
+func (s Services) ValidateTagName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("please specify the *name* of the tag")
+	}
+	if strings.Contains(name, ",") {
+		return fmt.Errorf("the name of a tag can not have a comma")
+	}
+	if utf8.RuneCountInString(name) > models.MaxTagNameLength {
+		return fmt.Errorf("the name of a tag can not be longer than %d characters", models.MaxTagNameLength)
+	}
+	return nil
+}
+
+// linkRegex matches the host of a link, without www, and the first segment of its path, which is
+// its section. It reads links the same way the tags of similar links are looked up
+var linkRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://(?:www\.)?([^/:?#]+)[^/?#]*(?:/([^/?#]+))?`)
+
+// maxMetadataWords is the number of words of the title and description of a link that are matched with tags
+const maxMetadataWords = 100
+
+// metadataKeys returns the tag keys of the words of text and of every two words following each
+// other, so that "Go lang" matches a tag named "golang"
+func metadataKeys(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+	if len(words) > maxMetadataWords {
+		words = words[:maxMetadataWords]
+	}
+	var keys []string
+	for i, word := range words {
+		keys = append(keys, models.TagKey(word))
+		if i > 0 {
+			keys = append(keys, models.TagKey(words[i-1]+word))
+		}
+	}
+	return keys
+}
+
+// siteName returns the name of the site of a host, which is the label before its public suffix,
+// e.g. github for github.com and bbc for bbc.co.uk
+func siteName(host string) string {
+	labels := strings.Split(host, ".")
+	switch {
+	case len(labels) < 2:
+		return host
+	case len(labels) > 2 && len(labels[len(labels)-1]) == 2 && len(labels[len(labels)-2]) <= 3:
+		return labels[len(labels)-3]
+	default:
+		return labels[len(labels)-2]
+	}
+}
+
+// SuggestTags suggests tags for a link. The tags the user put on links of the same site come first,
+// then their tags found in the title or description parsed from the link and then the name of the
+// platform or site of the link, which is suggested even when the user has no such tag yet
+func (s Services) SuggestTags(userId int64, link, title, description string, limit int) ([]models.TagSuggestion, error) {
+	link = strings.TrimSpace(link)
+	match := linkRegex.FindStringSubmatch(link)
+	if match == nil {
+		return nil, ErrInvalidLink
+	}
+	host, section := strings.ToLower(match[1]), match[2]
+
+	suggestions := make([]models.TagSuggestion, 0, limit)
+	suggested := map[string]bool{}
+	suggest := func(tag models.Tag, reason string) {
+		if key := models.TagKey(tag.Name); !suggested[key] && len(suggestions) < limit {
+			suggested[key] = true
+			suggestions = append(suggestions, models.TagSuggestion{Tag: tag, Reason: reason})
+		}
+	}
+
+	similar, err := s.Repositories.Tag.GetTagsOfSimilarLinks(userId, link, host, section, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range similar {
+		suggest(tag, models.TagSuggestionSimilarLinks)
+	}
+
+	site, _ := s.GetPlatformFromLink(link)
+	if site == "others" {
+		site = siteName(host)
+	}
+	keys := append(metadataKeys(title+" "+description), models.TagKey(site))
+	tags, err := s.Repositories.Tag.GetTagsByKeys(userId, keys, maxMetadataWords)
+	if err != nil {
+		return nil, err
+	}
+	var siteTag *models.Tag
+	for i, tag := range tags {
+		if models.TagKey(tag.Name) == models.TagKey(site) {
+			siteTag = &tags[i]
+			continue
+		}
+		suggest(tag, models.TagSuggestionMetadata)
+	}
+	if siteTag == nil {
+		siteTag = &models.Tag{UserID: userId, Name: site}
+	}
+	suggest(*siteTag, models.TagSuggestionDomain)
+	return suggestions, nil
+}
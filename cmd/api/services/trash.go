@@ -0,0 +1,23 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// PurgeExpiredTrash permanently removes the pipes and bookmarks that have been in the trash
+// for longer than the configured retention period
+func (s Services) PurgeExpiredTrash(now time.Time) error {
+	if s.Scheduler.TrashRetention <= 0 {
+		return nil
+	}
+
+	purged, err := s.Repositories.Trash.PurgeExpired(now.Add(-s.Scheduler.TrashRetention))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		s.Logger.Info().Msg(fmt.Sprintf("purged %d items from the trash", purged))
+	}
+	return nil
+}
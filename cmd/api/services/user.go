@@ -78,6 +78,9 @@ func (s Services) MarkUserAsVerified(user models.User, token string) (models.Use
 	if err != nil {
 		s.Logger.Err(err).Msg("Could not delete verification token from db")
 	}
+	if err = s.AttachShareInvitations(user); err != nil {
+		s.Logger.Err(err).Msg("Could not attach the pipes shared with the email of the user")
+	}
 	return user, nil
 }
 
@@ -0,0 +1,117 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+/*
+TestCoOwnerSharePipeFlow tests the flow involved in a co-owner sharing a pipe on behalf of its owner
+--------------------
+# Tested endpoints:
+---| /v1/pipe/:id/share (POST)
+*/
+func TestCoOwnerSharePipeFlow(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	// make the global user a co-owner of the second pipe of user1
+	query := `
+	INSERT INTO shared_pipe_receivers (sharer_id, shared_pipe_id, receiver_id, code, is_accepted, role)
+	VALUES (1, 2, $1, 'e2eCoOwner01', true, 'co-owner')
+	`
+	if _, err := db.Exec(query, globalUserID); err != nil {
+		t.Fatalf("could not make the user a co-owner: %s", err)
+	}
+
+	t.Run("/v1/pipe/:id/share", func(t *testing.T) {
+		t.Run("share publicly as a co-owner", func(t *testing.T) {
+			resJSON := struct {
+				Message string `json:"message"`
+				Data    struct {
+					ShareCode string `json:"share_code"`
+				} `json:"data"`
+			}{}
+			req, err := http.NewRequest(http.MethodPost, "/v1/pipe/2/share?type=public", bytes.NewBuffer([]byte(`{}`)))
+			if err != nil {
+				t.Fatalf("could not create request %s", err)
+			}
+			req = attachAuthHeader(req)
+			res := executeRequest(req)
+			resBody, _ := io.ReadAll(res.Body)
+			json.Unmarshal(resBody, &resJSON)
+			t.Log(resJSON.Message)
+
+			checkResponseCode(t, http.StatusCreated, res.Code)
+			if resJSON.Data.ShareCode == "" {
+				t.Fatalf("expected a share code")
+			}
+		})
+
+		t.Run("share privately as a co-owner", func(t *testing.T) {
+			reqBody := []byte(`{"username": "user3"}`)
+			req, err := http.NewRequest(http.MethodPost, "/v1/pipe/2/share?type=private", bytes.NewBuffer(reqBody))
+			if err != nil {
+				t.Fatalf("could not create request %s", err)
+			}
+			req = attachAuthHeader(req)
+			res := executeRequest(req)
+
+			checkResponseCode(t, http.StatusOK, res.Code)
+		})
+	})
+}
+
+/*
+TestShareLinkPermissionFlow tests that pipes added through read-only share links can be read but not forked
+--------------------
+# Tested endpoints:
+---| /v1/pipe/add-pipe (POST)
+---| /v1/pipe/:id/fork (POST)
+*/
+func TestShareLinkPermissionFlow(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	// share the pipes of user2 through a read-only and a fork link
+	query := `
+	INSERT INTO shared_pipes (sharer_id, pipe_id, type, code, permission)
+	VALUES (2, 3, 'public', 'e2eReadOnly01', 'read-only'), (2, 4, 'public', 'e2eFork01', 'fork')
+	`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("could not share the pipes: %s", err)
+	}
+
+	testCases := map[string]struct {
+		code     string
+		pipeID   string
+		wantCode int
+	}{
+		"read-only link": {code: "e2eReadOnly01", pipeID: "3", wantCode: http.StatusForbidden},
+		"fork link":      {code: "e2eFork01", pipeID: "4", wantCode: http.StatusCreated},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/pipe/add-pipe?code="+tc.code, nil)
+			if err != nil {
+				t.Fatalf("could not create request %s", err)
+			}
+			req = attachAuthHeader(req)
+			res := executeRequest(req)
+			checkResponseCode(t, http.StatusOK, res.Code)
+
+			req, err = http.NewRequest(http.MethodPost, "/v1/pipe/"+tc.pipeID+"/fork", nil)
+			if err != nil {
+				t.Fatalf("could not create request %s", err)
+			}
+			req = attachAuthHeader(req)
+			res = executeRequest(req)
+			checkResponseCode(t, tc.wantCode, res.Code)
+		})
+	}
+}
@@ -105,6 +105,13 @@ func createApplicationInstance() internal.Application {
 		PasswordReset:       postgres.NewPasswordResetActions(db, logger),
 		Tag:                 postgres.NewTagActions(db, logger),
 		Search:              postgres.NewSearchActions(db, logger),
+		Reminder:            postgres.NewReminderActions(db, logger),
+		Trash:               postgres.NewTrashActions(db, logger),
+		History:             postgres.NewHistoryActions(db, logger),
+		Follow:              postgres.NewFollowActions(db, logger),
+		Activity:            postgres.NewActivityActions(db, logger),
+		Comment:             postgres.NewCommentActions(db, logger),
+		Moderation:          postgres.NewModerationActions(db, logger),
 	}
 
 	appInstance := internal.Application{
@@ -8,5 +8,12 @@ var (
 	ErrDuplicateUsername  = fmt.Errorf("user with username already exits")
 	ErrDuplicateEmail     = fmt.Errorf("user with email already exits")
 	ErrDuplicateTwitterID = fmt.Errorf("user with twitter_id already exits")
+	ErrPipeInTrash        = fmt.Errorf("the pipe this bookmark belongs to is in the trash")
+	ErrInvalidCursor      = fmt.Errorf("invalid pagination cursor")
+	ErrPipeCycle          = fmt.Errorf("a pipe can not be nested in itself or in one of its own nested pipes")
+	ErrSmartPipe          = fmt.Errorf("bookmarks can not be added to a smart pipe")
+	ErrTagCycle           = fmt.Errorf("a tag can not be put under itself or under one of the tags under it")
+	ErrTagNameTooLong     = fmt.Errorf("the name of a tag along with the tags it's under is too long")
+	ErrForkNotAllowed     = fmt.Errorf("this pipe was shared with a read-only link and can't be forked")
 	//ErrNoRowsInResultSet = fmt.Errorf("no rows in result set")
 )
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// activityFeedOrdering lists the activities of the users someone follows from the most recent
+var activityFeedOrdering = ordering{
+	name:  "activity-feed-" + models.SortNewest,
+	keys:  []sortKey{{expr: "a.created_at", desc: true}, {expr: "a.id", desc: true}},
+	table: "activities a",
+}
+
+type activityActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewActivityActions(db *sql.DB, logger zerolog.Logger) repository.ActivityRepository {
+	return activityActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+// RecordActivity records something a user did on one of their pipes
+func (a activityActions) RecordActivity(activity models.Activity) (models.Activity, error) {
+	query := `
+	INSERT INTO activities (actor_id, verb, pipe_id, bookmark_id, source_pipe_id)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := a.Db.QueryRowContext(
+		ctx,
+		query,
+		activity.ActorID,
+		activity.Verb,
+		activity.PipeID,
+		activity.BookmarkID,
+		activity.SourcePipeID,
+	).Scan(&activity.ID, &activity.CreatedAt)
+	if err != nil {
+		return activity, err
+	}
+	return activity, nil
+}
+
+// GetActivityFeed gets a page of what the users a user follows did on their public pipes, from the
+// most recent. The feed is read from the activities of the followed users as they are, so activities
+// on pipes that were made private or deleted since, or of users who made their profile private,
+// drop out of it
+func (a activityActions) GetActivityFeed(userId int64, filter models.Filter) ([]models.FeedActivity, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := activityFeedOrdering.page(ctx, a.Db, filter, []interface{}{userId})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT a.id, a.actor_id, a.verb, a.pipe_id, a.bookmark_id, a.source_pipe_id, a.created_at,
+	    u.username, COALESCE(NULLIF(p.display_name, ''), p.name), b.url
+	FROM user_follows f
+	    INNER JOIN activities a ON a.actor_id=f.followee_id
+	    INNER JOIN users u ON a.actor_id=u.id
+	    INNER JOIN pipes p ON a.pipe_id=p.id AND p.user_id=a.actor_id
+	    LEFT JOIN bookmarks b ON a.bookmark_id=b.id
+	WHERE f.follower_id=$1 AND u.public_profile=true
+	    AND p.deleted_at IS NULL AND p.visibility='` + models.PipeVisibilityPublic + `'
+	    AND (a.bookmark_id IS NULL OR b.deleted_at IS NULL)
+	    AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := a.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	var activities []models.FeedActivity
+	for rows.Next() {
+		var activity models.FeedActivity
+		err := rows.Scan(
+			&activity.ID,
+			&activity.ActorID,
+			&activity.Verb,
+			&activity.PipeID,
+			&activity.BookmarkID,
+			&activity.SourcePipeID,
+			&activity.CreatedAt,
+			&activity.Actor,
+			&activity.PipeName,
+			&activity.BookmarkUrl,
+		)
+		if err != nil {
+			return nil, models.Pagination{}, err
+		}
+		activities = append(activities, activity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := activityFeedOrdering.pagination(filter, len(activities), func(i int) int64 { return activities[i].ID })
+	return activities[:size], pagination, nil
+}
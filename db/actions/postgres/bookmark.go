@@ -3,12 +3,25 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/mypipeapp/mypipeapi/db/models"
 	"github.com/mypipeapp/mypipeapi/db/repository"
 	"github.com/rs/zerolog"
 	"time"
 )
 
+// bookmarkColumns is the list of columns selected whenever a full bookmark
+// record is retrieved. It expects the bookmarks table to be aliased as b and
+// must be kept in sync with scanBookmark
+const bookmarkColumns = `
+	b.id, b.user_id, b.pipe_id, b.platform, b.url, b.title, b.position, b.created_at,
+	b.is_read, b.read_at, b.is_starred, b.starred_at, b.is_archived, b.archived_at, b.deleted_at, b.forked_from,
+	b.added_by`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type bookmarkActions struct {
 	Db     *sql.DB
 	Logger zerolog.Logger
@@ -21,28 +34,90 @@ func NewBookmarkActions(db *sql.DB, logger zerolog.Logger) repository.BookmarkRe
 	}
 }
 
-// CreateBookmark creates a single bookmark record for a user
-func (b bookmarkActions) CreateBookmark(bm models.Bookmark) (models.Bookmark, error) {
-	var newBm models.Bookmark
+// scanBookmark reads a row selected with bookmarkColumns into a bookmark
+func scanBookmark(row rowScanner) (models.Bookmark, error) {
+	var bookmark models.Bookmark
+	err := row.Scan(
+		&bookmark.ID,
+		&bookmark.UserID,
+		&bookmark.PipeID,
+		&bookmark.Platform,
+		&bookmark.Url,
+		&bookmark.Title,
+		&bookmark.Position,
+		&bookmark.CreatedAt,
+		&bookmark.IsRead,
+		&bookmark.ReadAt,
+		&bookmark.IsStarred,
+		&bookmark.StarredAt,
+		&bookmark.IsArchived,
+		&bookmark.ArchivedAt,
+		&bookmark.DeletedAt,
+		&bookmark.ForkedFrom,
+		&bookmark.AddedBy,
+	)
+	return bookmark, err
+}
+
+// bookmarkStateCondition returns the SQL condition that matches bookmarks in the given state
+func bookmarkStateCondition(state string) string {
+	switch state {
+	case models.BookmarkStateUnread:
+		return "b.is_read=false AND b.is_archived=false"
+	case models.BookmarkStateRead:
+		return "b.is_read=true AND b.is_archived=false"
+	case models.BookmarkStateStarred:
+		return "b.is_starred=true"
+	case models.BookmarkStateArchived:
+		return "b.is_archived=true"
+	case models.BookmarkStateAll:
+		return "true"
+	default:
+		return "b.is_archived=false"
+	}
+}
+
+// bookmarkOrdering returns the ordering of a list of bookmarks for a sort option
+func bookmarkOrdering(sort string) ordering {
+	o := ordering{name: "bookmarks-" + sort, table: "bookmarks b"}
+	switch sort {
+	case models.SortNewest:
+		o.keys = []sortKey{{expr: "b.created_at", desc: true}, {expr: "b.id", desc: true}}
+	case models.SortOldest:
+		o.keys = []sortKey{{expr: "b.created_at"}, {expr: "b.id"}}
+	case models.SortTitle:
+		o.keys = []sortKey{{expr: "lower(COALESCE(NULLIF(b.title, ''), b.url))"}, {expr: "b.id"}}
+	case models.SortPlatform:
+		o.keys = []sortKey{{expr: "b.platform"}, {expr: "b.created_at", desc: true}, {expr: "b.id", desc: true}}
+	default:
+		o.name = "bookmarks-" + models.SortManual
+		o.keys = []sortKey{{expr: "b.pipe_id"}, {expr: "b.position"}, {expr: "b.id"}}
+	}
+	return o
+}
 
+// CreateBookmark creates a single bookmark record for a user at the end of its pipe.
+// The bookmark is added by its user unless AddedBy names the collaborator who added it.
+// Bookmarks can't be created in a smart pipe
+func (b bookmarkActions) CreateBookmark(bm models.Bookmark) (models.Bookmark, error) {
 	query := `
-	INSERT INTO bookmarks 
-	    (user_id, pipe_id, platform, url) 
-	VALUES($1, $2, $3, $4) 
-	RETURNING id, user_id, pipe_id, platform, url, created_at`
+	INSERT INTO bookmarks AS b
+	    (user_id, pipe_id, platform, url, title, position, added_by)
+	VALUES($1, $2, $3, $4, $5, $6, COALESCE($7, $1))
+	RETURNING` + bookmarkColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := b.Db.QueryRowContext(ctx, query, bm.UserID, bm.PipeID, bm.Platform, bm.Url).Scan(
-		&newBm.ID,
-		&newBm.UserID,
-		&newBm.PipeID,
-		&newBm.Platform,
-		&newBm.Url,
-		&newBm.CreatedAt,
-	)
+	if err := ensureStandardPipe(ctx, b.Db, bm.PipeID); err != nil {
+		return models.Bookmark{}, err
+	}
+	position, err := bookmarkPositionScope(bm.PipeID).nextPosition(ctx, b.Db)
+	if err != nil {
+		return models.Bookmark{}, err
+	}
 
+	newBm, err := scanBookmark(b.Db.QueryRowContext(ctx, query, bm.UserID, bm.PipeID, bm.Platform, bm.Url, bm.Title, position, bm.AddedBy))
 	if err != nil {
 		return models.Bookmark{}, err
 	}
@@ -50,28 +125,21 @@ func (b bookmarkActions) CreateBookmark(bm models.Bookmark) (models.Bookmark, er
 	return newBm, nil
 }
 
-// GetBookmark retrieve a single bookmark by ID and a designated User
+// GetBookmark retrieve a single bookmark by ID and a designated User, who either owns the
+// bookmark or collaborates on its pipe
 func (b bookmarkActions) GetBookmark(bmID, userID int64) (models.Bookmark, error) {
-	var bookmark models.Bookmark
-
 	query := `
-	SELECT id, user_id, pipe_id, platform, url, created_at 
-	FROM bookmarks 
-	WHERE id=$1 AND user_id=$2 
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
+	WHERE b.id=$2 AND b.deleted_at IS NULL AND (
+	    b.user_id=$1 OR b.pipe_id IN (` + receivedPipes + `)
+	)
 	LIMIT 1
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := b.Db.QueryRowContext(ctx, query, bmID, userID).Scan(
-		&bookmark.ID,
-		&bookmark.UserID,
-		&bookmark.PipeID,
-		&bookmark.Platform,
-		&bookmark.Url,
-		&bookmark.CreatedAt,
-	)
-
+	bookmark, err := scanBookmark(b.Db.QueryRowContext(ctx, query, userID, bmID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Bookmark{}, ErrNoRecord
@@ -82,44 +150,99 @@ func (b bookmarkActions) GetBookmark(bmID, userID int64) (models.Bookmark, error
 	return bookmark, nil
 }
 
-// GetBookmarks retrieves all bookmarks for a user and a designated pipe
-func (b bookmarkActions) GetBookmarks(userID, pipeID int64) ([]models.Bookmark, error) {
-	var bookmarks []models.Bookmark
+// GetBookmarks retrieves a page of the bookmarks for a user and a designated pipe, which the user
+// owns or has accepted from another user. The query of a smart pipe is evaluated to find its bookmarks,
+// and its state is used when the filter has none
+func (b bookmarkActions) GetBookmarks(userID, pipeID int64, filter models.BookmarkFilter) ([]models.Bookmark, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	source, err := getPipeSource(ctx, b.Db, userID, pipeID)
+	if err != nil {
+		if err == ErrNoRecord {
+			return nil, models.Pagination{Limit: filter.PageLimit()}, nil
+		}
+		return nil, models.Pagination{}, err
+	}
+	condition, args := source.condition("$1", filter.State, []interface{}{pipeID})
+	condition, args = bookmarkTagCondition(condition, filter.Tag, args)
+
+	o := bookmarkOrdering(filter.Sort)
+	pageCondition, pageClauses, args, err := o.page(ctx, b.Db, filter.Filter, args)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
 	query := `
-	SELECT id, user_id, pipe_id, url, platform, created_at
-	FROM bookmarks
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
 	WHERE
-	    (user_id=$1 AND pipe_id=$2) OR
-	    (
-	        pipe_id IN
-	        (
-	            SELECT spr.shared_pipe_id
-	            FROM shared_pipe_receivers spr
-	            WHERE shared_pipe_id=$2 AND receiver_id=$1 AND is_accepted=true
-             )
-        )
+	    ` + condition + `
+	    AND b.deleted_at IS NULL
+	    AND ` + pageCondition + `
+	` + pageClauses
 
-	`
+	rows, err := b.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	return b.collectBookmarkPage(rows, o, filter.Filter)
+}
+
+// GetBookmarksByState retrieves a page of the bookmarks in a particular state across all the pipes owned by a user
+func (b bookmarkActions) GetBookmarksByState(userID int64, filter models.BookmarkFilter) ([]models.Bookmark, models.Pagination, error) {
+	condition, args := bookmarkTagCondition("b.user_id=$1 AND "+bookmarkStateCondition(filter.State), filter.Tag, []interface{}{userID})
+	o := bookmarkOrdering(filter.Sort)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	rows, err := b.Db.QueryContext(ctx, query, userID, pipeID)
+	pageCondition, pageClauses, args, err := o.page(ctx, b.Db, filter.Filter, args)
 	if err != nil {
-		return bookmarks, err
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
+	WHERE ` + condition + ` AND b.deleted_at IS NULL
+	    AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := b.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	return b.collectBookmarkPage(rows, o, filter.Filter)
+}
+
+// bookmarkTagCondition narrows down condition to the bookmarks with a tag, ignoring case and whitespace,
+// or with a tag under it. An empty tag leaves condition as it is
+func bookmarkTagCondition(condition, tag string, args []interface{}) (string, []interface{}) {
+	name := models.NormalizeTagName(tag)
+	if name == "" {
+		return condition, args
 	}
+	args = append(args, models.TagKey(name))
+	return condition + " AND " + taggedUnder(fmt.Sprintf("%s=$%d", tagKey("t.name"), len(args))), args
+}
+
+// collectBookmarkPage collects the bookmarks fetched for a page of a list sorted with o
+func (b bookmarkActions) collectBookmarkPage(rows *sql.Rows, o ordering, filter models.Filter) ([]models.Bookmark, models.Pagination, error) {
+	bookmarks, err := b.collectBookmarks(rows)
+	if err != nil {
+		return bookmarks, models.Pagination{}, err
+	}
+	pagination, size := o.pagination(filter, len(bookmarks), func(i int) int64 { return bookmarks[i].ID })
+	return bookmarks[:size], pagination, nil
+}
+
+// collectBookmarks scans every row selected with bookmarkColumns and attaches the tags of each bookmark
+func (b bookmarkActions) collectBookmarks(rows *sql.Rows) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
 	defer rows.Close()
 
 	for rows.Next() {
-		var bookmark models.Bookmark
-		if err := rows.Scan(
-			&bookmark.ID,
-			&bookmark.UserID,
-			&bookmark.PipeID,
-			&bookmark.Url,
-			&bookmark.Platform,
-			&bookmark.CreatedAt,
-		); err != nil {
+		bookmark, err := scanBookmark(rows)
+		if err != nil {
 			return bookmarks, err
 		}
 		bookmark, _ = b.ParseTags(bookmark)
@@ -132,10 +255,10 @@ func (b bookmarkActions) GetBookmarks(userID, pipeID int64) ([]models.Bookmark,
 	return bookmarks, nil
 }
 
-// GetBookmarksCount gets the total amount of bookmarks that belongs to a user
+// GetBookmarksCount gets the total amount of bookmarks that belongs to a user, excluding the ones in the trash
 func (b bookmarkActions) GetBookmarksCount(userID int64) (int, error) {
 	var bmCount int
-	query := `SELECT COUNT(id) FROM bookmarks WHERE user_id=$1`
+	query := `SELECT COUNT(id) FROM bookmarks WHERE user_id=$1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -148,23 +271,173 @@ func (b bookmarkActions) GetBookmarksCount(userID int64) (int, error) {
 	return bmCount, nil
 }
 
+// UpdateBookmarkState marks a bookmark as read/unread, starred/unstarred or archived/unarchived.
+// The timestamp of a state is set when it's switched on and cleared when it's switched off
+func (b bookmarkActions) UpdateBookmarkState(bmID, userID int64, update models.BookmarkStateUpdate) (models.Bookmark, error) {
+	var bookmark models.Bookmark
+	query := `
+	UPDATE bookmarks b
+	SET
+	    is_read=COALESCE($3, b.is_read),
+	    read_at=CASE WHEN $3::boolean IS NULL THEN b.read_at WHEN $3::boolean THEN COALESCE(b.read_at, now()) ELSE NULL END,
+	    is_starred=COALESCE($4, b.is_starred),
+	    starred_at=CASE WHEN $4::boolean IS NULL THEN b.starred_at WHEN $4::boolean THEN COALESCE(b.starred_at, now()) ELSE NULL END,
+	    is_archived=COALESCE($5, b.is_archived),
+	    archived_at=CASE WHEN $5::boolean IS NULL THEN b.archived_at WHEN $5::boolean THEN COALESCE(b.archived_at, now()) ELSE NULL END
+	WHERE b.id=$1 AND b.user_id=$2 AND b.deleted_at IS NULL
+	RETURNING` + bookmarkColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := b.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	defer tx.Rollback()
+
+	history := newOperationLog(userID, models.HistoryActionState)
+	err = history.trackBookmarks(ctx, tx, "b.id=$1", []interface{}{bmID}, func() error {
+		var err error
+		bookmark, err = scanBookmark(tx.QueryRowContext(ctx, query, bmID, userID, update.Read, update.Starred, update.Archived))
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Bookmark{}, ErrNoRecord
+		}
+		return models.Bookmark{}, err
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return models.Bookmark{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Bookmark{}, err
+	}
+
+	bookmark, _ = b.ParseTags(bookmark)
+	return bookmark, nil
+}
+
+// UpdateBookmark changes the title, the pipe or the tags of a bookmark. A bookmark moved to
+// another pipe is placed at the end of that pipe, which can't be a smart pipe. actorID is the owner
+// of the bookmark or the collaborator editing it for them
+func (b bookmarkActions) UpdateBookmark(bmID, userID, actorID int64, update models.BookmarkUpdate) (models.Bookmark, error) {
+	var bookmark models.Bookmark
+	query := `
+	UPDATE bookmarks b
+	SET
+	    title=COALESCE($3, b.title),
+	    position=CASE WHEN $4::int IS NOT NULL AND $4::int<>b.pipe_id THEN $5 ELSE b.position END,
+	    pipe_id=COALESCE($4, b.pipe_id)
+	WHERE b.id=$1 AND b.user_id=$2 AND b.deleted_at IS NULL
+	RETURNING` + bookmarkColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := b.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	defer tx.Rollback()
+
+	action := models.HistoryActionEdit
+	switch {
+	case update.PipeID != nil:
+		action = models.HistoryActionMove
+	case update.Title == nil && update.Tags != nil:
+		action = models.HistoryActionRetag
+	}
+
+	history := newActorOperationLog(userID, actorID, action)
+	err = history.trackBookmarks(ctx, tx, "b.id=$1", []interface{}{bmID}, func() error {
+		var position string
+		if update.PipeID != nil {
+			if err := ensureStandardPipe(ctx, tx, *update.PipeID); err != nil {
+				return err
+			}
+			var err error
+			if position, err = bookmarkPositionScope(*update.PipeID).nextPosition(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		bookmark, err = scanBookmark(tx.QueryRowContext(ctx, query, bmID, userID, update.Title, update.PipeID, position))
+		if err != nil {
+			return err
+		}
+		if update.Tags != nil {
+			return setBookmarkTags(ctx, tx, bmID, update.Tags)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Bookmark{}, ErrNoRecord
+		}
+		return models.Bookmark{}, err
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return models.Bookmark{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Bookmark{}, err
+	}
+
+	bookmark, _ = b.ParseTags(bookmark)
+	return bookmark, nil
+}
+
+// MoveBookmarks changes the manual order of the bookmarks in a pipe. The moves are applied one after
+// the other, so a move may refer to a bookmark placed by an earlier one. actorID is the owner of the
+// pipe or the collaborator reordering it for them
+func (b bookmarkActions) MoveBookmarks(userID, actorID, pipeID int64, moves []models.PositionMove) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := b.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scope := bookmarkPositionScope(pipeID)
+	history := newActorOperationLog(userID, actorID, models.HistoryActionReorder)
+	err = history.trackBookmarks(ctx, tx, scope.condition, []interface{}{scope.arg}, func() error {
+		return applyMoves(ctx, tx, scope, moves)
+	})
+	if err != nil {
+		return err
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteBookmark moves a bookmark to the trash. The bookmark can be restored until it is purged
 func (b bookmarkActions) DeleteBookmark(bmID, userID int64) (bool, error) {
-	deleteQuery := `DELETE FROM bookmarks WHERE id=$1 AND user_id=$2`
+	deleteQuery := `UPDATE bookmarks SET deleted_at=now() WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	_, err := b.Db.ExecContext(ctx, deleteQuery, bmID, userID)
+	res, err := b.Db.ExecContext(ctx, deleteQuery, bmID, userID)
 	if err != nil {
 		return false, err
 	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return false, ErrNoRecord
+	}
 	return true, nil
 }
 
 func (b bookmarkActions) ParseTags(bookmark models.Bookmark) (models.Bookmark, error) {
 	query := `
-	SELECT bt.id, bt.tag_id, bt.bookmark_id, t.name 
-	FROM bookmark_tag bt 
+	SELECT bt.id, bt.tag_id, bt.bookmark_id, t.name
+	FROM bookmark_tag bt
 		INNER JOIN tags t on bt.tag_id = t.id
 	WHERE bookmark_id=$1
     `
@@ -177,6 +450,7 @@ func (b bookmarkActions) ParseTags(bookmark models.Bookmark) (models.Bookmark, e
 		b.Logger.Err(err).Msg("there was an error parsing tags on bookmark")
 		return bookmark, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		bookmarkToTag := models.BookmarkToTag{}
 		_ = rows.Scan(
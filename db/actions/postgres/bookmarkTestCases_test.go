@@ -2,6 +2,14 @@ package postgres
 
 import "github.com/mypipeapp/mypipeapi/db/models"
 
+var trueValue = true
+
+var newTitle = "Asian Muslim Shorts"
+
+var firstUserId, secondUserId int64 = 1, 2
+
+var secondPipeId int64 = 2
+
 var createBookmarkTestCases = map[string]struct {
 	inputBookmark models.Bookmark
 	wantBookmark  models.Bookmark
@@ -20,6 +28,25 @@ var createBookmarkTestCases = map[string]struct {
 			Platform: "twitter",
 			PipeID:   1,
 			Url:      "https://twitter.com/Mc_Phils/status/1589501899015090178?s=20&t=AXekm5YnalcWausr3fuqlA",
+			AddedBy:  &firstUserId,
+		},
+		wantErr: nil,
+	},
+	"added by a collaborator": {
+		inputBookmark: models.Bookmark{
+			UserID:   1,
+			Platform: "youtube",
+			PipeID:   2,
+			Url:      "https://youtu.be/Acgk_Jl95es",
+			AddedBy:  &secondUserId,
+		},
+		wantBookmark: models.Bookmark{
+			ID:       7,
+			UserID:   1,
+			Platform: "youtube",
+			PipeID:   2,
+			Url:      "https://youtu.be/Acgk_Jl95es",
+			AddedBy:  &secondUserId,
 		},
 		wantErr: nil,
 	},
@@ -54,6 +81,7 @@ var getBookmarkTestCases = map[string]struct {
 var getBookmarksTestCases = map[string]struct {
 	inputPipeId   int64
 	inputUserId   int64
+	inputFilter   models.BookmarkFilter
 	wantBookmarks []models.Bookmark
 	wantErr       error
 }{
@@ -78,6 +106,81 @@ var getBookmarksTestCases = map[string]struct {
 		wantBookmarks: []models.Bookmark{},
 		wantErr:       nil,
 	},
+	"unread filter": {
+		inputUserId:   1,
+		inputPipeId:   1,
+		inputFilter:   models.BookmarkFilter{State: models.BookmarkStateUnread},
+		wantBookmarks: []models.Bookmark{{ID: 1, UserID: 1, PipeID: 1}},
+		wantErr:       nil,
+	},
+	"archived filter": {
+		inputUserId:   1,
+		inputPipeId:   1,
+		inputFilter:   models.BookmarkFilter{State: models.BookmarkStateArchived},
+		wantBookmarks: []models.Bookmark{},
+		wantErr:       nil,
+	},
+}
+
+var getBookmarksByStateTestCases = map[string]struct {
+	inputUserId    int64
+	inputFilter    models.BookmarkFilter
+	wantBookmarks  []models.Bookmark
+	wantPagination models.Pagination
+	wantErr        error
+}{
+	"all unread": {
+		inputUserId: 1,
+		inputFilter: models.BookmarkFilter{State: models.BookmarkStateUnread, Sort: models.SortNewest},
+		wantBookmarks: []models.Bookmark{
+			{ID: 2, UserID: 1, PipeID: 2},
+			{ID: 1, UserID: 1, PipeID: 1},
+		},
+		wantPagination: models.Pagination{Limit: models.DefaultPageLimit},
+		wantErr:        nil,
+	},
+	"starred": {
+		inputUserId:    1,
+		inputFilter:    models.BookmarkFilter{State: models.BookmarkStateStarred},
+		wantBookmarks:  []models.Bookmark{},
+		wantPagination: models.Pagination{Limit: models.DefaultPageLimit},
+		wantErr:        nil,
+	},
+	"first page": {
+		inputUserId: 1,
+		inputFilter: models.BookmarkFilter{
+			Filter: models.Filter{Limit: 1},
+			State:  models.BookmarkStateUnread,
+			Sort:   models.SortNewest,
+		},
+		wantBookmarks: []models.Bookmark{{ID: 2, UserID: 1, PipeID: 2}},
+		wantPagination: models.Pagination{
+			Limit:      1,
+			NextCursor: encodeCursor("bookmarks-"+models.SortNewest, 2),
+			HasMore:    true,
+		},
+		wantErr: nil,
+	},
+	"last page": {
+		inputUserId: 1,
+		inputFilter: models.BookmarkFilter{
+			Filter: models.Filter{Limit: 1, Cursor: encodeCursor("bookmarks-"+models.SortNewest, 2)},
+			State:  models.BookmarkStateUnread,
+			Sort:   models.SortNewest,
+		},
+		wantBookmarks:  []models.Bookmark{{ID: 1, UserID: 1, PipeID: 1}},
+		wantPagination: models.Pagination{Limit: 1},
+		wantErr:        nil,
+	},
+	"cursor of another sort": {
+		inputUserId: 1,
+		inputFilter: models.BookmarkFilter{
+			Filter: models.Filter{Cursor: encodeCursor("bookmarks-"+models.SortOldest, 2)},
+			State:  models.BookmarkStateUnread,
+			Sort:   models.SortNewest,
+		},
+		wantErr: ErrInvalidCursor,
+	},
 }
 
 var getBookmarksCountTestCases = map[string]struct {
@@ -92,6 +195,73 @@ var getBookmarksCountTestCases = map[string]struct {
 	},
 }
 
+var updateBookmarkTestCases = map[string]struct {
+	inputBookmarkId int64
+	inputUserId     int64
+	inputUpdate     models.BookmarkUpdate
+	wantBookmark    models.Bookmark
+	wantErr         error
+}{
+	"rename": {
+		inputBookmarkId: 1,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkUpdate{Title: &newTitle},
+		wantBookmark:    models.Bookmark{ID: 1, PipeID: 1, Title: newTitle, Tags: []string{"Beautiful Asian Muslim", "Quick Blows"}},
+		wantErr:         nil,
+	},
+	"move to another pipe": {
+		inputBookmarkId: 1,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkUpdate{PipeID: &secondPipeId},
+		wantBookmark:    models.Bookmark{ID: 1, PipeID: 2, Tags: []string{"Beautiful Asian Muslim", "Quick Blows"}},
+		wantErr:         nil,
+	},
+	"retag": {
+		inputBookmarkId: 1,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkUpdate{Tags: []string{"Quick Blows", "Shorts"}},
+		wantBookmark:    models.Bookmark{ID: 1, PipeID: 1, Tags: []string{"Quick Blows", "Shorts"}},
+		wantErr:         nil,
+	},
+	"bookmark belongs to another user": {
+		inputBookmarkId: 3,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkUpdate{Title: &newTitle},
+		wantBookmark:    models.Bookmark{},
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var updateBookmarkStateTestCases = map[string]struct {
+	inputBookmarkId int64
+	inputUserId     int64
+	inputUpdate     models.BookmarkStateUpdate
+	wantBookmark    models.Bookmark
+	wantErr         error
+}{
+	"mark as read and starred": {
+		inputBookmarkId: 1,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkStateUpdate{Read: &trueValue, Starred: &trueValue},
+		wantBookmark:    models.Bookmark{ID: 1, IsRead: true, IsStarred: true},
+		wantErr:         nil,
+	},
+	"archive": {
+		inputBookmarkId: 2,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkStateUpdate{Archived: &trueValue},
+		wantBookmark:    models.Bookmark{ID: 2, IsArchived: true},
+		wantErr:         nil,
+	},
+	"bookmark belongs to another user": {
+		inputBookmarkId: 3,
+		inputUserId:     1,
+		inputUpdate:     models.BookmarkStateUpdate{Read: &trueValue},
+		wantBookmark:    models.Bookmark{},
+		wantErr:         ErrNoRecord,
+	},
+}
+
 var deleteBookmarkTestCases = map[string]struct {
 	inputBookmarkId int64
 	inputUserId     int64
@@ -104,4 +274,36 @@ var deleteBookmarkTestCases = map[string]struct {
 		wantResponse:    true,
 		wantErr:         nil,
 	},
+	"invalid bookmark id": {
+		inputBookmarkId: 200,
+		inputUserId:     1,
+		wantResponse:    false,
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var moveBookmarksTestCases = map[string]struct {
+	inputPipeId     int64
+	inputMoves      []models.PositionMove
+	wantBookmarkIds []int64
+	wantErr         error
+}{
+	"move to the top": {
+		inputPipeId:     1,
+		inputMoves:      []models.PositionMove{{ID: 8, AfterID: 0}},
+		wantBookmarkIds: []int64{8, 1, 7},
+		wantErr:         nil,
+	},
+	"several moves": {
+		inputPipeId:     1,
+		inputMoves:      []models.PositionMove{{ID: 8, AfterID: 0}, {ID: 1, AfterID: 7}},
+		wantBookmarkIds: []int64{8, 7, 1},
+		wantErr:         nil,
+	},
+	"bookmark is in another pipe": {
+		inputPipeId:     1,
+		inputMoves:      []models.PositionMove{{ID: 2, AfterID: 0}},
+		wantBookmarkIds: []int64{1, 7, 8},
+		wantErr:         ErrNoRecord,
+	},
 }
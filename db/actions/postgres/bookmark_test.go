@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"github.com/mypipeapp/mypipeapi/db/models"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -25,6 +26,7 @@ func Test_bookmark_Create(t *testing.T) {
 
 				assert.Equal(t, gotBookmark.ID, tc.wantBookmark.ID)
 				assert.Equal(t, gotBookmark.PipeID, tc.wantBookmark.PipeID)
+				assert.Equal(t, tc.wantBookmark.AddedBy, gotBookmark.AddedBy)
 			}
 		})
 	}
@@ -61,7 +63,7 @@ func Test_bookmark_GetBookmarks(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			db := newTestDb(t)
 			ba := NewBookmarkActions(db, logger)
-			gotBookmarks, gotErr := ba.GetBookmarks(tc.inputUserId, tc.inputPipeId)
+			gotBookmarks, _, gotErr := ba.GetBookmarks(tc.inputUserId, tc.inputPipeId, tc.inputFilter)
 			assert.Equal(t, tc.wantErr, gotErr)
 
 			if nil == gotErr {
@@ -72,6 +74,34 @@ func Test_bookmark_GetBookmarks(t *testing.T) {
 	}
 }
 
+func Test_bookmark_MoveBookmarks(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := moveBookmarksTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ba := NewBookmarkActions(db, logger)
+			for _, url := range []string{"https://youtu.be/7", "https://youtu.be/8"} {
+				_, err := ba.CreateBookmark(models.Bookmark{UserID: 1, PipeID: tc.inputPipeId, Url: url, Platform: "youtube"})
+				assert.Nil(t, err)
+			}
+
+			gotErr := ba.MoveBookmarks(1, 1, tc.inputPipeId, tc.inputMoves)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			gotBookmarks, _, err := ba.GetBookmarks(1, tc.inputPipeId, models.BookmarkFilter{Sort: models.SortManual})
+			assert.Nil(t, err)
+			assert.Equal(t, len(tc.wantBookmarkIds), len(gotBookmarks))
+			for i, bookmark := range gotBookmarks {
+				assert.Equal(t, tc.wantBookmarkIds[i], bookmark.ID)
+			}
+		})
+	}
+}
+
 func Test_bookmark_GetBookmarksCount(t *testing.T) {
 	if testing.Short() {
 		t.Skip(skipMessage)
@@ -92,6 +122,78 @@ func Test_bookmark_GetBookmarksCount(t *testing.T) {
 	}
 }
 
+func Test_bookmark_GetBookmarksByState(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getBookmarksByStateTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ba := NewBookmarkActions(db, logger)
+			gotBookmarks, gotPagination, gotErr := ba.GetBookmarksByState(tc.inputUserId, tc.inputFilter)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, len(tc.wantBookmarks), len(gotBookmarks))
+				for i, bookmark := range gotBookmarks {
+					assert.Equal(t, tc.wantBookmarks[i].ID, bookmark.ID)
+				}
+				assert.Equal(t, tc.wantPagination, gotPagination)
+			}
+		})
+	}
+}
+
+func Test_bookmark_UpdateBookmarkState(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateBookmarkStateTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ba := NewBookmarkActions(db, logger)
+			gotBookmark, gotErr := ba.UpdateBookmarkState(tc.inputBookmarkId, tc.inputUserId, tc.inputUpdate)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantBookmark.ID, gotBookmark.ID)
+				assert.Equal(t, tc.wantBookmark.IsRead, gotBookmark.IsRead)
+				assert.Equal(t, tc.wantBookmark.IsStarred, gotBookmark.IsStarred)
+				assert.Equal(t, tc.wantBookmark.IsArchived, gotBookmark.IsArchived)
+				assert.Equal(t, tc.wantBookmark.IsRead, gotBookmark.ReadAt != nil)
+				assert.Equal(t, tc.wantBookmark.IsArchived, gotBookmark.ArchivedAt != nil)
+			}
+		})
+	}
+}
+
+func Test_bookmark_UpdateBookmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateBookmarkTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ba := NewBookmarkActions(db, logger)
+			gotBookmark, gotErr := ba.UpdateBookmark(tc.inputBookmarkId, tc.inputUserId, tc.inputUserId, tc.inputUpdate)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantBookmark.ID, gotBookmark.ID)
+				assert.Equal(t, tc.wantBookmark.PipeID, gotBookmark.PipeID)
+				assert.Equal(t, tc.wantBookmark.Title, gotBookmark.Title)
+				assert.ElementsMatch(t, tc.wantBookmark.Tags, gotBookmark.Tags)
+			}
+		})
+	}
+}
+
 func Test_bookmark_DeleteBookmark(t *testing.T) {
 	if testing.Short() {
 		t.Skip(skipMessage)
@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const commentColumns = `
+	c.id, c.bookmark_id, c.user_id, u.username, c.parent_id, c.body, c.edited_at, c.deleted_at, c.created_at`
+
+// commentOrdering lists the comments on a bookmark from the oldest, so that replies come after
+// the comments they reply to
+var commentOrdering = ordering{
+	name:  "comments-" + models.SortOldest,
+	keys:  []sortKey{{expr: "c.created_at"}, {expr: "c.id"}},
+	table: "bookmark_comments c",
+}
+
+type commentActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewCommentActions(db *sql.DB, logger zerolog.Logger) repository.CommentRepository {
+	return commentActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+func scanComment(row rowScanner) (models.BookmarkComment, error) {
+	var comment models.BookmarkComment
+	err := row.Scan(
+		&comment.ID,
+		&comment.BookmarkID,
+		&comment.UserID,
+		&comment.Username,
+		&comment.ParentID,
+		&comment.Body,
+		&comment.EditedAt,
+		&comment.DeletedAt,
+		&comment.CreatedAt,
+	)
+	return comment, err
+}
+
+// CreateComment adds a comment to a bookmark. A reply must be to a comment on the same
+// bookmark that was not deleted
+func (ca commentActions) CreateComment(comment models.BookmarkComment) (models.BookmarkComment, error) {
+	query := `
+	WITH inserted AS (
+	    INSERT INTO bookmark_comments (bookmark_id, user_id, parent_id, body)
+	    SELECT $1, $2, $3, $4
+	    WHERE $3::int IS NULL OR EXISTS (
+	        SELECT 1 FROM bookmark_comments pc WHERE pc.id=$3 AND pc.bookmark_id=$1 AND pc.deleted_at IS NULL
+	    )
+	    RETURNING *
+	)
+	SELECT` + commentColumns + `
+	FROM inserted c
+	    INNER JOIN users u ON c.user_id=u.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	created, err := scanComment(ca.Db.QueryRowContext(ctx, query, comment.BookmarkID, comment.UserID, comment.ParentID, comment.Body))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return created, ErrNoRecord
+		}
+		return created, err
+	}
+	return created, nil
+}
+
+// GetComment retrieves a comment that was not deleted
+func (ca commentActions) GetComment(commentId int64) (models.BookmarkComment, error) {
+	query := `
+	SELECT` + commentColumns + `
+	FROM bookmark_comments c
+	    INNER JOIN users u ON c.user_id=u.id
+	WHERE c.id=$1 AND c.deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	comment, err := scanComment(ca.Db.QueryRowContext(ctx, query, commentId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return comment, ErrNoRecord
+		}
+		return comment, err
+	}
+	return comment, nil
+}
+
+// GetComments gets a page of the comments on a bookmark, from the oldest. Deleted comments are
+// only listed while replies to them are, so that threads keep their shape
+func (ca commentActions) GetComments(bookmarkId int64, filter models.Filter) ([]models.BookmarkComment, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := commentOrdering.page(ctx, ca.Db, filter, []interface{}{bookmarkId})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + commentColumns + `
+	FROM bookmark_comments c
+	    INNER JOIN users u ON c.user_id=u.id
+	WHERE c.bookmark_id=$1 AND (
+	    c.deleted_at IS NULL OR EXISTS (
+	        SELECT 1 FROM bookmark_comments r WHERE r.parent_id=c.id AND r.deleted_at IS NULL
+	    )
+	) AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := ca.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	var comments []models.BookmarkComment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, models.Pagination{}, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := commentOrdering.pagination(filter, len(comments), func(i int) int64 { return comments[i].ID })
+	return comments[:size], pagination, nil
+}
+
+// UpdateComment changes the body of a comment written by the given user
+func (ca commentActions) UpdateComment(commentId, userId int64, body string) (models.BookmarkComment, error) {
+	query := `
+	UPDATE bookmark_comments c
+	SET body=$3, edited_at=now()
+	FROM users u
+	WHERE c.id=$1 AND c.user_id=$2 AND c.deleted_at IS NULL AND u.id=c.user_id
+	RETURNING` + commentColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	comment, err := scanComment(ca.Db.QueryRowContext(ctx, query, commentId, userId, body))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return comment, ErrNoRecord
+		}
+		return comment, err
+	}
+	return comment, nil
+}
+
+// DeleteComment marks a comment as deleted and clears its body
+func (ca commentActions) DeleteComment(commentId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := ca.Db.ExecContext(ctx, `
+	UPDATE bookmark_comments SET body='', deleted_at=now() WHERE id=$1 AND deleted_at IS NULL
+	`, commentId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// AddReaction records a user reacting to a bookmark with an emoji. Reacting twice with the
+// same emoji is a no-op
+func (ca commentActions) AddReaction(bookmarkId, userId int64, emoji string) error {
+	query := `
+	INSERT INTO bookmark_reactions (bookmark_id, user_id, emoji)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (bookmark_id, user_id, emoji) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	_, err := ca.Db.ExecContext(ctx, query, bookmarkId, userId, emoji)
+	return err
+}
+
+// RemoveReaction takes back a reaction of a user to a bookmark
+func (ca commentActions) RemoveReaction(bookmarkId, userId int64, emoji string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := ca.Db.ExecContext(ctx, `
+	DELETE FROM bookmark_reactions WHERE bookmark_id=$1 AND user_id=$2 AND emoji=$3
+	`, bookmarkId, userId, emoji)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// GetReactions counts the reactions to a bookmark by emoji, from the most used, and tells
+// which of them the given user reacted with
+func (ca commentActions) GetReactions(bookmarkId, userId int64) ([]models.ReactionCount, error) {
+	query := `
+	SELECT emoji, COUNT(*), bool_or(user_id=$2)
+	FROM bookmark_reactions
+	WHERE bookmark_id=$1
+	GROUP BY emoji
+	ORDER BY COUNT(*) DESC, MIN(created_at)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := ca.Db.QueryContext(ctx, query, bookmarkId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactions []models.ReactionCount
+	for rows.Next() {
+		var reaction models.ReactionCount
+		if err := rows.Scan(&reaction.Emoji, &reaction.Count, &reaction.Reacted); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
@@ -0,0 +1,42 @@
+package postgres
+
+var createCommentTestCases = map[string]struct {
+	inputBookmarkId int64
+	inputReply      bool
+	inputParentId   int64
+	wantErr         error
+}{
+	"comment": {
+		inputBookmarkId: 2,
+		wantErr:         nil,
+	},
+	"reply": {
+		inputBookmarkId: 2,
+		inputReply:      true,
+		wantErr:         nil,
+	},
+	"reply to a comment on another bookmark": {
+		inputBookmarkId: 1,
+		inputReply:      true,
+		wantErr:         ErrNoRecord,
+	},
+	"reply to a comment that does not exist": {
+		inputBookmarkId: 2,
+		inputParentId:   1000,
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var updateCommentTestCases = map[string]struct {
+	inputUserId int64
+	wantErr     error
+}{
+	"author": {
+		inputUserId: secondUserId,
+		wantErr:     nil,
+	},
+	"another user": {
+		inputUserId: firstUserId,
+		wantErr:     ErrNoRecord,
+	},
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_comment_CreateComment(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := createCommentTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ca := NewCommentActions(db, logger)
+			comment := models.BookmarkComment{BookmarkID: tc.inputBookmarkId, UserID: secondUserId, Body: "@user1 have a look"}
+			if tc.inputReply {
+				parent, err := ca.CreateComment(models.BookmarkComment{BookmarkID: 2, UserID: firstUserId, Body: "first"})
+				assert.NoError(t, err)
+				comment.ParentID = &parent.ID
+			} else if tc.inputParentId != 0 {
+				comment.ParentID = &tc.inputParentId
+			}
+
+			gotComment, gotErr := ca.CreateComment(comment)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, "user2", gotComment.Username)
+				assert.Equal(t, comment.Body, gotComment.Body)
+				assert.Equal(t, comment.ParentID, gotComment.ParentID)
+			}
+		})
+	}
+}
+
+func Test_comment_UpdateComment(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateCommentTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ca := NewCommentActions(db, logger)
+			comment, err := ca.CreateComment(models.BookmarkComment{BookmarkID: 2, UserID: secondUserId, Body: "first"})
+			assert.NoError(t, err)
+
+			gotComment, gotErr := ca.UpdateComment(comment.ID, tc.inputUserId, "edited")
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, "edited", gotComment.Body)
+				assert.NotNil(t, gotComment.EditedAt)
+			}
+		})
+	}
+}
+
+func Test_comment_DeleteComment(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ca := NewCommentActions(db, logger)
+	parent, err := ca.CreateComment(models.BookmarkComment{BookmarkID: 2, UserID: firstUserId, Body: "first"})
+	assert.NoError(t, err)
+	reply, err := ca.CreateComment(models.BookmarkComment{BookmarkID: 2, UserID: secondUserId, ParentID: &parent.ID, Body: "second"})
+	assert.NoError(t, err)
+
+	// a deleted comment stays in the thread while it has replies
+	assert.NoError(t, ca.DeleteComment(parent.ID))
+	assert.Equal(t, ErrNoRecord, ca.DeleteComment(parent.ID))
+	gotComments, _, gotErr := ca.GetComments(2, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotComments, 2)
+	assert.Equal(t, parent.ID, gotComments[0].ID)
+	assert.Empty(t, gotComments[0].Body)
+	assert.NotNil(t, gotComments[0].DeletedAt)
+
+	assert.NoError(t, ca.DeleteComment(reply.ID))
+	gotComments, _, gotErr = ca.GetComments(2, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Empty(t, gotComments)
+}
+
+func Test_comment_Reactions(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ca := NewCommentActions(db, logger)
+	assert.NoError(t, ca.AddReaction(2, firstUserId, "👍"))
+	assert.NoError(t, ca.AddReaction(2, firstUserId, "👍"))
+	assert.NoError(t, ca.AddReaction(2, secondUserId, "👍"))
+	assert.NoError(t, ca.AddReaction(2, secondUserId, "🎉"))
+
+	gotReactions, gotErr := ca.GetReactions(2, firstUserId)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, []models.ReactionCount{
+		{Emoji: "👍", Count: 2, Reacted: true},
+		{Emoji: "🎉", Count: 1, Reacted: false},
+	}, gotReactions)
+
+	assert.NoError(t, ca.RemoveReaction(2, secondUserId, "🎉"))
+	assert.Equal(t, ErrNoRecord, ca.RemoveReaction(2, secondUserId, "🎉"))
+	gotReactions, gotErr = ca.GetReactions(2, secondUserId)
+	assert.NoError(t, gotErr)
+	assert.Equal(t, []models.ReactionCount{{Emoji: "👍", Count: 2, Reacted: true}}, gotReactions)
+}
@@ -0,0 +1,311 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const pipeFollowColumns = `
+	f.id, f.pipe_id, f.user_id, f.notify, f.digest_sent_at, f.created_at, f.modified_at`
+
+// followedPipeOrdering lists the pipes a user follows from the most recently followed
+var followedPipeOrdering = ordering{
+	name:  "followed-pipes-" + models.SortNewest,
+	keys:  []sortKey{{expr: "f.created_at", desc: true}, {expr: "f.id", desc: true}},
+	table: "pipe_follows f",
+}
+
+// followingFeedOrdering lists the bookmarks of the pipes a user follows from the most recently added
+var followingFeedOrdering = ordering{
+	name:  "following-feed-" + models.SortNewest,
+	keys:  []sortKey{{expr: "b.created_at", desc: true}, {expr: "b.id", desc: true}},
+	table: "bookmarks b",
+}
+
+// pipeVisibleTo returns the condition that matches when the pipe aliased as p can be followed by the
+// user in userExpr, which is when anyone can see it or it's shared with the user, directly or through
+// one of the pipes it's nested in. Public pipes are only seen by anyone while their owner's profile is
+// public, unlisted pipes whatever the profile. The owner of a pipe does not follow it
+func pipeVisibleTo(userExpr string) string {
+	return `(
+	p.deleted_at IS NULL AND p.user_id<>` + userExpr + ` AND (
+	    p.visibility='` + models.PipeVisibilityUnlisted + `'
+	    OR (p.visibility='` + models.PipeVisibilityPublic + `' AND EXISTS (
+	        SELECT 1 FROM users pu WHERE pu.id=p.user_id AND pu.public_profile=true
+	    ))
+	    OR EXISTS (
+	        WITH RECURSIVE ancestors AS (
+	            SELECT p.id, p.parent_id
+	            UNION
+	            SELECT a.id, a.parent_id FROM pipes a INNER JOIN ancestors an ON a.id=an.parent_id
+	        )
+	        SELECT 1 FROM shared_pipe_receivers spr
+	        WHERE spr.shared_pipe_id IN (SELECT id FROM ancestors)
+	            AND spr.receiver_id=` + userExpr + ` AND spr.is_accepted=true
+	    )
+	))`
+}
+
+type followActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewFollowActions(db *sql.DB, logger zerolog.Logger) repository.FollowRepository {
+	return followActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+func scanPipeFollow(row rowScanner) (models.PipeFollow, error) {
+	var follow models.PipeFollow
+	err := row.Scan(
+		&follow.ID,
+		&follow.PipeID,
+		&follow.UserID,
+		&follow.Notify,
+		&follow.DigestSentAt,
+		&follow.CreatedAt,
+		&follow.ModifiedAt,
+	)
+	return follow, err
+}
+
+// FollowPipe makes a user follow a pipe they can see. Following a pipe the user already
+// follows changes how they hear about its bookmarks
+func (f followActions) FollowPipe(userId, pipeId int64, notify string) (models.PipeFollow, error) {
+	query := `
+	INSERT INTO pipe_follows AS f (pipe_id, user_id, notify)
+	SELECT p.id, $1, COALESCE(NULLIF($3, ''), 'instant')
+	FROM pipes p
+	WHERE p.id=$2 AND ` + pipeVisibleTo("$1") + `
+	ON CONFLICT (pipe_id, user_id) DO UPDATE
+	SET notify=EXCLUDED.notify, modified_at=now()
+	RETURNING` + pipeFollowColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	follow, err := scanPipeFollow(f.Db.QueryRowContext(ctx, query, userId, pipeId, notify))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return follow, ErrNoRecord
+		}
+		return follow, err
+	}
+	return follow, nil
+}
+
+// UpdatePipeFollow changes how a user hears about the bookmarks of a pipe they follow
+func (f followActions) UpdatePipeFollow(userId, pipeId int64, notify string) (models.PipeFollow, error) {
+	query := `
+	UPDATE pipe_follows f
+	SET notify=$3, modified_at=now()
+	WHERE f.user_id=$1 AND f.pipe_id=$2
+	RETURNING` + pipeFollowColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	follow, err := scanPipeFollow(f.Db.QueryRowContext(ctx, query, userId, pipeId, notify))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return follow, ErrNoRecord
+		}
+		return follow, err
+	}
+	return follow, nil
+}
+
+// UnfollowPipe stops a user from following a pipe
+func (f followActions) UnfollowPipe(userId, pipeId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := f.Db.ExecContext(ctx, `DELETE FROM pipe_follows WHERE user_id=$1 AND pipe_id=$2`, userId, pipeId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// GetFollowedPipes gets a page of the pipes a user follows and can still see, from the most recently followed
+func (f followActions) GetFollowedPipes(userId int64, filter models.Filter) ([]models.FollowedPipe, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := followedPipeOrdering.page(ctx, f.Db, filter, []interface{}{userId})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + pipeFollowColumns + `,
+	    COALESCE(NULLIF(p.display_name, ''), p.name), u.username,
+	    (SELECT COUNT(*) FROM bookmarks fb WHERE fb.pipe_id=p.id AND fb.deleted_at IS NULL)
+	FROM pipe_follows f
+	    INNER JOIN pipes p ON f.pipe_id=p.id
+	    INNER JOIN users u ON p.user_id=u.id
+	WHERE f.user_id=$1 AND ` + pipeVisibleTo("$1") + ` AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := f.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	var pipes []models.FollowedPipe
+	for rows.Next() {
+		var pipe models.FollowedPipe
+		err := rows.Scan(
+			&pipe.ID,
+			&pipe.PipeID,
+			&pipe.UserID,
+			&pipe.Notify,
+			&pipe.DigestSentAt,
+			&pipe.CreatedAt,
+			&pipe.ModifiedAt,
+			&pipe.PipeName,
+			&pipe.Creator,
+			&pipe.Bookmarks,
+		)
+		if err != nil {
+			return nil, models.Pagination{}, err
+		}
+		pipes = append(pipes, pipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := followedPipeOrdering.pagination(filter, len(pipes), func(i int) int64 { return pipes[i].ID })
+	return pipes[:size], pagination, nil
+}
+
+// GetPipeFollowers retrieves the ids of the users following a pipe they can still see who chose
+// to hear about its bookmarks in the given way
+func (f followActions) GetPipeFollowers(pipeId int64, notify string) ([]int64, error) {
+	query := `
+	SELECT f.user_id
+	FROM pipe_follows f
+	    INNER JOIN pipes p ON f.pipe_id=p.id
+	WHERE f.pipe_id=$1 AND f.notify=$2 AND ` + pipeVisibleTo("f.user_id") + `
+	ORDER BY f.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := f.Db.QueryContext(ctx, query, pipeId, notify)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIds []int64
+	for rows.Next() {
+		var userId int64
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, rows.Err()
+}
+
+// ClaimFollowDigests picks up to limit follows with a daily digest that was last sent, or that were
+// created, before the given time and records that their digest is being sent now. It returns the number
+// of bookmarks other users added to the followed pipes since then, leaving out pipes without any
+func (f followActions) ClaimFollowDigests(before time.Time, limit int) ([]models.FollowDigest, error) {
+	query := `
+	WITH due AS (
+	    SELECT id, user_id, pipe_id, COALESCE(digest_sent_at, created_at) AS since
+	    FROM pipe_follows
+	    WHERE notify='` + models.FollowNotifyDigest + `' AND COALESCE(digest_sent_at, created_at) <= $1
+	    ORDER BY COALESCE(digest_sent_at, created_at)
+	    LIMIT $2
+	    FOR UPDATE SKIP LOCKED
+	), claimed AS (
+	    UPDATE pipe_follows cf
+	    SET digest_sent_at=now()
+	    FROM due
+	    WHERE cf.id=due.id
+	    RETURNING due.user_id, due.pipe_id, due.since
+	)
+	SELECT c.user_id, p.id, COALESCE(NULLIF(p.display_name, ''), p.name), COUNT(b.id)
+	FROM claimed c
+	    INNER JOIN pipes p ON c.pipe_id=p.id
+	    INNER JOIN bookmarks b ON b.pipe_id=p.id AND b.deleted_at IS NULL AND b.created_at > c.since
+	        AND COALESCE(b.added_by, b.user_id)<>c.user_id
+	WHERE ` + pipeVisibleTo("c.user_id") + `
+	GROUP BY c.user_id, p.id
+	ORDER BY c.user_id, p.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := f.Db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []models.FollowDigest
+	for rows.Next() {
+		var digest models.FollowDigest
+		if err := rows.Scan(&digest.UserID, &digest.PipeID, &digest.PipeName, &digest.Bookmarks); err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+// GetFollowingFeed gets a page of the bookmarks of every pipe a user follows and can still see,
+// from the most recently added
+func (f followActions) GetFollowingFeed(userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := followingFeedOrdering.page(ctx, f.Db, filter, []interface{}{userId})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
+	    INNER JOIN pipe_follows f ON f.pipe_id=b.pipe_id AND f.user_id=$1
+	    INNER JOIN pipes p ON b.pipe_id=p.id
+	WHERE b.deleted_at IS NULL AND ` + pipeVisibleTo("$1") + ` AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := f.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	var bookmarks []models.Bookmark
+	for rows.Next() {
+		bookmark, err := scanBookmark(rows)
+		if err != nil {
+			return nil, models.Pagination{}, err
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := followingFeedOrdering.pagination(filter, len(bookmarks), func(i int) int64 { return bookmarks[i].ID })
+	return bookmarks[:size], pagination, nil
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var followPipeTestCases = map[string]struct {
+	inputUserId int64
+	inputPipeId int64
+	inputNotify string
+	wantNotify  string
+	wantErr     error
+}{
+	"pipe shared with the user": {
+		inputUserId: secondUserId,
+		inputPipeId: 2,
+		inputNotify: "",
+		wantNotify:  models.FollowNotifyInstant,
+		wantErr:     nil,
+	},
+	"with a digest": {
+		inputUserId: secondUserId,
+		inputPipeId: 2,
+		inputNotify: models.FollowNotifyDigest,
+		wantNotify:  models.FollowNotifyDigest,
+		wantErr:     nil,
+	},
+	"share not accepted": {
+		inputUserId: secondUserId,
+		inputPipeId: 1,
+		wantErr:     ErrNoRecord,
+	},
+	"own pipe": {
+		inputUserId: firstUserId,
+		inputPipeId: 1,
+		wantErr:     ErrNoRecord,
+	},
+	"private pipe": {
+		inputUserId: 3,
+		inputPipeId: 2,
+		wantErr:     ErrNoRecord,
+	},
+}
+
+// followPipeVisibilityTestCases follow the pipes made visible by setPipeVisibility as the third user
+var followPipeVisibilityTestCases = map[string]struct {
+	inputPublic bool
+	inputPipeId int64
+	wantErr     error
+}{
+	"public pipe of a public profile": {
+		inputPublic: true,
+		inputPipeId: 1,
+		wantErr:     nil,
+	},
+	"public pipe of a private profile": {
+		inputPublic: false,
+		inputPipeId: 1,
+		wantErr:     ErrNoRecord,
+	},
+	"unlisted pipe of a private profile": {
+		inputPublic: false,
+		inputPipeId: 2,
+		wantErr:     nil,
+	},
+}
+
+var updatePipeFollowTestCases = map[string]struct {
+	inputFollow bool
+	inputNotify string
+	wantErr     error
+}{
+	"success": {
+		inputFollow: true,
+		inputNotify: models.FollowNotifyMute,
+		wantErr:     nil,
+	},
+	"not following": {
+		inputFollow: false,
+		inputNotify: models.FollowNotifyMute,
+		wantErr:     ErrNoRecord,
+	},
+}
+
+var unfollowPipeTestCases = map[string]struct {
+	inputFollow bool
+	wantErr     error
+}{
+	"success": {
+		inputFollow: true,
+		wantErr:     nil,
+	},
+	"not following": {
+		inputFollow: false,
+		wantErr:     ErrNoRecord,
+	},
+}
+
+var getPipeFollowersTestCases = map[string]struct {
+	inputFollowNotify string
+	inputNotify       string
+	wantFollowers     []int64
+}{
+	"instant followers": {
+		inputFollowNotify: models.FollowNotifyInstant,
+		inputNotify:       models.FollowNotifyInstant,
+		wantFollowers:     []int64{secondUserId},
+	},
+	"muted followers are left out": {
+		inputFollowNotify: models.FollowNotifyMute,
+		inputNotify:       models.FollowNotifyInstant,
+		wantFollowers:     nil,
+	},
+}
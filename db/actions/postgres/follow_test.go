@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_follow_FollowPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := followPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			fa := NewFollowActions(db, logger)
+			gotFollow, gotErr := fa.FollowPipe(tc.inputUserId, tc.inputPipeId, tc.inputNotify)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputUserId, gotFollow.UserID)
+				assert.Equal(t, tc.inputPipeId, gotFollow.PipeID)
+				assert.Equal(t, tc.wantNotify, gotFollow.Notify)
+			}
+		})
+	}
+}
+
+func Test_follow_FollowPipe_visibility(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := followPipeVisibilityTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			fa := NewFollowActions(db, logger)
+			ua := NewUserActions(db, logger)
+			setPipeVisibility(t, db)
+			_, err := ua.UpdateProfileSettings(firstUserId, models.ProfileSettings{PublicProfile: tc.inputPublic})
+			assert.NoError(t, err)
+
+			_, gotErr := fa.FollowPipe(3, tc.inputPipeId, "")
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func Test_follow_UpdatePipeFollow(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updatePipeFollowTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			fa := NewFollowActions(db, logger)
+			if tc.inputFollow {
+				_, err := fa.FollowPipe(secondUserId, 2, "")
+				assert.NoError(t, err)
+			}
+
+			gotFollow, gotErr := fa.UpdatePipeFollow(secondUserId, 2, tc.inputNotify)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputNotify, gotFollow.Notify)
+			}
+		})
+	}
+}
+
+func Test_follow_UnfollowPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := unfollowPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			fa := NewFollowActions(db, logger)
+			if tc.inputFollow {
+				_, err := fa.FollowPipe(secondUserId, 2, "")
+				assert.NoError(t, err)
+			}
+
+			gotErr := fa.UnfollowPipe(secondUserId, 2)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			gotPipes, _, err := fa.GetFollowedPipes(secondUserId, models.Filter{})
+			assert.NoError(t, err)
+			assert.Empty(t, gotPipes)
+		})
+	}
+}
+
+func Test_follow_GetFollowedPipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	fa := NewFollowActions(db, logger)
+	_, err := fa.FollowPipe(secondUserId, 2, "")
+	assert.NoError(t, err)
+
+	gotPipes, _, gotErr := fa.GetFollowedPipes(secondUserId, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotPipes, 1)
+	assert.Equal(t, int64(2), gotPipes[0].PipeID)
+	assert.Equal(t, "user1", gotPipes[0].Creator)
+	assert.Equal(t, 1, gotPipes[0].Bookmarks)
+}
+
+func Test_follow_GetPipeFollowers(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getPipeFollowersTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			fa := NewFollowActions(db, logger)
+			_, err := fa.FollowPipe(secondUserId, 2, tc.inputFollowNotify)
+			assert.NoError(t, err)
+
+			gotFollowers, gotErr := fa.GetPipeFollowers(2, tc.inputNotify)
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.wantFollowers, gotFollowers)
+		})
+	}
+}
+
+func Test_follow_ClaimFollowDigests(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	fa := NewFollowActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	_, err := fa.FollowPipe(secondUserId, 2, models.FollowNotifyDigest)
+	assert.NoError(t, err)
+	_, err = ba.CreateBookmark(models.Bookmark{UserID: 1, PipeID: 2, Url: "https://youtu.be/7", Platform: "youtube"})
+	assert.NoError(t, err)
+
+	gotDigests, gotErr := fa.ClaimFollowDigests(time.Now().Add(time.Minute), 10)
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotDigests, 1)
+	assert.Equal(t, secondUserId, gotDigests[0].UserID)
+	assert.Equal(t, 1, gotDigests[0].Bookmarks)
+
+	// nothing was added since the digest was sent
+	gotDigests, gotErr = fa.ClaimFollowDigests(time.Now().Add(time.Minute), 10)
+	assert.NoError(t, gotErr)
+	assert.Empty(t, gotDigests)
+}
+
+func Test_follow_GetFollowingFeed(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	fa := NewFollowActions(db, logger)
+	_, err := fa.FollowPipe(secondUserId, 2, "")
+	assert.NoError(t, err)
+
+	gotBookmarks, _, gotErr := fa.GetFollowingFeed(secondUserId, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotBookmarks, 1)
+	assert.Equal(t, int64(2), gotBookmarks[0].ID)
+}
@@ -0,0 +1,529 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"sort"
+	"time"
+)
+
+// historyOrdering lists the most recent operations first
+var historyOrdering = ordering{
+	name:  "history-" + models.SortNewest,
+	keys:  []sortKey{{expr: "o.created_at", desc: true}, {expr: "o.id", desc: true}},
+	table: "history_operations o",
+}
+
+type historyActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewHistoryActions(db *sql.DB, logger zerolog.Logger) repository.HistoryRepository {
+	return historyActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+// operationLog collects the changes made by an operation so they can be saved
+// in the same transaction as the operation itself
+type operationLog struct {
+	userID  int64
+	actorID int64
+	action  string
+	changes []models.HistoryChange
+}
+
+func newOperationLog(userID int64, action string) *operationLog {
+	return newActorOperationLog(userID, userID, action)
+}
+
+// newActorOperationLog collects the changes made by a collaborator to the pipes and bookmarks of userID
+func newActorOperationLog(userID, actorID int64, action string) *operationLog {
+	return &operationLog{userID: userID, actorID: actorID, action: action}
+}
+
+// add records the change of a pipe or bookmark from before to after. Nothing is
+// recorded when the snapshots are the same
+func (l *operationLog) add(entityType string, entityID int64, before, after interface{}) error {
+	beforeBytes, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterBytes, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(beforeBytes, afterBytes) {
+		return nil
+	}
+	l.changes = append(l.changes, models.HistoryChange{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     beforeBytes,
+		After:      afterBytes,
+	})
+	return nil
+}
+
+// trackBookmarks runs change and records how it changed the bookmarks matching condition.
+// condition may refer to the bookmarks table as b
+func (l *operationLog) trackBookmarks(ctx context.Context, tx *sql.Tx, condition string, args []interface{}, change func() error) error {
+	before, err := snapshotBookmarks(ctx, tx, condition, args...)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := snapshotBookmarks(ctx, tx, condition, args...)
+	if err != nil {
+		return err
+	}
+	for id, snapshot := range after {
+		if previous, ok := before[id]; ok {
+			if err := l.add(models.HistoryEntityBookmark, id, previous, snapshot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// trackPipes runs change and records how it changed the pipes matching condition.
+// condition may refer to the pipes table as p
+func (l *operationLog) trackPipes(ctx context.Context, tx *sql.Tx, condition string, args []interface{}, change func() error) error {
+	before, err := snapshotPipes(ctx, tx, condition, args...)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := snapshotPipes(ctx, tx, condition, args...)
+	if err != nil {
+		return err
+	}
+	for id, snapshot := range after {
+		if previous, ok := before[id]; ok {
+			if err := l.add(models.HistoryEntityPipe, id, previous, snapshot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// save stores the operation and its changes. Operations that didn't change anything are not stored
+func (l *operationLog) save(ctx context.Context, tx *sql.Tx) error {
+	if len(l.changes) == 0 {
+		return nil
+	}
+
+	var operationID int64
+	query := `INSERT INTO history_operations (user_id, actor_id, action) VALUES ($1, $2, $3) RETURNING id`
+	if err := tx.QueryRowContext(ctx, query, l.userID, l.actorID, l.action).Scan(&operationID); err != nil {
+		return err
+	}
+
+	changeQuery := `
+	INSERT INTO history_changes (operation_id, entity_type, entity_id, before, after)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, change := range l.changes {
+		_, err := tx.ExecContext(ctx, changeQuery, operationID, change.EntityType, change.EntityID, string(change.Before), string(change.After))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotBookmarks returns the tracked fields of the bookmarks matching condition, keyed by their id
+func snapshotBookmarks(ctx context.Context, db sqlExecutor, condition string, args ...interface{}) (map[int64]models.BookmarkSnapshot, error) {
+	query := `
+	SELECT
+	    b.id, b.pipe_id, b.title, b.position, b.is_read, b.is_starred, b.is_archived, b.deleted_at IS NOT NULL,
+	    COALESCE(array_agg(t.name ORDER BY t.name) FILTER (WHERE t.name IS NOT NULL), '{}')
+	FROM bookmarks b
+	    LEFT JOIN bookmark_tag bt ON bt.bookmark_id=b.id
+	    LEFT JOIN tags t ON t.id=bt.tag_id
+	WHERE ` + condition + `
+	GROUP BY b.id
+	`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[int64]models.BookmarkSnapshot)
+	for rows.Next() {
+		var id int64
+		var snapshot models.BookmarkSnapshot
+		err := rows.Scan(
+			&id,
+			&snapshot.PipeID,
+			&snapshot.Title,
+			&snapshot.Position,
+			&snapshot.IsRead,
+			&snapshot.IsStarred,
+			&snapshot.IsArchived,
+			&snapshot.Trashed,
+			pq.Array(&snapshot.Tags),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.Tags == nil {
+			snapshot.Tags = []string{}
+		}
+		snapshots[id] = snapshot
+	}
+	return snapshots, rows.Err()
+}
+
+// snapshotPipes returns the tracked fields of the pipes matching condition, keyed by their id
+func snapshotPipes(ctx context.Context, db sqlExecutor, condition string, args ...interface{}) (map[int64]models.PipeSnapshot, error) {
+	query := `
+	SELECT p.id, p.name, COALESCE(p.cover_photo, ''), p.position, p.parent_id, p.query,
+	       p.display_name, p.description, p.icon, p.color, p.visibility, p.deleted_at IS NOT NULL
+	FROM pipes p
+	WHERE ` + condition
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[int64]models.PipeSnapshot)
+	for rows.Next() {
+		var id int64
+		var snapshot models.PipeSnapshot
+		var query []byte
+		err := rows.Scan(
+			&id,
+			&snapshot.Name,
+			&snapshot.CoverPhoto,
+			&snapshot.Position,
+			&snapshot.ParentID,
+			&query,
+			&snapshot.DisplayName,
+			&snapshot.Description,
+			&snapshot.Icon,
+			&snapshot.Color,
+			&snapshot.Visibility,
+			&snapshot.Trashed,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.Query, err = parseSmartQuery(query); err != nil {
+			return nil, err
+		}
+		snapshots[id] = snapshot
+	}
+	return snapshots, rows.Err()
+}
+
+// setBookmarkTags replaces the tags of a bookmark, creating the tags its owner doesn't have yet
+func setBookmarkTags(ctx context.Context, db sqlExecutor, bmID int64, tags []string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM bookmark_tag WHERE bookmark_id=$1`, bmID); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return attachBookmarkTags(ctx, db, bmID, tags)
+}
+
+// GetHistory retrieves a page of the operations made by a user, the most recent first. The changes
+// a user made to pipes shared with them are included, those collaborators made to the pipes of the
+// user are not
+func (h historyActions) GetHistory(userID int64, filter models.HistoryFilter) ([]models.HistoryOperation, models.Pagination, error) {
+	args := []interface{}{userID}
+	entityCondition := "true"
+	if filter.EntityType != "" {
+		args = append(args, filter.EntityType, filter.EntityID)
+		entityCondition = `EXISTS (
+		    SELECT 1 FROM history_changes hc
+		    WHERE hc.operation_id=o.id AND hc.entity_type=$2 AND hc.entity_id=$3
+		)`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := historyOrdering.page(ctx, h.Db, filter.Filter, args)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT o.id, o.user_id, o.actor_id, o.action, o.undone_at, o.created_at
+	FROM history_operations o
+	WHERE o.actor_id=$1 AND ` + entityCondition + ` AND ` + pageCondition + `
+	` + pageClauses
+
+	operations, err := h.queryOperations(ctx, h.Db, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := historyOrdering.pagination(filter.Filter, len(operations), func(i int) int64 { return operations[i].ID })
+	operations = operations[:size]
+
+	if err := h.attachChanges(ctx, h.Db, operations, filter); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	return operations, pagination, nil
+}
+
+// Undo reverts the last count operations of a user that haven't been undone yet, the most
+// recent first. Every pipe and bookmark changed by an operation is put back the way it was
+// before the operation; the ones that have been deleted since are skipped. Only the operations
+// made by the user are undone, including the changes they made to pipes shared with them
+func (h historyActions) Undo(userID int64, count int) ([]models.HistoryOperation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT o.id, o.user_id, o.actor_id, o.action, o.undone_at, o.created_at
+	FROM history_operations o
+	WHERE o.actor_id=$1 AND o.undone_at IS NULL
+	ORDER BY o.created_at DESC, o.id DESC
+	LIMIT $2
+	FOR UPDATE
+	`
+	operations, err := h.queryOperations(ctx, tx, query, userID, count)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.attachChanges(ctx, tx, operations, models.HistoryFilter{}); err != nil {
+		return nil, err
+	}
+
+	undoneAt := time.Now()
+	var ids []int64
+	for i, operation := range operations {
+		for _, change := range restoreOrder(operation.Changes) {
+			if err := h.restore(ctx, tx, operation.UserID, change); err != nil {
+				return nil, err
+			}
+		}
+		operations[i].UndoneAt = &undoneAt
+		ids = append(ids, operation.ID)
+	}
+
+	if len(ids) > 0 {
+		undoQuery := `UPDATE history_operations SET undone_at=$2 WHERE id = ANY($1)`
+		if _, err := tx.ExecContext(ctx, undoQuery, pq.Array(ids), undoneAt); err != nil {
+			return nil, err
+		}
+	}
+	return operations, tx.Commit()
+}
+
+// restore puts a pipe or bookmark of userID back in the state it was before a change
+func (h historyActions) restore(ctx context.Context, tx *sql.Tx, userID int64, change models.HistoryChange) error {
+	switch change.EntityType {
+	case models.HistoryEntityBookmark:
+		var snapshot, after models.BookmarkSnapshot
+		if err := json.Unmarshal(change.Before, &snapshot); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(change.After, &after); err != nil {
+			return err
+		}
+		isTrashed, wasTrashed := trashStates(snapshot.Trashed, after.Trashed)
+		// the bookmark is only put back in its pipe while the pipe is still around
+		query := `
+		UPDATE bookmarks b
+		SET
+		    deleted_at=CASE WHEN $10::boolean THEN COALESCE(b.deleted_at, now()) ELSE NULL END,
+		    pipe_id=$3,
+		    title=$4,
+		    position=$5,
+		    is_read=$6,
+		    read_at=CASE WHEN $6::boolean THEN COALESCE(b.read_at, now()) ELSE NULL END,
+		    is_starred=$7,
+		    starred_at=CASE WHEN $7::boolean THEN COALESCE(b.starred_at, now()) ELSE NULL END,
+		    is_archived=$8,
+		    archived_at=CASE WHEN $8::boolean THEN COALESCE(b.archived_at, now()) ELSE NULL END
+		WHERE b.id=$1 AND b.user_id=$2 AND (b.deleted_at IS NOT NULL)=$9 AND EXISTS (
+		    SELECT 1 FROM pipes p WHERE p.id=$3 AND p.user_id=$2 AND p.deleted_at IS NULL
+		)
+		`
+		res, err := tx.ExecContext(ctx, query, change.EntityID, userID, snapshot.PipeID, snapshot.Title,
+			snapshot.Position, snapshot.IsRead, snapshot.IsStarred, snapshot.IsArchived, isTrashed, wasTrashed)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return nil
+		}
+		return setBookmarkTags(ctx, tx, change.EntityID, snapshot.Tags)
+
+	case models.HistoryEntityPipe:
+		var snapshot, after models.PipeSnapshot
+		if err := json.Unmarshal(change.Before, &snapshot); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(change.After, &after); err != nil {
+			return err
+		}
+		isTrashed, wasTrashed := trashStates(snapshot.Trashed, after.Trashed)
+		// the pipe is only nested back in its parent while the parent is still around
+		query := `
+		UPDATE pipes
+		SET
+		    name=$3, cover_photo=$4, position=$5, parent_id=$6, query=$7,
+		    display_name=COALESCE(NULLIF($8, ''), $3), description=$9, icon=$10, color=$11,
+		    visibility=COALESCE(NULLIF($12, ''), visibility),
+		    deleted_at=CASE WHEN $14::boolean THEN COALESCE(deleted_at, now()) ELSE NULL END, modified_at=now()
+		WHERE id=$1 AND user_id=$2 AND (deleted_at IS NOT NULL)=$13 AND (
+		    $6::int IS NULL OR EXISTS (SELECT 1 FROM pipes pp WHERE pp.id=$6 AND pp.user_id=$2 AND pp.deleted_at IS NULL)
+		)
+		`
+		smartQuery, err := smartQueryValue(snapshot.Query)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			change.EntityID,
+			userID,
+			snapshot.Name,
+			snapshot.CoverPhoto,
+			snapshot.Position,
+			snapshot.ParentID,
+			smartQuery,
+			snapshot.DisplayName,
+			snapshot.Description,
+			snapshot.Icon,
+			snapshot.Color,
+			snapshot.Visibility,
+			isTrashed,
+			wasTrashed,
+		)
+		if dbErr, ok := err.(*pq.Error); ok && dbErr.Code == "23505" {
+			return ErrRecordExists
+		}
+		return err
+	}
+	return nil
+}
+
+// trashStates returns whether a changed pipe or bookmark has to be in the trash for the change to be
+// reverted and whether it goes back to the trash. Only the operation that moved a pipe or bookmark in
+// or out of the trash can move it back, the ones trashed since are skipped like before
+func trashStates(before, after bool) (isTrashed, wasTrashed bool) {
+	if before == after {
+		return false, false
+	}
+	return after, before
+}
+
+// restoreOrder sorts the changes of an operation in the order they can be reverted: the pipes first,
+// starting with the ones that come back from the trash, so that the pipes and bookmarks nested in them
+// can be put back in them
+func restoreOrder(changes []models.HistoryChange) []models.HistoryChange {
+	rank := func(change models.HistoryChange) int {
+		if change.EntityType != models.HistoryEntityPipe {
+			return 2
+		}
+		var before, after struct {
+			Trashed bool `json:"trashed"`
+		}
+		if json.Unmarshal(change.Before, &before) == nil && json.Unmarshal(change.After, &after) == nil &&
+			!before.Trashed && after.Trashed {
+			return 0
+		}
+		return 1
+	}
+
+	ordered := append([]models.HistoryChange(nil), changes...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rank(ordered[i]) < rank(ordered[j])
+	})
+	return ordered
+}
+
+// queryOperations retrieves the operations selected by query, without their changes
+func (h historyActions) queryOperations(ctx context.Context, db sqlExecutor, query string, args ...interface{}) ([]models.HistoryOperation, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var operations []models.HistoryOperation
+	for rows.Next() {
+		var operation models.HistoryOperation
+		err := rows.Scan(&operation.ID, &operation.UserID, &operation.ActorID, &operation.Action, &operation.UndoneAt, &operation.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+	return operations, rows.Err()
+}
+
+// attachChanges loads the changes of every operation. When the filter is about a single
+// pipe or bookmark, only the changes made to it are loaded
+func (h historyActions) attachChanges(ctx context.Context, db sqlExecutor, operations []models.HistoryOperation, filter models.HistoryFilter) error {
+	if len(operations) == 0 {
+		return nil
+	}
+
+	indexes := make(map[int64]int, len(operations))
+	ids := make([]int64, len(operations))
+	for i, operation := range operations {
+		indexes[operation.ID] = i
+		ids[i] = operation.ID
+	}
+
+	args := []interface{}{pq.Array(ids)}
+	query := `
+	SELECT operation_id, entity_type, entity_id, before, after
+	FROM history_changes
+	WHERE operation_id = ANY($1)`
+	if filter.EntityType != "" {
+		args = append(args, filter.EntityType, filter.EntityID)
+		query += ` AND entity_type=$2 AND entity_id=$3`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var operationID int64
+		var change models.HistoryChange
+		var before, after []byte
+		if err := rows.Scan(&operationID, &change.EntityType, &change.EntityID, &before, &after); err != nil {
+			return err
+		}
+		change.Before, change.After = before, after
+		i := indexes[operationID]
+		operations[i].Changes = append(operations[i].Changes, change)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var getHistoryTestCases = map[string]struct {
+	inputUserId    int64
+	inputFilter    models.HistoryFilter
+	wantOperations []string
+	wantErr        error
+}{
+	"every operation": {
+		inputUserId:    1,
+		inputFilter:    models.HistoryFilter{},
+		wantOperations: []string{models.HistoryActionRetag, models.HistoryActionEdit, models.HistoryActionState},
+		wantErr:        nil,
+	},
+	"operations on a bookmark": {
+		inputUserId:    1,
+		inputFilter:    models.HistoryFilter{EntityType: models.HistoryEntityBookmark, EntityID: 1},
+		wantOperations: []string{models.HistoryActionRetag, models.HistoryActionState},
+		wantErr:        nil,
+	},
+	"operations on a pipe": {
+		inputUserId:    1,
+		inputFilter:    models.HistoryFilter{EntityType: models.HistoryEntityPipe, EntityID: 1},
+		wantOperations: []string{models.HistoryActionEdit},
+		wantErr:        nil,
+	},
+	"first page": {
+		inputUserId:    1,
+		inputFilter:    models.HistoryFilter{Filter: models.Filter{Limit: 2}},
+		wantOperations: []string{models.HistoryActionRetag, models.HistoryActionEdit},
+		wantErr:        nil,
+	},
+	"another user": {
+		inputUserId:    2,
+		inputFilter:    models.HistoryFilter{},
+		wantOperations: []string{},
+		wantErr:        nil,
+	},
+}
+
+var undoTestCases = map[string]struct {
+	inputUserId  int64
+	inputCount   int
+	wantUndone   int
+	wantTags     []string
+	wantIsRead   bool
+	wantPipeName string
+	wantErr      error
+}{
+	"last operation": {
+		inputUserId:  1,
+		inputCount:   1,
+		wantUndone:   1,
+		wantTags:     []string{"Beautiful Asian Muslim", "Quick Blows"},
+		wantIsRead:   true,
+		wantPipeName: "Shorts",
+		wantErr:      nil,
+	},
+	"every operation": {
+		inputUserId:  1,
+		inputCount:   models.MaxUndoCount,
+		wantUndone:   3,
+		wantTags:     []string{"Beautiful Asian Muslim", "Quick Blows"},
+		wantIsRead:   false,
+		wantPipeName: "Youtube Shorts",
+		wantErr:      nil,
+	},
+	"nothing to undo": {
+		inputUserId:  2,
+		inputCount:   1,
+		wantUndone:   0,
+		wantTags:     []string{},
+		wantIsRead:   true,
+		wantPipeName: "Shorts",
+		wantErr:      nil,
+	},
+}
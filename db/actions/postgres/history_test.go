@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// makeHistory marks bookmark 1 as read, renames pipe 1 to "Shorts" and then wipes the tags of bookmark 1
+func makeHistory(t *testing.T, db *sql.DB) {
+	ba := NewBookmarkActions(db, logger)
+	pa := NewPipeActions(db, logger)
+
+	_, err := ba.UpdateBookmarkState(1, 1, models.BookmarkStateUpdate{Read: &trueValue})
+	assert.Nil(t, err)
+	_, err = pa.UpdatePipe(1, 1, 1, models.Pipe{Name: "Shorts"})
+	assert.Nil(t, err)
+	_, err = ba.UpdateBookmark(1, 1, 1, models.BookmarkUpdate{Tags: []string{}})
+	assert.Nil(t, err)
+}
+
+func Test_history_GetHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getHistoryTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ha := NewHistoryActions(db, logger)
+			makeHistory(t, db)
+
+			gotOperations, _, gotErr := ha.GetHistory(tc.inputUserId, tc.inputFilter)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, len(tc.wantOperations), len(gotOperations))
+				for i, operation := range gotOperations {
+					assert.Equal(t, tc.wantOperations[i], operation.Action)
+					assert.NotEmpty(t, operation.Changes)
+					if tc.inputFilter.EntityType != "" {
+						for _, change := range operation.Changes {
+							assert.Equal(t, tc.inputFilter.EntityID, change.EntityID)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_history_Undo(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := undoTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ha := NewHistoryActions(db, logger)
+			ba := NewBookmarkActions(db, logger)
+			pa := NewPipeActions(db, logger)
+			makeHistory(t, db)
+
+			gotUndone, gotErr := ha.Undo(tc.inputUserId, tc.inputCount)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantUndone, len(gotUndone))
+
+				bookmark, err := ba.GetBookmark(1, 1)
+				assert.Nil(t, err)
+				assert.ElementsMatch(t, tc.wantTags, bookmark.Tags)
+				assert.Equal(t, tc.wantIsRead, bookmark.IsRead)
+
+				pipe, err := pa.GetPipe(1, 1)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.wantPipeName, pipe.Name)
+
+				// undone operations can't be undone twice
+				again, err := ha.Undo(tc.inputUserId, tc.inputCount)
+				assert.Nil(t, err)
+				for _, operation := range again {
+					for _, undone := range gotUndone {
+						assert.NotEqual(t, undone.ID, operation.ID)
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_history_Undo_merge(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ha := NewHistoryActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	pa := NewPipeActions(db, logger)
+	// the same video as bookmark 1, which goes to the trash when pipe 2 is merged into pipe 1
+	duplicate, err := ba.CreateBookmark(models.Bookmark{UserID: 1, PipeID: 2, Url: "https://youtu.be/Acgk_Jl95es", Platform: "youtube"})
+	assert.Nil(t, err)
+	_, err = pa.MergePipes(1, 1, models.PipeMerge{SourceIDs: []int64{2}})
+	assert.Nil(t, err)
+
+	undone, err := ha.Undo(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(undone))
+	assert.Equal(t, models.HistoryActionMerge, undone[0].Action)
+
+	_, err = pa.GetPipe(2, 1)
+	assert.Nil(t, err)
+	restored, err := ba.GetBookmark(duplicate.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), restored.PipeID)
+	kept, err := ba.GetBookmark(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), kept.PipeID)
+}
+
+func Test_history_Undo_split(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ha := NewHistoryActions(db, logger)
+	pa := NewPipeActions(db, logger)
+	pipes, err := pa.SplitPipe(1, 2, models.PipeSplit{By: models.PipeSplitByPlatform})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pipes))
+
+	undone, err := ha.Undo(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(undone))
+	assert.Equal(t, models.HistoryActionSplit, undone[0].Action)
+
+	_, err = pa.GetPipe(pipes[0].ID, 1)
+	assert.Equal(t, ErrNoRecord, err)
+	source, err := pa.GetPipe(2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, source.Bookmarks)
+}
+
+func Test_history_Undo_collaborator(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ha := NewHistoryActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	original, err := ba.GetBookmark(2, 1)
+	assert.Nil(t, err)
+	// user 2 edits a bookmark of the second pipe of user 1, which is shared with them
+	title := "Edited by a collaborator"
+	_, err = ba.UpdateBookmark(2, 1, 2, models.BookmarkUpdate{Title: &title})
+	assert.Nil(t, err)
+
+	operations, _, err := ha.GetHistory(2, models.HistoryFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(operations))
+	assert.Equal(t, int64(1), operations[0].UserID)
+	assert.Equal(t, int64(2), operations[0].ActorID)
+
+	// the owner can't undo the changes of a collaborator
+	undone, err := ha.Undo(1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(undone))
+	bookmark, err := ba.GetBookmark(2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, title, bookmark.Title)
+
+	undone, err = ha.Undo(2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(undone))
+	bookmark, err = ba.GetBookmark(2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, original.Title, bookmark.Title)
+}
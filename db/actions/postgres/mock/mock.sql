@@ -48,12 +48,15 @@ VALUES
 
 -- populate tags table
 INSERT INTO tags
-    (name)
+    (user_id, name)
 VALUES
-    ('Beautiful Asian Muslim'),
-    ('Quick Blows'),
-    ('Twerk Videos'),
-    ('iOS 16 releases');
+    (1, 'Beautiful Asian Muslim'),
+    (1, 'Quick Blows'),
+    (1, 'Twerk Videos'),
+    (2, 'iOS 16 releases'),
+    (2, 'Beautiful Asian Muslim'),
+    (3, 'Beautiful Asian Muslim'),
+    (3, 'Quick Blows');
 
 -- populate bookmark_tags table
 INSERT INTO bookmark_tag
@@ -64,10 +67,10 @@ VALUES
     (2, 2),
     (2, 3),
     (3, 4),
-    (3, 1),
-    (4, 1),
-    (5, 1),
-    (6, 2);
+    (3, 5),
+    (4, 5),
+    (5, 6),
+    (6, 7);
 
 -- populate notifications table
 INSERT INTO notifications
@@ -90,4 +93,10 @@ INSERT INTO shared_pipe_receivers
     (sharer_id, shared_pipe_id, receiver_id, code, created_at, modified_at, is_accepted)
 VALUES
     (1, 2, 2, 'MG78k9lig68', now(), now(), true),
-    (1, 1, 2, 'MG78k9lig67', now(), now(), false);
\ No newline at end of file
+    (1, 1, 2, 'MG78k9lig67', now(), now(), false);
+-- populate bookmark_reminders table
+INSERT INTO bookmark_reminders
+    (user_id, bookmark_id, note, remind_at)
+VALUES
+    (1, 1, 'Watch this on the weekend', now() - interval '1 hour'),
+    (1, 2, '', now() + interval '2 days');
@@ -0,0 +1,320 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const reportColumns = `
+	r.id, r.reporter_id, r.target_type, r.target_id, r.target_user_id, r.pipe_id, r.reason, r.details,
+	r.status, r.action, r.note, r.resolved_by, r.resolved_at, r.created_at, (
+	    SELECT COUNT(*) FROM reports tr
+	    WHERE tr.target_type=r.target_type AND tr.target_id=r.target_id AND tr.status='` + models.ReportStatusOpen + `'
+	)`
+
+// reportTargets select the user behind what the user in $1 reports and the pipe it's in, for the id
+// in $2. Users report what they can see and don't own, and any user but themselves
+var reportTargets = map[string]string{
+	models.ReportTargetPipe: `
+	SELECT p.user_id, p.id FROM pipes p
+	WHERE p.id=$2 AND ` + pipeVisibleTo("$1"),
+	models.ReportTargetBookmark: `
+	SELECT p.user_id, p.id FROM bookmarks b
+	    INNER JOIN pipes p ON b.pipe_id=p.id
+	WHERE b.id=$2 AND b.deleted_at IS NULL AND ` + pipeVisibleTo("$1"),
+	models.ReportTargetComment: `
+	SELECT c.user_id, p.id FROM bookmark_comments c
+	    INNER JOIN bookmarks b ON c.bookmark_id=b.id
+	    INNER JOIN pipes p ON b.pipe_id=p.id
+	WHERE c.id=$2 AND c.deleted_at IS NULL AND c.user_id<>$1 AND b.deleted_at IS NULL
+	    AND ((p.user_id=$1 AND p.deleted_at IS NULL) OR ` + pipeVisibleTo("$1") + `)`,
+	models.ReportTargetUser: `
+	SELECT u.id, NULL::int FROM users u
+	WHERE u.id=$2 AND u.id<>$1`,
+}
+
+// reportQueueOrdering lists reports from the oldest, so that the moderation queue is worked in order
+var reportQueueOrdering = ordering{
+	name:  "reports-" + models.SortOldest,
+	keys:  []sortKey{{expr: "r.created_at"}, {expr: "r.id"}},
+	table: "reports r",
+}
+
+type moderationActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewModerationActions(db *sql.DB, logger zerolog.Logger) repository.ModerationRepository {
+	return moderationActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+func scanReport(row rowScanner) (models.Report, error) {
+	var report models.Report
+	err := row.Scan(
+		&report.ID,
+		&report.ReporterID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.TargetUserID,
+		&report.PipeID,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		&report.Action,
+		&report.Note,
+		&report.ResolvedBy,
+		&report.ResolvedAt,
+		&report.CreatedAt,
+		&report.TargetReports,
+	)
+	return report, err
+}
+
+// CreateReport records a user reporting something they can see. It returns ErrNoRecord when there's
+// nothing the user can report with the given type and id, and ErrRecordExists when the user already
+// reported it and the report is still open
+func (m moderationActions) CreateReport(report models.Report) (models.Report, error) {
+	target, ok := reportTargets[report.TargetType]
+	if !ok {
+		return report, ErrNoRecord
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := m.Db.QueryRowContext(ctx, target, report.ReporterID, report.TargetID).Scan(&report.TargetUserID, &report.PipeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return report, ErrNoRecord
+		}
+		return report, err
+	}
+
+	query := `
+	INSERT INTO reports (reporter_id, target_type, target_id, target_user_id, pipe_id, reason, details)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (reporter_id, target_type, target_id) WHERE status='` + models.ReportStatusOpen + `' DO NOTHING
+	RETURNING id
+	`
+	var reportId int64
+	err = m.Db.QueryRowContext(
+		ctx,
+		query,
+		report.ReporterID,
+		report.TargetType,
+		report.TargetID,
+		report.TargetUserID,
+		report.PipeID,
+		report.Reason,
+		report.Details,
+	).Scan(&reportId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return report, ErrRecordExists
+		}
+		return report, err
+	}
+	return m.GetReport(reportId)
+}
+
+// GetReport retrieves a report
+func (m moderationActions) GetReport(reportId int64) (models.Report, error) {
+	query := `
+	SELECT` + reportColumns + `
+	FROM reports r
+	WHERE r.id=$1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	report, err := scanReport(m.Db.QueryRowContext(ctx, query, reportId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return report, ErrNoRecord
+		}
+		return report, err
+	}
+	return report, nil
+}
+
+// GetReports gets a page of the reports with a status, from the oldest
+func (m moderationActions) GetReports(status string, filter models.Filter) ([]models.Report, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := reportQueueOrdering.page(ctx, m.Db, filter, []interface{}{status})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + reportColumns + `
+	FROM reports r
+	WHERE r.status=$1 AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := m.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	var reports []models.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, models.Pagination{}, err
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := reportQueueOrdering.pagination(filter, len(reports), func(i int) int64 { return reports[i].ID })
+	return reports[:size], pagination, nil
+}
+
+// ResolveReports records what a moderator did about a report. Every open report on the same target
+// is closed along with it, as dismissed when the moderator took no action and as resolved otherwise
+func (m moderationActions) ResolveReports(report models.Report, moderatorId int64, action, note string) (models.Report, error) {
+	status := models.ReportStatusResolved
+	if action == models.ModerationDismiss {
+		status = models.ReportStatusDismissed
+	}
+	query := `
+	UPDATE reports
+	SET status=$3, action=$4, note=$5, resolved_by=$6, resolved_at=now()
+	WHERE target_type=$1 AND target_id=$2 AND status='` + models.ReportStatusOpen + `'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	_, err := m.Db.ExecContext(ctx, query, report.TargetType, report.TargetID, status, action, note, moderatorId)
+	if err != nil {
+		return report, err
+	}
+	return m.GetReport(report.ID)
+}
+
+// HidePipe makes a pipe private, disables its public share link and keeps its owner from publishing it again
+func (m moderationActions) HidePipe(pipeId int64) error {
+	return m.unpublishPipe(pipeId, true)
+}
+
+// UnhidePipe lets the owner of a hidden pipe publish it again. The pipe stays private until they do
+func (m moderationActions) UnhidePipe(pipeId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := m.Db.ExecContext(ctx, `UPDATE pipes SET hidden_at=NULL WHERE id=$1 AND hidden_at IS NOT NULL`, pipeId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// UnpublishPipe makes a pipe private and disables its public share link. Its owner can publish it again
+func (m moderationActions) UnpublishPipe(pipeId int64) error {
+	return m.unpublishPipe(pipeId, false)
+}
+
+// unpublishPipe makes a pipe private and disables its public share link, hiding it when hide is set.
+// The users the pipe was shared with keep their access
+func (m moderationActions) unpublishPipe(pipeId int64, hide bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := m.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE pipes
+	SET visibility='` + models.PipeVisibilityPrivate + `', hidden_at=CASE WHEN $2 THEN COALESCE(hidden_at, now()) ELSE hidden_at END,
+	    modified_at=now()
+	WHERE id=$1
+	`
+	result, err := tx.ExecContext(ctx, query, pipeId, hide)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	query = `DELETE FROM shared_pipes WHERE pipe_id=$1 AND type='` + models.PipeShareTypePublic + `'`
+	if _, err := tx.ExecContext(ctx, query, pipeId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsPipeHidden reports whether moderators hid a pipe
+func (m moderationActions) IsPipeHidden(pipeId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var hidden bool
+	err := m.Db.QueryRowContext(ctx, `SELECT hidden_at IS NOT NULL FROM pipes WHERE id=$1`, pipeId).Scan(&hidden)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, ErrNoRecord
+		}
+		return false, err
+	}
+	return hidden, nil
+}
+
+// SuspendUser keeps a user from using their account and makes their profile private. The public
+// share links they created stop working while they are suspended
+func (m moderationActions) SuspendUser(userId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := m.Db.ExecContext(ctx, `
+	UPDATE users SET suspended_at=COALESCE(suspended_at, now()), public_profile=false WHERE id=$1
+	`, userId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// UnsuspendUser lifts the suspension of a user. Their profile stays private until they make it public again
+func (m moderationActions) UnsuspendUser(userId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := m.Db.ExecContext(ctx, `UPDATE users SET suspended_at=NULL WHERE id=$1 AND suspended_at IS NOT NULL`, userId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
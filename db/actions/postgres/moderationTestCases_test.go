@@ -0,0 +1,49 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var createReportTestCases = map[string]struct {
+	inputTargetType string
+	inputTargetId   int64
+	wantTargetUser  int64
+	wantErr         error
+}{
+	"public pipe": {
+		inputTargetType: models.ReportTargetPipe,
+		inputTargetId:   1,
+		wantTargetUser:  firstUserId,
+		wantErr:         nil,
+	},
+	"bookmark in an unlisted pipe": {
+		inputTargetType: models.ReportTargetBookmark,
+		inputTargetId:   2,
+		wantTargetUser:  firstUserId,
+		wantErr:         nil,
+	},
+	"own pipe": {
+		inputTargetType: models.ReportTargetPipe,
+		inputTargetId:   3,
+		wantErr:         ErrNoRecord,
+	},
+	"private pipe of another user": {
+		inputTargetType: models.ReportTargetPipe,
+		inputTargetId:   5,
+		wantErr:         ErrNoRecord,
+	},
+	"user": {
+		inputTargetType: models.ReportTargetUser,
+		inputTargetId:   firstUserId,
+		wantTargetUser:  firstUserId,
+		wantErr:         nil,
+	},
+	"themselves": {
+		inputTargetType: models.ReportTargetUser,
+		inputTargetId:   secondUserId,
+		wantErr:         ErrNoRecord,
+	},
+	"unknown target type": {
+		inputTargetType: "tag",
+		inputTargetId:   1,
+		wantErr:         ErrNoRecord,
+	},
+}
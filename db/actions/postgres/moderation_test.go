@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_moderation_CreateReport(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := createReportTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			setPipeVisibility(t, db)
+			ma := NewModerationActions(db, logger)
+
+			report := models.Report{
+				ReporterID: secondUserId,
+				TargetType: tc.inputTargetType,
+				TargetID:   tc.inputTargetId,
+				Reason:     models.ReportReasonSpam,
+			}
+			gotReport, gotErr := ma.CreateReport(report)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				assert.Equal(t, tc.wantTargetUser, gotReport.TargetUserID)
+				assert.Equal(t, models.ReportStatusOpen, gotReport.Status)
+				assert.Equal(t, 1, gotReport.TargetReports)
+
+				// the report stays open until a moderator looks into it
+				_, err := ma.CreateReport(report)
+				assert.Equal(t, ErrRecordExists, err)
+			}
+		})
+	}
+}
+
+func Test_moderation_ResolveReports(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	setPipeVisibility(t, db)
+	ma := NewModerationActions(db, logger)
+	var reports []models.Report
+	for _, reporterId := range []int64{secondUserId, 3} {
+		report, err := ma.CreateReport(models.Report{
+			ReporterID: reporterId,
+			TargetType: models.ReportTargetPipe,
+			TargetID:   1,
+			Reason:     models.ReportReasonSpam,
+		})
+		assert.NoError(t, err)
+		reports = append(reports, report)
+	}
+	assert.Equal(t, 2, reports[1].TargetReports)
+
+	// closing a report closes every open report on the same target
+	gotReport, err := ma.ResolveReports(reports[0], firstUserId, models.ModerationDismiss, "not spam")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReportStatusDismissed, gotReport.Status)
+	assert.Equal(t, "not spam", gotReport.Note)
+
+	gotReports, _, err := ma.GetReports(models.ReportStatusOpen, models.Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, gotReports)
+	gotReports, _, err = ma.GetReports(models.ReportStatusDismissed, models.Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, gotReports, 2)
+}
+
+func Test_moderation_HidePipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	setPipeVisibility(t, db)
+	ma := NewModerationActions(db, logger)
+	pa := NewPipeActions(db, logger)
+
+	assert.NoError(t, ma.UnpublishPipe(2))
+	pipe, err := pa.GetPipe(2, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PipeVisibilityPrivate, pipe.Visibility)
+	hidden, err := ma.IsPipeHidden(2)
+	assert.NoError(t, err)
+	assert.False(t, hidden)
+
+	assert.NoError(t, ma.HidePipe(1))
+	pipe, err = pa.GetPipe(1, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PipeVisibilityPrivate, pipe.Visibility)
+	hidden, err = ma.IsPipeHidden(1)
+	assert.NoError(t, err)
+	assert.True(t, hidden)
+
+	assert.NoError(t, ma.UnhidePipe(1))
+	assert.Equal(t, ErrNoRecord, ma.UnhidePipe(1))
+	assert.Equal(t, ErrNoRecord, ma.HidePipe(1000))
+}
+
+func Test_moderation_SuspendUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	makeProfilePublic(t, db)
+	ma := NewModerationActions(db, logger)
+	ua := NewUserActions(db, logger)
+
+	assert.NoError(t, ma.SuspendUser(firstUserId))
+	user, err := ua.GetUserById(firstUserId)
+	assert.NoError(t, err)
+	assert.NotNil(t, user.SuspendedAt)
+	settings, err := ua.GetProfileSettings(firstUserId)
+	assert.NoError(t, err)
+	assert.False(t, settings.PublicProfile)
+
+	assert.NoError(t, ma.UnsuspendUser(firstUserId))
+	assert.Equal(t, ErrNoRecord, ma.UnsuspendUser(firstUserId))
+	user, err = ua.GetUserById(firstUserId)
+	assert.NoError(t, err)
+	assert.Nil(t, user.SuspendedAt)
+}
@@ -49,39 +49,51 @@ func (n notificationActions) CreateNotification(userId int64, message, metadata
 	return notification, nil
 }
 
-// GetNotifications retrieves the notifications belonging to a user
-func (n notificationActions) GetNotifications(userId int64) ([]models.Notification, error) {
+// notificationOrdering lists the most recent notifications first
+var notificationOrdering = ordering{
+	name:  "notifications-" + models.SortNewest,
+	keys:  []sortKey{{expr: "n.created_at", desc: true}, {expr: "n.id", desc: true}},
+	table: "notifications n",
+}
+
+// GetNotifications retrieves a page of the notifications belonging to a user
+func (n notificationActions) GetNotifications(userId int64, filter models.Filter) ([]models.Notification, models.Pagination, error) {
 	var notifications []models.Notification
-	query := `
-	SELECT 
-	    id, user_id, message, read, metadata, created_at 
-	FROM notifications 
-	WHERE user_id=$1
-	ORDER BY created_at DESC
-	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	rows, err := n.Db.QueryContext(ctx, query, userId)
+	pageCondition, pageClauses, args, err := notificationOrdering.page(ctx, n.Db, filter, []interface{}{userId})
+	if err != nil {
+		return notifications, models.Pagination{}, err
+	}
+	query := `
+	SELECT 
+	    n.id, n.user_id, n.message, n.read, n.metadata, n.created_at 
+	FROM notifications n
+	WHERE n.user_id=$1 AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := n.Db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return notifications, err
+		return notifications, models.Pagination{}, err
 	}
 
 	defer rows.Close()
 	for rows.Next() {
 		var notification models.Notification
 		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Message, &notification.Read, &notification.MetaData, &notification.CreatedAt); err != nil {
-			return notifications, err
+			return notifications, models.Pagination{}, err
 		}
 		notifications = append(notifications, notification)
 	}
 
 	if err := rows.Err(); err != nil {
-		return notifications, err
+		return notifications, models.Pagination{}, err
 	}
 
-	return notifications, nil
+	pagination, size := notificationOrdering.pagination(filter, len(notifications), func(i int) int64 { return notifications[i].ID })
+	return notifications[:size], pagination, nil
 
 }
 
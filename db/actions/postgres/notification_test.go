@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"github.com/mypipeapp/mypipeapi/db/models"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -40,7 +41,7 @@ func Test_notification_GetNotifications(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			db := newTestDb(t)
 			na := NewNotificationActions(db, logger)
-			gotNotifications, gotErr := na.GetNotifications(tc.inputUserId)
+			gotNotifications, _, gotErr := na.GetNotifications(tc.inputUserId, models.Filter{})
 			assert.Equal(t, tc.wantErr, gotErr)
 
 			if nil == gotErr {
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"strconv"
+	"strings"
+)
+
+// sortKey is one of the expressions a list is sorted by
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+// ordering is the way a list is sorted. The last key must be the unique id of the items so that
+// every item has a stable place in the list and can be pointed at by a cursor
+type ordering struct {
+	// name tells the orderings of a list apart, so that a cursor can't be used with another ordering
+	name string
+	keys []sortKey
+	// table is the aliased table the keys are read from, e.g. "bookmarks b"
+	table string
+}
+
+// orderBy returns the ORDER BY clause of the ordering
+func (o ordering) orderBy() string {
+	clauses := make([]string, len(o.keys))
+	for i, key := range o.keys {
+		clauses[i] = key.expr
+		if key.desc {
+			clauses[i] += " DESC"
+		}
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// after returns a condition that matches the items coming after the item whose id is passed
+// as param. The keys of that item are read with subqueries that shadow the alias of the outer
+// query, so the same expressions can be used on both sides
+func (o ordering) after(param string) string {
+	id := o.keys[len(o.keys)-1].expr
+	cursorValue := func(key sortKey) string {
+		return fmt.Sprintf("(SELECT %s FROM %s WHERE %s=%s)", key.expr, o.table, id, param)
+	}
+
+	conditions := make([]string, len(o.keys))
+	for i, key := range o.keys {
+		var parts []string
+		for _, previous := range o.keys[:i] {
+			parts = append(parts, previous.expr+" = "+cursorValue(previous))
+		}
+		operator := " > "
+		if key.desc {
+			operator = " < "
+		}
+		parts = append(parts, key.expr+operator+cursorValue(key))
+		conditions[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// page returns the condition that skips the items up to the cursor of filter and the ORDER BY
+// and LIMIT clauses of the page, as pageClauses does. The item a cursor points at must still exist,
+// since the keys it's compared with are read from it, and ErrInvalidCursor is returned when it's gone
+func (o ordering) page(ctx context.Context, db sqlExecutor, filter models.Filter, args []interface{}) (string, string, []interface{}, error) {
+	if filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor, o.name)
+		if err != nil {
+			return "", "", args, err
+		}
+		var exists int
+		query := fmt.Sprintf("SELECT 1 FROM %s WHERE %s=$1", o.table, o.keys[len(o.keys)-1].expr)
+		if err := db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+			if err == sql.ErrNoRows {
+				return "", "", args, ErrInvalidCursor
+			}
+			return "", "", args, err
+		}
+	}
+	return o.pageClauses(filter, args)
+}
+
+// pageClauses returns the condition that skips the items up to the cursor of filter and the ORDER BY
+// and LIMIT clauses of the page. The cursor is appended to args when one is given. One item more
+// than the page limit is fetched to find out whether there is a page after this one
+func (o ordering) pageClauses(filter models.Filter, args []interface{}) (string, string, []interface{}, error) {
+	condition := "true"
+	if filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor, o.name)
+		if err != nil {
+			return "", "", args, err
+		}
+		args = append(args, id)
+		condition = o.after(fmt.Sprintf("$%d", len(args)))
+	}
+	tail := fmt.Sprintf("ORDER BY %s LIMIT %d", o.orderBy(), filter.PageLimit()+1)
+	return condition, tail, args, nil
+}
+
+// pagination builds the pagination of a page out of the number of items fetched for it and
+// returns how many of them belong on the page. idAt returns the id of the item at index i
+func (o ordering) pagination(filter models.Filter, fetched int, idAt func(i int) int64) (models.Pagination, int) {
+	pagination := models.Pagination{Limit: filter.PageLimit()}
+	if fetched <= pagination.Limit {
+		return pagination, fetched
+	}
+	pagination.HasMore = true
+	pagination.NextCursor = encodeCursor(o.name, idAt(pagination.Limit-1))
+	return pagination, pagination.Limit
+}
+
+// encodeCursor returns an opaque cursor pointing at the item with the given id
+func encodeCursor(name string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name + ":" + strconv.FormatInt(id, 10)))
+}
+
+// decodeCursor returns the id of the item a cursor points at. The cursor must have been
+// created for the ordering with the given name
+func decodeCursor(cursor, name string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 || parts[0] != name {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_pagination_decodeCursor(t *testing.T) {
+	testCases := map[string]struct {
+		inputCursor string
+		inputName   string
+		wantId      int64
+		wantErr     error
+	}{
+		"valid cursor":           {inputCursor: encodeCursor("bookmarks-newest", 42), inputName: "bookmarks-newest", wantId: 42},
+		"cursor of another sort": {inputCursor: encodeCursor("bookmarks-oldest", 42), inputName: "bookmarks-newest", wantErr: ErrInvalidCursor},
+		"not base64":             {inputCursor: "not a cursor!", inputName: "bookmarks-newest", wantErr: ErrInvalidCursor},
+		"missing id":             {inputCursor: base64.RawURLEncoding.EncodeToString([]byte("bookmarks-newest")), inputName: "bookmarks-newest", wantErr: ErrInvalidCursor},
+		"id is not a number":     {inputCursor: base64.RawURLEncoding.EncodeToString([]byte("bookmarks-newest:abc")), inputName: "bookmarks-newest", wantErr: ErrInvalidCursor},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gotId, gotErr := decodeCursor(tc.inputCursor, tc.inputName)
+			assert.Equal(t, tc.wantErr, gotErr)
+			assert.Equal(t, tc.wantId, gotId)
+		})
+	}
+}
+
+func Test_pagination_pageClauses(t *testing.T) {
+	o := ordering{
+		name:  "bookmarks-newest",
+		keys:  []sortKey{{expr: "b.created_at", desc: true}, {expr: "b.id", desc: true}},
+		table: "bookmarks b",
+	}
+
+	condition, tail, args, err := o.pageClauses(models.Filter{Limit: 500}, []interface{}{int64(1)})
+	assert.Nil(t, err)
+	assert.Equal(t, "true", condition)
+	assert.Equal(t, "ORDER BY b.created_at DESC, b.id DESC LIMIT 101", tail)
+	assert.Equal(t, []interface{}{int64(1)}, args)
+
+	condition, _, args, err = o.pageClauses(models.Filter{Cursor: encodeCursor(o.name, 7)}, []interface{}{int64(1)})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(7)}, args)
+	assert.Equal(t, "((b.created_at < (SELECT b.created_at FROM bookmarks b WHERE b.id=$2)) OR "+
+		"(b.created_at = (SELECT b.created_at FROM bookmarks b WHERE b.id=$2) AND b.id < (SELECT b.id FROM bookmarks b WHERE b.id=$2)))", condition)
+
+	pagination, size := o.pagination(models.Filter{Limit: 2}, 3, func(i int) int64 { return int64(10 + i) })
+	assert.Equal(t, 2, size)
+	assert.Equal(t, models.Pagination{Limit: 2, NextCursor: encodeCursor(o.name, 11), HasMore: true}, pagination)
+
+	pagination, size = o.pagination(models.Filter{Limit: 2}, 2, func(i int) int64 { return int64(10 + i) })
+	assert.Equal(t, 2, size)
+	assert.Equal(t, models.Pagination{Limit: 2}, pagination)
+}
+
+func Test_pagination_page(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	o := bookmarkOrdering(models.SortNewest)
+
+	_, _, args, err := o.page(context.Background(), db, models.Filter{Cursor: encodeCursor(o.name, 1)}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1)}, args)
+
+	// a cursor pointing at an item that's gone can't tell where the page starts
+	_, _, _, err = o.page(context.Background(), db, models.Filter{Cursor: encodeCursor(o.name, 999)}, nil)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
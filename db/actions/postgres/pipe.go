@@ -10,6 +10,47 @@ import (
 	"time"
 )
 
+// pipeColumns is the list of columns selected whenever a pipe is listed along with
+// its bookmark count and creator. It expects the pipes table to be aliased as p, the bookmarks
+// as b and the users as u, grouped by p.id and u.username, and must be kept in sync with scanPipe
+const pipeColumns = `
+	p.id, p.name, COALESCE(NULLIF(p.display_name, ''), p.name), p.description, p.icon, p.color, p.visibility,
+	p.cover_photo, p.position, p.created_at, p.modified_at, p.user_id, p.parent_id, p.deleted_at,
+	p.type, p.query, p.forked_from, p.sync_upstream, COUNT(b.pipe_id) AS total_bookmarks, (
+	    WITH RECURSIVE subtree AS (
+	        SELECT p.id
+	        UNION
+	        SELECT c.id FROM pipes c INNER JOIN subtree s ON c.parent_id=s.id WHERE c.deleted_at IS NULL
+	    )
+	    SELECT COUNT(*) FROM bookmarks sb WHERE sb.pipe_id IN (SELECT id FROM subtree) AND sb.deleted_at IS NULL
+	) AS tree_bookmarks, u.username`
+
+// receivedPipes selects the ids of the pipes shared with the user in $1 that they have accepted,
+// along with every pipe nested in them
+const receivedPipes = `
+	WITH RECURSIVE received AS (
+	    SELECT spr.shared_pipe_id AS id FROM shared_pipe_receivers spr
+	    WHERE spr.receiver_id=$1 AND spr.is_accepted=true
+	    UNION
+	    SELECT c.id FROM pipes c INNER JOIN received r ON c.parent_id=r.id WHERE c.deleted_at IS NULL
+	)
+	SELECT id FROM received`
+
+// pipeSubtree selects the id of the pipe in $1 and of every pipe nested in it
+const pipeSubtree = `
+	WITH RECURSIVE subtree AS (
+	    SELECT id FROM pipes WHERE id=$1
+	    UNION
+	    SELECT c.id FROM pipes c INNER JOIN subtree s ON c.parent_id=s.id
+	)
+	SELECT id FROM subtree`
+
+// pipeJoins joins the bookmarks that are not in the trash and the creator of a pipe
+const pipeJoins = `
+	FROM pipes p
+		LEFT JOIN bookmarks b ON p.id=b.pipe_id AND b.deleted_at IS NULL
+		LEFT JOIN users u ON p.user_id=u.id`
+
 type pipeActions struct {
 	Db     *sql.DB
 	Logger zerolog.Logger
@@ -22,10 +63,61 @@ func NewPipeActions(db *sql.DB, logger zerolog.Logger) repository.PipeRepository
 	}
 }
 
+// scanPipe reads a row selected with pipeColumns into a pipe
+func scanPipe(row rowScanner) (models.Pipe, error) {
+	var pipe models.Pipe
+	var query []byte
+	err := row.Scan(
+		&pipe.ID,
+		&pipe.Name,
+		&pipe.DisplayName,
+		&pipe.Description,
+		&pipe.Icon,
+		&pipe.Color,
+		&pipe.Visibility,
+		&pipe.CoverPhoto,
+		&pipe.Position,
+		&pipe.CreatedAt,
+		&pipe.ModifiedAt,
+		&pipe.UserID,
+		&pipe.ParentID,
+		&pipe.DeletedAt,
+		&pipe.Type,
+		&query,
+		&pipe.ForkedFrom,
+		&pipe.SyncUpstream,
+		&pipe.Bookmarks,
+		&pipe.TreeBookmarks,
+		&pipe.Creator,
+	)
+	if err != nil {
+		return pipe, err
+	}
+	pipe.Query, err = parseSmartQuery(query)
+	return pipe, err
+}
+
+// pipeOrdering returns the ordering of a list of pipes for a sort option
+func pipeOrdering(sort string) ordering {
+	o := ordering{name: "pipes-" + sort, table: "pipes p"}
+	switch sort {
+	case models.SortNewest:
+		o.keys = []sortKey{{expr: "p.created_at", desc: true}, {expr: "p.id", desc: true}}
+	case models.SortOldest:
+		o.keys = []sortKey{{expr: "p.created_at"}, {expr: "p.id"}}
+	case models.SortTitle:
+		o.keys = []sortKey{{expr: "lower(p.name)"}, {expr: "p.id"}}
+	default:
+		o.name = "pipes-" + models.SortManual
+		o.keys = []sortKey{{expr: "p.position"}, {expr: "p.id"}}
+	}
+	return o
+}
+
 // PipeAlreadyExists checks if a pipe exits in a user's collection
 func (p pipeActions) PipeAlreadyExists(pipeName string, userId int64) (bool, error) {
 	var pipe models.Pipe
-	query := `SELECT id, name FROM pipes WHERE name=$1 AND user_id=$2 LIMIT 1`
+	query := `SELECT id, name FROM pipes WHERE name=$1 AND user_id=$2 AND deleted_at IS NULL LIMIT 1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -41,25 +133,63 @@ func (p pipeActions) PipeAlreadyExists(pipeName string, userId int64) (bool, err
 	return true, nil
 }
 
-// CreatePipe creates a new pipe
+// CreatePipe creates a new pipe at the end of the user's collection, nested in pipe.ParentID when it is set.
+// A pipe without a type is a standard pipe, a pipe without a display name is displayed by its name
+// and a pipe without a visibility is private
 func (p pipeActions) CreatePipe(pipe models.Pipe) (models.Pipe, error) {
 	var newPipe models.Pipe
 	query := `
 	INSERT INTO pipes 
-	    (user_id, name, cover_photo) 
-	VALUES($1, $2, $3) 
-	RETURNING id, name, cover_photo, user_id
+	    (user_id, name, cover_photo, position, parent_id, type, query, display_name, description, icon, color, visibility) 
+	VALUES($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), $2), $9, $10, $11, COALESCE(NULLIF($12, ''), 'private')) 
+	RETURNING id, name, cover_photo, position, user_id, parent_id, type, display_name, description, icon, color, visibility
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, pipe.UserID, pipe.Name, pipe.CoverPhoto).Scan(
+	position, err := pipePositionScope(pipe.UserID).nextPosition(ctx, p.Db)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+	if pipe.Type == "" {
+		pipe.Type = models.PipeTypeStandard
+	}
+	smartQuery, err := smartQueryValue(pipe.Query)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+
+	err = p.Db.QueryRowContext(
+		ctx,
+		query,
+		pipe.UserID,
+		pipe.Name,
+		pipe.CoverPhoto,
+		position,
+		pipe.ParentID,
+		pipe.Type,
+		smartQuery,
+		pipe.DisplayName,
+		pipe.Description,
+		pipe.Icon,
+		pipe.Color,
+		pipe.Visibility,
+	).Scan(
 		&newPipe.ID,
 		&newPipe.Name,
 		&newPipe.CoverPhoto,
+		&newPipe.Position,
 		&newPipe.UserID,
+		&newPipe.ParentID,
+		&newPipe.Type,
+		&newPipe.DisplayName,
+		&newPipe.Description,
+		&newPipe.Icon,
+		&newPipe.Color,
+		&newPipe.Visibility,
 	)
+	newPipe.Query = pipe.Query
 
 	if err != nil {
 		if dbErr, ok := err.(*pq.Error); ok {
@@ -75,13 +205,9 @@ func (p pipeActions) CreatePipe(pipe models.Pipe) (models.Pipe, error) {
 
 // GetPipe gets a pipe by the pipeId and userId
 func (p pipeActions) GetPipe(pipeID, userID int64) (models.Pipe, error) {
-	var pipe models.Pipe
 	query := `
-	SELECT p.id, p.name, p.cover_photo, p.created_at, p.modified_at, p.user_id, COUNT(b.pipe_id) AS total_bookmarks, u.username
-	FROM pipes p
-		LEFT JOIN bookmarks b ON p.id=b.pipe_id
-		LEFT JOIN users u ON p.user_id=u.id
-	WHERE p.user_id=$1 AND p.id = $2
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.user_id=$1 AND p.id = $2 AND p.deleted_at IS NULL
 	GROUP BY p.id, u.username
 	ORDER BY p.id
 	LIMIT 1
@@ -90,36 +216,26 @@ func (p pipeActions) GetPipe(pipeID, userID int64) (models.Pipe, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, userID, pipeID).Scan(
-		&pipe.ID,
-		&pipe.Name,
-		&pipe.CoverPhoto,
-		&pipe.CreatedAt,
-		&pipe.ModifiedAt,
-		&pipe.UserID,
-		&pipe.Bookmarks,
-		&pipe.Creator,
-	)
-
+	pipe, err := scanPipe(p.Db.QueryRowContext(ctx, query, userID, pipeID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Pipe{}, ErrNoRecord
 		}
 		return models.Pipe{}, err
 	}
-	return pipe, nil
+	pipes := []models.Pipe{pipe}
+	if err := countSmartBookmarks(ctx, p.Db, pipes); err != nil {
+		return models.Pipe{}, err
+	}
+	return pipes[0], nil
 
 }
 
 // GetPipeByName gets a pipe by name and a designated userId
 func (p pipeActions) GetPipeByName(pipeName string, userID int64) (models.Pipe, error) {
-	var pipe models.Pipe
 	query := `
-	SELECT p.id, p.name, p.cover_photo, p.created_at, p.modified_at, p.user_id, COUNT(b.pipe_id) AS total_bookmarks, u.username
-	FROM pipes p
-		LEFT JOIN bookmarks b ON p.id=b.pipe_id
-		LEFT JOIN users u ON p.user_id=u.id
-	WHERE p.name=$1 AND p.user_id = $2
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.name=$1 AND p.user_id = $2 AND p.deleted_at IS NULL
 	GROUP BY p.id, u.username
 	ORDER BY p.id
 	LIMIT 1
@@ -128,17 +244,7 @@ func (p pipeActions) GetPipeByName(pipeName string, userID int64) (models.Pipe,
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, pipeName, userID).Scan(
-		&pipe.ID,
-		&pipe.Name,
-		&pipe.CoverPhoto,
-		&pipe.CreatedAt,
-		&pipe.ModifiedAt,
-		&pipe.UserID,
-		&pipe.Bookmarks,
-		&pipe.Creator,
-	)
-
+	pipe, err := scanPipe(p.Db.QueryRowContext(ctx, query, pipeName, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Pipe{}, ErrNoRecord
@@ -152,11 +258,8 @@ func (p pipeActions) GetPipeByName(pipeName string, userID int64) (models.Pipe,
 func (p pipeActions) GetPipeAndResource(pipeID, userID int64) (models.PipeAndResource, error) {
 	var pipeAndR models.PipeAndResource
 	query := `
-	SELECT p.id, p.name, p.cover_photo, p.created_at, p.modified_at, p.user_id , COUNT(b.pipe_id) AS total_bookmarks, u.username
-	FROM pipes p 
-	    LEFT JOIN bookmarks b ON p.id=b.pipe_id
-		LEFT JOIN users u ON p.user_id=u.id
-	WHERE p.id=$1 AND p.user_id=$2
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.id=$1 AND p.user_id=$2 AND p.deleted_at IS NULL
 	GROUP BY p.id, u.username
 	LIMIT 1
 	`
@@ -164,66 +267,159 @@ func (p pipeActions) GetPipeAndResource(pipeID, userID int64) (models.PipeAndRes
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, pipeID, userID).Scan(
-		&pipeAndR.Pipe.ID,
-		&pipeAndR.Pipe.Name,
-		&pipeAndR.Pipe.CoverPhoto,
-		&pipeAndR.Pipe.CreatedAt,
-		&pipeAndR.Pipe.ModifiedAt,
-		&pipeAndR.Pipe.UserID,
-		&pipeAndR.Pipe.Bookmarks,
-		&pipeAndR.Pipe.Creator,
-	)
+	var err error
+	pipeAndR.Pipe, err = scanPipe(p.Db.QueryRowContext(ctx, query, pipeID, userID))
 	if err != nil {
 		return models.PipeAndResource{}, nil
 	}
-	// get bookmarks
+	pipeAndR.Children, err = p.getDescendants(ctx, []int64{pipeID}, pipeOrdering(models.SortManual), true)
+	if err != nil {
+		return models.PipeAndResource{}, err
+	}
+	pipes := append([]models.Pipe{pipeAndR.Pipe}, pipeAndR.Children...)
+	if err := countSmartBookmarks(ctx, p.Db, pipes); err != nil {
+		return models.PipeAndResource{}, err
+	}
+	pipeAndR.Pipe, pipeAndR.Children = pipes[0], pipes[1:]
+	// get bookmarks, a page at a time until there are none left
 	bActions := NewBookmarkActions(p.Db, p.Logger)
-	pipeAndR.Bookmarks, err = bActions.GetBookmarks(userID, pipeID)
+	// the states of the bookmarks are the owner's own, so archived bookmarks are part of the pipe as well
+	filter := models.BookmarkFilter{Filter: models.Filter{Limit: models.MaxPageLimit}, State: models.BookmarkStateAll}
+	for {
+		bookmarks, pagination, err := bActions.GetBookmarks(userID, pipeID, filter)
+		if err != nil {
+			return models.PipeAndResource{}, err
+		}
+		pipeAndR.Bookmarks = append(pipeAndR.Bookmarks, bookmarks...)
+		if !pagination.HasMore {
+			return pipeAndR, nil
+		}
+		filter.Cursor = pagination.NextCursor
+	}
+}
+
+// GetPipes gets a page of the pipes that belongs to a user
+func (p pipeActions) GetPipes(userID int64, filter models.PipeFilter) ([]models.Pipe, models.Pagination, error) {
+	o := pipeOrdering(filter.Sort)
+	if filter.Sort == "" || filter.Sort == models.SortManual {
+		// positions only order the pipes of a single owner, so
+		// pipes shared with the user come after their own
+		o.keys = append([]sortKey{{expr: "p.user_id<>$1"}}, o.keys...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := o.page(ctx, p.Db, filter.Filter, []interface{}{userID})
 	if err != nil {
-		return models.PipeAndResource{}, nil
+		return nil, models.Pagination{}, err
+	}
+	treeCondition := "true"
+	if filter.Tree {
+		// pipes nested in a pipe the user can't see are listed at the top level
+		treeCondition = `(
+			p.parent_id IS NULL OR (
+				p.parent_id NOT IN (SELECT id FROM pipes WHERE user_id=$1 AND deleted_at IS NULL)
+				AND p.parent_id NOT IN (` + receivedPipes + `)
+			)
+		)`
 	}
-	return pipeAndR, nil
+	query := `
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.deleted_at IS NULL AND (
+		p.user_id=$1 OR p.id IN (` + receivedPipes + `)
+	) AND ` + treeCondition + ` AND ` + pageCondition + `
+	GROUP BY p.id, u.username
+	` + pageClauses
+
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pipes, pagination, err := collectPipePage(rows, o, filter.Filter)
+	if err != nil {
+		return pipes, pagination, err
+	}
+	if !filter.Tree {
+		return pipes, pagination, countSmartBookmarks(ctx, p.Db, pipes)
+	}
+
+	ids := make([]int64, len(pipes))
+	for i, pipe := range pipes {
+		ids[i] = pipe.ID
+	}
+	descendants, err := p.getDescendants(ctx, ids, pipeOrdering(filter.Sort), false)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pipes = nestPipes(pipes, descendants)
+	return pipes, pagination, countSmartBookmarks(ctx, p.Db, pipes)
 }
 
-// GetPipes gets all pipes that belongs to a user
-func (p pipeActions) GetPipes(userID int64) ([]models.Pipe, error) {
-	var pipes []models.Pipe
+// getDescendants retrieves the pipes nested in the given pipes, sorted with o. When directOnly is set,
+// only the pipes nested right in them are retrieved, otherwise the pipes at every level below them are
+func (p pipeActions) getDescendants(ctx context.Context, pipeIDs []int64, o ordering, directOnly bool) ([]models.Pipe, error) {
+	nested := `UNION SELECT c.id FROM pipes c INNER JOIN descendants d ON c.parent_id=d.id WHERE c.deleted_at IS NULL`
+	if directOnly {
+		nested = ""
+	}
 	query := `
-	SELECT p.id, p.name, p.cover_photo, p.created_at, p.modified_at, p.user_id, COUNT(b.pipe_id) AS total_bookmarks, u.username
-	FROM pipes p
-		LEFT JOIN bookmarks b ON p.id=b.pipe_id
-		LEFT JOIN users u ON p.user_id=u.id
-	WHERE p.user_id=$1 OR p.id  IN (
-			SELECT spr.shared_pipe_id FROM shared_pipe_receivers spr WHERE receiver_id=$1 AND is_accepted=true
-		)
+	WITH RECURSIVE descendants AS (
+	    SELECT id FROM pipes WHERE parent_id = ANY($1) AND deleted_at IS NULL
+	    ` + nested + `
+	)
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.id IN (SELECT id FROM descendants)
 	GROUP BY p.id, u.username
-	ORDER BY p.id;
-	`
+	ORDER BY ` + o.orderBy()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	rows, err := p.Db.QueryContext(ctx, query, pq.Array(pipeIDs))
+	if err != nil {
+		return nil, err
+	}
+	return collectPipes(rows)
+}
+
+// nestPipes places every descendant under its parent, starting from the given top level pipes.
+// The order of the pipes at each level is kept
+func nestPipes(pipes, descendants []models.Pipe) []models.Pipe {
+	children := make(map[int64][]models.Pipe)
+	for _, descendant := range descendants {
+		if descendant.ParentID != nil {
+			children[*descendant.ParentID] = append(children[*descendant.ParentID], descendant)
+		}
+	}
+
+	var nest func(pipes []models.Pipe) []models.Pipe
+	nest = func(pipes []models.Pipe) []models.Pipe {
+		for i := range pipes {
+			pipes[i].Children = nest(children[pipes[i].ID])
+		}
+		return pipes
+	}
+	return nest(pipes)
+}
 
-	rows, err := p.Db.QueryContext(ctx, query, userID)
+// collectPipePage collects the pipes fetched for a page of a list sorted with o
+func collectPipePage(rows *sql.Rows, o ordering, filter models.Filter) ([]models.Pipe, models.Pagination, error) {
+	pipes, err := collectPipes(rows)
 	if err != nil {
-		return pipes, err
+		return pipes, models.Pagination{}, err
 	}
+	pagination, size := o.pagination(filter, len(pipes), func(i int) int64 { return pipes[i].ID })
+	return pipes[:size], pagination, nil
+}
+
+// collectPipes scans every row selected with pipeColumns
+func collectPipes(rows *sql.Rows) ([]models.Pipe, error) {
+	var pipes []models.Pipe
 	defer rows.Close()
+
 	for rows.Next() {
-		var pipe models.Pipe
-		if err := rows.Scan(
-			&pipe.ID,
-			&pipe.Name,
-			&pipe.CoverPhoto,
-			&pipe.CreatedAt,
-			&pipe.ModifiedAt,
-			&pipe.UserID,
-			&pipe.Bookmarks,
-			&pipe.Creator,
-		); err != nil {
+		pipe, err := scanPipe(rows)
+		if err != nil {
 			return pipes, err
 		}
-
 		pipes = append(pipes, pipe)
 	}
 
@@ -233,10 +429,10 @@ func (p pipeActions) GetPipes(userID int64) ([]models.Pipe, error) {
 	return pipes, nil
 }
 
-// GetPipesCount gets the total number of pipes owned by a particular user
+// GetPipesCount gets the total number of pipes owned by a particular user, excluding the ones in the trash
 func (p pipeActions) GetPipesCount(userID int64) (int, error) {
 	var pipesCount int
-	query := "SELECT COUNT(id) FROM pipes WHERE user_id=$1"
+	query := "SELECT COUNT(id) FROM pipes WHERE user_id=$1 AND deleted_at IS NULL"
 	err := p.Db.QueryRow(query, userID).Scan(&pipesCount)
 	if err != nil {
 		return pipesCount, err
@@ -245,36 +441,81 @@ func (p pipeActions) GetPipesCount(userID int64) (int, error) {
 	return pipesCount, nil
 }
 
-// UpdatePipe updates a specific pipe
-func (p pipeActions) UpdatePipe(userID int64, pipeID int64, updatedBody models.Pipe) (models.Pipe, error) {
+// UpdatePipe updates a specific pipe. The query of a pipe is only kept for smart pipes. actorID is
+// the owner of the pipe or the collaborator editing it for them
+func (p pipeActions) UpdatePipe(userID, actorID, pipeID int64, updatedBody models.Pipe) (models.Pipe, error) {
 	var pipe models.Pipe
 	query := `
 	UPDATE pipes 
 	SET 
 	    name=$3, 
 		cover_photo=$4,
+		query=CASE WHEN type='smart' THEN $5::jsonb ELSE NULL END,
+		display_name=COALESCE(NULLIF($6, ''), $3),
+		description=$7,
+		icon=$8,
+		color=$9,
+		visibility=COALESCE(NULLIF($10, ''), visibility),
+		sync_upstream=$11 AND forked_from IS NOT NULL,
 		modified_at=now()
-	WHERE id=$1 AND user_id=$2
-	RETURNING id, user_id, name, cover_photo, created_at, modified_at`
+	WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+	RETURNING
+	    id, user_id, name, display_name, description, icon, color, visibility, cover_photo, type,
+	    forked_from, sync_upstream, created_at, modified_at`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(
-		ctx,
-		query,
-		pipeID,
-		userID,
-		updatedBody.Name,
-		updatedBody.CoverPhoto,
-	).Scan(
-		&pipe.ID,
-		&pipe.UserID,
-		&pipe.Name,
-		&pipe.CoverPhoto,
-		&pipe.CreatedAt,
-		&pipe.ModifiedAt,
-	)
+	smartQuery, err := smartQueryValue(updatedBody.Query)
+	if err != nil {
+		return pipe, err
+	}
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return pipe, err
+	}
+	defer tx.Rollback()
+
+	history := newActorOperationLog(userID, actorID, models.HistoryActionEdit)
+	err = history.trackPipes(ctx, tx, "p.id=$1", []interface{}{pipeID}, func() error {
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			pipeID,
+			userID,
+			updatedBody.Name,
+			updatedBody.CoverPhoto,
+			smartQuery,
+			updatedBody.DisplayName,
+			updatedBody.Description,
+			updatedBody.Icon,
+			updatedBody.Color,
+			updatedBody.Visibility,
+			updatedBody.SyncUpstream,
+		).Scan(
+			&pipe.ID,
+			&pipe.UserID,
+			&pipe.Name,
+			&pipe.DisplayName,
+			&pipe.Description,
+			&pipe.Icon,
+			&pipe.Color,
+			&pipe.Visibility,
+			&pipe.CoverPhoto,
+			&pipe.Type,
+			&pipe.ForkedFrom,
+			&pipe.SyncUpstream,
+			&pipe.CreatedAt,
+			&pipe.ModifiedAt,
+		)
+	})
+	if err == nil {
+		err = history.save(ctx, tx)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -288,25 +529,131 @@ func (p pipeActions) UpdatePipe(userID int64, pipeID int64, updatedBody models.P
 
 		return pipe, err
 	}
+	if pipe.Type == models.PipeTypeSmart {
+		pipe.Query = updatedBody.Query
+	}
 
 	return pipe, nil
 
 }
 
-// DeletePipe removes a single pipe that belongs to a particular user and pipeID
-func (p pipeActions) DeletePipe(userID, pipeID int64) (bool, error) {
-	deleteQuery := `DELETE FROM pipes WHERE id=$1 AND user_id=$2`
+// MovePipes changes the manual order of the pipes owned by a user. The moves are applied one after
+// the other, so a move may refer to a pipe placed by an earlier one
+func (p pipeActions) MovePipes(userID int64, moves []models.PositionMove) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scope := pipePositionScope(userID)
+	history := newOperationLog(userID, models.HistoryActionReorder)
+	err = history.trackPipes(ctx, tx, scope.condition, []interface{}{scope.arg}, func() error {
+		return applyMoves(ctx, tx, scope, moves)
+	})
+	if err != nil {
+		return err
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+// NestPipe moves a pipe, along with every pipe nested in it, into another pipe of the same user.
+// A nil parentID moves the pipe to the top level. A pipe can't be moved into itself or into
+// one of the pipes nested in it
+func (p pipeActions) NestPipe(userID, pipeID int64, parentID *int64) (models.Pipe, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	_, err := p.Db.ExecContext(ctx, deleteQuery, pipeID, userID)
+	tx, err := p.Db.BeginTx(ctx, nil)
 	if err != nil {
-		p.Logger.Err(err).Msg("An error occurred while updating pipe")
-		if err == sql.ErrNoRows {
-			return false, ErrNoRecord
+		return models.Pipe{}, err
+	}
+	defer tx.Rollback()
+
+	// lock the pipes of the user so that two moves can't build a cycle together
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM pipes WHERE user_id=$1 AND deleted_at IS NULL FOR UPDATE`, userID); err != nil {
+		return models.Pipe{}, err
+	}
+
+	if parentID != nil {
+		var isCycle bool
+		query := `
+		SELECT EXISTS (` + pipeSubtree + ` WHERE id=$2)
+		FROM pipes
+		WHERE id=$2 AND user_id=$3 AND deleted_at IS NULL
+		`
+		if err := tx.QueryRowContext(ctx, query, pipeID, *parentID, userID).Scan(&isCycle); err != nil {
+			if err == sql.ErrNoRows {
+				return models.Pipe{}, ErrNoRecord
+			}
+			return models.Pipe{}, err
 		}
+		if isCycle {
+			return models.Pipe{}, ErrPipeCycle
+		}
+	}
+
+	history := newOperationLog(userID, models.HistoryActionMove)
+	err = history.trackPipes(ctx, tx, "p.id=$1", []interface{}{pipeID}, func() error {
+		query := `UPDATE pipes SET parent_id=$3, modified_at=now() WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL`
+		res, err := tx.ExecContext(ctx, query, pipeID, userID, parentID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return ErrNoRecord
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Pipe{}, err
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return models.Pipe{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Pipe{}, err
+	}
+	return p.GetPipe(pipeID, userID)
+}
+
+// DeletePipe moves a pipe that belongs to a particular user and pipeID to the trash along with all
+// of its bookmarks and the pipes nested in it. Everything is stamped with the same deletion time as the
+// pipe so that it can be told apart from what was already in the trash when the pipe is restored
+func (p pipeActions) DeletePipe(userID, pipeID int64) (bool, error) {
+	deleteQuery := `
+	WITH RECURSIVE subtree AS (
+	    SELECT id FROM pipes WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+	    UNION
+	    SELECT c.id FROM pipes c INNER JOIN subtree s ON c.parent_id=s.id WHERE c.deleted_at IS NULL
+	), trashed AS (
+	    UPDATE pipes SET deleted_at=now() WHERE id IN (SELECT id FROM subtree)
+	    RETURNING id, deleted_at
+	), trashed_bookmarks AS (
+	    UPDATE bookmarks b SET deleted_at=trashed.deleted_at
+	    FROM trashed
+	    WHERE b.pipe_id=trashed.id AND b.deleted_at IS NULL
+	)
+	SELECT COUNT(*) FROM trashed WHERE id=$1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var trashed int
+	err := p.Db.QueryRowContext(ctx, deleteQuery, pipeID, userID).Scan(&trashed)
+	if err != nil {
+		p.Logger.Err(err).Msg("An error occurred while deleting pipe")
 		return false, err
 	}
+	if trashed == 0 {
+		return false, ErrNoRecord
+	}
 	return true, nil
 }
@@ -0,0 +1,39 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var forkPipeTestCases = map[string]struct {
+	inputUserId   int64
+	inputPipeId   int64
+	inputFork     models.PipeFork
+	wantBookmarks int
+	wantErr       error
+}{
+	"fork of an owned pipe": {
+		inputUserId:   1,
+		inputPipeId:   1,
+		inputFork:     models.PipeFork{Name: "shorts", DisplayName: "Shorts"},
+		wantBookmarks: 1,
+	},
+	"fork without a new name of an owned pipe": {
+		inputUserId: 1,
+		inputPipeId: 1,
+		wantErr:     ErrRecordExists,
+	},
+	"fork of a pipe received through a fork link": {
+		inputUserId:   2,
+		inputPipeId:   2,
+		inputFork:     models.PipeFork{Name: "received", DisplayName: "Received"},
+		wantBookmarks: 1,
+	},
+	"fork of a pipe that is not shared with the user": {
+		inputUserId: 1,
+		inputPipeId: 3,
+		wantErr:     ErrNoRecord,
+	},
+	"fork of a pipe that does not exist": {
+		inputUserId: 1,
+		inputPipeId: 100,
+		wantErr:     ErrNoRecord,
+	},
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var canonicalURLTestCases = map[string]struct {
+	inputUrl string
+	wantUrl  string
+}{
+	"short youtube link": {
+		inputUrl: "https://youtu.be/Acgk_Jl95es",
+		wantUrl:  "youtube.com/watch?v=Acgk_Jl95es",
+	},
+	"youtube link with tracking": {
+		inputUrl: "http://www.youtube.com/watch?v=Acgk_Jl95es&utm_source=twitter&feature=share#t=10",
+		wantUrl:  "youtube.com/watch?v=Acgk_Jl95es",
+	},
+	"mobile link with a trailing slash": {
+		inputUrl: "https://m.Example.com/posts/1/",
+		wantUrl:  "example.com/posts/1",
+	},
+	"parameters are sorted": {
+		inputUrl: "https://example.com/search?q=go&page=2",
+		wantUrl:  "example.com/search?page=2&q=go",
+	},
+	"not a link": {
+		inputUrl: " not a link ",
+		wantUrl:  "not a link",
+	},
+}
+
+var mergePipesTestCases = map[string]struct {
+	inputUserId   int64
+	inputTargetId int64
+	inputMerge    models.PipeMerge
+	wantBookmarks int
+	wantErr       error
+}{
+	"merge of owned pipes": {
+		inputUserId:   1,
+		inputTargetId: 1,
+		inputMerge:    models.PipeMerge{SourceIDs: []int64{2}},
+		wantBookmarks: 2,
+	},
+	"merge of a pipe of another user": {
+		inputUserId:   1,
+		inputTargetId: 1,
+		inputMerge:    models.PipeMerge{SourceIDs: []int64{3}},
+		wantErr:       ErrNoRecord,
+	},
+	"merge into a pipe of another user": {
+		inputUserId:   1,
+		inputTargetId: 3,
+		inputMerge:    models.PipeMerge{SourceIDs: []int64{2}},
+		wantErr:       ErrNoRecord,
+	},
+	"cover from a pipe that is not merged": {
+		inputUserId:   1,
+		inputTargetId: 1,
+		inputMerge:    models.PipeMerge{SourceIDs: []int64{2}, CoverFromID: 3},
+		wantErr:       ErrNoRecord,
+	},
+}
+
+var splitPipeTestCases = map[string]struct {
+	inputUserId int64
+	inputPipeId int64
+	inputSplit  models.PipeSplit
+	wantPipes   []string
+	wantErr     error
+}{
+	"split by every tag": {
+		inputUserId: 1,
+		inputPipeId: 1,
+		inputSplit:  models.PipeSplit{By: models.PipeSplitByTag},
+		wantPipes:   []string{"youtube shorts - beautiful asian muslim"},
+	},
+	"split by a tag": {
+		inputUserId: 1,
+		inputPipeId: 1,
+		inputSplit:  models.PipeSplit{By: models.PipeSplitByTag, Values: []string{"Quick Blows"}},
+		wantPipes:   []string{"youtube shorts - quick blows"},
+	},
+	"split by platform": {
+		inputUserId: 1,
+		inputPipeId: 2,
+		inputSplit:  models.PipeSplit{By: models.PipeSplitByPlatform},
+		wantPipes:   []string{"tiktok - tiktok"},
+	},
+	"split by a tag no bookmark has": {
+		inputUserId: 1,
+		inputPipeId: 1,
+		inputSplit:  models.PipeSplit{By: models.PipeSplitByTag, Values: []string{"golang"}},
+		wantPipes:   []string{},
+	},
+	"split of a pipe of another user": {
+		inputUserId: 1,
+		inputPipeId: 3,
+		inputSplit:  models.PipeSplit{By: models.PipeSplitByTag},
+		wantErr:     ErrNoRecord,
+	},
+}
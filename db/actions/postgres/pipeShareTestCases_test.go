@@ -3,11 +3,13 @@ package postgres
 import (
 	"fmt"
 	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
 )
 
 var createPipeShareRecordTestCases = map[string]struct {
 	inputShareData     models.SharedPipe
 	inputShareReceiver string
+	inputRole          string
 	wantData           models.SharedPipe
 	wantErr            error
 }{
@@ -19,6 +21,7 @@ var createPipeShareRecordTestCases = map[string]struct {
 			Code:     "jjfjji9993",
 		},
 		inputShareReceiver: "user2",
+		inputRole:          models.PipeRoleEditor,
 		wantData: models.SharedPipe{
 			SharerID: 1,
 			PipeID:   1,
@@ -160,3 +163,313 @@ var acceptPrivateShareTestCases = map[string]struct {
 		wantErr: nil,
 	},
 }
+
+var getPipeAccessTestCases = map[string]struct {
+	inputPipeId int64
+	inputUserId int64
+	wantAccess  models.PipeAccess
+	wantErr     error
+}{
+	"owner": {
+		inputPipeId: 1,
+		inputUserId: 1,
+		wantAccess:  models.PipeAccess{PipeID: 1, OwnerID: 1, Role: models.PipeRoleOwner},
+		wantErr:     nil,
+	},
+	"accepted share": {
+		inputPipeId: 2,
+		inputUserId: 2,
+		wantAccess:  models.PipeAccess{PipeID: 2, OwnerID: 1, Role: models.PipeRoleViewer},
+		wantErr:     nil,
+	},
+	"share not accepted yet": {
+		inputPipeId: 1,
+		inputUserId: 2,
+		wantAccess:  models.PipeAccess{},
+		wantErr:     ErrNoRecord,
+	},
+	"pipe not shared with user": {
+		inputPipeId: 1,
+		inputUserId: 3,
+		wantAccess:  models.PipeAccess{},
+		wantErr:     ErrNoRecord,
+	},
+}
+
+var updateReceiverRoleTestCases = map[string]struct {
+	inputPipeId     int64
+	inputReceiverId int64
+	inputRole       string
+	wantErr         error
+}{
+	"success": {
+		inputPipeId:     2,
+		inputReceiverId: 2,
+		inputRole:       models.PipeRoleEditor,
+		wantErr:         nil,
+	},
+	"pipe not shared with user": {
+		inputPipeId:     2,
+		inputReceiverId: 3,
+		inputRole:       models.PipeRoleEditor,
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var getPipeCollaboratorsTestCases = map[string]struct {
+	inputPipeId       int64
+	wantCollaborators []string
+	wantErr           error
+}{
+	"shared pipe": {
+		inputPipeId:       2,
+		wantCollaborators: []string{"user2"},
+		wantErr:           nil,
+	},
+	"pipe not shared": {
+		inputPipeId:       3,
+		wantCollaborators: nil,
+		wantErr:           nil,
+	},
+}
+
+var deletePipeReceiverTestCases = map[string]struct {
+	inputPipeId     int64
+	inputReceiverId int64
+	wantErr         error
+}{
+	"accepted share": {
+		inputPipeId:     2,
+		inputReceiverId: 2,
+		wantErr:         nil,
+	},
+	"private share not accepted yet": {
+		inputPipeId:     1,
+		inputReceiverId: 2,
+		wantErr:         nil,
+	},
+	"pipe not shared with user": {
+		inputPipeId:     2,
+		inputReceiverId: 3,
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var updateSharedPipeCodeTestCases = map[string]struct {
+	inputPipeId int64
+	inputCode   string
+	wantErr     error
+}{
+	"success": {
+		inputPipeId: 2,
+		inputCode:   "NewCode12345",
+		wantErr:     nil,
+	},
+	"pipe not shared publicly": {
+		inputPipeId: 1,
+		inputCode:   "NewCode12345",
+		wantErr:     ErrNoRecord,
+	},
+}
+
+var deleteSharedPipeTestCases = map[string]struct {
+	inputPipeId int64
+	wantErr     error
+}{
+	"success": {
+		inputPipeId: 2,
+		wantErr:     nil,
+	},
+	"pipe not shared publicly": {
+		inputPipeId: 1,
+		wantErr:     ErrNoRecord,
+	},
+}
+
+var oneRedemption = 1
+var tomorrow = time.Now().Add(24 * time.Hour)
+var yesterday = time.Now().Add(-24 * time.Hour)
+
+var updateShareLinkTestCases = map[string]struct {
+	inputShare models.SharedPipe
+	wantShare  models.SharedPipe
+	wantErr    error
+}{
+	"success": {
+		inputShare: models.SharedPipe{
+			ID:             2,
+			ExpiresAt:      &tomorrow,
+			MaxRedemptions: &oneRedemption,
+			PasswordHash:   "hash",
+			Permission:     models.SharePermissionReadOnly,
+		},
+		wantShare: models.SharedPipe{
+			ID:             2,
+			PipeID:         2,
+			Code:           "MG78k9lig68",
+			MaxRedemptions: &oneRedemption,
+			PasswordHash:   "hash",
+			Permission:     models.SharePermissionReadOnly,
+		},
+		wantErr: nil,
+	},
+	"limits removed": {
+		inputShare: models.SharedPipe{ID: 2},
+		wantShare: models.SharedPipe{
+			ID:         2,
+			PipeID:     2,
+			Code:       "MG78k9lig68",
+			Permission: models.SharePermissionFork,
+		},
+		wantErr: nil,
+	},
+	"invalid share id": {
+		inputShare: models.SharedPipe{ID: 100},
+		wantShare:  models.SharedPipe{},
+		wantErr:    ErrNoRecord,
+	},
+}
+
+var redeemSharedPipeTestCases = map[string]struct {
+	inputShare       models.SharedPipe
+	inputRedemptions int
+	wantErr          error
+}{
+	"unlimited": {
+		inputShare:       models.SharedPipe{ID: 2},
+		inputRedemptions: 3,
+		wantErr:          nil,
+	},
+	"within limit": {
+		inputShare:       models.SharedPipe{ID: 2, MaxRedemptions: &oneRedemption, ExpiresAt: &tomorrow},
+		inputRedemptions: 1,
+		wantErr:          nil,
+	},
+	"limit reached": {
+		inputShare:       models.SharedPipe{ID: 2, MaxRedemptions: &oneRedemption},
+		inputRedemptions: 2,
+		wantErr:          ErrNoRecord,
+	},
+	"expired": {
+		inputShare:       models.SharedPipe{ID: 2, ExpiresAt: &yesterday},
+		inputRedemptions: 1,
+		wantErr:          ErrNoRecord,
+	},
+}
+
+var getReceivedSharesTestCases = map[string]struct {
+	inputUserId int64
+	inputStatus string
+	wantIds     []int64
+}{
+	"every share": {
+		inputUserId: secondUserId,
+		inputStatus: "",
+		wantIds:     []int64{2, 1},
+	},
+	"pending shares": {
+		inputUserId: secondUserId,
+		inputStatus: models.ShareStatusPending,
+		wantIds:     []int64{2},
+	},
+	"accepted shares": {
+		inputUserId: secondUserId,
+		inputStatus: models.ShareStatusAccepted,
+		wantIds:     []int64{1},
+	},
+	"no shares": {
+		inputUserId: firstUserId,
+		inputStatus: "",
+		wantIds:     nil,
+	},
+}
+
+var getReceivedShareTestCases = map[string]struct {
+	inputId     int64
+	inputUserId int64
+	wantErr     error
+}{
+	"success": {
+		inputId:     2,
+		inputUserId: secondUserId,
+		wantErr:     nil,
+	},
+	"shared with another user": {
+		inputId:     2,
+		inputUserId: firstUserId,
+		wantErr:     ErrNoRecord,
+	},
+}
+
+var declinePrivateShareTestCases = map[string]struct {
+	inputReceiver models.SharedPipeReceiver
+	wantErr       error
+}{
+	"success": {
+		inputReceiver: models.SharedPipeReceiver{ID: 2, ReceiverID: 2},
+		wantErr:       nil,
+	},
+	"already accepted": {
+		inputReceiver: models.SharedPipeReceiver{ID: 1, ReceiverID: 2},
+		wantErr:       ErrNoRecord,
+	},
+}
+
+var renewPrivateShareTestCases = map[string]struct {
+	inputReceiver  models.SharedPipeReceiver
+	inputExpiresAt time.Time
+	wantErr        error
+}{
+	"success": {
+		inputReceiver:  models.SharedPipeReceiver{ID: 2, ReceiverID: 2},
+		inputExpiresAt: tomorrow,
+		wantErr:        nil,
+	},
+	"already accepted": {
+		inputReceiver:  models.SharedPipeReceiver{ID: 1, ReceiverID: 2},
+		inputExpiresAt: tomorrow,
+		wantErr:        ErrNoRecord,
+	},
+}
+
+var createShareInvitationTestCases = map[string]struct {
+	inputInvitation models.PipeShareInvitation
+	inputAttach     bool
+	wantErr         error
+}{
+	"success": {
+		inputInvitation: models.PipeShareInvitation{SharerID: 1, PipeID: 1, Email: "new@gmail.com", Code: "INV78k9lig01", ExpiresAt: tomorrow},
+		wantErr:         nil,
+	},
+	"already attached": {
+		inputInvitation: models.PipeShareInvitation{SharerID: 1, PipeID: 1, Email: "new@gmail.com", Code: "INV78k9lig01", ExpiresAt: tomorrow},
+		inputAttach:     true,
+		wantErr:         ErrRecordExists,
+	},
+}
+
+var attachShareInvitationsTestCases = map[string]struct {
+	inputInvitation models.PipeShareInvitation
+	inputUserId     int64
+	inputEmail      string
+	wantReceivers   int
+}{
+	"success": {
+		inputInvitation: models.PipeShareInvitation{SharerID: 1, PipeID: 1, Email: "New@gmail.com", Code: "INV78k9lig01", Role: models.PipeRoleEditor, ExpiresAt: tomorrow},
+		inputUserId:     4,
+		inputEmail:      "new@gmail.com",
+		wantReceivers:   1,
+	},
+	"expired invitation": {
+		inputInvitation: models.PipeShareInvitation{SharerID: 1, PipeID: 1, Email: "new@gmail.com", Code: "INV78k9lig01", ExpiresAt: yesterday},
+		inputUserId:     4,
+		inputEmail:      "new@gmail.com",
+		wantReceivers:   0,
+	},
+	"pipe already shared with the user": {
+		inputInvitation: models.PipeShareInvitation{SharerID: 1, PipeID: 2, Email: "new@gmail.com", Code: "INV78k9lig01", ExpiresAt: tomorrow},
+		inputUserId:     secondUserId,
+		inputEmail:      "new@gmail.com",
+		wantReceivers:   0,
+	},
+}
@@ -34,9 +34,32 @@ var createPipeTestCases = map[string]struct {
 			CoverPhoto: "https://images.unsplash.com/photo-1611162616305-c69b3fa7fbe0?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxzZWFyY2h8NHx8eW91dHViZSUyMGxvZ298ZW58MHx8MHx8&auto=format&fit=crop&w=500&q=60",
 		},
 		wantPipe: models.Pipe{
-			Name:       "Instagram",
-			UserID:     1,
-			CoverPhoto: "https://images.unsplash.com/photo-1611162616305-c69b3fa7fbe0?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxzZWFyY2h8NHx8eW91dHViZSUyMGxvZ298ZW58MHx8MHx8&auto=format&fit=crop&w=500&q=60",
+			Name:        "Instagram",
+			DisplayName: "Instagram",
+			UserID:      1,
+			CoverPhoto:  "https://images.unsplash.com/photo-1611162616305-c69b3fa7fbe0?ixlib=rb-4.0.3&ixid=MnwxMjA3fDB8MHxzZWFyY2h8NHx8eW91dHViZSUyMGxvZ298ZW58MHx8MHx8&auto=format&fit=crop&w=500&q=60",
+			Visibility:  models.PipeVisibilityPrivate,
+		},
+		wantErr: nil,
+	},
+	"with metadata": {
+		inputPipe: models.Pipe{
+			Name:        "golang talks",
+			DisplayName: "GoLang Talks",
+			UserID:      1,
+			Description: "Talks from **GopherCon**",
+			Icon:        "🐹",
+			Color:       "#00add8",
+			Visibility:  models.PipeVisibilityPublic,
+		},
+		wantPipe: models.Pipe{
+			Name:        "golang talks",
+			DisplayName: "GoLang Talks",
+			UserID:      1,
+			Description: "Talks from **GopherCon**",
+			Icon:        "🐹",
+			Color:       "#00add8",
+			Visibility:  models.PipeVisibilityPublic,
 		},
 		wantErr: nil,
 	},
@@ -117,8 +140,10 @@ var getPipeByNameTestCases = map[string]struct {
 var getPipeAndResourceTestCases = map[string]struct {
 	inputPipeId int64
 	inputUserId int64
-	wantResult  models.PipeAndResource
-	wantErr     error
+	// archivedBookmarkId is a bookmark the owner archives before the pipe is retrieved
+	archivedBookmarkId int64
+	wantResult         models.PipeAndResource
+	wantErr            error
 }{
 	"success": {
 		inputUserId: 1,
@@ -136,10 +161,28 @@ var getPipeAndResourceTestCases = map[string]struct {
 		},
 		wantErr: nil,
 	},
+	"archived bookmarks": {
+		inputUserId:        1,
+		inputPipeId:        1,
+		archivedBookmarkId: 1,
+		wantResult: models.PipeAndResource{
+			Pipe: models.Pipe{
+				Name:    "Youtube Shorts",
+				Creator: "user1",
+				UserID:  1,
+				ID:      1,
+			},
+			Bookmarks: []models.Bookmark{
+				{Url: "https://youtu.be/Acgk_Jl95es", Platform: "youtube", PipeID: 1, UserID: 1},
+			},
+		},
+		wantErr: nil,
+	},
 }
 
 var getPipesTestCases = map[string]struct {
 	inputUserId int64
+	inputFilter models.PipeFilter
 	wantPipes   []models.Pipe
 	wantErr     error
 }{
@@ -151,6 +194,25 @@ var getPipesTestCases = map[string]struct {
 		},
 		wantErr: nil,
 	},
+	"sorted by title": {
+		inputUserId: 1,
+		inputFilter: models.PipeFilter{Sort: models.SortTitle},
+		wantPipes: []models.Pipe{
+			{Name: "TikTok", ID: 2, UserID: 1, Creator: "user1"},
+			{Name: "Youtube Shorts", ID: 1, UserID: 1, Creator: "user1"},
+		},
+		wantErr: nil,
+	},
+	"shared pipes come after own pipes": {
+		inputUserId: 2,
+		inputFilter: models.PipeFilter{Sort: models.SortManual},
+		wantPipes: []models.Pipe{
+			{Name: "Youtube Shorts", ID: 3, UserID: 2, Creator: "user2"},
+			{Name: "TikTok", ID: 4, UserID: 2, Creator: "user2"},
+			{Name: "TikTok", ID: 2, UserID: 1, Creator: "user1"},
+		},
+		wantErr: nil,
+	},
 }
 
 var getPipesCountTestCases = map[string]struct {
@@ -222,4 +284,85 @@ var deletePipeTestCases = map[string]struct {
 		wantResponse: true,
 		wantErr:      nil,
 	},
+	"pipe belongs to another user": {
+		inputUserId:  2,
+		inputPipeId:  1,
+		wantResponse: false,
+		wantErr:      ErrNoRecord,
+	},
+}
+
+var movePipesTestCases = map[string]struct {
+	inputUserId  int64
+	inputMoves   []models.PositionMove
+	wantPipesIds []int64
+	wantErr      error
+}{
+	"move to the top": {
+		inputUserId:  1,
+		inputMoves:   []models.PositionMove{{ID: 2, AfterID: 0}},
+		wantPipesIds: []int64{2, 1},
+		wantErr:      nil,
+	},
+	"move after another pipe": {
+		inputUserId:  1,
+		inputMoves:   []models.PositionMove{{ID: 2, AfterID: 0}, {ID: 2, AfterID: 1}},
+		wantPipesIds: []int64{1, 2},
+		wantErr:      nil,
+	},
+	"pipe belongs to another user": {
+		inputUserId:  1,
+		inputMoves:   []models.PositionMove{{ID: 3, AfterID: 0}},
+		wantPipesIds: []int64{1, 2},
+		wantErr:      ErrNoRecord,
+	},
+}
+
+var firstPipeId int64 = 1
+
+var nestPipeTestCases = map[string]struct {
+	inputUserId      int64
+	inputPipeId      int64
+	inputParentId    *int64
+	wantRootIds      []int64
+	wantChildren     int
+	wantTreeBookmark int
+	wantErr          error
+}{
+	"nest in another pipe": {
+		inputUserId:      1,
+		inputPipeId:      2,
+		inputParentId:    &firstPipeId,
+		wantRootIds:      []int64{1},
+		wantChildren:     1,
+		wantTreeBookmark: 2,
+		wantErr:          nil,
+	},
+	"move to the top level": {
+		inputUserId:      1,
+		inputPipeId:      2,
+		inputParentId:    nil,
+		wantRootIds:      []int64{1, 2},
+		wantChildren:     0,
+		wantTreeBookmark: 1,
+		wantErr:          nil,
+	},
+	"nest in itself": {
+		inputUserId:      1,
+		inputPipeId:      1,
+		inputParentId:    &firstPipeId,
+		wantRootIds:      []int64{1, 2},
+		wantChildren:     0,
+		wantTreeBookmark: 1,
+		wantErr:          ErrPipeCycle,
+	},
+	"parent belongs to another user": {
+		inputUserId:      2,
+		inputPipeId:      3,
+		inputParentId:    &firstPipeId,
+		wantRootIds:      []int64{3, 4},
+		wantChildren:     0,
+		wantTreeBookmark: 1,
+		wantErr:          ErrNoRecord,
+	},
 }
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+// ForkPipe copies a pipe the user owns or has accepted from another user into a new pipe of the user,
+// along with its bookmarks, their tags and the metadata of the pipe. The bookmarks a smart pipe selects
+// are copied into a standard pipe. Pipes nested in the forked pipe are not copied. Pipes the user only
+// added through read-only share links can't be forked and return ErrForkNotAllowed
+func (p pipeActions) ForkPipe(userID, pipeID int64, fork models.PipeFork) (models.Pipe, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+	defer tx.Rollback()
+
+	source, err := getPipeSource(ctx, tx, userID, pipeID)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+	if source.ownerID != userID {
+		var canFork bool
+		forkQuery := `
+		WITH RECURSIVE ancestors AS (
+		    SELECT id, parent_id FROM pipes WHERE id=$2
+		    UNION
+		    SELECT a.id, a.parent_id FROM pipes a INNER JOIN ancestors an ON a.id=an.parent_id
+		)
+		SELECT EXISTS (
+		    SELECT 1 FROM shared_pipe_receivers spr
+		    WHERE spr.shared_pipe_id IN (SELECT id FROM ancestors)
+		        AND spr.receiver_id=$1 AND spr.is_accepted=true AND spr.permission=$3
+		)
+		`
+		if err := tx.QueryRowContext(ctx, forkQuery, userID, pipeID, models.SharePermissionFork).Scan(&canFork); err != nil {
+			return models.Pipe{}, err
+		}
+		if !canFork {
+			return models.Pipe{}, ErrForkNotAllowed
+		}
+	}
+	position, err := pipePositionScope(userID).nextPosition(ctx, tx)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+
+	var forkID int64
+	query := `
+	INSERT INTO pipes
+	    (user_id, name, display_name, description, icon, color, cover_photo, position, forked_from, sync_upstream)
+	SELECT
+	    $1, COALESCE(NULLIF($3, ''), p.name), CASE WHEN $3='' THEN p.display_name ELSE COALESCE(NULLIF($4, ''), $3) END,
+	    p.description, p.icon, p.color, p.cover_photo, $5, p.id, $6
+	FROM pipes p
+	WHERE p.id=$2
+	RETURNING id
+	`
+	err = tx.QueryRowContext(ctx, query, userID, pipeID, fork.Name, fork.DisplayName, position, fork.SyncUpstream).Scan(&forkID)
+	if err != nil {
+		if dbErr, ok := err.(*pq.Error); ok && dbErr.Code == "23505" {
+			return models.Pipe{}, ErrRecordExists
+		}
+		return models.Pipe{}, err
+	}
+
+	if _, err := copyUpstreamBookmarks(ctx, tx, source, pipeID, forkID, userID); err != nil {
+		return models.Pipe{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Pipe{}, err
+	}
+	return p.GetPipe(forkID, userID)
+}
+
+// SyncForks copies the bookmarks added to forked pipes since their forks were last synced into the forks that
+// keep in sync with them, going through at most limit forks, least recently synced first. Forks of pipes that
+// are in the trash or are no longer shared with the owner of the fork are skipped. A fork that fails to sync is
+// logged and tried again on a later run, after the others. It returns the number of bookmarks copied
+func (p pipeActions) SyncForks(limit int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	type fork struct {
+		id, userID, upstreamID int64
+	}
+	var forks []fork
+	query := `
+	SELECT id, user_id, forked_from
+	FROM pipes
+	WHERE sync_upstream=true AND forked_from IS NOT NULL AND deleted_at IS NULL
+	ORDER BY synced_at NULLS FIRST, id
+	LIMIT $1
+	`
+	rows, err := p.Db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f fork
+		if err := rows.Scan(&f.id, &f.userID, &f.upstreamID); err != nil {
+			return 0, err
+		}
+		forks = append(forks, f)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var copied int64
+	for _, f := range forks {
+		n, err := p.syncFork(ctx, f.id, f.userID, f.upstreamID)
+		if err != nil {
+			// a fork that can't be synced goes to the back of the line, so that it doesn't hold up the others
+			p.Logger.Err(err).Msg(fmt.Sprintf("could not sync fork %d", f.id))
+			if _, err := p.Db.ExecContext(ctx, `UPDATE pipes SET synced_at=now() WHERE id=$1`, f.id); err != nil {
+				return copied, err
+			}
+			continue
+		}
+		copied += n
+	}
+	return copied, nil
+}
+
+// syncFork copies the bookmarks of the upstream pipe that are missing from a fork into the fork
+func (p pipeActions) syncFork(ctx context.Context, forkID, userID, upstreamID int64) (int64, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var copied int64
+	source, err := getPipeSource(ctx, tx, userID, upstreamID)
+	switch err {
+	case nil:
+		if copied, err = copyUpstreamBookmarks(ctx, tx, source, upstreamID, forkID, userID); err != nil {
+			return 0, err
+		}
+	case ErrNoRecord:
+		// the upstream pipe is out of reach, but may come back from the trash or be shared again
+	default:
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE pipes SET synced_at=now() WHERE id=$1`, forkID); err != nil {
+		return 0, err
+	}
+	return copied, tx.Commit()
+}
+
+// copyUpstreamBookmarks copies the bookmarks of the upstream pipe that were never copied into the fork
+// to the end of the fork, along with their tags. A copy the owner of the fork has since deleted is not
+// copied again, and neither are the copies in the fork when a smart pipe of the same user is forked.
+// It returns the number of bookmarks copied
+func copyUpstreamBookmarks(ctx context.Context, tx *sql.Tx, source pipeSource, upstreamID, forkID, userID int64) (int64, error) {
+	// every bookmark of a standard pipe is copied, while a smart pipe keeps to the state of its query
+	state := models.BookmarkStateAll
+	if source.pipeType == models.PipeTypeSmart {
+		state = ""
+	}
+	condition, args := source.condition("$2", state, []interface{}{forkID, upstreamID})
+	query := `
+	SELECT b.id
+	FROM bookmarks b
+	WHERE b.deleted_at IS NULL AND ` + condition + `
+	    AND b.pipe_id<>$1
+	    AND NOT EXISTS (SELECT 1 FROM bookmarks fb WHERE fb.pipe_id=$1 AND fb.forked_from=b.id)
+	ORDER BY b.pipe_id, b.position, b.id
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	positions, err := bookmarkPositionScope(forkID).nextPositions(ctx, tx, len(ids))
+	if err != nil {
+		return 0, err
+	}
+	copyQuery := `
+	INSERT INTO bookmarks (user_id, pipe_id, platform, url, title, position, forked_from, added_by)
+	SELECT $1, $2, u.platform, u.url, u.title, ranked.position, u.id, $1
+	FROM unnest($3::bigint[], $4::text[]) AS ranked(id, position)
+	    INNER JOIN bookmarks u ON u.id=ranked.id
+	`
+	if _, err := tx.ExecContext(ctx, copyQuery, userID, forkID, pq.Array(ids), pq.Array(positions)); err != nil {
+		return 0, err
+	}
+	if err := copyUpstreamTags(ctx, tx, forkID, userID, ids); err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// copyUpstreamTags puts the tags of the upstream bookmarks with the given ids on their copies in the fork.
+// The copies get tags of the owner of the fork with the same names, which are created along with the tags
+// they are under when they don't have them
+func copyUpstreamTags(ctx context.Context, tx *sql.Tx, forkID, userID int64, ids []int64) error {
+	namesQuery := `
+	SELECT DISTINCT t.name
+	FROM bookmark_tag bt
+	    INNER JOIN tags t ON t.id=bt.tag_id
+	WHERE bt.bookmark_id = ANY($1)
+	`
+	rows, err := tx.QueryContext(ctx, namesQuery, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, models.NormalizeTagName(name))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := createTagPaths(ctx, tx, userID, names); err != nil {
+		return err
+	}
+
+	attachQuery := `
+	INSERT INTO bookmark_tag (bookmark_id, tag_id)
+	SELECT c.id, ft.id
+	FROM bookmarks c
+	    INNER JOIN bookmark_tag bt ON bt.bookmark_id=c.forked_from
+	    INNER JOIN tags t ON t.id=bt.tag_id
+	    INNER JOIN tags ft ON ft.user_id=$1 AND ` + tagKey("ft.name") + `=` + tagKey("t.name") + `
+	WHERE c.pipe_id=$2 AND c.forked_from = ANY($3)
+	ON CONFLICT (bookmark_id, tag_id) DO NOTHING
+	`
+	_, err = tx.ExecContext(ctx, attachQuery, userID, forkID, pq.Array(ids))
+	return err
+}
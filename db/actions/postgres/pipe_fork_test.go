@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_pipe_ForkPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := forkPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			gotPipe, gotErr := pa.ForkPipe(tc.inputUserId, tc.inputPipeId, tc.inputFork)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputUserId, gotPipe.UserID)
+				assert.Equal(t, tc.inputFork.DisplayName, gotPipe.DisplayName)
+				assert.Equal(t, &tc.inputPipeId, gotPipe.ForkedFrom)
+				assert.Equal(t, tc.wantBookmarks, gotPipe.Bookmarks)
+
+				// the tags of the forked bookmarks are copied along
+				gotBookmarks, _, err := NewBookmarkActions(db, logger).GetBookmarks(tc.inputUserId, gotPipe.ID, models.BookmarkFilter{})
+				assert.Nil(t, err)
+				for _, bookmark := range gotBookmarks {
+					assert.NotNil(t, bookmark.ForkedFrom)
+					upstream, err := NewBookmarkActions(db, logger).GetBookmark(*bookmark.ForkedFrom, tc.inputUserId)
+					assert.Nil(t, err)
+					assert.ElementsMatch(t, upstream.Tags, bookmark.Tags)
+				}
+			}
+		})
+	}
+}
+
+func Test_pipe_ForkPipe_readOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	// user 2 received the second pipe of user 1 through a read-only link
+	_, err := db.Exec("UPDATE shared_pipe_receivers SET permission=$1 WHERE shared_pipe_id=2 AND receiver_id=2", models.SharePermissionReadOnly)
+	assert.Nil(t, err)
+
+	_, err = pa.ForkPipe(2, 2, models.PipeFork{Name: "received"})
+	assert.Equal(t, ErrForkNotAllowed, err)
+
+	// the bookmarks of the pipe can still be read
+	gotBookmarks, _, err := NewBookmarkActions(db, logger).GetBookmarks(2, 2, models.BookmarkFilter{})
+	assert.Nil(t, err)
+	assert.Len(t, gotBookmarks, 1)
+}
+
+func Test_pipe_SyncForks(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	synced, err := pa.ForkPipe(1, 1, models.PipeFork{Name: "synced", SyncUpstream: true})
+	assert.Nil(t, err)
+	unsynced, err := pa.ForkPipe(1, 1, models.PipeFork{Name: "unsynced"})
+	assert.Nil(t, err)
+
+	_, err = ba.CreateBookmark(models.Bookmark{UserID: 1, PipeID: 1, Url: "https://youtu.be/7", Platform: "youtube"})
+	assert.Nil(t, err)
+
+	copied, err := pa.SyncForks(10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), copied)
+
+	// bookmarks that were already copied are not copied again
+	copied, err = pa.SyncForks(10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), copied)
+
+	gotPipe, err := pa.GetPipe(synced.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, gotPipe.Bookmarks)
+	gotPipe, err = pa.GetPipe(unsynced.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, gotPipe.Bookmarks)
+}
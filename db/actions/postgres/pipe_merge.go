@@ -0,0 +1,508 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPipeNameLength is the longest name a pipe can have
+const maxPipeNameLength = 50
+
+// trackingParams are the query parameters that only tell where a link was shared from
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"igshid":  true,
+	"si":      true,
+	"feature": true,
+	"ref":     true,
+}
+
+// canonicalURL returns the form of a link that two bookmarks of the same page have in common.
+// The scheme, the www and m subdomains, the fragment, a trailing slash and tracking parameters are
+// dropped, the remaining parameters are sorted and short youtu.be links are expanded
+func canonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	params := u.Query()
+	if host == "youtu.be" && path != "" {
+		params.Set("v", strings.TrimPrefix(path, "/"))
+		host, path = "youtube.com", "/watch"
+	}
+	for param := range params {
+		if trackingParams[strings.ToLower(param)] || strings.HasPrefix(strings.ToLower(param), "utm_") {
+			params.Del(param)
+		}
+	}
+
+	canonical := host + path
+	if len(params) > 0 {
+		canonical += "?" + params.Encode()
+	}
+	return canonical
+}
+
+// MergePipes moves the bookmarks of the source pipes to the end of the target pipe and moves the source pipes
+// to the trash. Bookmarks of the same page are kept once: the first one, in the target pipe and then in the order
+// of the sources, gets the tags of the others, which go to the trash with the source pipes. The pipes nested in
+// the source pipes are nested in the target pipe and the shares of the source pipes become shares of the target,
+// which keeps a single public link
+func (p pipeActions) MergePipes(userID, targetID int64, merge models.PipeMerge) (models.Pipe, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+	defer tx.Rollback()
+
+	// lock the pipes of the user so that no pipe is nested in a source pipe while it is merged
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM pipes WHERE user_id=$1 AND deleted_at IS NULL FOR UPDATE`, userID); err != nil {
+		return models.Pipe{}, err
+	}
+
+	pipeIDs := append([]int64{targetID}, merge.SourceIDs...)
+	covers, err := mergedPipeCovers(ctx, tx, userID, pipeIDs)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+
+	var isCycle bool
+	cycleQuery := `
+	WITH RECURSIVE subtree AS (
+	    SELECT id FROM pipes WHERE id = ANY($1)
+	    UNION
+	    SELECT c.id FROM pipes c INNER JOIN subtree s ON c.parent_id=s.id WHERE c.deleted_at IS NULL
+	)
+	SELECT EXISTS (SELECT 1 FROM subtree WHERE id=$2)
+	`
+	if err := tx.QueryRowContext(ctx, cycleQuery, pq.Array(merge.SourceIDs), targetID).Scan(&isCycle); err != nil {
+		return models.Pipe{}, err
+	}
+	if isCycle {
+		return models.Pipe{}, ErrPipeCycle
+	}
+
+	keepers, duplicates, err := mergedBookmarks(ctx, tx, targetID, pipeIDs)
+	if err != nil {
+		return models.Pipe{}, err
+	}
+
+	// the bookmarks and pipes nested in the merged pipes, the trashed duplicates and sources included, are tracked
+	// so that undoing the merge brings the source pipes back with their bookmarks
+	history := newOperationLog(userID, models.HistoryActionMerge)
+	args := []interface{}{pq.Array(pipeIDs)}
+	err = history.trackBookmarks(ctx, tx, "b.pipe_id = ANY($1)", args, func() error {
+		return history.trackPipes(ctx, tx, "p.id = ANY($1) OR p.parent_id = ANY($1)", args, func() error {
+			tagQuery := `
+			INSERT INTO bookmark_tag (bookmark_id, tag_id)
+			SELECT DISTINCT d.keeper_id, bt.tag_id
+			FROM unnest($1::bigint[], $2::bigint[]) AS d(keeper_id, id)
+			    INNER JOIN bookmark_tag bt ON bt.bookmark_id=d.id
+			WHERE NOT EXISTS (SELECT 1 FROM bookmark_tag kt WHERE kt.bookmark_id=d.keeper_id AND kt.tag_id=bt.tag_id)
+			`
+			duplicateIDs := make([]int64, 0, len(duplicates))
+			keeperIDs := make([]int64, 0, len(duplicates))
+			for id, keeperID := range duplicates {
+				duplicateIDs = append(duplicateIDs, id)
+				keeperIDs = append(keeperIDs, keeperID)
+			}
+			if _, err := tx.ExecContext(ctx, tagQuery, pq.Array(keeperIDs), pq.Array(duplicateIDs)); err != nil {
+				return err
+			}
+
+			positions, err := bookmarkPositionScope(targetID).nextPositions(ctx, tx, len(keepers))
+			if err != nil {
+				return err
+			}
+			moveQuery := `
+			UPDATE bookmarks b
+			SET pipe_id=$1, position=m.position
+			FROM unnest($2::bigint[], $3::text[]) AS m(id, position)
+			WHERE b.id=m.id
+			`
+			if _, err := tx.ExecContext(ctx, moveQuery, targetID, pq.Array(keepers), pq.Array(positions)); err != nil {
+				return err
+			}
+
+			cover := covers[targetID]
+			if merge.CoverFromID != 0 {
+				if _, ok := covers[merge.CoverFromID]; !ok {
+					return ErrNoRecord
+				}
+				cover = covers[merge.CoverFromID]
+			}
+			for _, id := range merge.SourceIDs {
+				if cover != "" {
+					break
+				}
+				cover = covers[id]
+			}
+
+			statements := []struct {
+				query string
+				args  []interface{}
+			}{
+				{`UPDATE pipes SET cover_photo=$2, modified_at=now() WHERE id=$1`, []interface{}{targetID, cover}},
+				{`UPDATE pipes SET parent_id=$1 WHERE parent_id = ANY($2) AND NOT id = ANY($2) AND deleted_at IS NULL`, []interface{}{targetID, pq.Array(merge.SourceIDs)}},
+				// the target keeps a single public link, its own if it has one, and the users who added a
+				// source pipe through another public link are moved over to the link that is kept
+				{`
+				WITH public_links AS (
+				    SELECT id, code, ROW_NUMBER() OVER (ORDER BY pipe_id<>$1, id) AS n
+				    FROM shared_pipes
+				    WHERE (pipe_id=$1 OR pipe_id = ANY($2)) AND type=$3
+				), dropped AS (
+				    DELETE FROM shared_pipes s USING public_links l WHERE s.id=l.id AND l.n>1
+				    RETURNING s.code
+				)
+				UPDATE shared_pipe_receivers
+				SET code=(SELECT code FROM public_links WHERE n=1), modified_at=now()
+				WHERE shared_pipe_id = ANY($2) AND code IN (SELECT code FROM dropped)
+				`, []interface{}{targetID, pq.Array(merge.SourceIDs), models.PipeShareTypePublic}},
+				{`UPDATE shared_pipes SET pipe_id=$1, modified_at=now() WHERE pipe_id = ANY($2)`, []interface{}{targetID, pq.Array(merge.SourceIDs)}},
+				{`UPDATE shared_pipe_receivers SET shared_pipe_id=$1, modified_at=now() WHERE shared_pipe_id = ANY($2)`, []interface{}{targetID, pq.Array(merge.SourceIDs)}},
+				// a user the target and a source pipe were both shared with keeps a single share, accepted if any was
+				{`
+				DELETE FROM shared_pipe_receivers r
+				USING shared_pipe_receivers o
+				WHERE r.shared_pipe_id=$1 AND o.shared_pipe_id=$1 AND r.receiver_id=o.receiver_id
+				    AND (COALESCE(o.is_accepted, false), o.id) > (COALESCE(r.is_accepted, false), r.id)
+				`, []interface{}{targetID}},
+				{`UPDATE bookmarks SET deleted_at=now() WHERE id = ANY($1)`, []interface{}{pq.Array(duplicateIDs)}},
+				{`UPDATE pipes SET deleted_at=now() WHERE id = ANY($1)`, []interface{}{pq.Array(merge.SourceIDs)}},
+			}
+			for _, statement := range statements {
+				if _, err := tx.ExecContext(ctx, statement.query, statement.args...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return models.Pipe{}, err
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return models.Pipe{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Pipe{}, err
+	}
+	return p.GetPipe(targetID, userID)
+}
+
+// mergedPipeCovers checks that every pipe of a merge is a standard pipe of the user that is not in the trash,
+// and returns the cover photo of each of them
+func mergedPipeCovers(ctx context.Context, tx *sql.Tx, userID int64, pipeIDs []int64) (map[int64]string, error) {
+	query := `
+	SELECT id, type, COALESCE(NULLIF(cover_photo, 'NULL'), '')
+	FROM pipes
+	WHERE id = ANY($1) AND user_id=$2 AND deleted_at IS NULL
+	`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(pipeIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	covers := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var pipeType, cover string
+		if err := rows.Scan(&id, &pipeType, &cover); err != nil {
+			return nil, err
+		}
+		if pipeType == models.PipeTypeSmart {
+			return nil, ErrSmartPipe
+		}
+		covers[id] = cover
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range pipeIDs {
+		if _, ok := covers[id]; !ok {
+			return nil, ErrNoRecord
+		}
+	}
+	return covers, nil
+}
+
+// mergedBookmarks goes through the bookmarks of the merged pipes, the target pipe first, and returns the
+// bookmarks of the source pipes that have to move to the target pipe along with the duplicates of a page
+// that are dropped, mapped to the bookmark that is kept for the page
+func mergedBookmarks(ctx context.Context, tx *sql.Tx, targetID int64, pipeIDs []int64) ([]int64, map[int64]int64, error) {
+	query := `
+	SELECT id, pipe_id, url
+	FROM bookmarks
+	WHERE pipe_id = ANY($1::bigint[]) AND deleted_at IS NULL
+	ORDER BY array_position($1::bigint[], pipe_id::bigint), position, id
+	`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(pipeIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var moved []int64
+	kept := make(map[string]int64)
+	duplicates := make(map[int64]int64)
+	for rows.Next() {
+		var id, pipeID int64
+		var link string
+		if err := rows.Scan(&id, &pipeID, &link); err != nil {
+			return nil, nil, err
+		}
+		page := canonicalURL(link)
+		if keeperID, ok := kept[page]; ok {
+			duplicates[id] = keeperID
+			continue
+		}
+		kept[page] = id
+		if pipeID != targetID {
+			moved = append(moved, id)
+		}
+	}
+	return moved, duplicates, rows.Err()
+}
+
+// SplitPipe moves the bookmarks of a pipe into new pipes, one for each tag or platform, placed at the end of
+// the collection of the user next to the split pipe. The new pipes are named after the split pipe and their
+// value, take its icon and colour and are shared with the users the split pipe is shared with.
+// Pipes that would have no bookmarks are not created
+func (p pipeActions) SplitPipe(userID, pipeID int64, split models.PipeSplit) ([]models.Pipe, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var source models.Pipe
+	sourceQuery := `
+	SELECT COALESCE(NULLIF(display_name, ''), name), parent_id, icon, color, type
+	FROM pipes
+	WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+	FOR UPDATE
+	`
+	err = tx.QueryRowContext(ctx, sourceQuery, pipeID, userID).Scan(&source.DisplayName, &source.ParentID, &source.Icon, &source.Color, &source.Type)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	if source.Type == models.PipeTypeSmart {
+		return nil, ErrSmartPipe
+	}
+
+	values, groups, err := splitBookmarks(ctx, tx, pipeID, split)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	positions, err := pipePositionScope(userID).nextPositions(ctx, tx, len(values))
+	if err != nil {
+		return nil, err
+	}
+	pipeIDs := make([]int64, len(values))
+	var movedIDs []int64
+	for _, value := range values {
+		movedIDs = append(movedIDs, groups[value]...)
+	}
+	history := newOperationLog(userID, models.HistoryActionSplit)
+	err = history.trackBookmarks(ctx, tx, "b.id = ANY($1)", []interface{}{pq.Array(movedIDs)}, func() error {
+		for i, value := range values {
+			displayName := source.DisplayName + " - " + value
+			if utf8.RuneCountInString(displayName) > maxPipeNameLength {
+				displayName = string([]rune(displayName)[:maxPipeNameLength])
+			}
+			query := `
+			INSERT INTO pipes (user_id, name, display_name, icon, color, position, parent_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+			`
+			err := tx.QueryRowContext(
+				ctx,
+				query,
+				userID,
+				strings.ToLower(strings.TrimSpace(displayName)),
+				strings.TrimSpace(displayName),
+				source.Icon,
+				source.Color,
+				positions[i],
+				source.ParentID,
+			).Scan(&pipeIDs[i])
+			if err != nil {
+				if dbErr, ok := err.(*pq.Error); ok && dbErr.Code == "23505" {
+					return ErrRecordExists
+				}
+				return err
+			}
+
+			moveQuery := `
+			UPDATE bookmarks b
+			SET pipe_id=$1, position=m.position
+			FROM unnest($2::bigint[], $3::text[]) AS m(id, position)
+			WHERE b.id=m.id
+			`
+			bookmarkIDs := groups[value]
+			if _, err := tx.ExecContext(ctx, moveQuery, pipeIDs[i], pq.Array(bookmarkIDs), pq.Array(rankSequence(len(bookmarkIDs)))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the new pipes are recorded as taken out of the trash so that undoing the split puts them there
+	created, err := snapshotPipes(ctx, tx, "p.id = ANY($1)", pq.Array(pipeIDs))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range pipeIDs {
+		before := created[id]
+		before.Trashed = true
+		if err := history.add(models.HistoryEntityPipe, id, before, created[id]); err != nil {
+			return nil, err
+		}
+	}
+	if err := history.save(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	// every new pipe gets its own code for each code the split pipe was received with, so that a code
+	// keeps leading to a single pipe
+	shareQuery := `
+	WITH codes AS (
+	    SELECT n.id AS pipe_id, c.code AS old_code, substr(md5(random()::text || n.id::text), 1, 15) AS code
+	    FROM unnest($2::bigint[]) AS n(id), (SELECT DISTINCT code FROM shared_pipe_receivers WHERE shared_pipe_id=$1) c
+	)
+	INSERT INTO shared_pipe_receivers (sharer_id, shared_pipe_id, receiver_id, is_accepted, code, role, permission)
+	SELECT r.sharer_id, c.pipe_id, r.receiver_id, r.is_accepted, c.code, r.role, r.permission
+	FROM shared_pipe_receivers r
+	    INNER JOIN codes c ON c.old_code IS NOT DISTINCT FROM r.code
+	WHERE r.shared_pipe_id=$1
+	`
+	if _, err := tx.ExecContext(ctx, shareQuery, pipeID, pq.Array(pipeIDs)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.id = ANY($1)
+	GROUP BY p.id, u.username
+	ORDER BY p.position, p.id
+	`
+	rows, err := p.Db.QueryContext(ctx, query, pq.Array(pipeIDs))
+	if err != nil {
+		return nil, err
+	}
+	return collectPipes(rows)
+}
+
+// splitBookmarks groups the bookmarks of a pipe by the value of the split they go to.
+// It returns the values that have bookmarks, in the order of the split, along with the groups
+func splitBookmarks(ctx context.Context, tx *sql.Tx, pipeID int64, split models.PipeSplit) ([]string, map[string][]int64, error) {
+	query := `SELECT b.id, lower(b.platform) FROM bookmarks b WHERE b.pipe_id=$1 AND b.deleted_at IS NULL ORDER BY b.position, b.id`
+	if split.By == models.PipeSplitByTag {
+		query = `
+		SELECT b.id, lower(t.name)
+		FROM bookmarks b
+		    INNER JOIN bookmark_tag bt ON bt.bookmark_id=b.id
+		    INNER JOIN tags t ON t.id=bt.tag_id
+		WHERE b.pipe_id=$1 AND b.deleted_at IS NULL
+		ORDER BY b.position, b.id
+		`
+	}
+	rows, err := tx.QueryContext(ctx, query, pipeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var bookmarkIDs []int64
+	bookmarkValues := make(map[int64]map[string]bool)
+	usage := make(map[string]int)
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, nil, err
+		}
+		if bookmarkValues[id] == nil {
+			bookmarkIDs = append(bookmarkIDs, id)
+			bookmarkValues[id] = make(map[string]bool)
+		}
+		bookmarkValues[id][value] = true
+		usage[value]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]string, 0, len(split.Values))
+	seen := make(map[string]bool)
+	for _, value := range split.Values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		for value := range usage {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if usage[values[i]] != usage[values[j]] {
+				return usage[values[i]] > usage[values[j]]
+			}
+			return values[i] < values[j]
+		})
+	}
+
+	groups := make(map[string][]int64)
+	for _, id := range bookmarkIDs {
+		for _, value := range values {
+			if bookmarkValues[id][value] {
+				groups[value] = append(groups[value], id)
+				break
+			}
+		}
+	}
+	var used []string
+	for _, value := range values {
+		if len(groups[value]) > 0 {
+			used = append(used, value)
+		}
+	}
+	return used, groups, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_pipe_canonicalURL(t *testing.T) {
+	testCases := canonicalURLTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.wantUrl, canonicalURL(tc.inputUrl))
+		})
+	}
+}
+
+func Test_pipe_MergePipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := mergePipesTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			gotPipe, gotErr := pa.MergePipes(tc.inputUserId, tc.inputTargetId, tc.inputMerge)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantBookmarks, gotPipe.Bookmarks)
+				for _, sourceId := range tc.inputMerge.SourceIDs {
+					_, err := pa.GetPipe(sourceId, tc.inputUserId)
+					assert.Equal(t, ErrNoRecord, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_pipe_MergePipes_duplicates(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	// the same video as bookmark 1, shared from another place
+	duplicate, err := ba.CreateBookmark(models.Bookmark{UserID: 1, PipeID: 2, Url: "https://www.youtube.com/watch?v=Acgk_Jl95es&utm_source=twitter", Platform: "youtube"})
+	assert.Nil(t, err)
+	_, err = ba.UpdateBookmark(duplicate.ID, 1, 1, models.BookmarkUpdate{Tags: []string{"Twerk Videos"}})
+	assert.Nil(t, err)
+
+	gotPipe, err := pa.MergePipes(1, 1, models.PipeMerge{SourceIDs: []int64{2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, gotPipe.Bookmarks)
+
+	kept, err := ba.GetBookmark(1, 1)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Beautiful Asian Muslim", "Quick Blows", "Twerk Videos"}, kept.Tags)
+	_, err = ba.GetBookmark(duplicate.ID, 1)
+	assert.Equal(t, ErrNoRecord, err)
+}
+
+func Test_pipe_MergePipes_publicLinks(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	// pipe 2 is already shared publicly with MG78k9lig68
+	_, err := db.Exec(`INSERT INTO shared_pipes (sharer_id, pipe_id, type, code) VALUES (1, 1, 'public', 'mergeTarget01')`)
+	assert.Nil(t, err)
+
+	_, err = pa.MergePipes(1, 1, models.PipeMerge{SourceIDs: []int64{2}})
+	assert.Nil(t, err)
+
+	// the target keeps its own public link, and the receivers of the other one are moved over to it
+	var codes []string
+	rows, err := db.Query(`SELECT code FROM shared_pipes WHERE pipe_id=1 AND type='public'`)
+	assert.Nil(t, err)
+	for rows.Next() {
+		var code string
+		assert.Nil(t, rows.Scan(&code))
+		codes = append(codes, code)
+	}
+	assert.Nil(t, rows.Err())
+	assert.Equal(t, []string{"mergeTarget01"}, codes)
+
+	var code string
+	err = db.QueryRow(`SELECT code FROM shared_pipe_receivers WHERE shared_pipe_id=1 AND receiver_id=2`).Scan(&code)
+	assert.Nil(t, err)
+	assert.Equal(t, "mergeTarget01", code)
+}
+
+func Test_pipe_SplitPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := splitPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			gotPipes, gotErr := pa.SplitPipe(tc.inputUserId, tc.inputPipeId, tc.inputSplit)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, len(tc.wantPipes), len(gotPipes))
+				for i, pipe := range gotPipes {
+					assert.Equal(t, tc.wantPipes[i], pipe.Name)
+					assert.Equal(t, 1, pipe.Bookmarks)
+				}
+			}
+		})
+	}
+}
+
+func Test_pipe_SplitPipe_codes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	_, err := NewBookmarkActions(db, logger).CreateBookmark(models.Bookmark{UserID: 1, PipeID: 2, Url: "https://youtu.be/7", Platform: "youtube"})
+	assert.Nil(t, err)
+	pipes, err := pa.SplitPipe(1, 2, models.PipeSplit{By: models.PipeSplitByPlatform})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(pipes))
+
+	// the receivers of the split pipe get a new code for each new pipe
+	codes := map[string]bool{"MG78k9lig68": true}
+	for _, pipe := range pipes {
+		var code string
+		err := db.QueryRow(`SELECT code FROM shared_pipe_receivers WHERE shared_pipe_id=$1 AND receiver_id=2`, pipe.ID).Scan(&code)
+		assert.Nil(t, err)
+		assert.False(t, codes[code])
+		codes[code] = true
+	}
+}
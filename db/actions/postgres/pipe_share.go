@@ -16,6 +16,25 @@ var (
 	ErrCannotSharePipeToSelf = fmt.Errorf("you cannot share pipe to yourself")
 )
 
+// sharedPipeColumns is the list of columns selected whenever a full pipe share record is retrieved.
+// It must be kept in sync with scanSharedPipe
+const sharedPipeColumns = `
+	id, sharer_id, pipe_id, type, code, created_at, modified_at,
+	expires_at, max_redemptions, redemptions, COALESCE(password_hash, ''), permission`
+
+// receiverColumns is the list of columns selected whenever a full pipe receiver record is retrieved.
+// It expects the shared_pipe_receivers table to be aliased as spr and must be kept in sync with scanReceiver
+const receiverColumns = `
+	spr.id, spr.sharer_id, spr.shared_pipe_id, spr.receiver_id, spr.code, spr.is_accepted, spr.role,
+	spr.permission, spr.declined_at, spr.expires_at, spr.created_at, spr.modified_at`
+
+// receivedShareOrdering lists the pipes shared with a user from the most recently shared
+var receivedShareOrdering = ordering{
+	name:  "received-shares-" + models.SortNewest,
+	keys:  []sortKey{{expr: "spr.created_at", desc: true}, {expr: "spr.id", desc: true}},
+	table: "shared_pipe_receivers spr",
+}
+
 type pipeShareActions struct {
 	Db     *sql.DB
 	Logger zerolog.Logger
@@ -28,8 +47,49 @@ func NewPipeShareActions(db *sql.DB, logger zerolog.Logger) repository.PipeShare
 	}
 }
 
-// CreatePipeShareRecord creates a pipe share record for a user
-func (p pipeShareActions) CreatePipeShareRecord(pipeShareData models.SharedPipe, receiver string) (models.SharedPipe, error) {
+// scanSharedPipe reads a row selected with sharedPipeColumns into a pipe share
+func scanSharedPipe(row rowScanner) (models.SharedPipe, error) {
+	var sharedPipe models.SharedPipe
+	err := row.Scan(
+		&sharedPipe.ID,
+		&sharedPipe.SharerID,
+		&sharedPipe.PipeID,
+		&sharedPipe.Type,
+		&sharedPipe.Code,
+		&sharedPipe.CreatedAt,
+		&sharedPipe.ModifiedAt,
+		&sharedPipe.ExpiresAt,
+		&sharedPipe.MaxRedemptions,
+		&sharedPipe.Redemptions,
+		&sharedPipe.PasswordHash,
+		&sharedPipe.Permission,
+	)
+	return sharedPipe, err
+}
+
+// scanReceiver reads a row selected with receiverColumns into a pipe receiver
+func scanReceiver(row rowScanner) (models.SharedPipeReceiver, error) {
+	var receiver models.SharedPipeReceiver
+	err := row.Scan(
+		&receiver.ID,
+		&receiver.SharerId,
+		&receiver.SharedPipeId,
+		&receiver.ReceiverID,
+		&receiver.Code,
+		&receiver.IsAccepted,
+		&receiver.Role,
+		&receiver.Permission,
+		&receiver.DeclinedAt,
+		&receiver.ExpiresAt,
+		&receiver.CreatedAt,
+		&receiver.ModifiedAt,
+	)
+	return receiver, err
+}
+
+// CreatePipeShareRecord creates a pipe share record for a user. The receiver of a private share
+// collaborates on the pipe with the given role
+func (p pipeShareActions) CreatePipeShareRecord(pipeShareData models.SharedPipe, receiver string, role string) (models.SharedPipe, error) {
 	var query string
 	var err error
 	uActions := NewUserActions(p.Db, p.Logger)
@@ -37,23 +97,23 @@ func (p pipeShareActions) CreatePipeShareRecord(pipeShareData models.SharedPipe,
 	case models.PipeShareTypePublic:
 		query = `
 		INSERT INTO shared_pipes 
-		    (sharer_id, pipe_id, type, code) 
-		VALUES ($1, $2, $3, $4) 
-		RETURNING id, sharer_id, pipe_id, type, code, created_at, modified_at
-		`
+		    (sharer_id, pipe_id, type, code, expires_at, max_redemptions, password_hash, permission) 
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), COALESCE(NULLIF($8, ''), 'fork')) 
+		RETURNING` + sharedPipeColumns
 
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
-		err = p.Db.QueryRowContext(ctx, query, pipeShareData.SharerID, pipeShareData.PipeID, pipeShareData.Type, pipeShareData.Code).Scan(
-			&pipeShareData.ID,
-			&pipeShareData.SharerID,
-			&pipeShareData.PipeID,
-			&pipeShareData.Type,
-			&pipeShareData.Code,
-			&pipeShareData.CreatedAt,
-			&pipeShareData.ModifiedAt,
-		)
+		pipeShareData, err = scanSharedPipe(p.Db.QueryRowContext(ctx, query,
+			pipeShareData.SharerID,
+			pipeShareData.PipeID,
+			pipeShareData.Type,
+			pipeShareData.Code,
+			pipeShareData.ExpiresAt,
+			pipeShareData.MaxRedemptions,
+			pipeShareData.PasswordHash,
+			pipeShareData.Permission,
+		))
 	case models.PipeShareTypePrivate:
 		pipeShareReceiver, err := uActions.GetUserByUsername(receiver)
 		if err != nil {
@@ -68,25 +128,17 @@ func (p pipeShareActions) CreatePipeShareRecord(pipeShareData models.SharedPipe,
 		if pipeShareReceiver.ID == pipeShareData.SharerID {
 			return models.SharedPipe{}, ErrCannotSharePipeToSelf
 		}
+		expiresAt := time.Now().Add(models.PrivateShareTTL)
 		//var sharedTo model.SharedPipeReceiver
 		query = `
 		INSERT INTO shared_pipes (sharer_id, pipe_id, type, code) 
 		VALUES ($1, $2, $3, $4) 
-		RETURNING id, sharer_id, pipe_id, type, code, created_at, modified_at
-		`
+		RETURNING` + sharedPipeColumns
 
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
-		err = p.Db.QueryRowContext(ctx, query, pipeShareData.SharerID, pipeShareData.PipeID, pipeShareData.Type, pipeShareData.Code).Scan(
-			&pipeShareData.ID,
-			&pipeShareData.SharerID,
-			&pipeShareData.PipeID,
-			&pipeShareData.Type,
-			&pipeShareData.Code,
-			&pipeShareData.CreatedAt,
-			&pipeShareData.ModifiedAt,
-		)
+		pipeShareData, err = scanSharedPipe(p.Db.QueryRowContext(ctx, query, pipeShareData.SharerID, pipeShareData.PipeID, pipeShareData.Type, pipeShareData.Code))
 
 		if err != nil {
 			return models.SharedPipe{}, err
@@ -96,6 +148,8 @@ func (p pipeShareActions) CreatePipeShareRecord(pipeShareData models.SharedPipe,
 			SharedPipeId: pipeShareData.PipeID,
 			ReceiverID:   pipeShareReceiver.ID,
 			Code:         pipeShareData.Code,
+			Role:         role,
+			ExpiresAt:    &expiresAt,
 		})
 		if err != nil {
 			return models.SharedPipe{}, err
@@ -112,38 +166,32 @@ func (p pipeShareActions) CreatePipeShareRecord(pipeShareData models.SharedPipe,
 
 // CreatePipeReceiver creates a receiver for a pipe share
 // receiver record for public pipe share is automatically marked as accepted
-// while private pipe shares needs to be accepted by the receiver in another step
+// while private pipe shares needs to be accepted by the receiver in another step.
+// Receivers are viewers unless they are given another role
 func (p pipeShareActions) CreatePipeReceiver(receiver models.SharedPipeReceiver) (models.SharedPipeReceiver, error) {
 	query := `
-	INSERT INTO shared_pipe_receivers 
-	    (sharer_id, shared_pipe_id, receiver_id, code, is_accepted)
-	VALUES ($1, $2, $3, $4, $5)
-	RETURNING id, sharer_id, shared_pipe_id, receiver_id, code, is_accepted, created_at, modified_at
-	`
+	INSERT INTO shared_pipe_receivers AS spr
+	    (sharer_id, shared_pipe_id, receiver_id, code, is_accepted, role, expires_at, permission)
+	VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), 'viewer'), $7, COALESCE(NULLIF($8, ''), 'fork'))
+	RETURNING` + receiverColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query,
+	newReceiver, err := scanReceiver(p.Db.QueryRowContext(ctx, query,
 		receiver.SharerId,
 		receiver.SharedPipeId,
 		receiver.ReceiverID,
 		receiver.Code,
 		receiver.IsAccepted,
-	).Scan(
-		&receiver.ID,
-		&receiver.SharerId,
-		&receiver.SharedPipeId,
-		&receiver.ReceiverID,
-		&receiver.Code,
-		&receiver.IsAccepted,
-		&receiver.CreatedAt,
-		&receiver.ModifiedAt,
-	)
+		receiver.Role,
+		receiver.ExpiresAt,
+		receiver.Permission,
+	))
 	if err != nil {
 		return receiver, err
 	}
-	return receiver, nil
+	return newReceiver, nil
 }
 
 // GetSharedPipe retrieves a pipe share record for a particular pipe
@@ -151,9 +199,8 @@ func (p pipeShareActions) GetSharedPipe(pipeId int64, shareType string) (models.
 	if strings.TrimSpace(shareType) == "" {
 		shareType = models.PipeShareTypePrivate
 	}
-	var sharedPipe models.SharedPipe
 	query := `
-	SELECT id, sharer_id, pipe_id, type, code, created_at 
+	SELECT` + sharedPipeColumns + `
 	FROM shared_pipes 
 	WHERE pipe_id=$1 AND type=$2
 	LIMIT 1
@@ -162,14 +209,7 @@ func (p pipeShareActions) GetSharedPipe(pipeId int64, shareType string) (models.
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, pipeId, shareType).Scan(
-		&sharedPipe.ID,
-		&sharedPipe.SharerID,
-		&sharedPipe.PipeID,
-		&sharedPipe.Type,
-		&sharedPipe.Code,
-		&sharedPipe.CreatedAt,
-	)
+	sharedPipe, err := scanSharedPipe(p.Db.QueryRowContext(ctx, query, pipeId, shareType))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -180,27 +220,21 @@ func (p pipeShareActions) GetSharedPipe(pipeId int64, shareType string) (models.
 	return sharedPipe, nil
 }
 
-// GetSharedPipeByCode retrieves a shared pipe record by share code
+// GetSharedPipeByCode retrieves a shared pipe record by share code.
+// Codes of pipes that are in the trash or that were shared by a suspended user can not be redeemed
 func (p pipeShareActions) GetSharedPipeByCode(code string) (models.SharedPipe, error) {
-	var sharedPipe models.SharedPipe
 	query := `
-	SELECT id,sharer_id, pipe_id, type, code, created_at 
+	SELECT` + sharedPipeColumns + `
 	FROM shared_pipes 
-	WHERE code=$1 
+	WHERE code=$1 AND pipe_id IN (SELECT id FROM pipes WHERE deleted_at IS NULL)
+	    AND sharer_id NOT IN (SELECT id FROM users WHERE suspended_at IS NOT NULL)
 	LIMIT 1
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, code).Scan(
-		&sharedPipe.ID,
-		&sharedPipe.SharerID,
-		&sharedPipe.PipeID,
-		&sharedPipe.Type,
-		&sharedPipe.Code,
-		&sharedPipe.CreatedAt,
-	)
+	sharedPipe, err := scanSharedPipe(p.Db.QueryRowContext(ctx, query, code))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.SharedPipe{}, ErrNoRecord
@@ -213,25 +247,17 @@ func (p pipeShareActions) GetSharedPipeByCode(code string) (models.SharedPipe, e
 // GetReceivedPipeRecord retrieves a received pipe record by pipe id and a designated user id
 func (p pipeShareActions) GetReceivedPipeRecord(pipeId, userId int64) (models.SharedPipeReceiver, error) {
 
-	var sharedPipe models.SharedPipeReceiver
 	query := `
-	SELECT id, sharer_id, shared_pipe_id, receiver_id, code, is_accepted
-	FROM shared_pipe_receivers 
-	WHERE shared_pipe_id=$1 AND receiver_id=$2 
+	SELECT` + receiverColumns + `
+	FROM shared_pipe_receivers spr
+	WHERE spr.shared_pipe_id=$1 AND spr.receiver_id=$2 
 	LIMIT 1
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, pipeId, userId).Scan(
-		&sharedPipe.ID,
-		&sharedPipe.SharerId,
-		&sharedPipe.SharedPipeId,
-		&sharedPipe.ReceiverID,
-		&sharedPipe.Code,
-		&sharedPipe.IsAccepted,
-	)
+	sharedPipe, err := scanReceiver(p.Db.QueryRowContext(ctx, query, pipeId, userId))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.SharedPipeReceiver{}, ErrNoRecord
@@ -245,25 +271,17 @@ func (p pipeShareActions) GetReceivedPipeRecord(pipeId, userId int64) (models.Sh
 // GetReceivedPipeRecordByCode retrieves a received pipe record by pipe id and a designated user id
 func (p pipeShareActions) GetReceivedPipeRecordByCode(code string, userId int64) (models.SharedPipeReceiver, error) {
 
-	var sharedPipe models.SharedPipeReceiver
 	query := `
-	SELECT id, sharer_id, shared_pipe_id, receiver_id, code, is_accepted
-	FROM shared_pipe_receivers 
-	WHERE code=$1 AND receiver_id=$2 
+	SELECT` + receiverColumns + `
+	FROM shared_pipe_receivers spr
+	WHERE spr.code=$1 AND spr.receiver_id=$2 
 	LIMIT 1
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, code, userId).Scan(
-		&sharedPipe.ID,
-		&sharedPipe.SharerId,
-		&sharedPipe.SharedPipeId,
-		&sharedPipe.ReceiverID,
-		&sharedPipe.Code,
-		&sharedPipe.IsAccepted,
-	)
+	sharedPipe, err := scanReceiver(p.Db.QueryRowContext(ctx, query, code, userId))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.SharedPipeReceiver{}, ErrNoRecord
@@ -274,32 +292,556 @@ func (p pipeShareActions) GetReceivedPipeRecordByCode(code string, userId int64)
 	return sharedPipe, nil
 }
 
-// AcceptPrivateShare accept a private share
+// AcceptPrivateShare accept a private share, which may have been declined before
 func (p pipeShareActions) AcceptPrivateShare(receiver models.SharedPipeReceiver) (models.SharedPipeReceiver, error) {
 	query := `
-	UPDATE shared_pipe_receivers 
+	UPDATE shared_pipe_receivers spr
 	SET 
-	    is_accepted=true, modified_at=now()
+	    is_accepted=true, declined_at=NULL, modified_at=now()
 	WHERE id=$1
-	RETURNING id, sharer_id, shared_pipe_id, receiver_id, code, is_accepted, created_at, modified_at
+	RETURNING` + receiverColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	accepted, err := scanReceiver(p.Db.QueryRowContext(ctx, query, receiver.ID))
+	if err != nil {
+		return receiver, err
+	}
+	return accepted, nil
+}
+
+// DeclinePrivateShare declines a private share that has not been accepted
+func (p pipeShareActions) DeclinePrivateShare(receiver models.SharedPipeReceiver) (models.SharedPipeReceiver, error) {
+	query := `
+	UPDATE shared_pipe_receivers spr
+	SET 
+	    declined_at=COALESCE(declined_at, now()), modified_at=now()
+	WHERE id=$1 AND is_accepted=false
+	RETURNING` + receiverColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	declined, err := scanReceiver(p.Db.QueryRowContext(ctx, query, receiver.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return receiver, ErrNoRecord
+		}
+		return receiver, err
+	}
+	return declined, nil
+}
+
+// RenewPrivateShare turns a private share that was declined or has expired back
+// into a pending invitation, which expires at the given time
+func (p pipeShareActions) RenewPrivateShare(receiver models.SharedPipeReceiver, expiresAt time.Time) (models.SharedPipeReceiver, error) {
+	query := `
+	UPDATE shared_pipe_receivers spr
+	SET 
+	    declined_at=NULL, expires_at=$2, created_at=now(), modified_at=now()
+	WHERE id=$1 AND is_accepted=false
+	RETURNING` + receiverColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	renewed, err := scanReceiver(p.Db.QueryRowContext(ctx, query, receiver.ID, expiresAt))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return receiver, ErrNoRecord
+		}
+		return receiver, err
+	}
+	return renewed, nil
+}
+
+// GetPipeAccess retrieves the role of a user on a pipe that is not in the trash. Owners get the owner role,
+// while the users the pipe or one of the pipes it's nested in was shared with get the highest role they
+// were given on an accepted share
+func (p pipeShareActions) GetPipeAccess(pipeId, userId int64) (models.PipeAccess, error) {
+	query := `
+	WITH RECURSIVE ancestors AS (
+	    SELECT id, parent_id, user_id, user_id AS owner_id FROM pipes WHERE id=$1 AND deleted_at IS NULL
+	    UNION
+	    SELECT p.id, p.parent_id, p.user_id, a.owner_id FROM pipes p INNER JOIN ancestors a ON p.id=a.parent_id
+	)
+	SELECT a.owner_id, CASE WHEN a.user_id=$2 THEN 'owner' ELSE spr.role END
+	FROM ancestors a
+	    LEFT JOIN shared_pipe_receivers spr
+	        ON spr.shared_pipe_id=a.id AND spr.receiver_id=$2 AND spr.is_accepted=true
+	WHERE a.user_id=$2 OR spr.id IS NOT NULL
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	err := p.Db.QueryRowContext(ctx, query, receiver.ID).Scan(
-		&receiver.ID,
-		&receiver.SharerId,
-		&receiver.SharedPipeId,
-		&receiver.ReceiverID,
-		&receiver.Code,
-		&receiver.IsAccepted,
-		&receiver.CreatedAt,
-		&receiver.ModifiedAt,
+	rows, err := p.Db.QueryContext(ctx, query, pipeId, userId)
+	if err != nil {
+		return models.PipeAccess{}, err
+	}
+	defer rows.Close()
+
+	access := models.PipeAccess{PipeID: pipeId}
+	for rows.Next() {
+		var ownerId int64
+		var role string
+		if err := rows.Scan(&ownerId, &role); err != nil {
+			return models.PipeAccess{}, err
+		}
+		if access.Role == "" || models.PipeRoleAllows(role, access.Role) {
+			access.OwnerID, access.Role = ownerId, role
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.PipeAccess{}, err
+	}
+	if access.Role == "" {
+		return models.PipeAccess{}, ErrNoRecord
+	}
+	return access, nil
+}
+
+// UpdateReceiverRole changes the role of a user a pipe was shared with
+func (p pipeShareActions) UpdateReceiverRole(pipeId, receiverId int64, role string) (models.SharedPipeReceiver, error) {
+	query := `
+	UPDATE shared_pipe_receivers spr
+	SET
+	    role=$3, modified_at=now()
+	WHERE shared_pipe_id=$1 AND receiver_id=$2
+	RETURNING` + receiverColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	receiver, err := scanReceiver(p.Db.QueryRowContext(ctx, query, pipeId, receiverId, role))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.SharedPipeReceiver{}, ErrNoRecord
+		}
+		return models.SharedPipeReceiver{}, err
+	}
+	return receiver, nil
+}
+
+// GetPipeCollaborators retrieves every user a pipe was shared with, whether they have accepted the share or not
+func (p pipeShareActions) GetPipeCollaborators(pipeId int64) ([]models.PipeCollaborator, error) {
+	query := `
+	SELECT` + receiverColumns + `, u.username, COALESCE(u.profile_name, '')
+	FROM shared_pipe_receivers spr
+	    INNER JOIN users u ON spr.receiver_id=u.id
+	WHERE spr.shared_pipe_id=$1
+	ORDER BY spr.created_at, spr.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := p.Db.QueryContext(ctx, query, pipeId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collaborators []models.PipeCollaborator
+	for rows.Next() {
+		var c models.PipeCollaborator
+		err := rows.Scan(
+			&c.ID,
+			&c.SharerId,
+			&c.SharedPipeId,
+			&c.ReceiverID,
+			&c.Code,
+			&c.IsAccepted,
+			&c.Role,
+			&c.Permission,
+			&c.DeclinedAt,
+			&c.ExpiresAt,
+			&c.CreatedAt,
+			&c.ModifiedAt,
+			&c.Username,
+			&c.ProfileName,
+		)
+		if err != nil {
+			return collaborators, err
+		}
+		collaborators = append(collaborators, c)
+	}
+	if err := rows.Err(); err != nil {
+		return collaborators, err
+	}
+	return collaborators, nil
+}
+
+// DeletePipeReceiver removes a user a pipe was shared with, who loses access to the pipe right away.
+// The private share created for them goes along, so its code can't be redeemed anymore
+func (p pipeShareActions) DeletePipeReceiver(pipeId, receiverId int64) (models.SharedPipeReceiver, error) {
+	query := `
+	DELETE FROM shared_pipe_receivers spr
+	WHERE shared_pipe_id=$1 AND receiver_id=$2
+	RETURNING` + receiverColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.SharedPipeReceiver{}, err
+	}
+	defer tx.Rollback()
+
+	receiver, err := scanReceiver(tx.QueryRowContext(ctx, query, pipeId, receiverId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.SharedPipeReceiver{}, ErrNoRecord
+		}
+		return models.SharedPipeReceiver{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM shared_pipes WHERE pipe_id=$1 AND code=$2 AND type=$3`,
+		pipeId, receiver.Code, models.PipeShareTypePrivate)
+	if err != nil {
+		return models.SharedPipeReceiver{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.SharedPipeReceiver{}, err
+	}
+	return receiver, nil
+}
+
+// UpdateSharedPipeCode replaces the code of a pipe share. The old code can't be redeemed anymore,
+// but the users who redeemed it keep their access to the pipe under the new code
+func (p pipeShareActions) UpdateSharedPipeCode(pipeId int64, shareType, code string) (models.SharedPipe, error) {
+	query := `
+	WITH receivers AS (
+	    UPDATE shared_pipe_receivers spr
+	    SET code=$3, modified_at=now()
+	    FROM shared_pipes sp
+	    WHERE sp.pipe_id=$1 AND sp.type=$2 AND spr.shared_pipe_id=$1 AND spr.code=sp.code
 	)
+	UPDATE shared_pipes
+	SET
+	    code=$3, modified_at=now()
+	WHERE pipe_id=$1 AND type=$2
+	RETURNING` + sharedPipeColumns
 
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	sharedPipe, err := scanSharedPipe(p.Db.QueryRowContext(ctx, query, pipeId, shareType, code))
 	if err != nil {
-		return receiver, err
+		if err == sql.ErrNoRows {
+			return models.SharedPipe{}, ErrNoRecord
+		}
+		return models.SharedPipe{}, err
+	}
+	return sharedPipe, nil
+}
+
+// DeleteSharedPipe disables the shares of a pipe of the given type, so that their codes can't be
+// redeemed anymore. The users who redeemed them keep their access to the pipe
+func (p pipeShareActions) DeleteSharedPipe(pipeId int64, shareType string) error {
+	query := `DELETE FROM shared_pipes WHERE pipe_id=$1 AND type=$2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := p.Db.ExecContext(ctx, query, pipeId, shareType)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// UpdateShareLink replaces the expiry, the redemption limit, the password and the permission of a
+// pipe share. Redemptions made so far keep counting towards the new limit, and the users who redeemed
+// the share get its new permission
+func (p pipeShareActions) UpdateShareLink(share models.SharedPipe) (models.SharedPipe, error) {
+	query := `
+	WITH receivers AS (
+	    UPDATE shared_pipe_receivers spr
+	    SET permission=COALESCE(NULLIF($5, ''), 'fork'), modified_at=now()
+	    FROM shared_pipes sp
+	    WHERE sp.id=$1 AND spr.shared_pipe_id=sp.pipe_id AND spr.code=sp.code
+	)
+	UPDATE shared_pipes
+	SET
+	    expires_at=$2,
+	    max_redemptions=$3,
+	    password_hash=NULLIF($4, ''),
+	    permission=COALESCE(NULLIF($5, ''), 'fork'),
+	    modified_at=now()
+	WHERE id=$1
+	RETURNING` + sharedPipeColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	sharedPipe, err := scanSharedPipe(p.Db.QueryRowContext(ctx, query,
+		share.ID,
+		share.ExpiresAt,
+		share.MaxRedemptions,
+		share.PasswordHash,
+		share.Permission,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.SharedPipe{}, ErrNoRecord
+		}
+		return models.SharedPipe{}, err
+	}
+	return sharedPipe, nil
+}
+
+// RedeemSharedPipe counts a redemption of a pipe share. It fails with ErrNoRecord
+// when the share has expired or has been redeemed as many times as it allows
+func (p pipeShareActions) RedeemSharedPipe(sharedPipeId int64) error {
+	query := `
+	UPDATE shared_pipes
+	SET redemptions=redemptions+1
+	WHERE id=$1
+	    AND (expires_at IS NULL OR expires_at > now())
+	    AND (max_redemptions IS NULL OR redemptions < max_redemptions)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := p.Db.ExecContext(ctx, query, sharedPipeId)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// receivedShareStatusCondition returns the SQL condition that matches the pipe receivers in the given status
+func receivedShareStatusCondition(status string) string {
+	switch status {
+	case models.ShareStatusPending:
+		return "spr.is_accepted=false AND spr.declined_at IS NULL AND (spr.expires_at IS NULL OR spr.expires_at > now())"
+	case models.ShareStatusAccepted:
+		return "spr.is_accepted=true"
+	case models.ShareStatusDeclined:
+		return "spr.is_accepted=false AND spr.declined_at IS NOT NULL"
+	case models.ShareStatusExpired:
+		return "spr.is_accepted=false AND spr.declined_at IS NULL AND spr.expires_at <= now()"
+	default:
+		return "true"
+	}
+}
+
+// GetReceivedShares retrieves a page of the pipes shared with a user in the given status, or in any status
+// when none is given. Pipes that are in the trash are left out
+func (p pipeShareActions) GetReceivedShares(userId int64, status string, filter models.Filter) ([]models.ReceivedShare, models.Pagination, error) {
+	var shares []models.ReceivedShare
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := receivedShareOrdering.page(ctx, p.Db, filter, []interface{}{userId})
+	if err != nil {
+		return shares, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + receiverColumns + `,
+	    COALESCE(NULLIF(pp.display_name, ''), pp.name), COALESCE(pp.cover_photo, ''), u.username
+	FROM shared_pipe_receivers spr
+	    INNER JOIN pipes pp ON spr.shared_pipe_id=pp.id AND pp.deleted_at IS NULL
+	    INNER JOIN users u ON spr.sharer_id=u.id
+	WHERE spr.receiver_id=$1 AND ` + receivedShareStatusCondition(status) + ` AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return shares, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	for rows.Next() {
+		var share models.ReceivedShare
+		err := rows.Scan(
+			&share.ID,
+			&share.SharerId,
+			&share.SharedPipeId,
+			&share.ReceiverID,
+			&share.Code,
+			&share.IsAccepted,
+			&share.Role,
+			&share.Permission,
+			&share.DeclinedAt,
+			&share.ExpiresAt,
+			&share.CreatedAt,
+			&share.ModifiedAt,
+			&share.PipeName,
+			&share.PipeCoverPhoto,
+			&share.SharerUsername,
+		)
+		if err != nil {
+			return shares, models.Pagination{}, err
+		}
+		share.Status = share.SharedPipeReceiver.Status(now)
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return shares, models.Pagination{}, err
+	}
+
+	pagination, size := receivedShareOrdering.pagination(filter, len(shares), func(i int) int64 { return shares[i].ID })
+	return shares[:size], pagination, nil
+}
+
+// GetReceivedShare retrieves a pipe receiver record by its id and the user it belongs to
+func (p pipeShareActions) GetReceivedShare(id, userId int64) (models.SharedPipeReceiver, error) {
+	query := `
+	SELECT` + receiverColumns + `
+	FROM shared_pipe_receivers spr
+	WHERE spr.id=$1 AND spr.receiver_id=$2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	receiver, err := scanReceiver(p.Db.QueryRowContext(ctx, query, id, userId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.SharedPipeReceiver{}, ErrNoRecord
+		}
+		return models.SharedPipeReceiver{}, err
 	}
 	return receiver, nil
 }
+
+// CreateShareInvitation creates an invitation to a pipe for an email. Inviting the same email to the
+// same pipe again renews the invitation with the new role and expiry, unless it was already attached
+// to an account, in which case ErrRecordExists is returned
+func (p pipeShareActions) CreateShareInvitation(invitation models.PipeShareInvitation) (models.PipeShareInvitation, error) {
+	query := `
+	INSERT INTO pipe_share_invitations AS i
+	    (sharer_id, pipe_id, email, code, role, expires_at)
+	VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'viewer'), $6)
+	ON CONFLICT (pipe_id, lower(email)) DO UPDATE
+	SET role=EXCLUDED.role, expires_at=EXCLUDED.expires_at, sharer_id=EXCLUDED.sharer_id, modified_at=now()
+	WHERE i.attached_at IS NULL
+	RETURNING 
+	    i.id, i.sharer_id, i.pipe_id, i.email, i.code, i.role, i.expires_at,
+	    i.receiver_id, i.attached_at, i.created_at, i.modified_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var created models.PipeShareInvitation
+	err := p.Db.QueryRowContext(ctx, query,
+		invitation.SharerID,
+		invitation.PipeID,
+		strings.TrimSpace(invitation.Email),
+		invitation.Code,
+		invitation.Role,
+		invitation.ExpiresAt,
+	).Scan(
+		&created.ID,
+		&created.SharerID,
+		&created.PipeID,
+		&created.Email,
+		&created.Code,
+		&created.Role,
+		&created.ExpiresAt,
+		&created.ReceiverID,
+		&created.AttachedAt,
+		&created.CreatedAt,
+		&created.ModifiedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return invitation, ErrRecordExists
+		}
+		return invitation, err
+	}
+	return created, nil
+}
+
+// AttachShareInvitations turns the invitations sent to an email that have not expired into private shares
+// of the user who owns the email, which they accept or decline like any other private share. Invitations
+// to pipes the user owns or that were already shared with them are attached without creating a share.
+// It returns the private shares created
+func (p pipeShareActions) AttachShareInvitations(userId int64, email string) ([]models.SharedPipeReceiver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE pipe_share_invitations i
+	SET receiver_id=$1, attached_at=now(), modified_at=now()
+	WHERE lower(i.email)=lower($2) AND i.attached_at IS NULL AND i.expires_at > now()
+	RETURNING i.sharer_id, i.pipe_id, i.code, i.role
+	`
+	rows, err := tx.QueryContext(ctx, query, userId, strings.TrimSpace(email))
+	if err != nil {
+		return nil, err
+	}
+	var invitations []models.PipeShareInvitation
+	for rows.Next() {
+		var invitation models.PipeShareInvitation
+		if err := rows.Scan(&invitation.SharerID, &invitation.PipeID, &invitation.Code, &invitation.Role); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(models.PrivateShareTTL)
+	var receivers []models.SharedPipeReceiver
+	for _, invitation := range invitations {
+		var skip bool
+		err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pipes WHERE id=$1 AND user_id=$2)
+		    OR EXISTS (SELECT 1 FROM shared_pipe_receivers WHERE shared_pipe_id=$1 AND receiver_id=$2)
+		`, invitation.PipeID, userId).Scan(&skip)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO shared_pipes (sharer_id, pipe_id, type, code)
+		VALUES ($1, $2, $3, $4)
+		`, invitation.SharerID, invitation.PipeID, models.PipeShareTypePrivate, invitation.Code)
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := scanReceiver(tx.QueryRowContext(ctx, `
+		INSERT INTO shared_pipe_receivers AS spr
+		    (sharer_id, shared_pipe_id, receiver_id, code, is_accepted, role, expires_at)
+		VALUES ($1, $2, $3, $4, false, $5, $6)
+		RETURNING`+receiverColumns,
+			invitation.SharerID, invitation.PipeID, userId, invitation.Code, invitation.Role, expiresAt,
+		))
+		if err != nil {
+			return nil, err
+		}
+		receivers = append(receivers, receiver)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return receivers, nil
+}
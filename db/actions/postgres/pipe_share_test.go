@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"github.com/mypipeapp/mypipeapi/db/models"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -16,7 +17,7 @@ func Test_pipe_share_CreatePipeShareRecord(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			db := newTestDb(t)
 			psa := NewPipeShareActions(db, logger)
-			gotData, gotErr := psa.CreatePipeShareRecord(tc.inputShareData, tc.inputShareReceiver)
+			gotData, gotErr := psa.CreatePipeShareRecord(tc.inputShareData, tc.inputShareReceiver, tc.inputRole)
 			assert.Equal(t, gotErr, tc.wantErr)
 			if nil == gotErr {
 				assert.Equal(t, gotData.Code, tc.wantData.Code)
@@ -130,3 +131,338 @@ func Test_pipe_share_AcceptPrivateShare(t *testing.T) {
 		})
 	}
 }
+
+func Test_pipe_share_GetPipeAccess(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getPipeAccessTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotAccess, gotErr := psa.GetPipeAccess(tc.inputPipeId, tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+			assert.Equal(t, tc.wantAccess, gotAccess)
+		})
+	}
+}
+
+func Test_pipe_share_UpdateReceiverRole(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateReceiverRoleTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotReceiver, gotErr := psa.UpdateReceiverRole(tc.inputPipeId, tc.inputReceiverId, tc.inputRole)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				assert.Equal(t, tc.inputRole, gotReceiver.Role)
+
+				gotAccess, err := psa.GetPipeAccess(tc.inputPipeId, tc.inputReceiverId)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.inputRole, gotAccess.Role)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_GetPipeCollaborators(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getPipeCollaboratorsTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotCollaborators, gotErr := psa.GetPipeCollaborators(tc.inputPipeId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			var gotUsernames []string
+			for _, collaborator := range gotCollaborators {
+				gotUsernames = append(gotUsernames, collaborator.Username)
+			}
+			assert.Equal(t, tc.wantCollaborators, gotUsernames)
+		})
+	}
+}
+
+func Test_pipe_share_DeletePipeReceiver(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := deletePipeReceiverTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotReceiver, gotErr := psa.DeletePipeReceiver(tc.inputPipeId, tc.inputReceiverId)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				_, err := psa.GetPipeAccess(tc.inputPipeId, tc.inputReceiverId)
+				assert.Equal(t, ErrNoRecord, err)
+				_, err = psa.GetReceivedPipeRecord(tc.inputPipeId, tc.inputReceiverId)
+				assert.Equal(t, ErrNoRecord, err)
+
+				// only the private share made for the receiver goes along with them
+				sharedPipe, err := psa.GetSharedPipeByCode(gotReceiver.Code)
+				if sharedPipe.Type == models.PipeShareTypePublic {
+					assert.NoError(t, err)
+				} else {
+					assert.Equal(t, ErrNoRecord, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_pipe_share_UpdateSharedPipeCode(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateSharedPipeCodeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotSharedPipe, gotErr := psa.UpdateSharedPipeCode(tc.inputPipeId, models.PipeShareTypePublic, tc.inputCode)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				assert.Equal(t, tc.inputCode, gotSharedPipe.Code)
+
+				_, err := psa.GetSharedPipeByCode("MG78k9lig68")
+				assert.Equal(t, ErrNoRecord, err)
+				_, err = psa.GetSharedPipeByCode(tc.inputCode)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_DeleteSharedPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := deleteSharedPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotErr := psa.DeleteSharedPipe(tc.inputPipeId, models.PipeShareTypePublic)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				_, err := psa.GetSharedPipe(tc.inputPipeId, models.PipeShareTypePublic)
+				assert.Equal(t, ErrNoRecord, err)
+
+				// users who added the pipe through the public link keep it
+				_, err = psa.GetPipeAccess(tc.inputPipeId, 2)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_UpdateShareLink(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateShareLinkTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotShare, gotErr := psa.UpdateShareLink(tc.inputShare)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				assert.Equal(t, tc.wantShare.PipeID, gotShare.PipeID)
+				assert.Equal(t, tc.wantShare.Code, gotShare.Code)
+				assert.Equal(t, tc.wantShare.MaxRedemptions, gotShare.MaxRedemptions)
+				assert.Equal(t, tc.wantShare.PasswordHash, gotShare.PasswordHash)
+				assert.Equal(t, tc.wantShare.Permission, gotShare.Permission)
+				assert.Equal(t, tc.inputShare.ExpiresAt == nil, gotShare.ExpiresAt == nil)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_RedeemSharedPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := redeemSharedPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			_, err := psa.UpdateShareLink(tc.inputShare)
+			assert.NoError(t, err)
+
+			var gotErr error
+			for i := 0; i < tc.inputRedemptions; i++ {
+				gotErr = psa.RedeemSharedPipe(tc.inputShare.ID)
+			}
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
+func Test_pipe_share_GetReceivedShares(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getReceivedSharesTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotShares, _, gotErr := psa.GetReceivedShares(tc.inputUserId, tc.inputStatus, models.Filter{})
+			assert.NoError(t, gotErr)
+
+			var gotIds []int64
+			for _, share := range gotShares {
+				gotIds = append(gotIds, share.ID)
+				if tc.inputStatus != "" {
+					assert.Equal(t, tc.inputStatus, share.Status)
+				}
+			}
+			assert.Equal(t, tc.wantIds, gotIds)
+		})
+	}
+}
+
+func Test_pipe_share_GetReceivedShare(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getReceivedShareTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotReceiver, gotErr := psa.GetReceivedShare(tc.inputId, tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputId, gotReceiver.ID)
+				assert.Equal(t, tc.inputUserId, gotReceiver.ReceiverID)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_DeclinePrivateShare(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := declinePrivateShareTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotReceiver, gotErr := psa.DeclinePrivateShare(tc.inputReceiver)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, models.ShareStatusDeclined, gotReceiver.Status(time.Now()))
+			}
+		})
+	}
+}
+
+func Test_pipe_share_RenewPrivateShare(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := renewPrivateShareTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			_, err := psa.DeclinePrivateShare(models.SharedPipeReceiver{ID: 2, ReceiverID: 2})
+			assert.NoError(t, err)
+
+			gotReceiver, gotErr := psa.RenewPrivateShare(tc.inputReceiver, tc.inputExpiresAt)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, models.ShareStatusPending, gotReceiver.Status(time.Now()))
+				assert.WithinDuration(t, tc.inputExpiresAt, *gotReceiver.ExpiresAt, time.Second)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_CreateShareInvitation(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := createShareInvitationTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			if tc.inputAttach {
+				_, err := psa.CreateShareInvitation(tc.inputInvitation)
+				assert.NoError(t, err)
+				_, err = psa.AttachShareInvitations(4, tc.inputInvitation.Email)
+				assert.NoError(t, err)
+			}
+
+			gotInvitation, gotErr := psa.CreateShareInvitation(tc.inputInvitation)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputInvitation.Email, gotInvitation.Email)
+				assert.Equal(t, models.PipeRoleViewer, gotInvitation.Role)
+				assert.Nil(t, gotInvitation.AttachedAt)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_AttachShareInvitations(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := attachShareInvitationsTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			_, err := psa.CreateShareInvitation(tc.inputInvitation)
+			assert.NoError(t, err)
+
+			gotReceivers, gotErr := psa.AttachShareInvitations(tc.inputUserId, tc.inputEmail)
+			assert.NoError(t, gotErr)
+			assert.Len(t, gotReceivers, tc.wantReceivers)
+			for _, receiver := range gotReceivers {
+				assert.Equal(t, tc.inputUserId, receiver.ReceiverID)
+				assert.Equal(t, tc.inputInvitation.Role, receiver.Role)
+				assert.Equal(t, models.ShareStatusPending, receiver.Status(time.Now()))
+			}
+
+			// invitations are only attached once
+			gotReceivers, gotErr = psa.AttachShareInvitations(tc.inputUserId, tc.inputEmail)
+			assert.NoError(t, gotErr)
+			assert.Empty(t, gotReceivers)
+		})
+	}
+}
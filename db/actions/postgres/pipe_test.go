@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"github.com/mypipeapp/mypipeapi/db/models"
 	"gotest.tools/assert"
 	"testing"
 )
@@ -40,7 +41,12 @@ func Test_pipe_CreatePipe(t *testing.T) {
 
 			if nil == gotErr {
 				assert.Equal(t, tc.wantPipe.Name, gotPipe.Name)
+				assert.Equal(t, tc.wantPipe.DisplayName, gotPipe.DisplayName)
 				assert.Equal(t, tc.wantPipe.UserID, gotPipe.UserID)
+				assert.Equal(t, tc.wantPipe.Description, gotPipe.Description)
+				assert.Equal(t, tc.wantPipe.Icon, gotPipe.Icon)
+				assert.Equal(t, tc.wantPipe.Color, gotPipe.Color)
+				assert.Equal(t, tc.wantPipe.Visibility, gotPipe.Visibility)
 			}
 		})
 	}
@@ -101,6 +107,11 @@ func Test_pipe_GetPipeAndResource(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			db := newTestDb(t)
 			pa := NewPipeActions(db, logger)
+			if tc.archivedBookmarkId != 0 {
+				archived := true
+				_, err := NewBookmarkActions(db, logger).UpdateBookmarkState(tc.archivedBookmarkId, tc.inputUserId, models.BookmarkStateUpdate{Archived: &archived})
+				assert.NilError(t, err)
+			}
 			gotResult, gotErr := pa.GetPipeAndResource(tc.inputPipeId, tc.inputUserId)
 			assert.Equal(t, tc.wantErr, gotErr)
 
@@ -113,6 +124,24 @@ func Test_pipe_GetPipeAndResource(t *testing.T) {
 	}
 }
 
+func Test_pipe_GetPipeAndResource_everyBookmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	_, err := db.Exec(`
+	INSERT INTO bookmarks (user_id, pipe_id, url, platform)
+	SELECT 1, 1, 'https://youtu.be/' || n, 'youtube' FROM generate_series(1, $1) AS n
+	`, models.MaxPageLimit+5)
+	assert.NilError(t, err)
+
+	// bookmarks aren't cut off at a page
+	gotResult, err := NewPipeActions(db, logger).GetPipeAndResource(1, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, models.MaxPageLimit+6, len(gotResult.Bookmarks))
+}
+
 func Test_pipe_GetPipes(t *testing.T) {
 	if testing.Short() {
 		t.Skip(skipMessage)
@@ -123,18 +152,91 @@ func Test_pipe_GetPipes(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			db := newTestDb(t)
 			pa := NewPipeActions(db, logger)
-			gotPipes, gotErr := pa.GetPipes(tc.inputUserId)
+			gotPipes, _, gotErr := pa.GetPipes(tc.inputUserId, tc.inputFilter)
 			assert.Equal(t, tc.wantErr, gotErr)
 
 			if nil == gotErr {
 				assert.Equal(t, len(tc.wantPipes), len(gotPipes))
 				assert.Equal(t, tc.wantPipes[0].Name, gotPipes[0].Name)
 				assert.Equal(t, tc.wantPipes[0].Creator, gotPipes[0].Creator)
+				for i, pipe := range gotPipes {
+					assert.Equal(t, tc.wantPipes[i].ID, pipe.ID)
+				}
+			}
+		})
+	}
+}
+
+func Test_pipe_MovePipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := movePipesTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			gotErr := pa.MovePipes(tc.inputUserId, tc.inputMoves)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			gotPipes, _, err := pa.GetPipes(tc.inputUserId, models.PipeFilter{Sort: models.SortManual})
+			assert.NilError(t, err)
+			assert.Equal(t, len(tc.wantPipesIds), len(gotPipes))
+			for i, pipe := range gotPipes {
+				assert.Equal(t, tc.wantPipesIds[i], pipe.ID)
 			}
 		})
 	}
 }
 
+func Test_pipe_NestPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := nestPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			_, gotErr := pa.NestPipe(tc.inputUserId, tc.inputPipeId, tc.inputParentId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			gotPipes, _, err := pa.GetPipes(tc.inputUserId, models.PipeFilter{Sort: models.SortManual, Tree: true})
+			assert.NilError(t, err)
+			assert.Equal(t, len(tc.wantRootIds), len(gotPipes))
+			for i, pipe := range gotPipes {
+				assert.Equal(t, tc.wantRootIds[i], pipe.ID)
+			}
+			assert.Equal(t, tc.wantChildren, len(gotPipes[0].Children))
+			assert.Equal(t, tc.wantTreeBookmark, gotPipes[0].TreeBookmarks)
+		})
+	}
+}
+
+func Test_pipe_NestPipe_cycle(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	_, err := pa.NestPipe(1, 2, &firstPipeId)
+	assert.NilError(t, err)
+
+	// pipe 1 can't go into pipe 2 now that pipe 2 is nested in it
+	_, err = pa.NestPipe(1, 1, &secondPipeId)
+	assert.Equal(t, ErrPipeCycle, err)
+
+	// trashing a pipe takes the pipes nested in it along
+	_, err = pa.DeletePipe(1, 1)
+	assert.NilError(t, err)
+	count, err := pa.GetPipesCount(1)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, count)
+}
+
 func Test_pipe_GetPipesCount(t *testing.T) {
 	if testing.Short() {
 		t.Skip(skipMessage)
@@ -165,7 +267,7 @@ func Test_pipe_UpdatePipe(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			db := newTestDb(t)
 			pa := NewPipeActions(db, logger)
-			gotPipe, gotErr := pa.UpdatePipe(tc.inputUserId, tc.inputPipeId, tc.inputUpdatedBody)
+			gotPipe, gotErr := pa.UpdatePipe(tc.inputUserId, tc.inputUserId, tc.inputPipeId, tc.inputUpdatedBody)
 			assert.Equal(t, tc.wantErr, gotErr)
 
 			if nil == gotErr {
@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	// rankDigits are the digits positions are written with. Positions are compared byte by byte
+	// (the position columns use the "C" collation), so the digits must be in ascending byte order
+	rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	// maxRankLength is the longest a position may grow before the list it belongs to is rebalanced
+	maxRankLength = 64
+)
+
+var errRankCollision = fmt.Errorf("there is no room between the given positions")
+
+// sqlExecutor is implemented by both *sql.DB and *sql.Tx
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rankBetween returns a position that sorts strictly between before and after. An empty before
+// stands for the start of the list and an empty after for its end. The positions returned never end
+// with the lowest digit, so there is always room left before any of them
+func rankBetween(before, after string) (string, error) {
+	if after != "" && before >= after {
+		return "", errRankCollision
+	}
+
+	var rank []byte
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(before) {
+			lo = strings.IndexByte(rankDigits, before[i])
+		}
+		hi := len(rankDigits)
+		if after != "" {
+			if i >= len(after) {
+				return "", errRankCollision
+			}
+			hi = strings.IndexByte(rankDigits, after[i])
+		}
+		if lo < 0 || hi < 0 {
+			return "", fmt.Errorf("invalid position between %q and %q", before, after)
+		}
+
+		switch {
+		case lo == hi:
+			rank = append(rank, rankDigits[lo])
+		case hi-lo > 1:
+			return string(append(rank, rankDigits[(lo+hi)/2])), nil
+		default:
+			// the digits are next to each other; keep the lower one and
+			// look for room anywhere after the rest of before
+			rank = append(rank, rankDigits[lo])
+			after = ""
+		}
+	}
+}
+
+// rankSequence returns n evenly spread positions in ascending order
+func rankSequence(n int) []string {
+	width := len(strconv.FormatInt(int64(n), len(rankDigits)))
+	ranks := make([]string, n)
+	for i := range ranks {
+		digits := strconv.FormatInt(int64(i+1), len(rankDigits))
+		ranks[i] = strings.Repeat("0", width-len(digits)) + digits + "i"
+	}
+	return ranks
+}
+
+// positionScope is a list of rows of a table that are ordered by their position column.
+// condition may only refer to arg as $1
+type positionScope struct {
+	table     string
+	condition string
+	arg       interface{}
+}
+
+// bookmarkPositionScope is the list of bookmarks in a pipe
+func bookmarkPositionScope(pipeID int64) positionScope {
+	return positionScope{table: "bookmarks", condition: "pipe_id=$1 AND deleted_at IS NULL", arg: pipeID}
+}
+
+// pipePositionScope is the list of pipes owned by a user
+func pipePositionScope(userID int64) positionScope {
+	return positionScope{table: "pipes", condition: "user_id=$1 AND deleted_at IS NULL", arg: userID}
+}
+
+// nextPosition returns a position after the last item of the scope
+func (s positionScope) nextPosition(ctx context.Context, db sqlExecutor) (string, error) {
+	var last string
+	query := `SELECT COALESCE(MAX(position), '') FROM ` + s.table + ` WHERE ` + s.condition
+	if err := db.QueryRowContext(ctx, query, s.arg).Scan(&last); err != nil {
+		return "", err
+	}
+	return rankBetween(last, "")
+}
+
+// nextPositions returns n positions in ascending order after the last item of the scope. They all
+// share the prefix of the next position, so adding many items at once doesn't make positions grow long
+func (s positionScope) nextPositions(ctx context.Context, db sqlExecutor, n int) ([]string, error) {
+	next, err := s.nextPosition(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	positions := rankSequence(n)
+	for i := range positions {
+		positions[i] = next + positions[i]
+	}
+	return positions, nil
+}
+
+// move places an item of the scope right after another one. When there is no room between the
+// new neighbours of the item, the whole scope is rebalanced and the move is tried again
+func (s positionScope) move(ctx context.Context, tx *sql.Tx, move models.PositionMove) error {
+	if move.ID == move.AfterID {
+		return nil
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		before, after, err := s.neighbours(ctx, tx, move)
+		if err != nil {
+			return err
+		}
+
+		rank, err := rankBetween(before, after)
+		if err != nil && err != errRankCollision {
+			return err
+		}
+		if err == nil && len(rank) <= maxRankLength {
+			query := `UPDATE ` + s.table + ` SET position=$2 WHERE id=$3 AND ` + s.condition
+			res, err := tx.ExecContext(ctx, query, s.arg, rank, move.ID)
+			if err != nil {
+				return err
+			}
+			if affected, _ := res.RowsAffected(); affected == 0 {
+				return ErrNoRecord
+			}
+			return nil
+		}
+
+		if err := s.rebalance(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return errRankCollision
+}
+
+// neighbours returns the positions the moved item has to fit between
+func (s positionScope) neighbours(ctx context.Context, tx *sql.Tx, move models.PositionMove) (string, string, error) {
+	var before, after string
+	if move.AfterID != 0 {
+		query := `SELECT position FROM ` + s.table + ` WHERE id=$2 AND ` + s.condition
+		if err := tx.QueryRowContext(ctx, query, s.arg, move.AfterID).Scan(&before); err != nil {
+			if err == sql.ErrNoRows {
+				return "", "", ErrNoRecord
+			}
+			return "", "", err
+		}
+	}
+
+	query := `
+	SELECT position FROM ` + s.table + `
+	WHERE ` + s.condition + ` AND id<>$2 AND (position, id) > ($3, $4)
+	ORDER BY position, id
+	LIMIT 1
+	`
+	err := tx.QueryRowContext(ctx, query, s.arg, move.ID, before, move.AfterID).Scan(&after)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", err
+	}
+	return before, after, nil
+}
+
+// rebalance rewrites the positions of every item of the scope so that they are evenly spread out again
+func (s positionScope) rebalance(ctx context.Context, tx *sql.Tx) error {
+	var ids []int64
+	query := `SELECT id FROM ` + s.table + ` WHERE ` + s.condition + ` ORDER BY position, id FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, query, s.arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	updateQuery := `
+	UPDATE ` + s.table + ` t
+	SET position=ranked.position
+	FROM (SELECT unnest($1::bigint[]) AS id, unnest($2::text[]) AS position) ranked
+	WHERE t.id=ranked.id
+	`
+	_, err = tx.ExecContext(ctx, updateQuery, pq.Array(ids), pq.Array(rankSequence(len(ids))))
+	return err
+}
+
+// applyMoves runs a batch of moves on a scope inside tx
+func applyMoves(ctx context.Context, tx *sql.Tx, s positionScope, moves []models.PositionMove) error {
+	for _, move := range moves {
+		if err := s.move(ctx, tx, move); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_position_rankBetween(t *testing.T) {
+	testCases := map[string]struct {
+		inputBefore string
+		inputAfter  string
+		wantErr     error
+	}{
+		"empty list":             {inputBefore: "", inputAfter: ""},
+		"top of the list":        {inputBefore: "", inputAfter: "00000001i"},
+		"end of the list":        {inputBefore: "00000002i", inputAfter: ""},
+		"between two items":      {inputBefore: "00000001i", inputAfter: "00000002i"},
+		"adjacent digits":        {inputBefore: "a", inputAfter: "b"},
+		"prefix of the next":     {inputBefore: "a", inputAfter: "a1"},
+		"last digit":             {inputBefore: "z", inputAfter: ""},
+		"same position":          {inputBefore: "i", inputAfter: "i", wantErr: errRankCollision},
+		"positions out of order": {inputBefore: "r", inputAfter: "i", wantErr: errRankCollision},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gotRank, gotErr := rankBetween(tc.inputBefore, tc.inputAfter)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.True(t, gotRank > tc.inputBefore)
+				if tc.inputAfter != "" {
+					assert.True(t, gotRank < tc.inputAfter)
+				}
+				assert.False(t, strings.HasSuffix(gotRank, "0"))
+			}
+		})
+	}
+}
+
+func Test_position_rankBetween_repeatedInserts(t *testing.T) {
+	// keep inserting right after the first item; every rank must still fit in between
+	first, _ := rankBetween("", "")
+	last, _ := rankBetween(first, "")
+	after := last
+	for i := 0; i < 200; i++ {
+		rank, err := rankBetween(first, after)
+		assert.Nil(t, err)
+		assert.True(t, first < rank && rank < after)
+		after = rank
+	}
+	assert.LessOrEqual(t, len(after), maxRankLength)
+}
+
+func Test_position_rankSequence(t *testing.T) {
+	for _, n := range []int{0, 1, 35, 36, 1000} {
+		ranks := rankSequence(n)
+		assert.Equal(t, n, len(ranks))
+		assert.True(t, sort.StringsAreSorted(ranks))
+		for i := 1; i < len(ranks); i++ {
+			assert.NotEqual(t, ranks[i-1], ranks[i])
+			_, err := rankBetween(ranks[i-1], ranks[i])
+			assert.Nil(t, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+// pipeActivity is the last time a pipe aliased as p or its bookmarks changed
+const pipeActivity = `GREATEST(p.modified_at, COALESCE((
+	SELECT MAX(ab.created_at) FROM bookmarks ab WHERE ab.pipe_id=p.id AND ab.deleted_at IS NULL
+), p.modified_at))`
+
+// pipePopularity is the number of users who added a pipe aliased as p to their collection or forked it
+const pipePopularity = `(
+	SELECT COUNT(*) FROM shared_pipe_receivers ar WHERE ar.shared_pipe_id=p.id AND ar.is_accepted=true
+) + (
+	SELECT COUNT(*) FROM pipes af WHERE af.forked_from=p.id AND af.deleted_at IS NULL
+)`
+
+// explorable matches the public pipes aliased as p whose owner, aliased as u, lists them in explore
+const explorable = `
+	p.deleted_at IS NULL AND p.visibility='` + models.PipeVisibilityPublic + `'
+	AND u.public_profile=true AND u.show_in_explore=true`
+
+// exploreOrdering returns the ordering of the pipes listed in explore for a sort option
+func exploreOrdering(sort string) ordering {
+	o := ordering{name: "explore-" + sort, table: "pipes p"}
+	if sort == models.ExploreSortPopular {
+		o.keys = []sortKey{{expr: pipePopularity, desc: true}, {expr: "p.id", desc: true}}
+	} else {
+		o.name = "explore-" + models.ExploreSortRecent
+		o.keys = []sortKey{{expr: pipeActivity, desc: true}, {expr: "p.id", desc: true}}
+	}
+	return o
+}
+
+// GetProfileSettings retrieves what a user lets other people see of them
+func (u userActions) GetProfileSettings(userId int64) (models.ProfileSettings, error) {
+	var settings models.ProfileSettings
+	query := `SELECT public_profile, show_in_explore FROM users WHERE id=$1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := u.Db.QueryRowContext(ctx, query, userId).Scan(&settings.PublicProfile, &settings.ShowInExplore)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return settings, ErrNoRecord
+		}
+		return settings, err
+	}
+	return settings, nil
+}
+
+// UpdateProfileSettings replaces what a user lets other people see of them
+func (u userActions) UpdateProfileSettings(userId int64, settings models.ProfileSettings) (models.ProfileSettings, error) {
+	query := `
+	UPDATE users 
+	SET public_profile=$2, show_in_explore=$3, modified_at=now()
+	WHERE id=$1
+	RETURNING public_profile, show_in_explore
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := u.Db.QueryRowContext(ctx, query, userId, settings.PublicProfile, settings.ShowInExplore).Scan(
+		&settings.PublicProfile,
+		&settings.ShowInExplore,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return settings, ErrNoRecord
+		}
+		return settings, err
+	}
+	return settings, nil
+}
+
+// GetPublicProfile retrieves the profile of a user who made it public
+func (u userActions) GetPublicProfile(username string) (models.PublicProfile, error) {
+	var profile models.PublicProfile
+	query := `
+	SELECT u.id, u.username, u.profile_name, u.cover_photo, u.created_at, (
+	    SELECT COUNT(*) FROM pipes p
+	    WHERE p.user_id=u.id AND p.deleted_at IS NULL AND p.visibility='` + models.PipeVisibilityPublic + `'
+	)
+	FROM users u
+	WHERE u.username=$1 AND u.public_profile=true
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := u.Db.QueryRowContext(ctx, query, username).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.ProfileName,
+		&profile.CoverPhoto,
+		&profile.CreatedAt,
+		&profile.PublicPipes,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return profile, ErrNoRecord
+		}
+		return profile, err
+	}
+	return profile, nil
+}
+
+// GetPublicPipes gets a page of the public pipes of a user, from the most recently active
+func (p pipeActions) GetPublicPipes(userID int64, filter models.Filter) ([]models.Pipe, models.Pagination, error) {
+	o := ordering{
+		name:  "public-pipes",
+		keys:  []sortKey{{expr: pipeActivity, desc: true}, {expr: "p.id", desc: true}},
+		table: "pipes p",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := o.page(ctx, p.Db, filter, []interface{}{userID})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE p.user_id=$1 AND p.deleted_at IS NULL AND p.visibility='` + models.PipeVisibilityPublic + `'
+	    AND u.public_profile=true AND ` + pageCondition + `
+	GROUP BY p.id, u.username
+	` + pageClauses
+
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pipes, pagination, err := collectPipePage(rows, o, filter)
+	if err != nil {
+		return pipes, pagination, err
+	}
+	return pipes, pagination, countSmartBookmarks(ctx, p.Db, pipes)
+}
+
+// GetPublicPipe retrieves a pipe of a user that anyone can see, along with its bookmarks and the pipes
+// nested in it that anyone can see. Unlisted pipes can be seen by anyone, while public pipes can only
+// be seen while the profile of their owner is public
+func (p pipeActions) GetPublicPipe(userID, pipeID int64) (models.PipeAndResource, error) {
+	query := `
+	SELECT p.id
+	FROM pipes p
+	    INNER JOIN users u ON p.user_id=u.id
+	WHERE p.id=$1 AND p.user_id=$2 AND p.deleted_at IS NULL AND (
+	    p.visibility='` + models.PipeVisibilityUnlisted + `'
+	    OR (p.visibility='` + models.PipeVisibilityPublic + `' AND u.public_profile=true)
+	)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := p.Db.QueryRowContext(ctx, query, pipeID, userID).Scan(&pipeID); err != nil {
+		if err == sql.ErrNoRows {
+			return models.PipeAndResource{}, ErrNoRecord
+		}
+		return models.PipeAndResource{}, err
+	}
+
+	pipeAndR, err := p.GetPipeAndResource(pipeID, userID)
+	if err != nil {
+		return pipeAndR, err
+	}
+	children := []models.Pipe{}
+	for _, child := range pipeAndR.Children {
+		if child.Visibility == models.PipeVisibilityUnlisted || child.Visibility == models.PipeVisibilityPublic {
+			children = append(children, child)
+		}
+	}
+	pipeAndR.Children = children
+	return pipeAndR, nil
+}
+
+// ExplorePipes gets a page of the public pipes listed in explore, either from the most recently
+// active or from the most popular, which are the ones added to most collections or forked most
+func (p pipeActions) ExplorePipes(sort string, filter models.Filter) ([]models.Pipe, models.Pagination, error) {
+	o := exploreOrdering(sort)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := o.page(ctx, p.Db, filter, nil)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + pipeColumns + pipeJoins + `
+	WHERE` + explorable + ` AND ` + pageCondition + `
+	GROUP BY p.id, u.username
+	` + pageClauses
+
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pipes, pagination, err := collectPipePage(rows, o, filter)
+	if err != nil {
+		return pipes, pagination, err
+	}
+	return pipes, pagination, countSmartBookmarks(ctx, p.Db, pipes)
+}
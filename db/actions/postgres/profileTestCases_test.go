@@ -0,0 +1,89 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var updateProfileSettingsTestCases = map[string]struct {
+	inputUserId   int64
+	inputSettings models.ProfileSettings
+	wantErr       error
+}{
+	"success": {
+		inputUserId:   firstUserId,
+		inputSettings: models.ProfileSettings{PublicProfile: true, ShowInExplore: false},
+		wantErr:       nil,
+	},
+	"user not found": {
+		inputUserId:   100,
+		inputSettings: models.ProfileSettings{PublicProfile: true},
+		wantErr:       ErrNoRecord,
+	},
+}
+
+var getPublicProfileTestCases = map[string]struct {
+	inputUsername   string
+	inputPublic     bool
+	wantPublicPipes int
+	wantErr         error
+}{
+	"public profile": {
+		inputUsername:   "user1",
+		inputPublic:     true,
+		wantPublicPipes: 1,
+		wantErr:         nil,
+	},
+	"private profile": {
+		inputUsername: "user1",
+		inputPublic:   false,
+		wantErr:       ErrNoRecord,
+	},
+}
+
+var getPublicPipeTestCases = map[string]struct {
+	inputPipeId    int64
+	inputPublic    bool
+	wantErr        error
+	wantBookmarks  int
+	wantVisibility string
+}{
+	"public pipe of a public profile": {
+		inputPipeId:    1,
+		inputPublic:    true,
+		wantErr:        nil,
+		wantBookmarks:  1,
+		wantVisibility: models.PipeVisibilityPublic,
+	},
+	"public pipe of a private profile": {
+		inputPipeId: 1,
+		inputPublic: false,
+		wantErr:     ErrNoRecord,
+	},
+	"unlisted pipe of a private profile": {
+		inputPipeId:    2,
+		inputPublic:    false,
+		wantErr:        nil,
+		wantBookmarks:  1,
+		wantVisibility: models.PipeVisibilityUnlisted,
+	},
+}
+
+var explorePipesTestCases = map[string]struct {
+	inputSort     string
+	inputSettings models.ProfileSettings
+	wantIds       []int64
+}{
+	"recent": {
+		inputSort:     models.ExploreSortRecent,
+		inputSettings: models.ProfileSettings{PublicProfile: true, ShowInExplore: true},
+		wantIds:       []int64{1},
+	},
+	"popular": {
+		inputSort:     models.ExploreSortPopular,
+		inputSettings: models.ProfileSettings{PublicProfile: true, ShowInExplore: true},
+		wantIds:       []int64{1},
+	},
+	"hidden from explore": {
+		inputSort:     models.ExploreSortRecent,
+		inputSettings: models.ProfileSettings{PublicProfile: true, ShowInExplore: false},
+		wantIds:       nil,
+	},
+}
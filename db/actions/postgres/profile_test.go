@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// setPipeVisibility makes the first pipe of the first user public and their second pipe unlisted
+func setPipeVisibility(t *testing.T, db *sql.DB) {
+	t.Helper()
+	pa := NewPipeActions(db, logger)
+	for pipeId, visibility := range map[int64]string{1: models.PipeVisibilityPublic, 2: models.PipeVisibilityUnlisted} {
+		pipe, err := pa.GetPipe(pipeId, firstUserId)
+		assert.NoError(t, err)
+		pipe.Visibility = visibility
+		_, err = pa.UpdatePipe(firstUserId, firstUserId, pipeId, pipe)
+		assert.NoError(t, err)
+	}
+}
+
+func Test_user_UpdateProfileSettings(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := updateProfileSettingsTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ua := NewUserActions(db, logger)
+			gotSettings, gotErr := ua.UpdateProfileSettings(tc.inputUserId, tc.inputSettings)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputSettings, gotSettings)
+				gotSettings, gotErr = ua.GetProfileSettings(tc.inputUserId)
+				assert.NoError(t, gotErr)
+				assert.Equal(t, tc.inputSettings, gotSettings)
+			}
+		})
+	}
+}
+
+func Test_user_GetPublicProfile(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getPublicProfileTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ua := NewUserActions(db, logger)
+			setPipeVisibility(t, db)
+			_, err := ua.UpdateProfileSettings(firstUserId, models.ProfileSettings{PublicProfile: tc.inputPublic})
+			assert.NoError(t, err)
+
+			gotProfile, gotErr := ua.GetPublicProfile(tc.inputUsername)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputUsername, gotProfile.Username)
+				assert.Equal(t, tc.wantPublicPipes, gotProfile.PublicPipes)
+			}
+		})
+	}
+}
+
+func Test_pipe_GetPublicPipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ua := NewUserActions(db, logger)
+	pa := NewPipeActions(db, logger)
+	setPipeVisibility(t, db)
+
+	gotPipes, _, gotErr := pa.GetPublicPipes(firstUserId, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Empty(t, gotPipes)
+
+	_, err := ua.UpdateProfileSettings(firstUserId, models.ProfileSettings{PublicProfile: true})
+	assert.NoError(t, err)
+	gotPipes, _, gotErr = pa.GetPublicPipes(firstUserId, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotPipes, 1)
+	assert.Equal(t, int64(1), gotPipes[0].ID)
+}
+
+func Test_pipe_GetPublicPipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getPublicPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ua := NewUserActions(db, logger)
+			pa := NewPipeActions(db, logger)
+			setPipeVisibility(t, db)
+			_, err := ua.UpdateProfileSettings(firstUserId, models.ProfileSettings{PublicProfile: tc.inputPublic})
+			assert.NoError(t, err)
+
+			gotPipe, gotErr := pa.GetPublicPipe(firstUserId, tc.inputPipeId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantVisibility, gotPipe.Pipe.Visibility)
+				assert.Len(t, gotPipe.Bookmarks, tc.wantBookmarks)
+			}
+		})
+	}
+}
+
+func Test_pipe_ExplorePipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := explorePipesTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ua := NewUserActions(db, logger)
+			pa := NewPipeActions(db, logger)
+			setPipeVisibility(t, db)
+			_, err := ua.UpdateProfileSettings(firstUserId, tc.inputSettings)
+			assert.NoError(t, err)
+
+			gotPipes, _, gotErr := pa.ExplorePipes(tc.inputSort, models.Filter{})
+			assert.NoError(t, gotErr)
+
+			var gotIds []int64
+			for _, pipe := range gotPipes {
+				gotIds = append(gotIds, pipe.ID)
+			}
+			assert.Equal(t, tc.wantIds, gotIds)
+		})
+	}
+}
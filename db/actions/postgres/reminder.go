@@ -0,0 +1,280 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const reminderColumns = `id, user_id, bookmark_id, note, remind_at, status, sent_at, snooze_count, created_at, modified_at`
+
+type reminderActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewReminderActions(db *sql.DB, logger zerolog.Logger) repository.ReminderRepository {
+	return reminderActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+// scanReminder reads a row selected with reminderColumns into a reminder
+func scanReminder(row rowScanner) (models.Reminder, error) {
+	var reminder models.Reminder
+	err := row.Scan(
+		&reminder.ID,
+		&reminder.UserID,
+		&reminder.BookmarkID,
+		&reminder.Note,
+		&reminder.RemindAt,
+		&reminder.Status,
+		&reminder.SentAt,
+		&reminder.SnoozeCount,
+		&reminder.CreatedAt,
+		&reminder.ModifiedAt,
+	)
+	return reminder, err
+}
+
+// CreateReminder schedules a reminder on a bookmark for a user
+func (r reminderActions) CreateReminder(reminder models.Reminder) (models.Reminder, error) {
+	query := `
+	INSERT INTO bookmark_reminders
+	    (user_id, bookmark_id, note, remind_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + reminderColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	newReminder, err := scanReminder(r.Db.QueryRowContext(ctx, query, reminder.UserID, reminder.BookmarkID, reminder.Note, reminder.RemindAt))
+	if err != nil {
+		return models.Reminder{}, err
+	}
+	return newReminder, nil
+}
+
+// GetReminder retrieves a single reminder by ID and a designated user
+func (r reminderActions) GetReminder(reminderID, userID int64) (models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM bookmark_reminders WHERE id=$1 AND user_id=$2 LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	reminder, err := scanReminder(r.Db.QueryRowContext(ctx, query, reminderID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Reminder{}, ErrNoRecord
+		}
+		return models.Reminder{}, err
+	}
+	return reminder, nil
+}
+
+// GetReminders retrieves the reminders of a user that are yet to be sent, the closest one first
+func (r reminderActions) GetReminders(userID int64) ([]models.Reminder, error) {
+	query := `
+	SELECT ` + reminderColumns + `
+	FROM bookmark_reminders
+	WHERE user_id=$1 AND status=$2
+	ORDER BY remind_at, id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := r.Db.QueryContext(ctx, query, userID, models.ReminderStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	return collectReminders(rows)
+}
+
+// ClaimDueReminders marks up to limit pending reminders that are due before the given time as sent
+// and returns them. Rows locked by another instance of the scheduler are skipped, so a reminder
+// is only ever claimed once. Reminders that can't be delivered must be released with ReleaseReminder.
+// Reminders on bookmarks in the trash are held back until they are restored
+func (r reminderActions) ClaimDueReminders(before time.Time, limit int) ([]models.Reminder, error) {
+	query := `
+	UPDATE bookmark_reminders
+	SET status=$3, sent_at=now(), modified_at=now()
+	WHERE id IN (
+	    SELECT id FROM bookmark_reminders
+	    WHERE status=$4 AND remind_at <= $1
+	        AND bookmark_id IN (SELECT id FROM bookmarks WHERE deleted_at IS NULL)
+	    ORDER BY remind_at
+	    LIMIT $2
+	    FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + reminderColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := r.Db.QueryContext(ctx, query, before, limit, models.ReminderStatusSent, models.ReminderStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	return collectReminders(rows)
+}
+
+// ReleaseReminder puts a claimed reminder that could not be delivered back in the pending state,
+// so that it's claimed again on the next run
+func (r reminderActions) ReleaseReminder(reminderID int64) error {
+	query := `UPDATE bookmark_reminders SET status=$2, sent_at=NULL, modified_at=now() WHERE id=$1 AND status=$3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := r.Db.ExecContext(ctx, query, reminderID, models.ReminderStatusPending, models.ReminderStatusSent)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// SnoozeReminder moves a reminder to a later time and puts it back in the pending state
+func (r reminderActions) SnoozeReminder(reminderID, userID int64, until time.Time) (models.Reminder, error) {
+	query := `
+	UPDATE bookmark_reminders
+	SET
+	    remind_at=$3,
+	    status=$4,
+	    sent_at=NULL,
+	    snooze_count=snooze_count+1,
+	    modified_at=now()
+	WHERE id=$1 AND user_id=$2
+	RETURNING ` + reminderColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	reminder, err := scanReminder(r.Db.QueryRowContext(ctx, query, reminderID, userID, until, models.ReminderStatusPending))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Reminder{}, ErrNoRecord
+		}
+		return models.Reminder{}, err
+	}
+	return reminder, nil
+}
+
+// DeleteReminder removes a reminder that belongs to a user
+func (r reminderActions) DeleteReminder(reminderID, userID int64) (bool, error) {
+	query := `DELETE FROM bookmark_reminders WHERE id=$1 AND user_id=$2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := r.Db.ExecContext(ctx, query, reminderID, userID)
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return false, ErrNoRecord
+	}
+	return true, nil
+}
+
+// UpdateRediscoverSetting turns the daily rediscover mode on or off for a user
+func (r reminderActions) UpdateRediscoverSetting(userID int64, enabled bool) error {
+	query := `UPDATE users SET rediscover_enabled=$2, modified_at=now() WHERE id=$1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := r.Db.ExecContext(ctx, query, userID, enabled)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// ClaimRediscoveryUsers picks up to limit users with rediscover mode turned on who have not been sent
+// a rediscovered bookmark since the given time, and records that they are being sent one now
+func (r reminderActions) ClaimRediscoveryUsers(before time.Time, limit int) ([]int64, error) {
+	var userIDs []int64
+	query := `
+	UPDATE users
+	SET last_rediscovered_at=now()
+	WHERE id IN (
+	    SELECT id FROM users
+	    WHERE rediscover_enabled=true AND (last_rediscovered_at IS NULL OR last_rediscovered_at <= $1)
+	    LIMIT $2
+	    FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := r.Db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return userIDs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return userIDs, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return userIDs, err
+	}
+	return userIDs, nil
+}
+
+// GetRediscoverBookmark picks a random unread bookmark of a user that was saved before the given time
+func (r reminderActions) GetRediscoverBookmark(userID int64, createdBefore time.Time) (models.Bookmark, error) {
+	query := `
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
+	WHERE b.user_id=$1 AND b.is_read=false AND b.is_archived=false AND b.deleted_at IS NULL AND b.created_at <= $2
+	ORDER BY random()
+	LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	bookmark, err := scanBookmark(r.Db.QueryRowContext(ctx, query, userID, createdBefore))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Bookmark{}, ErrNoRecord
+		}
+		return models.Bookmark{}, err
+	}
+	return bookmark, nil
+}
+
+// collectReminders scans every row selected with reminderColumns
+func collectReminders(rows *sql.Rows) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	defer rows.Close()
+
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return reminders, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	if err := rows.Err(); err != nil {
+		return reminders, err
+	}
+	return reminders, nil
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+var createReminderTestCases = map[string]struct {
+	inputReminder models.Reminder
+	wantReminder  models.Reminder
+	wantErr       error
+}{
+	"success": {
+		inputReminder: models.Reminder{
+			UserID:     1,
+			BookmarkID: 1,
+			Note:       "Read on saturday",
+			RemindAt:   time.Now().Add(72 * time.Hour),
+		},
+		wantReminder: models.Reminder{
+			ID:         3,
+			UserID:     1,
+			BookmarkID: 1,
+			Note:       "Read on saturday",
+			Status:     models.ReminderStatusPending,
+		},
+		wantErr: nil,
+	},
+}
+
+var getRemindersTestCases = map[string]struct {
+	inputUserId   int64
+	wantReminders []models.Reminder
+	wantErr       error
+}{
+	"success": {
+		inputUserId: 1,
+		wantReminders: []models.Reminder{
+			{ID: 1, BookmarkID: 1},
+			{ID: 2, BookmarkID: 2},
+		},
+		wantErr: nil,
+	},
+	"no reminders": {
+		inputUserId:   2,
+		wantReminders: []models.Reminder{},
+		wantErr:       nil,
+	},
+}
+
+var claimDueRemindersTestCases = map[string]struct {
+	inputBefore   time.Time
+	wantReminders []models.Reminder
+	wantErr       error
+}{
+	"only due reminders are claimed": {
+		inputBefore: time.Now(),
+		wantReminders: []models.Reminder{
+			{ID: 1, BookmarkID: 1, Status: models.ReminderStatusSent},
+		},
+		wantErr: nil,
+	},
+}
+
+var snoozeReminderTestCases = map[string]struct {
+	inputReminderId int64
+	inputUserId     int64
+	inputUntil      time.Time
+	wantReminder    models.Reminder
+	wantErr         error
+}{
+	"success": {
+		inputReminderId: 1,
+		inputUserId:     1,
+		inputUntil:      time.Now().Add(24 * time.Hour),
+		wantReminder:    models.Reminder{ID: 1, Status: models.ReminderStatusPending, SnoozeCount: 1},
+		wantErr:         nil,
+	},
+	"reminder belongs to another user": {
+		inputReminderId: 1,
+		inputUserId:     2,
+		inputUntil:      time.Now().Add(24 * time.Hour),
+		wantReminder:    models.Reminder{},
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var deleteReminderTestCases = map[string]struct {
+	inputReminderId int64
+	inputUserId     int64
+	wantResponse    bool
+	wantErr         error
+}{
+	"success": {
+		inputReminderId: 2,
+		inputUserId:     1,
+		wantResponse:    true,
+		wantErr:         nil,
+	},
+	"invalid reminder id": {
+		inputReminderId: 200,
+		inputUserId:     1,
+		wantResponse:    false,
+		wantErr:         ErrNoRecord,
+	},
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_reminder_CreateReminder(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := createReminderTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ra := NewReminderActions(db, logger)
+			gotReminder, gotErr := ra.CreateReminder(tc.inputReminder)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantReminder.ID, gotReminder.ID)
+				assert.Equal(t, tc.wantReminder.Note, gotReminder.Note)
+				assert.Equal(t, tc.wantReminder.Status, gotReminder.Status)
+				assert.WithinDuration(t, tc.inputReminder.RemindAt, gotReminder.RemindAt, time.Second)
+			}
+		})
+	}
+}
+
+func Test_reminder_GetReminders(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getRemindersTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ra := NewReminderActions(db, logger)
+			gotReminders, gotErr := ra.GetReminders(tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, len(tc.wantReminders), len(gotReminders))
+				for i, reminder := range gotReminders {
+					assert.Equal(t, tc.wantReminders[i].ID, reminder.ID)
+				}
+			}
+		})
+	}
+}
+
+func Test_reminder_ClaimDueReminders(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := claimDueRemindersTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ra := NewReminderActions(db, logger)
+			gotReminders, gotErr := ra.ClaimDueReminders(tc.inputBefore, 10)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, len(tc.wantReminders), len(gotReminders))
+				for i, reminder := range gotReminders {
+					assert.Equal(t, tc.wantReminders[i].ID, reminder.ID)
+					assert.Equal(t, tc.wantReminders[i].Status, reminder.Status)
+					assert.NotNil(t, reminder.SentAt)
+				}
+
+				// a reminder can only be claimed once
+				gotReminders, gotErr = ra.ClaimDueReminders(tc.inputBefore, 10)
+				assert.Nil(t, gotErr)
+				assert.Equal(t, 0, len(gotReminders))
+			}
+		})
+	}
+}
+
+func Test_reminder_ReleaseReminder(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ra := NewReminderActions(db, logger)
+
+	// only claimed reminders can be released
+	assert.Equal(t, ErrNoRecord, ra.ReleaseReminder(1))
+
+	gotReminders, err := ra.ClaimDueReminders(time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(gotReminders))
+	assert.NoError(t, ra.ReleaseReminder(gotReminders[0].ID))
+
+	// a released reminder is claimed again
+	gotReminders, err = ra.ClaimDueReminders(time.Now(), 10)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(gotReminders)) {
+		assert.Equal(t, int64(1), gotReminders[0].ID)
+	}
+}
+
+func Test_reminder_SnoozeReminder(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := snoozeReminderTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ra := NewReminderActions(db, logger)
+			gotReminder, gotErr := ra.SnoozeReminder(tc.inputReminderId, tc.inputUserId, tc.inputUntil)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.wantReminder.ID, gotReminder.ID)
+				assert.Equal(t, tc.wantReminder.Status, gotReminder.Status)
+				assert.Equal(t, tc.wantReminder.SnoozeCount, gotReminder.SnoozeCount)
+				assert.WithinDuration(t, tc.inputUntil, gotReminder.RemindAt, time.Second)
+			}
+		})
+	}
+}
+
+func Test_reminder_DeleteReminder(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := deleteReminderTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ra := NewReminderActions(db, logger)
+			gotResponse, gotErr := ra.DeleteReminder(tc.inputReminderId, tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+			assert.Equal(t, tc.wantResponse, gotResponse)
+		})
+	}
+}
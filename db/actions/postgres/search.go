@@ -18,126 +18,72 @@ func NewSearchActions(db *sql.DB, logger zerolog.Logger) repository.SearchReposi
 	return searchActions{Db: db, Logger: logger}
 }
 
-func (s searchActions) SearchThroughPipes(name string, userId int64) ([]models.Pipe, error) {
+// SearchThroughPipes retrieves a page of the pipes of a user whose name contains the search term
+func (s searchActions) SearchThroughPipes(name string, userId int64, filter models.Filter) ([]models.Pipe, models.Pagination, error) {
+	o := pipeOrdering(models.SortNewest)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := o.page(ctx, s.Db, filter, []interface{}{userId, name})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
 	query := `
-	SELECT p.id, p.name, p.cover_photo, p.created_at, p.modified_at, p.user_id, COUNT(b.pipe_id) AS total_bookmarks, u.username
-	FROM pipes p
-		LEFT JOIN bookmarks b ON p.id=b.pipe_id
-		LEFT JOIN users u ON p.user_id=u.id
+	SELECT` + pipeColumns + pipeJoins + `
 	WHERE 
 	    p.user_id=$1
+	    AND p.deleted_at IS NULL
 	    AND p.name ILIKE '%' || $2 || '%'
+	    AND ` + pageCondition + `
 	GROUP BY p.id, u.username
-	ORDER BY p.id
-    `
-
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	` + pageClauses
 
-	rows, err := s.Db.QueryContext(ctx, query, userId, name)
+	rows, err := s.Db.QueryContext(ctx, query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return []models.Pipe{}, ErrNoRecord
-		}
-		return []models.Pipe{}, err
+		return nil, models.Pagination{}, err
 	}
+	return collectPipePage(rows, o, filter)
+}
 
-	var pipes []models.Pipe
-	for rows.Next() {
-		pipe := models.Pipe{}
-		_ = rows.Scan(
-			&pipe.ID,
-			&pipe.Name,
-			&pipe.CoverPhoto,
-			&pipe.CreatedAt,
-			&pipe.ModifiedAt,
-			&pipe.UserID,
-			&pipe.Bookmarks,
-			&pipe.Creator,
-		)
-		pipes = append(pipes, pipe)
-	}
-	return pipes, nil
+// SearchThroughTags retrieves a page of the bookmarks of a user that have a tag containing the search term,
+// or a tag under such a tag
+func (s searchActions) SearchThroughTags(name string, userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error) {
+	return s.searchBookmarks(taggedUnder(`t.user_id=$1 AND t.name ILIKE '%' || $2 || '%'`), name, userId, filter)
 }
 
-func (s searchActions) SearchThroughTags(name string, userId int64) ([]models.Bookmark, error) {
-	query := `
-	SELECT
-    	bt.bookmark_id, b.user_id, b.pipe_id, b.platform, b.url, b.created_at
-	FROM bookmark_tag bt
-		INNER JOIN bookmarks b on b.id = bt.bookmark_id
-		INNER JOIN tags t on bt.tag_id = t.id
-    WHERE
-        b.user_id = $1
-        AND b.id = bt.bookmark_id
-        AND t.name ILIKE '%' || $2 || '%'
-    `
+// SearchThroughPlatform retrieves a page of the bookmarks of a user whose platform contains the search term
+func (s searchActions) SearchThroughPlatform(name string, userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error) {
+	return s.searchBookmarks(`b.platform ILIKE '%' || $2 || '%'`, name, userId, filter)
+}
+
+// searchBookmarks retrieves a page of the bookmarks of a user matching condition, which
+// refers to the search term as $2. The most recent bookmarks come first
+func (s searchActions) searchBookmarks(condition, name string, userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error) {
+	o := bookmarkOrdering(models.SortNewest)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
-	rows, err := s.Db.QueryContext(ctx, query, userId, name)
+
+	pageCondition, pageClauses, args, err := o.page(ctx, s.Db, filter, []interface{}{userId, name})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return []models.Bookmark{}, ErrNoRecord
-		}
-		return []models.Bookmark{}, err
+		return nil, models.Pagination{}, err
 	}
-	ba := NewBookmarkActions(s.Db, s.Logger)
-	bookmarks := make([]models.Bookmark, 0)
-	for rows.Next() {
-		bookmark := models.Bookmark{}
-		_ = rows.Scan(
-			&bookmark.ID,
-			&bookmark.UserID,
-			&bookmark.PipeID,
-			&bookmark.Platform,
-			&bookmark.Url,
-			&bookmark.CreatedAt,
-		)
-		bookmark, _ = ba.ParseTags(bookmark)
-		bookmarks = append(bookmarks, bookmark)
-	}
-
-	return bookmarks, nil
-}
-
-func (s searchActions) SearchThroughPlatform(name string, userId int64) ([]models.Bookmark, error) {
 	query := `
-	SELECT
-    	bt.bookmark_id, b.user_id, b.pipe_id, b.platform, b.url, b.created_at
-	FROM bookmark_tag bt
-		INNER JOIN bookmarks b on b.id = bt.bookmark_id
-		INNER JOIN tags t on bt.tag_id = t.id
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
     WHERE
         b.user_id = $1
-        AND b.id = bt.bookmark_id
-        AND b.platform ILIKE '%' || $2 || '%'
-    `
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-	rows, err := s.Db.QueryContext(ctx, query, userId, name)
+        AND b.deleted_at IS NULL
+        AND ` + condition + `
+        AND ` + pageCondition + `
+    ` + pageClauses
+
+	rows, err := s.Db.QueryContext(ctx, query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return []models.Bookmark{}, ErrNoRecord
-		}
-		return []models.Bookmark{}, err
-	}
-	ba := NewBookmarkActions(s.Db, s.Logger)
-	bookmarks := make([]models.Bookmark, 0)
-	for rows.Next() {
-		bookmark := models.Bookmark{}
-		_ = rows.Scan(
-			&bookmark.ID,
-			&bookmark.UserID,
-			&bookmark.PipeID,
-			&bookmark.Platform,
-			&bookmark.Url,
-			&bookmark.CreatedAt,
-		)
-		bookmark, _ = ba.ParseTags(bookmark)
-		bookmarks = append(bookmarks, bookmark)
+		return nil, models.Pagination{}, err
 	}
-
-	return bookmarks, nil
+	return bookmarkActions{Db: s.Db, Logger: s.Logger}.collectBookmarkPage(rows, o, filter)
 }
 
 func (s searchActions) SearchAll(name string, userId int64) ([]interface{}, error) {
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+var recordShareLinkEventTestCases = map[string]struct {
+	inputEvent     models.ShareLinkEvent
+	wantClientType string
+	wantErr        error
+}{
+	"view": {
+		inputEvent:     models.ShareLinkEvent{SharedPipeID: 2, PipeID: 2, Code: "MG78k9lig68", Event: models.ShareEventView, ClientType: models.ClientTypeWeb},
+		wantClientType: models.ClientTypeWeb,
+		wantErr:        nil,
+	},
+	"unknown client type": {
+		inputEvent:     models.ShareLinkEvent{SharedPipeID: 2, PipeID: 2, Code: "MG78k9lig68", Event: models.ShareEventRedemption},
+		wantClientType: models.ClientTypeOther,
+		wantErr:        nil,
+	},
+}
+
+var getShareLinkStatsTestCases = map[string]struct {
+	inputPipeId     int64
+	inputFrom       time.Time
+	inputTo         time.Time
+	wantDays        int
+	wantTotal       models.ShareLinkCounts
+	wantReferrers   int
+	wantClientTypes int
+}{
+	"today": {
+		inputPipeId:     2,
+		inputFrom:       time.Now(),
+		inputTo:         time.Now(),
+		wantDays:        1,
+		wantTotal:       models.ShareLinkCounts{Views: 2, Redemptions: 1, Clicks: 1},
+		wantReferrers:   2,
+		wantClientTypes: 2,
+	},
+	"last week": {
+		inputPipeId:     2,
+		inputFrom:       time.Now().AddDate(0, 0, -7),
+		inputTo:         time.Now().AddDate(0, 0, -1),
+		wantDays:        7,
+		wantTotal:       models.ShareLinkCounts{},
+		wantReferrers:   0,
+		wantClientTypes: 0,
+	},
+	"another pipe": {
+		inputPipeId:     1,
+		inputFrom:       time.Now(),
+		inputTo:         time.Now(),
+		wantDays:        1,
+		wantTotal:       models.ShareLinkCounts{},
+		wantReferrers:   0,
+		wantClientTypes: 0,
+	},
+}
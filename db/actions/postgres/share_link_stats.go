@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+// shareLinkCounts counts the events of the share_link_events table aliased as e
+const shareLinkCounts = `
+	COUNT(e.id) FILTER (WHERE e.event='` + models.ShareEventView + `'),
+	COUNT(e.id) FILTER (WHERE e.event='` + models.ShareEventRedemption + `'),
+	COUNT(e.id) FILTER (WHERE e.event='` + models.ShareEventClick + `')`
+
+// RecordShareLinkEvent records something that happened to a pipe shared with a code
+func (p pipeShareActions) RecordShareLinkEvent(event models.ShareLinkEvent) (models.ShareLinkEvent, error) {
+	query := `
+	INSERT INTO share_link_events
+	    (shared_pipe_id, pipe_id, code, event, bookmark_id, referrer, client_type)
+	VALUES ($1, $2, $3, $4, $5, $6, COALESCE(NULLIF($7, ''), 'other'))
+	RETURNING id, client_type, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := p.Db.QueryRowContext(ctx, query,
+		event.SharedPipeID,
+		event.PipeID,
+		event.Code,
+		event.Event,
+		event.BookmarkID,
+		event.Referrer,
+		event.ClientType,
+	).Scan(&event.ID, &event.ClientType, &event.CreatedAt)
+	if err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+// GetShareLinkStats aggregates the events of every code a pipe was shared with from the day of from
+// to the day of to, both included. Days are UTC days, and days without events are counted as well
+func (p pipeShareActions) GetShareLinkStats(pipeId int64, from, to time.Time) (models.ShareLinkStats, error) {
+	stats := models.ShareLinkStats{
+		From:      utcDay(from),
+		To:        utcDay(to),
+		Daily:     []models.ShareLinkDailyStats{},
+		Referrers: []models.ShareLinkSourceStats{},
+		Clients:   []models.ShareLinkSourceStats{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	query := `
+	SELECT d.day,` + shareLinkCounts + `
+	FROM generate_series($2::timestamp, $3::timestamp, interval '1 day') AS d(day)
+	    LEFT JOIN share_link_events e ON e.pipe_id=$1
+	        AND e.created_at >= d.day AT TIME ZONE 'UTC'
+	        AND e.created_at < (d.day + interval '1 day') AT TIME ZONE 'UTC'
+	GROUP BY d.day
+	ORDER BY d.day
+	`
+	rows, err := p.Db.QueryContext(ctx, query, pipeId, stats.From, stats.To)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day models.ShareLinkDailyStats
+		if err := rows.Scan(&day.Day, &day.Views, &day.Redemptions, &day.Clicks); err != nil {
+			return stats, err
+		}
+		day.Day = utcDay(day.Day)
+		stats.Total.Views += day.Views
+		stats.Total.Redemptions += day.Redemptions
+		stats.Total.Clicks += day.Clicks
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	if stats.Referrers, err = p.shareLinkSourceStats(ctx, "referrer", pipeId, stats.From, stats.To); err != nil {
+		return stats, err
+	}
+	if stats.Clients, err = p.shareLinkSourceStats(ctx, "client_type", pipeId, stats.From, stats.To); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// shareLinkSourceStats counts the events of a pipe's share codes in a range of UTC days by the value
+// of column, from the most frequent one
+func (p pipeShareActions) shareLinkSourceStats(ctx context.Context, column string, pipeId int64, from, to time.Time) ([]models.ShareLinkSourceStats, error) {
+	query := `
+	SELECT e.` + column + `,` + shareLinkCounts + `
+	FROM share_link_events e
+	WHERE e.pipe_id=$1
+	    AND e.created_at >= $2::timestamp AT TIME ZONE 'UTC'
+	    AND e.created_at < ($3::timestamp + interval '1 day') AT TIME ZONE 'UTC'
+	GROUP BY e.` + column + `
+	ORDER BY COUNT(e.id) DESC, e.` + column + `
+	`
+	rows, err := p.Db.QueryContext(ctx, query, pipeId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []models.ShareLinkSourceStats{}
+	for rows.Next() {
+		var source models.ShareLinkSourceStats
+		if err := rows.Scan(&source.Source, &source.Views, &source.Redemptions, &source.Clicks); err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+// utcDay returns the start of the UTC day of t
+func utcDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
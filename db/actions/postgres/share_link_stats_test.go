@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_pipe_share_RecordShareLinkEvent(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := recordShareLinkEventTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			gotEvent, gotErr := psa.RecordShareLinkEvent(tc.inputEvent)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.NotZero(t, gotEvent.ID)
+				assert.Equal(t, tc.wantClientType, gotEvent.ClientType)
+			}
+		})
+	}
+}
+
+func Test_pipe_share_GetShareLinkStats(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getShareLinkStatsTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			psa := NewPipeShareActions(db, logger)
+			bookmarkId := int64(2)
+			events := []models.ShareLinkEvent{
+				{Event: models.ShareEventView, Referrer: "twitter.com", ClientType: models.ClientTypeWeb},
+				{Event: models.ShareEventView, ClientType: models.ClientTypeIOS},
+				{Event: models.ShareEventRedemption, ClientType: models.ClientTypeIOS},
+				{Event: models.ShareEventClick, BookmarkID: &bookmarkId, Referrer: "twitter.com", ClientType: models.ClientTypeWeb},
+			}
+			for _, event := range events {
+				event.SharedPipeID, event.PipeID, event.Code = 2, 2, "MG78k9lig68"
+				_, err := psa.RecordShareLinkEvent(event)
+				assert.NoError(t, err)
+			}
+
+			gotStats, gotErr := psa.GetShareLinkStats(tc.inputPipeId, tc.inputFrom, tc.inputTo)
+			assert.NoError(t, gotErr)
+			assert.Len(t, gotStats.Daily, tc.wantDays)
+			assert.Equal(t, tc.wantTotal, gotStats.Total)
+			assert.Len(t, gotStats.Referrers, tc.wantReferrers)
+			assert.Len(t, gotStats.Clients, tc.wantClientTypes)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var smartPipeTestCases = map[string]struct {
+	inputQuery    models.SmartQuery
+	inputUserId   int64
+	wantBookmarks int
+}{
+	"bookmarks with a tag": {
+		inputQuery:    models.SmartQuery{Tags: []string{"quick blows"}},
+		inputUserId:   1,
+		wantBookmarks: 2,
+	},
+	"bookmarks from a platform": {
+		inputQuery:    models.SmartQuery{Platforms: []string{"TikTok"}},
+		inputUserId:   1,
+		wantBookmarks: 1,
+	},
+	"bookmarks with a tag in a source pipe": {
+		inputQuery:    models.SmartQuery{Tags: []string{"quick blows"}, PipeIDs: []int64{firstPipeId}},
+		inputUserId:   1,
+		wantBookmarks: 1,
+	},
+	"bookmarks from the last days": {
+		inputQuery:    models.SmartQuery{LastDays: 30},
+		inputUserId:   1,
+		wantBookmarks: 2,
+	},
+	"bookmarks in a state": {
+		inputQuery:    models.SmartQuery{State: models.BookmarkStateStarred},
+		inputUserId:   1,
+		wantBookmarks: 0,
+	},
+	"bookmarks of another user are left out": {
+		inputQuery:    models.SmartQuery{Platforms: []string{"tiktok"}},
+		inputUserId:   2,
+		wantBookmarks: 1,
+	},
+}
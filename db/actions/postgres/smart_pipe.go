@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"strings"
+)
+
+// pipeSource describes where the bookmarks of a pipe come from: the bookmarks filed in the pipe
+// for a standard pipe, or the bookmarks of its owner matching its query for a smart pipe
+type pipeSource struct {
+	ownerID  int64
+	pipeType string
+	query    *models.SmartQuery
+}
+
+// getPipeSource retrieves the source of a pipe the user owns or has accepted from another user
+func getPipeSource(ctx context.Context, db sqlExecutor, userID, pipeID int64) (pipeSource, error) {
+	var source pipeSource
+	var query []byte
+	err := db.QueryRowContext(ctx, `
+	SELECT p.user_id, p.type, p.query
+	FROM pipes p
+	WHERE p.id=$2 AND p.deleted_at IS NULL AND (
+	    p.user_id=$1 OR p.id IN (`+receivedPipes+`)
+	)
+	`, userID, pipeID).Scan(&source.ownerID, &source.pipeType, &query)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return pipeSource{}, ErrNoRecord
+		}
+		return pipeSource{}, err
+	}
+	source.query, err = parseSmartQuery(query)
+	return source, err
+}
+
+// condition returns the SQL condition that matches the bookmarks of the pipe in the given state.
+// It expects the bookmarks table to be aliased as b, refers to the pipe as pipeParam
+// and appends the other values it uses to args
+func (s pipeSource) condition(pipeParam, state string, args []interface{}) (string, []interface{}) {
+	if s.pipeType != models.PipeTypeSmart || s.query == nil {
+		return "b.pipe_id=" + pipeParam + " AND " + bookmarkStateCondition(state), args
+	}
+	if state == "" {
+		state = s.query.State
+	}
+	args = append(args, s.ownerID)
+	condition := fmt.Sprintf("b.user_id=$%d AND %s", len(args), bookmarkStateCondition(state))
+	queryCondition, args := smartQueryCondition(*s.query, args)
+	return condition + " AND " + queryCondition, args
+}
+
+// smartQueryCondition returns the SQL condition that matches the bookmarks selected by every criterion
+// of a smart pipe query but its state. It expects the bookmarks table to be aliased as b and appends
+// the values it uses to args
+func smartQueryCondition(q models.SmartQuery, args []interface{}) (string, []interface{}) {
+	conditions := []string{"true"}
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Tags) > 0 {
+		tags := make([]string, len(q.Tags))
+		for i, tag := range q.Tags {
+			tags[i] = models.TagKey(tag)
+		}
+		conditions = append(conditions, taggedUnder(tagKey("t.name")+" = ANY("+param(pq.Array(tags))+")"))
+	}
+	if len(q.Platforms) > 0 {
+		platforms := make([]string, len(q.Platforms))
+		for i, platform := range q.Platforms {
+			platforms[i] = strings.ToLower(strings.TrimSpace(platform))
+		}
+		conditions = append(conditions, "lower(b.platform) = ANY("+param(pq.Array(platforms))+")")
+	}
+	if len(q.PipeIDs) > 0 {
+		conditions = append(conditions, "b.pipe_id = ANY("+param(pq.Array(q.PipeIDs))+")")
+	}
+	if q.After != nil {
+		conditions = append(conditions, "b.created_at >= "+param(*q.After))
+	}
+	if q.Before != nil {
+		conditions = append(conditions, "b.created_at < "+param(*q.Before))
+	}
+	if q.LastDays > 0 {
+		conditions = append(conditions, "b.created_at >= now() - make_interval(days => "+param(q.LastDays)+")")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// countSmartBookmarks sets the bookmark counts of the smart pipes among pipes to the number
+// of bookmarks matching their query. Smart pipes hold no bookmarks of their own, so the counts
+// selected with pipeColumns only cover the pipes nested in them
+func countSmartBookmarks(ctx context.Context, db sqlExecutor, pipes []models.Pipe) error {
+	for i := range pipes {
+		pipe := &pipes[i]
+		if err := countSmartBookmarks(ctx, db, pipe.Children); err != nil {
+			return err
+		}
+		if pipe.Type != models.PipeTypeSmart || pipe.Query == nil {
+			continue
+		}
+		source := pipeSource{ownerID: pipe.UserID, pipeType: pipe.Type, query: pipe.Query}
+		condition, args := source.condition("", "", nil)
+
+		var count int
+		err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM bookmarks b WHERE b.deleted_at IS NULL AND `+condition, args...).Scan(&count)
+		if err != nil {
+			return err
+		}
+		pipe.TreeBookmarks += count - pipe.Bookmarks
+		pipe.Bookmarks = count
+	}
+	return nil
+}
+
+// ensureStandardPipe checks that bookmarks can be filed in a pipe, which is
+// the case for every pipe but smart pipes
+func ensureStandardPipe(ctx context.Context, db sqlExecutor, pipeID int64) error {
+	var pipeType string
+	err := db.QueryRowContext(ctx, `SELECT type FROM pipes WHERE id=$1`, pipeID).Scan(&pipeType)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if pipeType == models.PipeTypeSmart {
+		return ErrSmartPipe
+	}
+	return nil
+}
+
+// smartQueryValue returns the value a smart pipe query is stored as
+func smartQueryValue(q *models.SmartQuery) (interface{}, error) {
+	if q == nil {
+		return nil, nil
+	}
+	value, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	return string(value), nil
+}
+
+// parseSmartQuery reads a stored smart pipe query
+func parseSmartQuery(value []byte) (*models.SmartQuery, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var q models.SmartQuery
+	if err := json.Unmarshal(value, &q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
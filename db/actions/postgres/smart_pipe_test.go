@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_smartPipe_smartQueryCondition(t *testing.T) {
+	query := models.SmartQuery{Tags: []string{" GoLang "}, Platforms: []string{"YouTube"}, LastDays: 30}
+	gotCondition, gotArgs := smartQueryCondition(query, []interface{}{int64(1)})
+
+	assert.Contains(t, gotCondition, tagKey("t.name")+" = ANY($2)")
+	assert.Contains(t, gotCondition, "lower(b.platform) = ANY($3)")
+	assert.Contains(t, gotCondition, "make_interval(days => $4)")
+	assert.NotContains(t, gotCondition, "b.pipe_id")
+	assert.Equal(t, []interface{}{int64(1), pq.Array([]string{"golang"}), pq.Array([]string{"youtube"}), 30}, gotArgs)
+}
+
+func Test_smartPipe_GetBookmarks(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := smartPipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			ba := NewBookmarkActions(db, logger)
+			query := tc.inputQuery
+			pipe, err := pa.CreatePipe(models.Pipe{UserID: tc.inputUserId, Name: "smart", Type: models.PipeTypeSmart, Query: &query})
+			assert.Nil(t, err)
+
+			gotBookmarks, _, gotErr := ba.GetBookmarks(tc.inputUserId, pipe.ID, models.BookmarkFilter{})
+			assert.Nil(t, gotErr)
+			assert.Equal(t, tc.wantBookmarks, len(gotBookmarks))
+
+			gotPipe, gotErr := pa.GetPipe(pipe.ID, tc.inputUserId)
+			assert.Nil(t, gotErr)
+			assert.Equal(t, tc.wantBookmarks, gotPipe.Bookmarks)
+			assert.Equal(t, query, *gotPipe.Query)
+		})
+	}
+}
+
+func Test_smartPipe_CreateBookmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	pipe, err := pa.CreatePipe(models.Pipe{UserID: 1, Name: "smart", Type: models.PipeTypeSmart, Query: &models.SmartQuery{LastDays: 7}})
+	assert.Nil(t, err)
+
+	_, err = ba.CreateBookmark(models.Bookmark{UserID: 1, PipeID: pipe.ID, Url: "https://youtu.be/7", Platform: "youtube"})
+	assert.Equal(t, ErrSmartPipe, err)
+
+	_, err = ba.UpdateBookmark(1, 1, 1, models.BookmarkUpdate{PipeID: &pipe.ID})
+	assert.Equal(t, ErrSmartPipe, err)
+}
@@ -3,12 +3,98 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"github.com/lib/pq"
 	"github.com/mypipeapp/mypipeapi/db/models"
 	"github.com/mypipeapp/mypipeapi/db/repository"
 	"github.com/rs/zerolog"
+	"strings"
 	"time"
 )
 
+// tagUsage is the number of bookmarks out of the trash that a tag aliased as t is on
+const tagUsage = `(
+	SELECT COUNT(*) FROM bookmark_tag ut
+	    INNER JOIN bookmarks ub ON ub.id=ut.bookmark_id
+	WHERE ut.tag_id=t.id AND ub.deleted_at IS NULL
+)`
+
+const tagColumns = ` t.id, t.user_id, t.parent_id, t.name, ` + tagUsage + `, t.created_at, t.modified_at`
+
+// tagSubtree selects the ids of the tags of the user in $1 with one of the ids in $2 and of the tags under them,
+// however deep
+const tagSubtree = `
+WITH RECURSIVE subtree AS (
+    SELECT id FROM tags WHERE user_id=$1 AND id = ANY($2)
+    UNION
+    SELECT c.id FROM tags c INNER JOIN subtree s ON c.parent_id=s.id
+)`
+
+// tagUseWeight weighs the use of a tag on a bookmark, aliased as bt, by how recent it is. A tag put on
+// a bookmark today counts as 1 and half as much after 30 days
+const tagUseWeight = `(1.0 / (1 + EXTRACT(EPOCH FROM now()-bt.created_at) / 2592000))`
+
+// tagFrecency ranks a tag aliased as t by how often and how recently it was put on bookmarks out of the trash
+const tagFrecency = `(
+	SELECT COALESCE(SUM(` + tagUseWeight + `), 0) FROM bookmark_tag bt
+	    INNER JOIN bookmarks fb ON fb.id=bt.bookmark_id
+	WHERE bt.tag_id=t.id AND fb.deleted_at IS NULL
+)`
+
+// bookmarkHost is the host of the url of a bookmark aliased as b, without www
+const bookmarkHost = `lower(substring(b.url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:www\.)?([^/:?#]+)'))`
+
+// bookmarkSection is the first segment of the path of the url of a bookmark aliased as b
+const bookmarkSection = `substring(b.url from '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]+/([^/?#]+)')`
+
+// tagKey returns the expression of the key of the tag name expr, which is what models.TagKey returns for it
+func tagKey(expr string) string {
+	return `lower(regexp_replace(` + expr + `, '\s', '', 'g'))`
+}
+
+// taggedUnder returns the condition matching the bookmarks, aliased as b, that have a tag matching
+// tagCondition, which refers to the tags table as t, or a tag under such a tag
+func taggedUnder(tagCondition string) string {
+	return `b.id IN (
+	    WITH RECURSIVE matched AS (
+	        SELECT t.id FROM tags t WHERE ` + tagCondition + `
+	        UNION
+	        SELECT c.id FROM tags c INNER JOIN matched m ON c.parent_id=m.id
+	    )
+	    SELECT bt.bookmark_id FROM bookmark_tag bt WHERE bt.tag_id IN (SELECT id FROM matched)
+	)`
+}
+
+// tagWriteError returns the error of writing the name of a tag. It's ErrRecordExists when the user already
+// has a tag with the name and ErrTagNameTooLong when the name is longer than a name can be
+func tagWriteError(err error) error {
+	if dbErr, ok := err.(*pq.Error); ok {
+		switch dbErr.Code {
+		case "23505":
+			return ErrRecordExists
+		case "22001":
+			return ErrTagNameTooLong
+		}
+	}
+	return err
+}
+
+// likePattern escapes the characters LIKE gives a meaning to in s
+func likePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// tagOrdering returns the ordering of the tags of a user for a sort option
+func tagOrdering(sort string) ordering {
+	o := ordering{name: "tags-" + sort, table: "tags t"}
+	if sort == models.TagSortUsage {
+		o.keys = []sortKey{{expr: tagUsage, desc: true}, {expr: "t.id", desc: true}}
+	} else {
+		o.name = "tags-" + models.TagSortName
+		o.keys = []sortKey{{expr: "lower(t.name)"}, {expr: "t.id"}}
+	}
+	return o
+}
+
 type tagActions struct {
 	Db     *sql.DB
 	Logger zerolog.Logger
@@ -18,104 +104,756 @@ func NewTagActions(db *sql.DB, logger zerolog.Logger) repository.TagRepository {
 	return tagActions{Db: db, Logger: logger}
 }
 
+func scanTag(row rowScanner) (models.Tag, error) {
+	var tag models.Tag
+	err := row.Scan(
+		&tag.Id,
+		&tag.UserID,
+		&tag.ParentID,
+		&tag.Name,
+		&tag.Usage,
+		&tag.CreatedAt,
+		&tag.ModifiedAt,
+	)
+	return tag, err
+}
+
+// CreateTag creates a tag of a user. A name like lang/go puts the tag under the tag with the name before
+// its last slash, which is created along with the tags above it when the user doesn't have it. It returns
+// ErrRecordExists when the user already has a tag with the name, ignoring case and whitespace
 func (t tagActions) CreateTag(tag models.Tag) (models.Tag, error) {
-	var createdTag models.Tag
-	query := `
-	INSERT INTO tags (name)
-	VALUES ($1)
-	RETURNING id, name, created_at, modified_at
-	`
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
 
-	err := t.Db.QueryRow(query, tag.Name).Scan(
-		&createdTag.Id,
-		&createdTag.Name,
-		&createdTag.CreatedAt,
-		&createdTag.ModifiedAt,
-	)
+	tx, err := t.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return tag, err
 	}
-	return createdTag, nil
+	defer tx.Rollback()
+
+	parent := models.TagParent(tag.Name)
+	if parent != "" {
+		if err := createTagPaths(ctx, tx, tag.UserID, []string{parent}); err != nil {
+			return tag, tagWriteError(err)
+		}
+	}
+	query := `
+	INSERT INTO tags (user_id, name, parent_id)
+	SELECT $1, COALESCE(p.name || '/', '') || $3, p.id
+	FROM (VALUES ($2::text)) AS n(parent)
+	    LEFT JOIN tags p ON p.user_id=$1 AND ` + tagKey("p.name") + `=` + tagKey("n.parent") + `
+	RETURNING id
+	`
+	var tagId int64
+	if err := tx.QueryRowContext(ctx, query, tag.UserID, parent, models.TagLeaf(tag.Name)).Scan(&tagId); err != nil {
+		return tag, tagWriteError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return tag, err
+	}
+	return t.GetTag(tagId, tag.UserID)
 }
 
-func (t tagActions) GetTag(tagId int64) (models.Tag, error) {
-	var tag models.Tag
+// GetTag retrieves a tag of a user
+func (t tagActions) GetTag(tagId, userId int64) (models.Tag, error) {
 	query := `
-	SELECT id, name, created_at, modified_at 
-	FROM tags
-	WHERE id=$1
-    `
+	SELECT` + tagColumns + `
+	FROM tags t
+	WHERE t.id=$1 AND t.user_id=$2
+	`
 
-	err := t.Db.QueryRow(query, tagId).Scan(
-		&tag.Id,
-		&tag.Name,
-		&tag.CreatedAt,
-		&tag.ModifiedAt,
-	)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tag, err := scanTag(t.Db.QueryRowContext(ctx, query, tagId, userId))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return tag, ErrNoRecord
 		}
 		return tag, err
 	}
-
 	return tag, nil
 }
 
-func (t tagActions) GetTagByName(name string) (models.Tag, error) {
-	var tag models.Tag
+// GetTagByName retrieves the tag of a user with a name, ignoring case and whitespace
+func (t tagActions) GetTagByName(userId int64, name string) (models.Tag, error) {
 	query := `
-	SELECT id, name, created_at, modified_at 
-	FROM tags
-	WHERE name=$1
-	LIMIT 1
-    `
+	SELECT` + tagColumns + `
+	FROM tags t
+	WHERE t.user_id=$1 AND ` + tagKey("t.name") + `=$2
+	`
 
-	err := t.Db.QueryRow(query, name).Scan(
-		&tag.Id,
-		&tag.Name,
-		&tag.CreatedAt,
-		&tag.ModifiedAt,
-	)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tag, err := scanTag(t.Db.QueryRowContext(ctx, query, userId, models.TagKey(name)))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return tag, ErrNoRecord
 		}
 		return tag, err
 	}
-
 	return tag, nil
 }
 
-func (t tagActions) AddTagsToBookmark(bmId int64, tags []models.Tag) error {
-	// This call assumes the bookmark exists already
+// GetTags retrieves a page of the tags of a user along with the number of bookmarks they are on,
+// either by name or from the most used
+func (t tagActions) GetTags(userId int64, sort string, filter models.Filter) ([]models.Tag, models.Pagination, error) {
+	o := tagOrdering(sort)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := o.page(ctx, t.Db, filter, []interface{}{userId})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query := `
+	SELECT` + tagColumns + `
+	FROM tags t
+	WHERE t.user_id=$1 AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := t.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	tags, err := collectTags(rows)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := o.pagination(filter, len(tags), func(i int) int64 { return tags[i].Id })
+	return tags[:size], pagination, nil
+}
+
+// GetTagTree retrieves every tag of a user along with the number of bookmarks they are on, as a tree.
+// The tags that aren't under another tag are listed with the tags right under them as their children,
+// and so on. Tags with the same parent are sorted by name
+func (t tagActions) GetTagTree(userId int64) ([]models.Tag, error) {
+	query := `
+	SELECT` + tagColumns + `
+	FROM tags t
+	WHERE t.user_id=$1
+	ORDER BY lower(t.name), t.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := t.Db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := collectTags(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []models.Tag
+	children := map[int64][]models.Tag{}
 	for _, tag := range tags {
-		eTag, err := t.GetTagByName(tag.Name)
-		if err != nil {
-			// if there's not existing tag, create one
-			if err == ErrNoRecord {
-				eTag, err = t.CreateTag(tag)
-				if err != nil {
-					t.Logger.Err(err).Msg("an error occurred while creating new tag")
-				}
+		if tag.ParentID == nil {
+			roots = append(roots, tag)
+		} else {
+			children[*tag.ParentID] = append(children[*tag.ParentID], tag)
+		}
+	}
+	return tagTree(roots, children), nil
+}
+
+// tagTree puts the tags in children under each of tags, and under each of their own children, however deep
+func tagTree(tags []models.Tag, children map[int64][]models.Tag) []models.Tag {
+	for i := range tags {
+		tags[i].Children = tagTree(children[tags[i].Id], children)
+	}
+	return tags
+}
+
+// RenameTag renames a tag of a user, which stays under the same tag. The tags under it are renamed along
+// with it, in the queries of the smart pipes of the user as well. It returns ErrRecordExists when the user
+// already has another tag with the new name, ignoring case and whitespace, which the tag can be merged into instead.
+// The change of the tags of the bookmarks and of the smart pipes is recorded in the history of the user
+func (t tagActions) RenameTag(tagId, userId int64, name string) (models.Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := t.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	err = retagBookmarks(ctx, tx, userId, []int64{tagId}, func() error {
+		return retagSmartPipes(ctx, tx, userId, []int64{tagId}, 0, func() error {
+			query := `
+			UPDATE tags t
+			SET name=COALESCE((SELECT p.name FROM tags p WHERE p.id=t.parent_id) || '/', '') || $3, modified_at=now()
+			WHERE t.id=$1 AND t.user_id=$2
+			`
+			result, err := tx.ExecContext(ctx, query, tagId, userId, models.TagLeaf(name))
+			if err != nil {
+				return tagWriteError(err)
 			}
+			if affected, err := result.RowsAffected(); err != nil {
+				return err
+			} else if affected == 0 {
+				return ErrNoRecord
+			}
+			return tagWriteError(repathTags(ctx, tx, tagId))
+		})
+	})
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Tag{}, err
+	}
+	return t.GetTag(tagId, userId)
+}
+
+// MoveTag puts a tag of a user under another of their tags, or at the top when parentId is nil. The tag
+// and the tags under it are renamed after their new parent, in the queries of the smart pipes of the user
+// as well. It returns ErrNoRecord when the user doesn't
+// have either tag, ErrTagCycle when the parent is the tag itself or a tag under it and ErrRecordExists
+// when the user already has a tag with the name the tag would get. The change of the tags of the
+// bookmarks and of the smart pipes is recorded in the history of the user
+func (t tagActions) MoveTag(tagId, userId int64, parentId *int64) (models.Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
 
-			if err != ErrNoRecord {
-				t.Logger.Err(err).Msg("some weird type of error")
+	tx, err := t.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRowContext(ctx, `SELECT name FROM tags WHERE id=$1 AND user_id=$2 FOR UPDATE`, tagId, userId).Scan(&name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tag{}, ErrNoRecord
+		}
+		return models.Tag{}, err
+	}
+	path := models.TagLeaf(name)
+	if parentId != nil {
+		var parentPath string
+		query := `SELECT name FROM tags WHERE id=$1 AND user_id=$2`
+		if err := tx.QueryRowContext(ctx, query, *parentId, userId).Scan(&parentPath); err != nil {
+			if err == sql.ErrNoRows {
+				return models.Tag{}, ErrNoRecord
 			}
+			return models.Tag{}, err
+		}
+		under, err := tagUnder(ctx, tx, *parentId, []int64{tagId})
+		if err != nil {
+			return models.Tag{}, err
+		}
+		if under {
+			return models.Tag{}, ErrTagCycle
 		}
-		query := `
-		INSERT INTO bookmark_tag (bookmark_id, tag_id)
-		VALUES ($1, $2)
-		RETURNING id, bookmark_id, tag_id, created_at, modified_at
+		path = parentPath + models.TagSeparator + path
+	}
+
+	err = retagBookmarks(ctx, tx, userId, []int64{tagId}, func() error {
+		return retagSmartPipes(ctx, tx, userId, []int64{tagId}, 0, func() error {
+			query := `UPDATE tags SET parent_id=$2, name=$3, modified_at=now() WHERE id=$1`
+			if _, err := tx.ExecContext(ctx, query, tagId, parentId, path); err != nil {
+				return tagWriteError(err)
+			}
+			return tagWriteError(repathTags(ctx, tx, tagId))
+		})
+	})
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Tag{}, err
+	}
+	return t.GetTag(tagId, userId)
+}
+
+// tagUnder reports whether the tag with the given id is one of the tags with the ids in ancestorIds or is
+// under one of them, however deep
+func tagUnder(ctx context.Context, db sqlExecutor, tagId int64, ancestorIds []int64) (bool, error) {
+	query := `
+	WITH RECURSIVE ancestors AS (
+	    SELECT id, parent_id FROM tags WHERE id=$1
+	    UNION
+	    SELECT a.id, a.parent_id FROM tags a INNER JOIN ancestors d ON a.id=d.parent_id
+	)
+	SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = ANY($2))
+	`
+	var under bool
+	err := db.QueryRowContext(ctx, query, tagId, pq.Array(ancestorIds)).Scan(&under)
+	return under, err
+}
+
+// repathTags renames the tags under the tag with the given id, however deep, after the name of their parent
+func repathTags(ctx context.Context, db sqlExecutor, tagId int64) error {
+	query := `
+	WITH RECURSIVE paths AS (
+	    SELECT id, name::text AS path FROM tags WHERE id=$1
+	    UNION ALL
+	    SELECT c.id, p.path || '/' || substring(c.name from '[^/]+$')
+	    FROM tags c
+	        INNER JOIN paths p ON c.parent_id=p.id
+	)
+	UPDATE tags t
+	SET name=p.path, modified_at=now()
+	FROM paths p
+	WHERE t.id=p.id AND t.name<>p.path
+	`
+	_, err := db.ExecContext(ctx, query, tagId)
+	return err
+}
+
+// MergeTags puts the target tag on the bookmarks of the source tags and deletes the source tags.
+// The tags under the source tags are moved under the target. Every tag must belong to the user, and the
+// source tags must not repeat or include the target. It returns ErrTagCycle when the target is under one
+// of the source tags and ErrRecordExists when a tag moved under the target clashes with one already there.
+// Smart pipes of the user looking for a source tag look for the target instead. The change of the tags of
+// the bookmarks and of the smart pipes is recorded in the history of the user
+func (t tagActions) MergeTags(userId, targetId int64, sourceIds []int64) (models.Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := t.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	var owned int
+	tagIds := append([]int64{targetId}, sourceIds...)
+	query := `SELECT COUNT(*) FROM tags WHERE user_id=$1 AND id = ANY($2)`
+	if err := tx.QueryRowContext(ctx, query, userId, pq.Array(tagIds)).Scan(&owned); err != nil {
+		return models.Tag{}, err
+	}
+	if owned != len(tagIds) {
+		return models.Tag{}, ErrNoRecord
+	}
+	under, err := tagUnder(ctx, tx, targetId, sourceIds)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if under {
+		return models.Tag{}, ErrTagCycle
+	}
+
+	err = retagBookmarks(ctx, tx, userId, sourceIds, func() error {
+		// a bookmark with several of the source tags gets the target once, and the rest of its source
+		// tags go away with the source tags
+		repointQuery := `
+		UPDATE bookmark_tag bt
+		SET tag_id=$1, modified_at=now()
+		WHERE bt.id IN (
+		    SELECT MIN(st.id) FROM bookmark_tag st
+		    WHERE st.tag_id = ANY($2)
+		        AND NOT EXISTS (SELECT 1 FROM bookmark_tag kt WHERE kt.bookmark_id=st.bookmark_id AND kt.tag_id=$1)
+		    GROUP BY st.bookmark_id
+		)
 		`
+		if _, err := tx.ExecContext(ctx, repointQuery, targetId, pq.Array(sourceIds)); err != nil {
+			return err
+		}
+		reparentQuery := `UPDATE tags SET parent_id=$1, modified_at=now() WHERE parent_id = ANY($2)`
+		if _, err := tx.ExecContext(ctx, reparentQuery, targetId, pq.Array(sourceIds)); err != nil {
+			return err
+		}
+		return retagSmartPipes(ctx, tx, userId, sourceIds, targetId, func() error {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ANY($1)`, pq.Array(sourceIds)); err != nil {
+				return err
+			}
+			return tagWriteError(repathTags(ctx, tx, targetId))
+		})
+	})
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tags SET modified_at=now() WHERE id=$1`, targetId); err != nil {
+		return models.Tag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Tag{}, err
+	}
+	return t.GetTag(targetId, userId)
+}
+
+// DeleteTag deletes a tag of a user, along with the tags under it, and takes them off their bookmarks and
+// out of the queries of the smart pipes of the user. The change of the tags of the bookmarks and of the
+// smart pipes is recorded in the history of the user
+func (t tagActions) DeleteTag(tagId, userId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := t.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = retagBookmarks(ctx, tx, userId, []int64{tagId}, func() error {
+		return retagSmartPipes(ctx, tx, userId, []int64{tagId}, 0, func() error {
+			result, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id=$1 AND user_id=$2`, tagId, userId)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil {
+				return err
+			} else if affected == 0 {
+				return ErrNoRecord
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddTagsToBookmark puts tags on a bookmark, next to the tags it already has. The tags belong to
+// the owner of the bookmark, and the ones they don't have yet are created
+func (t tagActions) AddTagsToBookmark(bmId int64, tags []models.Tag) error {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	return attachBookmarkTags(ctx, t.Db, bmId, names)
+}
+
+// attachBookmarkTags puts the tags with the given names on a bookmark. Names are normalized and matched
+// with the tags of the owner of the bookmark by key, and the tags its owner doesn't have yet are created
+// along with the tags they are under
+func attachBookmarkTags(ctx context.Context, db sqlExecutor, bmID int64, tags []string) error {
+	var names []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		name := models.NormalizeTagName(tag)
+		if name == "" || seen[models.TagKey(name)] {
+			continue
+		}
+		seen[models.TagKey(name)] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var userID int64
+	if err := db.QueryRowContext(ctx, `SELECT user_id FROM bookmarks WHERE id=$1`, bmID).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoRecord
+		}
+		return err
+	}
+	if err := createTagPaths(ctx, db, userID, names); err != nil {
+		return err
+	}
+
+	attachQuery := `
+	INSERT INTO bookmark_tag (bookmark_id, tag_id)
+	SELECT b.id, t.id
+	FROM bookmarks b
+	    INNER JOIN tags t ON t.user_id=b.user_id
+	WHERE b.id=$1 AND ` + tagKey("t.name") + ` = ANY(SELECT ` + tagKey("n.name") + ` FROM unnest($2::text[]) AS n(name))
+	ON CONFLICT (bookmark_id, tag_id) DO NOTHING
+	`
+	_, err := db.ExecContext(ctx, attachQuery, bmID, pq.Array(names))
+	return err
+}
+
+// createTagPaths creates the tags of a user with the given normalized names, along with the tags they are under,
+// unless the user already has them. Tags are created a level at a time, so that each level finds its parents
+func createTagPaths(ctx context.Context, db sqlExecutor, userID int64, names []string) error {
+	var parents, leaves [][]string
+	seen := map[string]bool{}
+	for _, name := range names {
+		segments := strings.Split(name, models.TagSeparator)
+		for depth := range segments {
+			path := strings.Join(segments[:depth+1], models.TagSeparator)
+			if seen[models.TagKey(path)] {
+				continue
+			}
+			seen[models.TagKey(path)] = true
+			for len(leaves) <= depth {
+				parents = append(parents, nil)
+				leaves = append(leaves, nil)
+			}
+			parents[depth] = append(parents[depth], strings.Join(segments[:depth], models.TagSeparator))
+			leaves[depth] = append(leaves[depth], segments[depth])
+		}
+	}
+
+	query := `
+	INSERT INTO tags (user_id, name, parent_id)
+	SELECT $1, COALESCE(p.name || '/', '') || n.leaf, p.id
+	FROM unnest($2::text[], $3::text[]) AS n(parent, leaf)
+	    LEFT JOIN tags p ON p.user_id=$1 AND ` + tagKey("p.name") + `=` + tagKey("n.parent") + `
+	ON CONFLICT (user_id, ` + tagKey("name") + `) DO NOTHING
+	`
+	for depth := range leaves {
+		if _, err := db.ExecContext(ctx, query, userID, pq.Array(parents[depth]), pq.Array(leaves[depth])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AutocompleteTags retrieves the tags of a user that match what they typed so far, ignoring case and whitespace.
+// Tags starting with it come first, then the ones containing it and then the ones containing its characters
+// in order. Tags matching the same way are ranked by how often and how recently the user put them on bookmarks
+func (t tagActions) AutocompleteTags(userId int64, typed string, limit int) ([]models.Tag, error) {
+	key := models.TagKey(typed)
+	fuzzy := "%"
+	for _, r := range key {
+		fuzzy += likePattern(string(r)) + "%"
+	}
+	query := `
+	SELECT` + tagColumns + `
+	FROM tags t
+	WHERE t.user_id=$1 AND ` + tagKey("t.name") + ` LIKE $2
+	ORDER BY
+	    CASE WHEN ` + tagKey("t.name") + ` LIKE $3 THEN 0 WHEN ` + tagKey("t.name") + ` LIKE $4 THEN 1 ELSE 2 END,
+	    ` + tagFrecency + ` DESC, lower(t.name), t.id
+	LIMIT $5
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := t.Db.QueryContext(ctx, query, userId, fuzzy, likePattern(key)+"%", "%"+likePattern(key)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectTags(rows)
+}
+
+// GetTagsOfSimilarLinks retrieves the tags a user put on their bookmarks of links on the same host as a url,
+// without www. Tags put on the same url count the most and tags put on links in the same section, the first
+// segment of the path, count more than the rest. Recent uses count more than old ones
+func (t tagActions) GetTagsOfSimilarLinks(userId int64, url, host, section string, limit int) ([]models.Tag, error) {
+	query := `
+	SELECT` + tagColumns + `
+	FROM tags t
+	    INNER JOIN (
+	        SELECT bt.tag_id, SUM(CASE WHEN b.url=$2 THEN 3 WHEN ` + bookmarkSection + `=NULLIF($4, '') THEN 2 ELSE 1 END * ` + tagUseWeight + `) AS score
+	        FROM bookmark_tag bt
+	            INNER JOIN bookmarks b ON b.id=bt.bookmark_id
+	        WHERE b.user_id=$1 AND b.deleted_at IS NULL AND ` + bookmarkHost + `=$3
+	        GROUP BY bt.tag_id
+	    ) s ON s.tag_id=t.id
+	ORDER BY s.score DESC, t.id
+	LIMIT $5
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-		defer cancel()
-		_, err = t.Db.ExecContext(ctx, query, bmId, eTag.Id)
+	rows, err := t.Db.QueryContext(ctx, query, userId, url, strings.ToLower(host), section, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectTags(rows)
+}
+
+// GetTagsByKeys retrieves the tags of a user with one of the given keys, the most often and recently used first
+func (t tagActions) GetTagsByKeys(userId int64, keys []string, limit int) ([]models.Tag, error) {
+	query := `
+	SELECT` + tagColumns + `
+	FROM tags t
+	WHERE t.user_id=$1 AND ` + tagKey("t.name") + ` = ANY($2)
+	ORDER BY ` + tagFrecency + ` DESC, t.id
+	LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := t.Db.QueryContext(ctx, query, userId, pq.Array(keys), limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectTags(rows)
+}
+
+// collectTags scans every row selected with tagColumns and closes rows
+func collectTags(rows *sql.Rows) ([]models.Tag, error) {
+	defer rows.Close()
+	var tags []models.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
 		if err != nil {
-			t.Logger.Err(err).Msg("an error occurred while creating new tag")
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// retagBookmarks runs change, which changes the tags of a user with the given ids and the tags under them,
+// and records how it changed the tags of the bookmarks they are on and the queries of the smart pipes of
+// the user so that it can be undone
+func retagBookmarks(ctx context.Context, tx *sql.Tx, userId int64, tagIds []int64, change func() error) error {
+	query := tagSubtree + `
+	SELECT DISTINCT bt.bookmark_id
+	FROM bookmark_tag bt
+	WHERE bt.tag_id IN (SELECT id FROM subtree)
+	`
+	rows, err := tx.QueryContext(ctx, query, userId, pq.Array(tagIds))
+	if err != nil {
+		return err
+	}
+	var bookmarkIds []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		bookmarkIds = append(bookmarkIds, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	history := newOperationLog(userId, models.HistoryActionRetag)
+	err = history.trackBookmarks(ctx, tx, "b.id = ANY($1)", []interface{}{pq.Array(bookmarkIds)}, func() error {
+		smartPipes := "p.user_id=$1 AND p.type='" + models.PipeTypeSmart + "'"
+		return history.trackPipes(ctx, tx, smartPipes, []interface{}{userId}, change)
+	})
+	if err != nil {
+		return err
+	}
+	return history.save(ctx, tx)
+}
+
+// retagSmartPipes runs change, which renames, moves or deletes the tags of a user with the given ids and the tags
+// under them, and puts the new names of the tags in the queries of the smart pipes of the user. The tags
+// that are gone are replaced with the tag with the id in replacementId, or taken out of the queries when
+// it's 0. A query that would be left without any criterion keeps the tags that are gone, so that it keeps
+// matching no bookmark instead of every bookmark
+func retagSmartPipes(ctx context.Context, tx *sql.Tx, userId int64, tagIds []int64, replacementId int64, change func() error) error {
+	query := tagSubtree + `
+	SELECT t.id, t.name FROM tags t WHERE t.id IN (SELECT id FROM subtree)
+	`
+	before, err := tagNames(ctx, tx, query, userId, pq.Array(tagIds))
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	after, err := tagNames(ctx, tx, `SELECT t.id, t.name FROM tags t WHERE t.id = ANY($1) OR t.id=$2`, pq.Array(ids), replacementId)
+	if err != nil {
+		return err
+	}
+	renames := make(map[string]string, len(before))
+	for id, name := range before {
+		newName, ok := after[id]
+		if !ok {
+			newName = after[replacementId]
+		}
+		if newName != name {
+			renames[models.TagKey(name)] = newName
+		}
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, query FROM pipes WHERE user_id=$1 AND type=$2 AND query IS NOT NULL FOR UPDATE`, userId, models.PipeTypeSmart)
+	if err != nil {
+		return err
+	}
+	queries := make(map[int64]*models.SmartQuery)
+	for rows.Next() {
+		var id int64
+		var value []byte
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if queries[id], err = parseSmartQuery(value); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, q := range queries {
+		tags, changed := retagQuery(q.Tags, renames)
+		if !changed {
+			continue
+		}
+		retagged := *q
+		retagged.Tags = tags
+		if retagged.IsEmpty() {
+			continue
+		}
+		value, err := smartQueryValue(&retagged)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE pipes SET query=$2 WHERE id=$1`, id, value); err != nil {
+			return err
 		}
 	}
 	return nil
 }
+
+// retagQuery returns the tags of a smart pipe query with the tags in renames, keyed by the key of their old
+// name, given their new name or taken out when it's empty. Tags that end up with the same key are kept once
+func retagQuery(tags []string, renames map[string]string) ([]string, bool) {
+	var retagged []string
+	changed := false
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if newName, ok := renames[models.TagKey(tag)]; ok {
+			tag, changed = newName, true
+		}
+		if tag == "" || seen[models.TagKey(tag)] {
+			continue
+		}
+		seen[models.TagKey(tag)] = true
+		retagged = append(retagged, tag)
+	}
+	return retagged, changed
+}
+
+// tagNames returns the names of the tags selected by query, which selects their id and name, keyed by id
+func tagNames(ctx context.Context, db sqlExecutor, query string, args ...interface{}) (map[int64]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+var getTagsTestCases = map[string]struct {
+	inputUserId int64
+	inputSort   string
+	wantNames   []string
+	wantUsage   []int
+}{
+	"by name": {
+		inputUserId: firstUserId,
+		inputSort:   models.TagSortName,
+		wantNames:   []string{"Beautiful Asian Muslim", "Quick Blows", "Twerk Videos"},
+		wantUsage:   []int{1, 2, 1},
+	},
+	"by usage": {
+		inputUserId: firstUserId,
+		inputSort:   models.TagSortUsage,
+		wantNames:   []string{"Quick Blows", "Twerk Videos", "Beautiful Asian Muslim"},
+		wantUsage:   []int{2, 1, 1},
+	},
+	"tags of another user": {
+		inputUserId: secondUserId,
+		inputSort:   "",
+		wantNames:   []string{"Beautiful Asian Muslim", "iOS 16 releases"},
+		wantUsage:   []int{2, 1},
+	},
+}
+
+var renameTagTestCases = map[string]struct {
+	inputTagId int64
+	inputName  string
+	wantErr    error
+}{
+	"success": {
+		inputTagId: 3,
+		inputName:  "Dance Videos",
+		wantErr:    nil,
+	},
+	"name of another tag": {
+		inputTagId: 3,
+		inputName:  "Quick Blows",
+		wantErr:    ErrRecordExists,
+	},
+	"tag of another user": {
+		inputTagId: 4,
+		inputName:  "Dance Videos",
+		wantErr:    ErrNoRecord,
+	},
+}
+
+var autocompleteTagsTestCases = map[string]struct {
+	inputTyped string
+	wantNames  []string
+}{
+	"prefix": {
+		inputTyped: "quick",
+		wantNames:  []string{"Quick Blows"},
+	},
+	"prefixes before other matches": {
+		inputTyped: "b",
+		wantNames:  []string{"Beautiful Asian Muslim", "Quick Blows"},
+	},
+	"case and whitespace": {
+		inputTyped: "QUICK  blo",
+		wantNames:  []string{"Quick Blows"},
+	},
+	"fuzzy": {
+		inputTyped: "tw vid",
+		wantNames:  []string{"Twerk Videos"},
+	},
+	"nothing typed": {
+		inputTyped: "",
+		wantNames:  []string{"Quick Blows", "Beautiful Asian Muslim", "Twerk Videos"},
+	},
+	"no match": {
+		inputTyped: "golang",
+		wantNames:  nil,
+	},
+}
+
+var getTagsOfSimilarLinksTestCases = map[string]struct {
+	inputUrl     string
+	inputHost    string
+	inputSection string
+	wantNames    []string
+}{
+	"same host": {
+		inputUrl:     "https://www.tiktok.com/@someone/video/1",
+		inputHost:    "tiktok.com",
+		inputSection: "@someone",
+		wantNames:    []string{"Quick Blows", "Twerk Videos"},
+	},
+	"another host": {
+		inputUrl:     "https://youtu.be/dQw4w9WgXcQ",
+		inputHost:    "youtu.be",
+		inputSection: "dQw4w9WgXcQ",
+		wantNames:    []string{"Beautiful Asian Muslim", "Quick Blows"},
+	},
+	"host without bookmarks": {
+		inputUrl:  "https://github.com",
+		inputHost: "github.com",
+		wantNames: nil,
+	},
+}
+
+var moveTagTestCases = map[string]struct {
+	inputTag    string
+	inputParent string
+	wantName    string
+	wantChild   string
+	wantErr     error
+}{
+	"under another tag": {
+		inputTag:    "lang",
+		inputParent: "Quick Blows",
+		wantName:    "Quick Blows/lang",
+		wantChild:   "Quick Blows/lang/go",
+		wantErr:     nil,
+	},
+	"to the top": {
+		inputTag: "lang/rust",
+		wantName: "Rust",
+		wantErr:  nil,
+	},
+	"under itself": {
+		inputTag:    "lang",
+		inputParent: "lang",
+		wantErr:     ErrTagCycle,
+	},
+	"under a tag under it": {
+		inputTag:    "lang",
+		inputParent: "lang/go",
+		wantErr:     ErrTagCycle,
+	},
+	"name of another tag": {
+		inputTag: "lang/go",
+		wantErr:  ErrRecordExists,
+	},
+}
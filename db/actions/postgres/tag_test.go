@@ -0,0 +1,408 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_tag_GetTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getTagsTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTagActions(db, logger)
+
+			gotTags, _, err := ta.GetTags(tc.inputUserId, tc.inputSort, models.Filter{})
+			assert.NoError(t, err)
+			var gotNames []string
+			var gotUsage []int
+			for _, tag := range gotTags {
+				assert.Equal(t, tc.inputUserId, tag.UserID)
+				gotNames = append(gotNames, tag.Name)
+				gotUsage = append(gotUsage, tag.Usage)
+			}
+			assert.Equal(t, tc.wantNames, gotNames)
+			assert.Equal(t, tc.wantUsage, gotUsage)
+		})
+	}
+}
+
+func Test_tag_RenameTag(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := renameTagTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTagActions(db, logger)
+
+			gotTag, gotErr := ta.RenameTag(tc.inputTagId, firstUserId, tc.inputName)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				assert.Equal(t, tc.inputName, gotTag.Name)
+				bookmark, err := NewBookmarkActions(db, logger).GetBookmark(2, firstUserId)
+				assert.NoError(t, err)
+				assert.ElementsMatch(t, []string{"Quick Blows", tc.inputName}, bookmark.Tags)
+			}
+		})
+	}
+}
+
+func Test_tag_MergeTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+
+	// tags of other users can't be merged
+	_, err := ta.MergeTags(firstUserId, 1, []int64{5})
+	assert.Equal(t, ErrNoRecord, err)
+
+	gotTag, err := ta.MergeTags(firstUserId, 1, []int64{2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, gotTag.Usage)
+	for _, tagId := range []int64{2, 3} {
+		_, err := ta.GetTag(tagId, firstUserId)
+		assert.Equal(t, ErrNoRecord, err)
+	}
+	for _, bmId := range []int64{1, 2} {
+		bookmark, err := ba.GetBookmark(bmId, firstUserId)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Beautiful Asian Muslim"}, bookmark.Tags)
+	}
+
+	// the merge can be undone from the history
+	operations, _, err := NewHistoryActions(db, logger).GetHistory(firstUserId, models.HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, operations, 1)
+	assert.Equal(t, models.HistoryActionRetag, operations[0].Action)
+}
+
+func Test_tag_DeleteTag(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+
+	assert.NoError(t, ta.DeleteTag(2, firstUserId))
+	assert.Equal(t, ErrNoRecord, ta.DeleteTag(2, firstUserId))
+	assert.Equal(t, ErrNoRecord, ta.DeleteTag(4, firstUserId))
+
+	bookmark, err := NewBookmarkActions(db, logger).GetBookmark(1, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Beautiful Asian Muslim"}, bookmark.Tags)
+}
+
+func Test_tag_smartPipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	pa := NewPipeActions(db, logger)
+	pipe, err := pa.CreatePipe(models.Pipe{UserID: firstUserId, Name: "smart", Type: models.PipeTypeSmart, Query: &models.SmartQuery{Tags: []string{"quick blows", "Twerk Videos"}}})
+	assert.NoError(t, err)
+	lonePipe, err := pa.CreatePipe(models.Pipe{UserID: firstUserId, Name: "lone", Type: models.PipeTypeSmart, Query: &models.SmartQuery{Tags: []string{"Beautiful Asian Muslim"}}})
+	assert.NoError(t, err)
+	queryTags := func(pipeId int64) []string {
+		pipe, err := pa.GetPipe(pipeId, firstUserId)
+		assert.NoError(t, err)
+		return pipe.Query.Tags
+	}
+
+	// smart pipes look for the new name of a renamed tag
+	_, err = ta.RenameTag(2, firstUserId, "Fast Blows")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Fast Blows", "Twerk Videos"}, queryTags(pipe.ID))
+	gotPipe, err := pa.GetPipe(pipe.ID, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, gotPipe.Bookmarks)
+
+	// and for the tag a tag is merged into
+	_, err = ta.MergeTags(firstUserId, 1, []int64{3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Fast Blows", "Beautiful Asian Muslim"}, queryTags(pipe.ID))
+
+	// deleted tags are taken out, unless the query would be left without any criterion
+	assert.NoError(t, ta.DeleteTag(2, firstUserId))
+	assert.Equal(t, []string{"Beautiful Asian Muslim"}, queryTags(pipe.ID))
+	assert.NoError(t, ta.DeleteTag(1, firstUserId))
+	assert.Equal(t, []string{"Beautiful Asian Muslim"}, queryTags(lonePipe.ID))
+
+	// undoing the deletion puts the tag back in the query
+	_, err = NewHistoryActions(db, logger).Undo(firstUserId, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Fast Blows", "Beautiful Asian Muslim"}, queryTags(pipe.ID))
+}
+
+func Test_tag_RenameTag_undo(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	pa := NewPipeActions(db, logger)
+	pipe, err := pa.CreatePipe(models.Pipe{UserID: firstUserId, Name: "smart", Type: models.PipeTypeSmart, Query: &models.SmartQuery{Tags: []string{"Quick Blows"}}})
+	assert.NoError(t, err)
+	_, err = ta.RenameTag(2, firstUserId, "Fast Blows")
+	assert.NoError(t, err)
+
+	// undoing the rename puts the old name back in the query and on the bookmarks
+	undone, err := NewHistoryActions(db, logger).Undo(firstUserId, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, undone, 1) {
+		assert.Equal(t, models.HistoryActionRetag, undone[0].Action)
+	}
+	gotPipe, err := pa.GetPipe(pipe.ID, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Quick Blows"}, gotPipe.Query.Tags)
+	assert.Equal(t, 2, gotPipe.Bookmarks)
+}
+
+func Test_tag_AddTagsToBookmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+
+	// tags are created for the owner of the bookmark, even when another user has a tag with the name
+	err := ta.AddTagsToBookmark(3, []models.Tag{{Name: "Quick Blows"}, {Name: "iOS 16 releases"}})
+	assert.NoError(t, err)
+	gotTag, err := ta.GetTagByName(secondUserId, "Quick Blows")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, gotTag.Usage)
+
+	bookmark, err := NewBookmarkActions(db, logger).GetBookmark(3, secondUserId)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"iOS 16 releases", "Beautiful Asian Muslim", "Quick Blows"}, bookmark.Tags)
+
+	// names that only differ by case or whitespace are the same tag
+	err = ta.AddTagsToBookmark(1, []models.Tag{{Name: " quick  BLOWS "}, {Name: "Go  Lang"}, {Name: "golang"}})
+	assert.NoError(t, err)
+	bookmark, err = NewBookmarkActions(db, logger).GetBookmark(1, firstUserId)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Beautiful Asian Muslim", "Quick Blows", "Go Lang"}, bookmark.Tags)
+	_, err = ta.CreateTag(models.Tag{UserID: firstUserId, Name: "GoLang"})
+	assert.Equal(t, ErrRecordExists, err)
+}
+
+func Test_tag_AutocompleteTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := autocompleteTagsTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTagActions(db, logger)
+
+			gotTags, err := ta.AutocompleteTags(firstUserId, tc.inputTyped, models.DefaultTagSuggestions)
+			assert.NoError(t, err)
+			var gotNames []string
+			for _, tag := range gotTags {
+				gotNames = append(gotNames, tag.Name)
+			}
+			assert.Equal(t, tc.wantNames, gotNames)
+		})
+	}
+}
+
+func Test_tag_GetTagsOfSimilarLinks(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := getTagsOfSimilarLinksTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTagActions(db, logger)
+
+			gotTags, err := ta.GetTagsOfSimilarLinks(firstUserId, tc.inputUrl, tc.inputHost, tc.inputSection, models.DefaultTagSuggestions)
+			assert.NoError(t, err)
+			var gotNames []string
+			for _, tag := range gotTags {
+				gotNames = append(gotNames, tag.Name)
+			}
+			assert.Equal(t, tc.wantNames, gotNames)
+		})
+	}
+}
+
+// addTagTree puts lang/go on the first bookmark and lang/rust on the second one, and gives the first user
+// a Go tag at the top
+func addTagTree(t *testing.T, ta repository.TagRepository) {
+	assert.NoError(t, ta.AddTagsToBookmark(1, []models.Tag{{Name: "lang/go"}}))
+	assert.NoError(t, ta.AddTagsToBookmark(2, []models.Tag{{Name: " Lang / Rust "}}))
+	_, err := ta.CreateTag(models.Tag{UserID: firstUserId, Name: "Go"})
+	assert.NoError(t, err)
+}
+
+func Test_tag_GetTagTree(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	addTagTree(t, ta)
+
+	gotTags, err := ta.GetTagTree(firstUserId)
+	assert.NoError(t, err)
+	var gotNames []string
+	for _, tag := range gotTags {
+		gotNames = append(gotNames, tag.Name)
+	}
+	assert.Equal(t, []string{"Beautiful Asian Muslim", "Go", "lang", "Quick Blows", "Twerk Videos"}, gotNames)
+
+	lang := gotTags[2]
+	assert.Nil(t, lang.ParentID)
+	assert.Equal(t, 0, lang.Usage)
+	if assert.Len(t, lang.Children, 2) {
+		assert.Equal(t, "lang/go", lang.Children[0].Name)
+		assert.Equal(t, "lang/Rust", lang.Children[1].Name)
+		assert.Equal(t, lang.Id, *lang.Children[1].ParentID)
+	}
+}
+
+func Test_tag_MoveTag(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := moveTagTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTagActions(db, logger)
+			addTagTree(t, ta)
+
+			tag, err := ta.GetTagByName(firstUserId, tc.inputTag)
+			assert.NoError(t, err)
+			var parentId *int64
+			if tc.inputParent != "" {
+				parent, err := ta.GetTagByName(firstUserId, tc.inputParent)
+				assert.NoError(t, err)
+				parentId = &parent.Id
+			}
+
+			gotTag, gotErr := ta.MoveTag(tag.Id, firstUserId, parentId)
+			assert.Equal(t, tc.wantErr, gotErr)
+			if nil == gotErr {
+				assert.Equal(t, tc.wantName, gotTag.Name)
+				assert.Equal(t, parentId, gotTag.ParentID)
+				if tc.wantChild != "" {
+					child, err := ta.GetTagByName(firstUserId, tc.wantChild)
+					assert.NoError(t, err)
+					assert.Equal(t, tag.Id, *child.ParentID)
+				}
+			}
+		})
+	}
+
+	// tags of other users can't be moved or moved under
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	parentId := int64(4)
+	_, err := ta.MoveTag(1, firstUserId, &parentId)
+	assert.Equal(t, ErrNoRecord, err)
+	_, err = ta.MoveTag(4, firstUserId, nil)
+	assert.Equal(t, ErrNoRecord, err)
+}
+
+func Test_tag_MoveTag_smartPipes(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	pa := NewPipeActions(db, logger)
+	addTagTree(t, ta)
+	pipe, err := pa.CreatePipe(models.Pipe{UserID: firstUserId, Name: "smart", Type: models.PipeTypeSmart, Query: &models.SmartQuery{Tags: []string{"lang/go", "lang/rust"}}})
+	assert.NoError(t, err)
+
+	// smart pipes look for the new names of the moved tag and of the tags under it
+	lang, err := ta.GetTagByName(firstUserId, "lang")
+	assert.NoError(t, err)
+	parentId := int64(2)
+	_, err = ta.MoveTag(lang.Id, firstUserId, &parentId)
+	assert.NoError(t, err)
+
+	gotPipe, err := pa.GetPipe(pipe.ID, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Quick Blows/lang/go", "Quick Blows/lang/Rust"}, gotPipe.Query.Tags)
+	assert.Equal(t, 2, gotPipe.Bookmarks)
+
+	// undoing the move puts the old names back in the query
+	undone, err := NewHistoryActions(db, logger).Undo(firstUserId, 1)
+	assert.NoError(t, err)
+	assert.Len(t, undone, 1)
+	gotPipe, err = pa.GetPipe(pipe.ID, firstUserId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lang/go", "lang/rust"}, gotPipe.Query.Tags)
+}
+
+func Test_tag_hierarchy(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTagActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	addTagTree(t, ta)
+
+	// bookmarks with a tag under the tag they are looked up by are found along with it
+	bookmarks, _, err := ba.GetBookmarksByState(firstUserId, models.BookmarkFilter{State: models.BookmarkStateAll, Tag: "LANG"})
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+	bookmarks, _, err = ba.GetBookmarksByState(firstUserId, models.BookmarkFilter{State: models.BookmarkStateAll, Tag: "lang/go"})
+	assert.NoError(t, err)
+	if assert.Len(t, bookmarks, 1) {
+		assert.Equal(t, int64(1), bookmarks[0].ID)
+	}
+	bookmarks, _, err = ba.GetBookmarks(firstUserId, 2, models.BookmarkFilter{State: models.BookmarkStateAll, Tag: "lang"})
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 1)
+
+	bookmarks, _, err = NewSearchActions(db, logger).SearchThroughTags("lan", firstUserId, models.Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+
+	// a merged tag leaves the tags under it to the tag it's merged into
+	lang, err := ta.GetTagByName(firstUserId, "lang")
+	assert.NoError(t, err)
+	_, err = ta.MergeTags(firstUserId, 2, []int64{lang.Id})
+	assert.NoError(t, err)
+	rust, err := ta.GetTagByName(firstUserId, "Quick Blows/rust")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *rust.ParentID)
+
+	// renaming a tag renames the tags under it and deleting it deletes them
+	_, err = ta.RenameTag(2, firstUserId, "Languages")
+	assert.NoError(t, err)
+	_, err = ta.GetTagByName(firstUserId, "Languages/go")
+	assert.NoError(t, err)
+	assert.NoError(t, ta.DeleteTag(2, firstUserId))
+	_, err = ta.GetTag(rust.Id, firstUserId)
+	assert.Equal(t, ErrNoRecord, err)
+}
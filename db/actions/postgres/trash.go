@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/mypipeapp/mypipeapi/db/repository"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+type trashActions struct {
+	Db     *sql.DB
+	Logger zerolog.Logger
+}
+
+func NewTrashActions(db *sql.DB, logger zerolog.Logger) repository.TrashRepository {
+	return trashActions{
+		Db:     db,
+		Logger: logger,
+	}
+}
+
+// GetTrashedPipes retrieves the pipes a user has moved to the trash, the most recently deleted first.
+// The bookmark count of each pipe is the number of bookmarks that will be restored along with it.
+// Pipes that went to the trash with the pipe they are nested in are only listed through that pipe
+func (t trashActions) GetTrashedPipes(userID int64) ([]models.Pipe, error) {
+	query := `
+	SELECT` + pipeColumns + `
+	FROM pipes p
+		LEFT JOIN bookmarks b ON p.id=b.pipe_id AND b.deleted_at=p.deleted_at
+		LEFT JOIN users u ON p.user_id=u.id
+	WHERE p.user_id=$1 AND p.deleted_at IS NOT NULL AND NOT EXISTS (
+	    SELECT 1 FROM pipes pp WHERE pp.id=p.parent_id AND pp.deleted_at=p.deleted_at
+	)
+	GROUP BY p.id, u.username
+	ORDER BY p.deleted_at DESC, p.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := t.Db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectPipes(rows)
+}
+
+// GetTrashedBookmarks retrieves the bookmarks a user has moved to the trash one by one.
+// Bookmarks that went to the trash with their pipe are only listed through the pipe
+func (t trashActions) GetTrashedBookmarks(userID int64) ([]models.Bookmark, error) {
+	query := `
+	SELECT` + bookmarkColumns + `
+	FROM bookmarks b
+		INNER JOIN pipes p ON p.id=b.pipe_id
+	WHERE b.user_id=$1 AND b.deleted_at IS NOT NULL AND p.deleted_at IS NULL
+	ORDER BY b.deleted_at DESC, b.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rows, err := t.Db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return bookmarkActions{Db: t.Db, Logger: t.Logger}.collectBookmarks(rows)
+}
+
+// RestorePipe takes a pipe out of the trash together with the bookmarks and nested pipes that were
+// deleted with it. Anything that was already in the trash before the pipe was deleted stays there.
+// A pipe whose parent is still in the trash is restored at the top level
+func (t trashActions) RestorePipe(pipeID, userID int64) (models.Pipe, error) {
+	query := `
+	WITH RECURSIVE trashed AS (
+	    SELECT id, deleted_at FROM pipes
+	    WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL
+	    UNION
+	    SELECT c.id, c.deleted_at FROM pipes c
+	        INNER JOIN trashed t ON c.parent_id=t.id
+	    WHERE c.deleted_at=t.deleted_at
+	), restored AS (
+	    UPDATE pipes p SET
+	        deleted_at=NULL,
+	        modified_at=now(),
+	        parent_id=CASE
+	            WHEN p.id=$1 AND EXISTS (SELECT 1 FROM pipes pp WHERE pp.id=p.parent_id AND pp.deleted_at IS NOT NULL) THEN NULL
+	            ELSE p.parent_id
+	        END
+	    FROM trashed
+	    WHERE p.id=trashed.id
+	    RETURNING p.id
+	), restored_bookmarks AS (
+	    UPDATE bookmarks b SET deleted_at=NULL
+	    FROM trashed
+	    WHERE b.pipe_id=trashed.id AND b.deleted_at=trashed.deleted_at
+	)
+	SELECT COUNT(*) FROM restored
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var restored int
+	err := t.Db.QueryRowContext(ctx, query, pipeID, userID).Scan(&restored)
+	if err != nil {
+		if dbErr, ok := err.(*pq.Error); ok {
+			if dbErr.Code == "23505" {
+				// a pipe with the same name was created while this one was in the trash
+				return models.Pipe{}, ErrRecordExists
+			}
+		}
+		return models.Pipe{}, err
+	}
+	if restored == 0 {
+		return models.Pipe{}, ErrNoRecord
+	}
+
+	return NewPipeActions(t.Db, t.Logger).GetPipe(pipeID, userID)
+}
+
+// RestoreBookmark takes a bookmark out of the trash. A bookmark can not be restored while its pipe
+// is in the trash; the pipe has to be restored instead
+func (t trashActions) RestoreBookmark(bmID, userID int64) (models.Bookmark, error) {
+	var pipeDeletedAt *time.Time
+	lookupQuery := `
+	SELECT p.deleted_at
+	FROM bookmarks b
+		INNER JOIN pipes p ON p.id=b.pipe_id
+	WHERE b.id=$1 AND b.user_id=$2 AND b.deleted_at IS NOT NULL
+	LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := t.Db.QueryRowContext(ctx, lookupQuery, bmID, userID).Scan(&pipeDeletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Bookmark{}, ErrNoRecord
+		}
+		return models.Bookmark{}, err
+	}
+	if pipeDeletedAt != nil {
+		return models.Bookmark{}, ErrPipeInTrash
+	}
+
+	query := `
+	UPDATE bookmarks b SET deleted_at=NULL
+	WHERE b.id=$1 AND b.user_id=$2 AND b.deleted_at IS NOT NULL
+	RETURNING` + bookmarkColumns
+
+	ba := bookmarkActions{Db: t.Db, Logger: t.Logger}
+	bookmark, err := scanBookmark(t.Db.QueryRowContext(ctx, query, bmID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Bookmark{}, ErrNoRecord
+		}
+		return models.Bookmark{}, err
+	}
+	bookmark, _ = ba.ParseTags(bookmark)
+	return bookmark, nil
+}
+
+// PurgePipe permanently removes a pipe in the trash along with its bookmarks, nested pipes and share records
+func (t trashActions) PurgePipe(pipeID, userID int64) (bool, error) {
+	purged, err := t.purge(`id IN (`+pipeSubtree+`) AND user_id=$2`, `false`, pipeID, userID)
+	if err != nil {
+		return false, err
+	}
+	if purged == 0 {
+		return false, ErrNoRecord
+	}
+	return true, nil
+}
+
+// PurgeBookmark permanently removes a bookmark in the trash
+func (t trashActions) PurgeBookmark(bmID, userID int64) (bool, error) {
+	purged, err := t.purge(`false`, `id=$1 AND user_id=$2`, bmID, userID)
+	if err != nil {
+		return false, err
+	}
+	if purged == 0 {
+		return false, ErrNoRecord
+	}
+	return true, nil
+}
+
+// EmptyTrash permanently removes every pipe and bookmark a user has in the trash and returns how many were removed
+func (t trashActions) EmptyTrash(userID int64) (int64, error) {
+	return t.purge(`user_id=$1`, `user_id=$1`, userID)
+}
+
+// PurgeExpired permanently removes every pipe and bookmark that was moved to the trash before the given time
+func (t trashActions) PurgeExpired(before time.Time) (int64, error) {
+	return t.purge(`deleted_at <= $1`, `deleted_at <= $1`, before)
+}
+
+// purge permanently removes the trashed pipes and bookmarks matching the given conditions and
+// returns the number of pipes and bookmarks removed. Bookmarks, tags and reminders of a purged
+// pipe go with it through the foreign keys, while its share records are removed here
+func (t trashActions) purge(pipeCondition, bookmarkCondition string, args ...interface{}) (int64, error) {
+	var purged int64
+	query := `
+	WITH purged_pipes AS (
+	    DELETE FROM pipes WHERE deleted_at IS NOT NULL AND ` + pipeCondition + `
+	    RETURNING id
+	), purged_shares AS (
+	    DELETE FROM shared_pipes WHERE pipe_id IN (SELECT id FROM purged_pipes)
+	), purged_receivers AS (
+	    DELETE FROM shared_pipe_receivers WHERE shared_pipe_id IN (SELECT id FROM purged_pipes)
+	), purged_bookmarks AS (
+	    DELETE FROM bookmarks WHERE deleted_at IS NOT NULL AND ` + bookmarkCondition + `
+	    RETURNING id
+	)
+	SELECT (SELECT COUNT(*) FROM purged_pipes) + (SELECT COUNT(*) FROM purged_bookmarks)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := t.Db.QueryRowContext(ctx, query, args...).Scan(&purged)
+	if err != nil {
+		t.Logger.Err(err).Msg("An error occurred while purging the trash")
+		return purged, err
+	}
+	return purged, nil
+}
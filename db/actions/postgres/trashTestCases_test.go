@@ -0,0 +1,67 @@
+package postgres
+
+var restorePipeTestCases = map[string]struct {
+	inputPipeId       int64
+	inputUserId       int64
+	wantPipeBookmarks int
+	wantErr           error
+}{
+	"success": {
+		inputPipeId:       1,
+		inputUserId:       1,
+		wantPipeBookmarks: 1,
+		wantErr:           nil,
+	},
+	"pipe belongs to another user": {
+		inputPipeId:       1,
+		inputUserId:       2,
+		wantPipeBookmarks: 0,
+		wantErr:           ErrNoRecord,
+	},
+}
+
+var restoreBookmarkTestCases = map[string]struct {
+	inputBookmarkId int64
+	inputUserId     int64
+	trashPipe       bool
+	wantErr         error
+}{
+	"success": {
+		inputBookmarkId: 1,
+		inputUserId:     1,
+		trashPipe:       false,
+		wantErr:         nil,
+	},
+	"pipe is in the trash": {
+		inputBookmarkId: 1,
+		inputUserId:     1,
+		trashPipe:       true,
+		wantErr:         ErrPipeInTrash,
+	},
+	"bookmark belongs to another user": {
+		inputBookmarkId: 1,
+		inputUserId:     2,
+		trashPipe:       false,
+		wantErr:         ErrNoRecord,
+	},
+}
+
+var purgePipeTestCases = map[string]struct {
+	inputPipeId  int64
+	inputUserId  int64
+	wantResponse bool
+	wantErr      error
+}{
+	"success": {
+		inputPipeId:  1,
+		inputUserId:  1,
+		wantResponse: true,
+		wantErr:      nil,
+	},
+	"pipe is not in the trash": {
+		inputPipeId:  2,
+		inputUserId:  1,
+		wantResponse: false,
+		wantErr:      ErrNoRecord,
+	},
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_trash_GetTrash(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	pa := NewPipeActions(db, logger)
+	ba := NewBookmarkActions(db, logger)
+	ta := NewTrashActions(db, logger)
+
+	_, err := pa.DeletePipe(1, 1)
+	assert.Nil(t, err)
+	_, err = ba.DeleteBookmark(2, 1)
+	assert.Nil(t, err)
+
+	pipes, err := ta.GetTrashedPipes(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pipes))
+	assert.Equal(t, int64(1), pipes[0].ID)
+	assert.Equal(t, 1, pipes[0].Bookmarks)
+	assert.NotNil(t, pipes[0].DeletedAt)
+
+	// bookmarks deleted along with their pipe are listed through the pipe
+	bookmarks, err := ta.GetTrashedBookmarks(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(bookmarks))
+	assert.Equal(t, int64(2), bookmarks[0].ID)
+
+	// trashed items are left out of lists and counts
+	activePipes, _, err := pa.GetPipes(1, models.PipeFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(activePipes))
+	bmCount, err := ba.GetBookmarksCount(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, bmCount)
+}
+
+func Test_trash_RestorePipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := restorePipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			pa := NewPipeActions(db, logger)
+			ta := NewTrashActions(db, logger)
+			_, err := pa.DeletePipe(1, 1)
+			assert.Nil(t, err)
+
+			gotPipe, gotErr := ta.RestorePipe(tc.inputPipeId, tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputPipeId, gotPipe.ID)
+				assert.Nil(t, gotPipe.DeletedAt)
+				assert.Equal(t, tc.wantPipeBookmarks, gotPipe.Bookmarks)
+
+				bookmark, err := NewBookmarkActions(db, logger).GetBookmark(1, 1)
+				assert.Nil(t, err)
+				assert.NotEmpty(t, bookmark.Tags)
+			}
+		})
+	}
+}
+
+func Test_trash_RestoreBookmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := restoreBookmarkTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTrashActions(db, logger)
+			_, err := NewBookmarkActions(db, logger).DeleteBookmark(1, 1)
+			assert.Nil(t, err)
+			if tc.trashPipe {
+				_, err = NewPipeActions(db, logger).DeletePipe(1, 1)
+				assert.Nil(t, err)
+			}
+
+			gotBookmark, gotErr := ta.RestoreBookmark(tc.inputBookmarkId, tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if nil == gotErr {
+				assert.Equal(t, tc.inputBookmarkId, gotBookmark.ID)
+				assert.Nil(t, gotBookmark.DeletedAt)
+			}
+		})
+	}
+}
+
+func Test_trash_PurgePipe(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := purgePipeTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			ta := NewTrashActions(db, logger)
+			_, err := NewPipeActions(db, logger).DeletePipe(1, 1)
+			assert.Nil(t, err)
+
+			gotResponse, gotErr := ta.PurgePipe(tc.inputPipeId, tc.inputUserId)
+			assert.Equal(t, tc.wantErr, gotErr)
+			assert.Equal(t, tc.wantResponse, gotResponse)
+		})
+	}
+}
+
+func Test_trash_PurgeExpired(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	ta := NewTrashActions(db, logger)
+	_, err := NewPipeActions(db, logger).DeletePipe(1, 1)
+	assert.Nil(t, err)
+
+	// nothing has been in the trash for long enough
+	purged, err := ta.PurgeExpired(time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), purged)
+
+	// the pipe and the bookmark deleted with it
+	purged, err = ta.PurgeExpired(time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	_, err = ta.RestorePipe(1, 1)
+	assert.Equal(t, ErrNoRecord, err)
+}
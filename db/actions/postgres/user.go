@@ -128,7 +128,7 @@ func (u userActions) GetUserByTwitterID(twitterId string) (user models.User, err
 // GetUserById - Retrieves a user by their registered ID
 func (u userActions) GetUserById(userId int64) (user models.User, err error) {
 	query := `
-	SELECT id, username, email, profile_name, cover_photo, twitter_id, created_at, modified_at 
+	SELECT id, username, email, profile_name, cover_photo, twitter_id, created_at, modified_at, is_admin, suspended_at 
 	FROM users 
 	WHERE id=$1 
 	LIMIT 1`
@@ -145,6 +145,8 @@ func (u userActions) GetUserById(userId int64) (user models.User, err error) {
 		&user.TwitterId,
 		&user.CreatedAt,
 		&user.ModifiedAt,
+		&user.IsAdmin,
+		&user.SuspendedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return models.User{}, ErrNoRecord
@@ -369,6 +371,7 @@ func (u userActions) VerifyUser(user models.User) (models.User, error) {
 	if err != nil {
 		return user, err
 	}
+	user.EmailVerified = true
 	return user, nil
 }
 
@@ -0,0 +1,35 @@
+package postgres
+
+var followUserTestCases = map[string]struct {
+	inputFolloweeId int64
+	inputPublic     bool
+	inputBlocked    bool
+	wantErr         error
+}{
+	"success": {
+		inputFolloweeId: firstUserId,
+		inputPublic:     true,
+		wantErr:         nil,
+	},
+	"private profile": {
+		inputFolloweeId: firstUserId,
+		inputPublic:     false,
+		wantErr:         ErrNoRecord,
+	},
+	"blocked by the user": {
+		inputFolloweeId: firstUserId,
+		inputPublic:     true,
+		inputBlocked:    true,
+		wantErr:         ErrNoRecord,
+	},
+	"themselves": {
+		inputFolloweeId: secondUserId,
+		inputPublic:     true,
+		wantErr:         ErrNoRecord,
+	},
+	"user does not exist": {
+		inputFolloweeId: 1000,
+		inputPublic:     true,
+		wantErr:         ErrNoRecord,
+	},
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+// Followers, followings and blocked users are listed from the most recently followed or blocked
+var (
+	followersOrdering = ordering{
+		name:  "followers-" + models.SortNewest,
+		keys:  []sortKey{{expr: "f.created_at", desc: true}, {expr: "f.id", desc: true}},
+		table: "user_follows f",
+	}
+	followingOrdering = ordering{
+		name:  "following-" + models.SortNewest,
+		keys:  []sortKey{{expr: "f.created_at", desc: true}, {expr: "f.id", desc: true}},
+		table: "user_follows f",
+	}
+	blockedUsersOrdering = ordering{
+		name:  "blocked-users-" + models.SortNewest,
+		keys:  []sortKey{{expr: "ub.created_at", desc: true}, {expr: "ub.id", desc: true}},
+		table: "user_blocks ub",
+	}
+)
+
+// blockedBetween returns the condition that matches when either of the users in the two
+// expressions blocked the other
+func blockedBetween(userExpr, otherExpr string) string {
+	return `EXISTS (
+	    SELECT 1 FROM user_blocks bb
+	    WHERE (bb.blocker_id=` + userExpr + ` AND bb.blocked_id=` + otherExpr + `)
+	        OR (bb.blocker_id=` + otherExpr + ` AND bb.blocked_id=` + userExpr + `)
+	)`
+}
+
+// FollowUser makes a user follow another user with a public profile. Users can't follow
+// themselves nor a user when either of them blocked the other. Following a user twice is a no-op
+func (f followActions) FollowUser(followerId, followeeId int64) error {
+	query := `
+	INSERT INTO user_follows AS f (follower_id, followee_id)
+	SELECT $1, u.id
+	FROM users u
+	WHERE u.id=$2 AND u.id<>$1 AND u.public_profile=true AND NOT ` + blockedBetween("$1", "u.id") + `
+	ON CONFLICT (follower_id, followee_id) DO UPDATE
+	SET created_at=f.created_at
+	RETURNING f.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var id int64
+	if err := f.Db.QueryRowContext(ctx, query, followerId, followeeId).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoRecord
+		}
+		return err
+	}
+	return nil
+}
+
+// UnfollowUser stops a user from following another user
+func (f followActions) UnfollowUser(followerId, followeeId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := f.Db.ExecContext(ctx, `DELETE FROM user_follows WHERE follower_id=$1 AND followee_id=$2`, followerId, followeeId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// GetFollowers gets a page of the users following a user, from the most recent follower
+func (f followActions) GetFollowers(userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error) {
+	return f.getConnections(followersOrdering, `
+	SELECT f.id, u.id, u.username, u.profile_name, u.cover_photo, f.created_at
+	FROM user_follows f
+	    INNER JOIN users u ON f.follower_id=u.id
+	WHERE f.followee_id=$1`, userId, filter)
+}
+
+// GetFollowing gets a page of the users a user follows, from the most recently followed
+func (f followActions) GetFollowing(userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error) {
+	return f.getConnections(followingOrdering, `
+	SELECT f.id, u.id, u.username, u.profile_name, u.cover_photo, f.created_at
+	FROM user_follows f
+	    INNER JOIN users u ON f.followee_id=u.id
+	WHERE f.follower_id=$1`, userId, filter)
+}
+
+// BlockUser blocks a user on behalf of another. The follows between the two users, in either
+// direction, are removed and can't be made again until the block is lifted, and the pipes the
+// blocked user shared with the blocker that were not accepted yet are withdrawn. Blocking a user
+// twice is a no-op
+func (f followActions) BlockUser(blockerId, blockedId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	tx, err := f.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO user_blocks (blocker_id, blocked_id)
+	SELECT $1, u.id FROM users u WHERE u.id=$2 AND u.id<>$1
+	ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+		return err
+	}
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id=$1 AND blocked_id=$2)`, blockerId, blockedId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoRecord
+	}
+
+	query = `
+	DELETE FROM user_follows
+	WHERE (follower_id=$1 AND followee_id=$2) OR (follower_id=$2 AND followee_id=$1)
+	`
+	if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+		return err
+	}
+	// pipes the blocked user shared with the blocker and that were not accepted yet are withdrawn
+	query = `DELETE FROM shared_pipe_receivers WHERE receiver_id=$1 AND sharer_id=$2 AND is_accepted=false`
+	if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnblockUser lifts the block a user put on another user. The follows the block removed are not restored
+func (f followActions) UnblockUser(blockerId, blockedId int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	result, err := f.Db.ExecContext(ctx, `DELETE FROM user_blocks WHERE blocker_id=$1 AND blocked_id=$2`, blockerId, blockedId)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// GetBlockedUsers gets a page of the users a user blocked, from the most recently blocked
+func (f followActions) GetBlockedUsers(userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error) {
+	return f.getConnections(blockedUsersOrdering, `
+	SELECT ub.id, u.id, u.username, u.profile_name, u.cover_photo, ub.created_at
+	FROM user_blocks ub
+	    INNER JOIN users u ON ub.blocked_id=u.id
+	WHERE ub.blocker_id=$1`, userId, filter)
+}
+
+// IsBlocked reports whether a user blocked another user
+func (f followActions) IsBlocked(blockerId, blockedId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var blocked bool
+	err := f.Db.QueryRowContext(ctx, `
+	SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id=$1 AND blocked_id=$2)
+	`, blockerId, blockedId).Scan(&blocked)
+	return blocked, err
+}
+
+// getConnections gets a page of the users selected by query, which reads the id of the follow or
+// block they are listed for, their id, username, profile name and cover photo and the time they were
+// followed or blocked, for the user passed as its first param
+func (f followActions) getConnections(o ordering, query string, userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pageCondition, pageClauses, args, err := o.page(ctx, f.Db, filter, []interface{}{userId})
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	query += ` AND ` + pageCondition + `
+	` + pageClauses
+
+	rows, err := f.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	defer rows.Close()
+
+	var users []models.UserConnection
+	for rows.Next() {
+		var user models.UserConnection
+		err := rows.Scan(
+			&user.ConnectionID,
+			&user.UserID,
+			&user.Username,
+			&user.ProfileName,
+			&user.CoverPhoto,
+			&user.Since,
+		)
+		if err != nil {
+			return nil, models.Pagination{}, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	pagination, size := o.pagination(filter, len(users), func(i int) int64 { return users[i].ConnectionID })
+	return users[:size], pagination, nil
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// makeProfilePublic makes the profile of the first user public so that they can be followed
+func makeProfilePublic(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := NewUserActions(db, logger).UpdateProfileSettings(firstUserId, models.ProfileSettings{PublicProfile: true})
+	assert.NoError(t, err)
+}
+
+func Test_follow_FollowUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	testCases := followUserTestCases
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDb(t)
+			fa := NewFollowActions(db, logger)
+			if tc.inputPublic {
+				makeProfilePublic(t, db)
+				_, err := db.Exec(`UPDATE users SET public_profile=true WHERE id=$1`, secondUserId)
+				assert.NoError(t, err)
+			}
+			if tc.inputBlocked {
+				assert.NoError(t, fa.BlockUser(tc.inputFolloweeId, secondUserId))
+			}
+
+			gotErr := fa.FollowUser(secondUserId, tc.inputFolloweeId)
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			gotUsers, _, err := fa.GetFollowers(tc.inputFolloweeId, models.Filter{})
+			assert.NoError(t, err)
+			if nil == gotErr {
+				assert.Len(t, gotUsers, 1)
+				assert.Equal(t, secondUserId, gotUsers[0].UserID)
+				assert.Equal(t, "user2", gotUsers[0].Username)
+			} else {
+				assert.Empty(t, gotUsers)
+			}
+		})
+	}
+}
+
+func Test_follow_UnfollowUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	fa := NewFollowActions(db, logger)
+	makeProfilePublic(t, db)
+	assert.NoError(t, fa.FollowUser(secondUserId, firstUserId))
+
+	assert.NoError(t, fa.UnfollowUser(secondUserId, firstUserId))
+	assert.Equal(t, ErrNoRecord, fa.UnfollowUser(secondUserId, firstUserId))
+
+	gotUsers, _, err := fa.GetFollowing(secondUserId, models.Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, gotUsers)
+}
+
+func Test_follow_BlockUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	fa := NewFollowActions(db, logger)
+	makeProfilePublic(t, db)
+	assert.NoError(t, fa.FollowUser(secondUserId, firstUserId))
+
+	// blocking a follower removes their follow and keeps them from following again
+	assert.NoError(t, fa.BlockUser(firstUserId, secondUserId))
+	assert.NoError(t, fa.BlockUser(firstUserId, secondUserId))
+	gotUsers, _, err := fa.GetFollowers(firstUserId, models.Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, gotUsers)
+	assert.Equal(t, ErrNoRecord, fa.FollowUser(secondUserId, firstUserId))
+
+	gotUsers, _, err = fa.GetBlockedUsers(firstUserId, models.Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, gotUsers, 1)
+	assert.Equal(t, secondUserId, gotUsers[0].UserID)
+
+	assert.NoError(t, fa.UnblockUser(firstUserId, secondUserId))
+	assert.Equal(t, ErrNoRecord, fa.UnblockUser(firstUserId, secondUserId))
+	assert.NoError(t, fa.FollowUser(secondUserId, firstUserId))
+
+	assert.Equal(t, ErrNoRecord, fa.BlockUser(firstUserId, 1000))
+}
+
+func Test_activity_GetActivityFeed(t *testing.T) {
+	if testing.Short() {
+		t.Skip(skipMessage)
+	}
+
+	db := newTestDb(t)
+	fa := NewFollowActions(db, logger)
+	aa := NewActivityActions(db, logger)
+	makeProfilePublic(t, db)
+	setPipeVisibility(t, db)
+	assert.NoError(t, fa.FollowUser(secondUserId, firstUserId))
+
+	bookmarkId := int64(1)
+	for _, activity := range []models.Activity{
+		{ActorID: firstUserId, Verb: models.ActivityPipeCreated, PipeID: 1},
+		{ActorID: firstUserId, Verb: models.ActivityBookmarkAdded, PipeID: 1, BookmarkID: &bookmarkId},
+		// the second pipe of the first user is unlisted and the third belongs to the second user
+		{ActorID: firstUserId, Verb: models.ActivityPipeCreated, PipeID: 2},
+		{ActorID: secondUserId, Verb: models.ActivityPipeCreated, PipeID: 3},
+	} {
+		_, err := aa.RecordActivity(activity)
+		assert.NoError(t, err)
+	}
+
+	gotActivities, gotPagination, gotErr := aa.GetActivityFeed(secondUserId, models.Filter{Limit: 1})
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotActivities, 1)
+	assert.Equal(t, models.ActivityBookmarkAdded, gotActivities[0].Verb)
+	assert.Equal(t, "user1", gotActivities[0].Actor)
+	assert.NotNil(t, gotActivities[0].BookmarkUrl)
+	assert.True(t, gotPagination.HasMore)
+
+	gotActivities, gotPagination, gotErr = aa.GetActivityFeed(secondUserId, models.Filter{Limit: 1, Cursor: gotPagination.NextCursor})
+	assert.NoError(t, gotErr)
+	assert.Len(t, gotActivities, 1)
+	assert.Equal(t, models.ActivityPipeCreated, gotActivities[0].Verb)
+	assert.False(t, gotPagination.HasMore)
+
+	gotActivities, _, gotErr = aa.GetActivityFeed(firstUserId, models.Filter{})
+	assert.NoError(t, gotErr)
+	assert.Empty(t, gotActivities)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// What a user did that shows up in the activity feed of their followers
+const (
+	ActivityPipeCreated   = "pipe_created"
+	ActivityBookmarkAdded = "bookmark_added"
+	ActivityPipeForked    = "pipe_forked"
+)
+
+// Activity is something a user did on one of their pipes. BookmarkID is set for added bookmarks
+// and SourcePipeID for forks, where PipeID is the fork
+type Activity struct {
+	ID           int64     `json:"id"`
+	ActorID      int64     `json:"actor_id"`
+	Verb         string    `json:"verb"`
+	PipeID       int64     `json:"pipe_id"`
+	BookmarkID   *int64    `json:"bookmark_id"`
+	SourcePipeID *int64    `json:"source_pipe_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// FeedActivity is an activity as listed in the feed of a follower of its actor
+type FeedActivity struct {
+	Activity
+	Actor       string  `json:"actor"`
+	PipeName    string  `json:"pipe_name"`
+	BookmarkUrl *string `json:"bookmark_url"`
+}
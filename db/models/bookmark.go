@@ -2,12 +2,69 @@ package models
 
 import "time"
 
+const (
+	BookmarkStateUnread   = "unread"
+	BookmarkStateRead     = "read"
+	BookmarkStateStarred  = "starred"
+	BookmarkStateArchived = "archived"
+	BookmarkStateAll      = "all"
+)
+
 type Bookmark struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	PipeID    int64     `json:"pipe_id"`
-	Platform  string    `json:"platform"`
-	Url       string    `json:"url"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	PipeID     int64      `json:"pipe_id"`
+	Platform   string     `json:"platform"`
+	Url        string     `json:"url"`
+	Title      string     `json:"title"`
+	Position   string     `json:"position"`
+	Tags       []string   `json:"tags"`
+	IsRead     bool       `json:"is_read"`
+	ReadAt     *time.Time `json:"read_at"`
+	IsStarred  bool       `json:"is_starred"`
+	StarredAt  *time.Time `json:"starred_at"`
+	IsArchived bool       `json:"is_archived"`
+	ArchivedAt *time.Time `json:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	// ForkedFrom is the bookmark this bookmark was copied from when its pipe was forked
+	ForkedFrom *int64 `json:"forked_from,omitempty"`
+	// AddedBy is the user who added the bookmark, which is a collaborator
+	// rather than the owner for bookmarks added to a shared pipe
+	AddedBy *int64 `json:"added_by"`
+}
+
+// BookmarkFilter holds the options used to narrow down, order and paginate a list of bookmarks.
+// An empty State returns every bookmark that has not been archived and an empty Sort
+// orders bookmarks manually. A Tag keeps the bookmarks with the tag or a tag under it
+type BookmarkFilter struct {
+	Filter
+	State string
+	Sort  string
+	Tag   string
+}
+
+// BookmarkStateUpdate describes a change to the state of a bookmark.
+// Nil fields are left untouched
+type BookmarkStateUpdate struct {
+	Read     *bool `json:"read"`
+	Starred  *bool `json:"starred"`
+	Archived *bool `json:"archived"`
+}
+
+// BookmarkUpdate describes a change to a bookmark. Nil fields are left untouched and
+// a non-nil Tags replaces every tag of the bookmark
+type BookmarkUpdate struct {
+	Title  *string
+	PipeID *int64
+	Tags   []string
+}
+
+// ValidBookmarkState reports whether state can be used to filter bookmarks
+func ValidBookmarkState(state string) bool {
+	switch state {
+	case "", BookmarkStateUnread, BookmarkStateRead, BookmarkStateStarred, BookmarkStateArchived, BookmarkStateAll:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxCommentLength is the number of characters a comment can hold
+	MaxCommentLength = 2000
+	// maxReactionLength is the number of code points a reaction can hold, which leaves room
+	// for emojis joined into one and their modifiers
+	maxReactionLength = 10
+)
+
+// BookmarkComment is a comment on a bookmark, or a reply to one when ParentID is set.
+// A deleted comment keeps its place in the thread but loses its body
+type BookmarkComment struct {
+	ID         int64      `json:"id"`
+	BookmarkID int64      `json:"bookmark_id"`
+	UserID     int64      `json:"user_id"`
+	Username   string     `json:"username"`
+	ParentID   *int64     `json:"parent_id"`
+	Body       string     `json:"body"`
+	EditedAt   *time.Time `json:"edited_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ReactionCount is the number of users who reacted to a bookmark with an emoji. Reacted
+// is set when the user the reactions are read for is one of them
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// MDCommentMention is the metadata of the notification sent to a user mentioned in a comment
+type MDCommentMention struct {
+	Bookmark Bookmark        `json:"bookmark"`
+	Comment  BookmarkComment `json:"comment"`
+}
+
+// ValidReaction reports whether reaction is an emoji. Reactions are made of a few code points
+// outside of ASCII, so that words and punctuation can't be used as reactions
+func ValidReaction(reaction string) bool {
+	if reaction == "" || utf8.RuneCountInString(reaction) > maxReactionLength {
+		return false
+	}
+	for _, r := range reaction {
+		if r <= unicode.MaxASCII || unicode.IsLetter(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
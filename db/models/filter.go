@@ -1,10 +1,74 @@
 package models
 
+const (
+	SortManual   = "manual"
+	SortNewest   = "newest"
+	SortOldest   = "oldest"
+	SortTitle    = "title"
+	SortPlatform = "platform"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Filter holds the pagination options of a list. Lists are paginated with an opaque
+// cursor that points at the last item of the previous page; an empty Cursor starts
+// from the first page
 type Filter struct {
-	Limit int64
-	Page  int64
+	Limit  int
+	Cursor string
+}
+
+// PageLimit returns the number of items that should be on a page
+func (f Filter) PageLimit() int {
+	if f.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if f.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return f.Limit
+}
+
+// Pagination describes how to get to the page after the one returned
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// PipeFilter holds the options used to order and paginate a list of pipes.
+// An empty Sort orders pipes manually. When Tree is set, only the top level pipes
+// are listed and the pipes nested in them are returned as their children
+type PipeFilter struct {
+	Filter
+	Sort string
+	Tree bool
+}
+
+// PositionMove moves an item of a manually ordered list right after the item with AfterID.
+// An AfterID of 0 moves the item to the top of the list
+type PositionMove struct {
+	ID      int64 `json:"id" binding:"required"`
+	AfterID int64 `json:"after_id"`
+}
+
+// ValidBookmarkSort reports whether sort can be used to order bookmarks
+func ValidBookmarkSort(sort string) bool {
+	switch sort {
+	case "", SortManual, SortNewest, SortOldest, SortTitle, SortPlatform:
+		return true
+	}
+	return false
 }
 
-func (f *Filter) Offset() int64 {
-	return (f.Page - 1) * f.Page
+// ValidPipeSort reports whether sort can be used to order pipes
+func ValidPipeSort(sort string) bool {
+	switch sort {
+	case "", SortManual, SortNewest, SortOldest, SortTitle:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// How the follower of a pipe hears about the bookmarks added to it: as they are added,
+// in a daily digest or not at all
+const (
+	FollowNotifyInstant = "instant"
+	FollowNotifyDigest  = "digest"
+	FollowNotifyMute    = "mute"
+)
+
+// PipeFollow is a user following a pipe
+type PipeFollow struct {
+	ID           int64      `json:"id"`
+	PipeID       int64      `json:"pipe_id"`
+	UserID       int64      `json:"user_id"`
+	Notify       string     `json:"notify"`
+	DigestSentAt *time.Time `json:"digest_sent_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ModifiedAt   time.Time  `json:"modified_at"`
+}
+
+// FollowedPipe is a pipe a user follows, as listed among the pipes they follow
+type FollowedPipe struct {
+	PipeFollow
+	PipeName  string `json:"pipe_name"`
+	Creator   string `json:"creator"`
+	Bookmarks int    `json:"bookmarks"`
+}
+
+// FollowDigest counts the bookmarks added to a pipe a user follows since they were last sent a digest
+type FollowDigest struct {
+	UserID    int64  `json:"-"`
+	PipeID    int64  `json:"pipe_id"`
+	PipeName  string `json:"pipe_name"`
+	Bookmarks int    `json:"bookmarks"`
+}
+
+// MDPipeBookmarkAdded is the metadata of the notification sent to the followers of a pipe
+// when a bookmark is added to it
+type MDPipeBookmarkAdded struct {
+	Pipe     Pipe     `json:"pipe"`
+	Bookmark Bookmark `json:"bookmark"`
+}
+
+// MDFollowDigest is the metadata of the daily digest of the pipes a user follows
+type MDFollowDigest struct {
+	Pipes []FollowDigest `json:"pipes"`
+}
+
+// ValidFollowNotify reports whether notify is a way to hear about the bookmarks of a followed pipe
+func ValidFollowNotify(notify string) bool {
+	switch notify {
+	case FollowNotifyInstant, FollowNotifyDigest, FollowNotifyMute:
+		return true
+	}
+	return false
+}
+
+// UserConnection is a user listed among the followers, the followings or the blocked users of
+// another user. Since is when they were followed or blocked
+type UserConnection struct {
+	// ConnectionID is the id of the follow or the block the user is listed for
+	ConnectionID int64     `json:"-"`
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"username"`
+	ProfileName  string    `json:"profile_name"`
+	CoverPhoto   string    `json:"cover_photo"`
+	Since        time.Time `json:"since"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	HistoryEntityBookmark = "bookmark"
+	HistoryEntityPipe     = "pipe"
+)
+
+const (
+	HistoryActionEdit    = "edit"
+	HistoryActionMove    = "move"
+	HistoryActionRetag   = "retag"
+	HistoryActionReorder = "reorder"
+	HistoryActionState   = "state"
+	HistoryActionMerge   = "merge"
+	HistoryActionSplit   = "split"
+)
+
+// MaxUndoCount is the largest number of operations that can be undone at once
+const MaxUndoCount = 50
+
+// HistoryOperation is a change made by a user to one or more of their pipes and bookmarks.
+// ActorID is the user who made the change, either the owner or a collaborator of the pipes
+type HistoryOperation struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	ActorID   int64           `json:"actor_id"`
+	Action    string          `json:"action"`
+	Changes   []HistoryChange `json:"changes"`
+	UndoneAt  *time.Time      `json:"undone_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// HistoryChange holds the state of a single pipe or bookmark before and after an operation.
+// Before and After are a PipeSnapshot or a BookmarkSnapshot depending on EntityType
+type HistoryChange struct {
+	EntityType string          `json:"entity_type"`
+	EntityID   int64           `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+}
+
+// BookmarkSnapshot holds the fields of a bookmark that are tracked in its history
+type BookmarkSnapshot struct {
+	PipeID     int64    `json:"pipe_id"`
+	Title      string   `json:"title"`
+	Position   string   `json:"position"`
+	Tags       []string `json:"tags"`
+	IsRead     bool     `json:"is_read"`
+	IsStarred  bool     `json:"is_starred"`
+	IsArchived bool     `json:"is_archived"`
+	Trashed    bool     `json:"trashed,omitempty"`
+}
+
+// PipeSnapshot holds the fields of a pipe that are tracked in its history
+type PipeSnapshot struct {
+	Name        string      `json:"name"`
+	CoverPhoto  string      `json:"cover_photo"`
+	Position    string      `json:"position"`
+	ParentID    *int64      `json:"parent_id"`
+	Query       *SmartQuery `json:"query,omitempty"`
+	DisplayName string      `json:"display_name"`
+	Description string      `json:"description"`
+	Icon        string      `json:"icon"`
+	Color       string      `json:"color"`
+	Visibility  string      `json:"visibility"`
+	Trashed     bool        `json:"trashed,omitempty"`
+}
+
+// HistoryFilter holds the options used to narrow down and paginate the history of a user.
+// When EntityType is set, only the changes made to that pipe or bookmark are returned
+type HistoryFilter struct {
+	Filter
+	EntityType string
+	EntityID   int64
+}
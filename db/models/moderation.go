@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// What users can report
+const (
+	ReportTargetPipe     = "pipe"
+	ReportTargetBookmark = "bookmark"
+	ReportTargetComment  = "comment"
+	ReportTargetUser     = "user"
+)
+
+// Why users report something
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other"
+)
+
+// A report is open until a moderator acts on it, which resolves it, or dismisses it
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// What a moderator does about a report. Hiding a pipe makes it private and keeps its owner from
+// publishing it again, while unpublishing it only makes it private. Both act on the pipe holding a
+// reported bookmark. Hiding a comment deletes it and suspending the user behind what was reported
+// keeps them from using their account
+const (
+	ModerationHide      = "hide"
+	ModerationUnpublish = "unpublish"
+	ModerationSuspend   = "suspend"
+	ModerationDismiss   = "dismiss"
+)
+
+var moderationActions = map[string][]string{
+	ReportTargetPipe:     {ModerationHide, ModerationUnpublish, ModerationSuspend, ModerationDismiss},
+	ReportTargetBookmark: {ModerationHide, ModerationUnpublish, ModerationSuspend, ModerationDismiss},
+	ReportTargetComment:  {ModerationHide, ModerationSuspend, ModerationDismiss},
+	ReportTargetUser:     {ModerationSuspend, ModerationDismiss},
+}
+
+// Report is something a user reported for moderators to look into. TargetUserID is the owner of the
+// reported pipe or bookmark, the author of the reported comment or the reported user, and PipeID is the
+// pipe that was reported or that holds the reported bookmark or comment
+type Report struct {
+	ID           int64      `json:"id"`
+	ReporterID   int64      `json:"reporter_id"`
+	TargetType   string     `json:"target_type"`
+	TargetID     int64      `json:"target_id"`
+	TargetUserID int64      `json:"target_user_id"`
+	PipeID       *int64     `json:"pipe_id"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details"`
+	Status       string     `json:"status"`
+	Action       *string    `json:"action"`
+	Note         string     `json:"note"`
+	ResolvedBy   *int64     `json:"resolved_by"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// TargetReports is the number of open reports on the same target
+	TargetReports int `json:"target_reports"`
+}
+
+// ValidReportTarget reports whether target is something users can report
+func ValidReportTarget(target string) bool {
+	_, ok := moderationActions[target]
+	return ok
+}
+
+// ValidReportReason reports whether reason is a reason to report something
+func ValidReportReason(reason string) bool {
+	switch reason {
+	case ReportReasonSpam, ReportReasonHarassment, ReportReasonInappropriate, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+// ValidReportStatus reports whether status is the status of a report
+func ValidReportStatus(status string) bool {
+	return status == ReportStatusOpen || status == ReportStatusResolved || status == ReportStatusDismissed
+}
+
+// ValidModerationAction reports whether action can be taken on reports of the target type
+func ValidModerationAction(target, action string) bool {
+	for _, allowed := range moderationActions[target] {
+		if allowed == action {
+			return true
+		}
+	}
+	return false
+}
@@ -2,18 +2,108 @@ package models
 
 import "time"
 
+const (
+	PipeTypeStandard = "standard"
+	// PipeTypeSmart is the type of the pipes that hold no bookmarks of their own
+	// but list the bookmarks of their owner matching their Query
+	PipeTypeSmart = "smart"
+)
+
+const (
+	// PipeVisibilityPrivate pipes are only seen by their owner and the users they are shared with
+	PipeVisibilityPrivate = "private"
+	// PipeVisibilityUnlisted pipes can be seen by anyone with a link to them
+	PipeVisibilityUnlisted = "unlisted"
+	// PipeVisibilityPublic pipes are also listed on the public profile of their owner
+	PipeVisibilityPublic = "public"
+)
+
 type Pipe struct {
-	ID         int64     `json:"id"`
-	Name       string    `json:"name,omitempty"`
-	UserID     int64     `json:"user_id"`
-	CoverPhoto string    `json:"cover_photo"`
-	CreatedAt  time.Time `json:"created_at"`
-	ModifiedAt time.Time `json:"modified_at"`
-	Bookmarks  int       `json:"bookmarks"`
-	Creator    string    `json:"creator"`
+	ID int64 `json:"id"`
+	// Name is the lowercase key a pipe is looked up by, DisplayName is the name as the user typed it
+	Name        string      `json:"name,omitempty"`
+	DisplayName string      `json:"display_name"`
+	Description string      `json:"description"`
+	Icon        string      `json:"icon"`
+	Color       string      `json:"color"`
+	Visibility  string      `json:"visibility"`
+	UserID      int64       `json:"user_id"`
+	ParentID    *int64      `json:"parent_id"`
+	Type        string      `json:"type"`
+	Query       *SmartQuery `json:"query"`
+	// ForkedFrom is the pipe this pipe was copied from. A fork with SyncUpstream set
+	// keeps receiving the bookmarks added to that pipe
+	ForkedFrom   *int64     `json:"forked_from"`
+	SyncUpstream bool       `json:"sync_upstream"`
+	CoverPhoto   string     `json:"cover_photo"`
+	Position     string     `json:"position"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ModifiedAt   time.Time  `json:"modified_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Bookmarks    int        `json:"bookmarks"`
+	// TreeBookmarks is the number of bookmarks in the pipe and in every pipe nested in it
+	TreeBookmarks int    `json:"tree_bookmarks"`
+	Creator       string `json:"creator"`
+	Children      []Pipe `json:"children,omitempty"`
+}
+
+// PipeFork holds the options of a fork. An empty Name gives the fork the name of the forked pipe
+type PipeFork struct {
+	Name         string
+	DisplayName  string
+	SyncUpstream bool
+}
+
+const (
+	PipeSplitByTag      = "tag"
+	PipeSplitByPlatform = "platform"
+)
+
+// PipeMerge holds the options of a merge. The merged pipe takes the cover photo of the pipe with
+// CoverFromID; when it is not set, it keeps its own cover photo or takes the first one of SourceIDs
+type PipeMerge struct {
+	SourceIDs   []int64
+	CoverFromID int64
+}
+
+// PipeSplit holds the options of a split. A new pipe is made for each of Values, or for every tag or
+// platform of the split pipe, the most used first, when Values is empty. A bookmark goes to the pipe
+// of the first of its values and the bookmarks matching none of them stay in the split pipe
+type PipeSplit struct {
+	By     string
+	Values []string
 }
 
 type PipeAndResource struct {
 	Pipe      Pipe       `json:"pipe"`
+	Children  []Pipe     `json:"children"`
 	Bookmarks []Bookmark `json:"bookmarks"`
 }
+
+// SmartQuery is the definition of a smart pipe. A bookmark matches the query when it matches
+// every criterion that is set: it has at least one of Tags, it comes from one of Platforms, it is
+// in State and in one of PipeIDs, and it was created between After and Before or in the last LastDays
+type SmartQuery struct {
+	Tags      []string   `json:"tags,omitempty"`
+	Platforms []string   `json:"platforms,omitempty"`
+	State     string     `json:"state,omitempty"`
+	PipeIDs   []int64    `json:"pipe_ids,omitempty"`
+	After     *time.Time `json:"after,omitempty"`
+	Before    *time.Time `json:"before,omitempty"`
+	LastDays  int        `json:"last_days,omitempty"`
+}
+
+// IsEmpty reports whether the query has no criterion at all, which would match every bookmark
+func (q SmartQuery) IsEmpty() bool {
+	return len(q.Tags) == 0 && len(q.Platforms) == 0 && q.State == "" && len(q.PipeIDs) == 0 &&
+		q.After == nil && q.Before == nil && q.LastDays == 0
+}
+
+// ValidPipeVisibility reports whether visibility can be set on a pipe
+func ValidPipeVisibility(visibility string) bool {
+	switch visibility {
+	case PipeVisibilityPrivate, PipeVisibilityUnlisted, PipeVisibilityPublic:
+		return true
+	}
+	return false
+}
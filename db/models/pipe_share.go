@@ -7,6 +7,45 @@ const (
 	PipeShareTypePrivate = "private"
 )
 
+// Statuses of a pipe shared with a user. Private shares are pending until the receiver
+// accepts or declines them or they expire, while public shares are accepted when redeemed
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusAccepted = "accepted"
+	ShareStatusDeclined = "declined"
+	ShareStatusExpired  = "expired"
+)
+
+// PrivateShareTTL is how long a private share can be accepted or declined before it expires
+const PrivateShareTTL = 14 * 24 * time.Hour
+
+// Permissions of a share link. Both let their users preview the pipe and add it to their
+// collection, but only the users of fork links can fork it from there
+const (
+	SharePermissionReadOnly = "read-only"
+	SharePermissionFork     = "fork"
+)
+
+// Roles of the users a pipe is shared with, from the least to the most trusted.
+// Viewers can only read the pipe, contributors can also add bookmarks to it and edit the ones
+// they added, editors can edit every bookmark and the pipe itself and co-owners can also share it.
+// PipeRoleOwner is never given to a receiver, it's the role of the user who owns the pipe
+const (
+	PipeRoleViewer      = "viewer"
+	PipeRoleContributor = "contributor"
+	PipeRoleEditor      = "editor"
+	PipeRoleCoOwner     = "co-owner"
+	PipeRoleOwner       = "owner"
+)
+
+var pipeRoleRanks = map[string]int{
+	PipeRoleViewer:      1,
+	PipeRoleContributor: 2,
+	PipeRoleEditor:      3,
+	PipeRoleCoOwner:     4,
+	PipeRoleOwner:       5,
+}
+
 type SharedPipe struct {
 	ID         int64     `json:"id"`
 	SharerID   int64     `json:"sharer_id"`
@@ -15,6 +54,35 @@ type SharedPipe struct {
 	Code       string    `json:"code"`
 	CreatedAt  time.Time `json:"created_at"`
 	ModifiedAt time.Time `json:"modified_at"`
+	// ExpiresAt and MaxRedemptions limit how long and how many times the share can be redeemed
+	ExpiresAt      *time.Time `json:"expires_at"`
+	MaxRedemptions *int       `json:"max_redemptions"`
+	Redemptions    int        `json:"redemptions"`
+	PasswordHash   string     `json:"-"`
+	Permission     string     `json:"permission"`
+}
+
+// HasPassword reports whether the share asks for a password
+func (s SharedPipe) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// ShareLinkSettings describes the limits of a share link. Limits left empty don't apply
+type ShareLinkSettings struct {
+	ExpiresAt      *time.Time `json:"expires_at"`
+	MaxRedemptions *int       `json:"max_redemptions"`
+	Password       string     `json:"password"`
+	Permission     string     `json:"permission"`
+}
+
+// IsEmpty reports whether the settings set no limit at all
+func (s ShareLinkSettings) IsEmpty() bool {
+	return s.ExpiresAt == nil && s.MaxRedemptions == nil && s.Password == "" && s.Permission == ""
+}
+
+// ValidSharePermission reports whether permission can be given to a share link
+func ValidSharePermission(permission string) bool {
+	return permission == SharePermissionReadOnly || permission == SharePermissionFork
 }
 
 type SharedPipeReceiver struct {
@@ -25,7 +93,89 @@ type SharedPipeReceiver struct {
 	CreatedAt    time.Time `json:"created_at"`
 	Code         string    `json:"code"`
 	IsAccepted   bool      `json:"is_accepted"`
-	ModifiedAt   time.Time `json:"modified_at"`
+	Role         string    `json:"role"`
+	// Permission is the permission of the share link the pipe was added through
+	Permission string    `json:"permission"`
+	ModifiedAt time.Time `json:"modified_at"`
+	// DeclinedAt and ExpiresAt only apply to shares that have not been accepted
+	DeclinedAt *time.Time `json:"declined_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// Status returns the status of the share at the given time
+func (r SharedPipeReceiver) Status(now time.Time) string {
+	switch {
+	case r.IsAccepted:
+		return ShareStatusAccepted
+	case r.DeclinedAt != nil:
+		return ShareStatusDeclined
+	case r.ExpiresAt != nil && !r.ExpiresAt.After(now):
+		return ShareStatusExpired
+	}
+	return ShareStatusPending
+}
+
+// ReceivedShare is a pipe shared with a user, as listed in their inbox
+type ReceivedShare struct {
+	SharedPipeReceiver
+	Status         string `json:"status"`
+	PipeName       string `json:"pipe_name"`
+	PipeCoverPhoto string `json:"pipe_cover_photo"`
+	SharerUsername string `json:"sharer_username"`
+}
+
+// ValidShareStatus reports whether status can be used to filter the pipes shared with a user
+func ValidShareStatus(status string) bool {
+	switch status {
+	case "", ShareStatusPending, ShareStatusAccepted, ShareStatusDeclined, ShareStatusExpired:
+		return true
+	}
+	return false
+}
+
+// PipeShareInvitation is a pipe shared by email with someone who doesn't have an account yet.
+// It's attached to the account that signs up with or verifies the email before it expires
+type PipeShareInvitation struct {
+	ID         int64      `json:"id"`
+	SharerID   int64      `json:"sharer_id"`
+	PipeID     int64      `json:"pipe_id"`
+	Email      string     `json:"email"`
+	Code       string     `json:"-"`
+	Role       string     `json:"role"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ReceiverID *int64     `json:"receiver_id"`
+	AttachedAt *time.Time `json:"attached_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ModifiedAt time.Time  `json:"modified_at"`
+}
+
+// PipeCollaborator is a user a pipe was shared with
+type PipeCollaborator struct {
+	SharedPipeReceiver
+	Username    string `json:"username"`
+	ProfileName string `json:"profile_name"`
+}
+
+// PipeAccess describes what a user can do with a pipe they own or collaborate on
+type PipeAccess struct {
+	PipeID  int64  `json:"pipe_id"`
+	OwnerID int64  `json:"owner_id"`
+	Role    string `json:"role"`
+}
+
+// Allows reports whether the access grants at least the given role
+func (a PipeAccess) Allows(role string) bool {
+	return PipeRoleAllows(a.Role, role)
+}
+
+// ValidPipeRole reports whether role can be given to a user a pipe is shared with
+func ValidPipeRole(role string) bool {
+	return role != PipeRoleOwner && pipeRoleRanks[role] > 0
+}
+
+// PipeRoleAllows reports whether role grants at least everything required grants
+func PipeRoleAllows(role, required string) bool {
+	return pipeRoleRanks[role] > 0 && pipeRoleRanks[role] >= pipeRoleRanks[required]
 }
 
 // MDPrivatePipeShare - Metadata definitions for notification
@@ -34,3 +184,9 @@ type MDPrivatePipeShare struct {
 	Pipe   Pipe   `json:"pipe"`
 	Code   string `json:"code"`
 }
+
+// MDPipeShareRevoked - Metadata definitions for the notification sent when access to a pipe is revoked
+type MDPipeShareRevoked struct {
+	Owner User `json:"owner"`
+	Pipe  Pipe `json:"pipe"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Orders of the pipes listed in explore
+const (
+	ExploreSortRecent  = "recent"
+	ExploreSortPopular = "popular"
+)
+
+// ProfileSettings controls what other people see of a user. A user's public pipes are only listed
+// on their profile when it's public, and in explore when it's public and ShowInExplore is set
+type ProfileSettings struct {
+	PublicProfile bool `json:"public_profile"`
+	ShowInExplore bool `json:"show_in_explore"`
+}
+
+// PublicProfile is what anyone can see of a user with a public profile
+type PublicProfile struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	ProfileName string    `json:"profile_name"`
+	CoverPhoto  string    `json:"cover_photo"`
+	PublicPipes int       `json:"public_pipes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ValidExploreSort reports whether sort is an order of the pipes listed in explore
+func ValidExploreSort(sort string) bool {
+	return sort == "" || sort == ExploreSortRecent || sort == ExploreSortPopular
+}
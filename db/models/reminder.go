@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	ReminderStatusPending = "pending"
+	ReminderStatusSent    = "sent"
+)
+
+type Reminder struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	BookmarkID  int64      `json:"bookmark_id"`
+	Note        string     `json:"note"`
+	RemindAt    time.Time  `json:"remind_at"`
+	Status      string     `json:"status"`
+	SentAt      *time.Time `json:"sent_at"`
+	SnoozeCount int        `json:"snooze_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModifiedAt  time.Time  `json:"modified_at"`
+}
+
+// MDBookmarkReminder - Metadata definitions for reminder and rediscover notifications
+type MDBookmarkReminder struct {
+	ReminderID int64    `json:"reminder_id,omitempty"`
+	Note       string   `json:"note,omitempty"`
+	Bookmark   Bookmark `json:"bookmark"`
+}
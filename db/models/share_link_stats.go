@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Events recorded for a share code
+const (
+	ShareEventView       = "view"
+	ShareEventRedemption = "redemption"
+	ShareEventClick      = "click"
+)
+
+// Coarse types of the clients share events are recorded from
+const (
+	ClientTypeIOS     = "ios"
+	ClientTypeAndroid = "android"
+	ClientTypeWeb     = "web"
+	ClientTypeBot     = "bot"
+	ClientTypeOther   = "other"
+)
+
+// ShareLinkEvent is something that happened to a pipe shared with a code. Referrer only holds the host
+// of the page the event came from
+type ShareLinkEvent struct {
+	ID           int64     `json:"id"`
+	SharedPipeID int64     `json:"shared_pipe_id"`
+	PipeID       int64     `json:"pipe_id"`
+	Code         string    `json:"code"`
+	Event        string    `json:"event"`
+	BookmarkID   *int64    `json:"bookmark_id"`
+	Referrer     string    `json:"referrer"`
+	ClientType   string    `json:"client_type"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ShareLinkCounts counts the events of a pipe's share codes
+type ShareLinkCounts struct {
+	Views       int `json:"views"`
+	Redemptions int `json:"redemptions"`
+	Clicks      int `json:"clicks"`
+}
+
+// ShareLinkDailyStats counts the events of a pipe's share codes on a day
+type ShareLinkDailyStats struct {
+	Day time.Time `json:"day"`
+	ShareLinkCounts
+}
+
+// ShareLinkSourceStats counts the events of a pipe's share codes coming from a referrer or client type
+type ShareLinkSourceStats struct {
+	Source string `json:"source"`
+	ShareLinkCounts
+}
+
+// ShareLinkStats aggregates the events of a pipe's share codes over a range of days
+type ShareLinkStats struct {
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Total     ShareLinkCounts        `json:"total"`
+	Daily     []ShareLinkDailyStats  `json:"daily"`
+	Referrers []ShareLinkSourceStats `json:"referrers"`
+	Clients   []ShareLinkSourceStats `json:"clients"`
+}
@@ -1,12 +1,56 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
+// Orders of the tags of a user
+const (
+	TagSortName  = "name"
+	TagSortUsage = "usage"
+)
+
+// MaxTagNameLength is the number of characters a tag can have, along with the names of the tags it's under
+const MaxTagNameLength = 255
+
+// TagSeparator separates the name of a tag from the names of the tags it's under, as in lang/go
+const TagSeparator = "/"
+
+// How many tags are autocompleted or suggested at once, unless asked for fewer
+const (
+	DefaultTagSuggestions = 10
+	MaxTagSuggestions     = 20
+)
+
+// Why a tag is suggested for a link. Tags the user put on links of the same site come first, then
+// tags found in the title or description of the link and then the name of the site itself
+const (
+	TagSuggestionSimilarLinks = "similar_links"
+	TagSuggestionMetadata     = "metadata"
+	TagSuggestionDomain       = "domain"
+)
+
+// Tag is a label a user puts on their bookmarks. Tags belong to the user whose bookmarks they are on.
+// A tag can be put under another tag, and its name is then the name of its parent followed by its own,
+// e.g. lang/go under lang. Bookmarks found by a tag include the ones with a tag under it
 type Tag struct {
-	Id         int64     `json:"id"`
-	Name       string    `json:"name"`
+	Id       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+	ParentID *int64 `json:"parent_id"`
+	Name     string `json:"name"`
+	// Usage is the number of bookmarks, out of the trash, the tag is on
+	Usage      int       `json:"usage"`
 	CreatedAt  time.Time `json:"created_at"`
 	ModifiedAt time.Time `json:"modified_at"`
+	// Children are the tags right under the tag, listed when the tags are listed as a tree
+	Children []Tag `json:"children,omitempty"`
+}
+
+// TagSuggestion is a tag suggested for a link. A suggested tag the user doesn't have yet has no id
+type TagSuggestion struct {
+	Tag
+	Reason string `json:"reason"`
 }
 
 type BookmarkToTag struct {
@@ -15,3 +59,39 @@ type BookmarkToTag struct {
 	BookmarkId int64  `json:"BookmarkId"`
 	TagName    string `json:"tagName"`
 }
+
+// ValidTagSort reports whether sort can be used to order tags
+func ValidTagSort(sort string) bool {
+	return sort == "" || sort == TagSortName || sort == TagSortUsage
+}
+
+// NormalizeTagName trims each segment of a tag name and collapses the whitespace in it.
+// Empty segments are dropped, so " lang / / go " becomes "lang/go"
+func NormalizeTagName(name string) string {
+	var segments []string
+	for _, segment := range strings.Split(name, TagSeparator) {
+		if segment = strings.Join(strings.Fields(segment), " "); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, TagSeparator)
+}
+
+// TagLeaf returns the last segment of a tag name, which is the name of the tag without the tags it's under
+func TagLeaf(name string) string {
+	return name[strings.LastIndex(name, TagSeparator)+1:]
+}
+
+// TagParent returns the name of the tag a tag name is under, which is empty for a tag that isn't under another
+func TagParent(name string) string {
+	if i := strings.LastIndex(name, TagSeparator); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// TagKey is what tells tags apart. Names that only differ by case or whitespace, such as
+// "golang", "Golang" and "go lang", are the same tag
+func TagKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ""))
+}
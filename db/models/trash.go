@@ -0,0 +1,7 @@
+package models
+
+// Trash holds the pipes and bookmarks a user has deleted that are yet to be purged
+type Trash struct {
+	Pipes     []Pipe     `json:"pipes"`
+	Bookmarks []Bookmark `json:"bookmarks"`
+}
@@ -21,6 +21,9 @@ type User struct {
 	EmailVerified bool      `json:"email_verified"`
 	CreatedAt     time.Time `json:"created_at"`
 	ModifiedAt    time.Time `json:"modified_at"`
+	// IsAdmin and SuspendedAt are only read when a user is retrieved by id and are never sent to clients
+	IsAdmin     bool       `json:"-"`
+	SuspendedAt *time.Time `json:"-"`
 }
 
 type UserAuth struct {
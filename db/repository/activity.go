@@ -0,0 +1,8 @@
+package repository
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+type ActivityRepository interface {
+	RecordActivity(activity models.Activity) (models.Activity, error)
+	GetActivityFeed(userId int64, filter models.Filter) ([]models.FeedActivity, models.Pagination, error)
+}
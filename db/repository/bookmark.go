@@ -5,8 +5,12 @@ import "github.com/mypipeapp/mypipeapi/db/models"
 type BookmarkRepository interface {
 	CreateBookmark(bm models.Bookmark) (models.Bookmark, error)
 	GetBookmark(bmID, userID int64) (models.Bookmark, error)
-	GetBookmarks(userID, pipeID int64) ([]models.Bookmark, error)
+	GetBookmarks(userID, pipeID int64, filter models.BookmarkFilter) ([]models.Bookmark, models.Pagination, error)
+	GetBookmarksByState(userID int64, filter models.BookmarkFilter) ([]models.Bookmark, models.Pagination, error)
 	ParseTags(bookmark models.Bookmark) (models.Bookmark, error)
 	GetBookmarksCount(userID int64) (int, error)
+	UpdateBookmarkState(bmID, userID int64, update models.BookmarkStateUpdate) (models.Bookmark, error)
+	UpdateBookmark(bmID, userID, actorID int64, update models.BookmarkUpdate) (models.Bookmark, error)
+	MoveBookmarks(userID, actorID, pipeID int64, moves []models.PositionMove) error
 	DeleteBookmark(bmID, userID int64) (bool, error)
 }
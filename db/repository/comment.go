@@ -0,0 +1,14 @@
+package repository
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+type CommentRepository interface {
+	CreateComment(comment models.BookmarkComment) (models.BookmarkComment, error)
+	GetComment(commentId int64) (models.BookmarkComment, error)
+	GetComments(bookmarkId int64, filter models.Filter) ([]models.BookmarkComment, models.Pagination, error)
+	UpdateComment(commentId, userId int64, body string) (models.BookmarkComment, error)
+	DeleteComment(commentId int64) error
+	AddReaction(bookmarkId, userId int64, emoji string) error
+	RemoveReaction(bookmarkId, userId int64, emoji string) error
+	GetReactions(bookmarkId, userId int64) ([]models.ReactionCount, error)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+type FollowRepository interface {
+	FollowPipe(userId, pipeId int64, notify string) (models.PipeFollow, error)
+	UpdatePipeFollow(userId, pipeId int64, notify string) (models.PipeFollow, error)
+	UnfollowPipe(userId, pipeId int64) error
+	GetFollowedPipes(userId int64, filter models.Filter) ([]models.FollowedPipe, models.Pagination, error)
+	GetPipeFollowers(pipeId int64, notify string) ([]int64, error)
+	ClaimFollowDigests(before time.Time, limit int) ([]models.FollowDigest, error)
+	GetFollowingFeed(userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error)
+	FollowUser(followerId, followeeId int64) error
+	UnfollowUser(followerId, followeeId int64) error
+	GetFollowers(userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error)
+	GetFollowing(userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error)
+	BlockUser(blockerId, blockedId int64) error
+	UnblockUser(blockerId, blockedId int64) error
+	GetBlockedUsers(userId int64, filter models.Filter) ([]models.UserConnection, models.Pagination, error)
+	IsBlocked(blockerId, blockedId int64) (bool, error)
+}
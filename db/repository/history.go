@@ -0,0 +1,8 @@
+package repository
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+type HistoryRepository interface {
+	GetHistory(userID int64, filter models.HistoryFilter) ([]models.HistoryOperation, models.Pagination, error)
+	Undo(userID int64, count int) ([]models.HistoryOperation, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "github.com/mypipeapp/mypipeapi/db/models"
+
+type ModerationRepository interface {
+	CreateReport(report models.Report) (models.Report, error)
+	GetReport(reportId int64) (models.Report, error)
+	GetReports(status string, filter models.Filter) ([]models.Report, models.Pagination, error)
+	ResolveReports(report models.Report, moderatorId int64, action, note string) (models.Report, error)
+	HidePipe(pipeId int64) error
+	UnhidePipe(pipeId int64) error
+	UnpublishPipe(pipeId int64) error
+	IsPipeHidden(pipeId int64) (bool, error)
+	SuspendUser(userId int64) error
+	UnsuspendUser(userId int64) error
+}
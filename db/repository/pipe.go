@@ -8,8 +8,17 @@ type PipeRepository interface {
 	GetPipe(pipeId, userId int64) (models.Pipe, error)
 	GetPipeByName(pipeName string, userId int64) (models.Pipe, error)
 	GetPipeAndResource(pipeId, userId int64) (models.PipeAndResource, error)
-	GetPipes(userId int64) ([]models.Pipe, error)
+	GetPipes(userId int64, filter models.PipeFilter) ([]models.Pipe, models.Pagination, error)
 	GetPipesCount(userId int64) (int, error)
-	UpdatePipe(userId int64, pipeId int64, updatedBody models.Pipe) (models.Pipe, error)
+	UpdatePipe(userId, actorId, pipeId int64, updatedBody models.Pipe) (models.Pipe, error)
+	MovePipes(userId int64, moves []models.PositionMove) error
+	NestPipe(userId, pipeId int64, parentId *int64) (models.Pipe, error)
 	DeletePipe(userID, pipeID int64) (bool, error)
+	ForkPipe(userId, pipeId int64, fork models.PipeFork) (models.Pipe, error)
+	SyncForks(limit int) (int64, error)
+	MergePipes(userId, targetId int64, merge models.PipeMerge) (models.Pipe, error)
+	SplitPipe(userId, pipeId int64, split models.PipeSplit) ([]models.Pipe, error)
+	GetPublicPipes(userId int64, filter models.Filter) ([]models.Pipe, models.Pagination, error)
+	GetPublicPipe(userId, pipeId int64) (models.PipeAndResource, error)
+	ExplorePipes(sort string, filter models.Filter) ([]models.Pipe, models.Pagination, error)
 }
@@ -1,13 +1,32 @@
 package repository
 
-import "github.com/mypipeapp/mypipeapi/db/models"
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
 
 type PipeShareRepository interface {
-	CreatePipeShareRecord(pipeShareData models.SharedPipe, receiver string) (models.SharedPipe, error)
+	CreatePipeShareRecord(pipeShareData models.SharedPipe, receiver string, role string) (models.SharedPipe, error)
 	CreatePipeReceiver(receiver models.SharedPipeReceiver) (models.SharedPipeReceiver, error)
 	GetSharedPipe(pipeId int64, shareType string) (models.SharedPipe, error)
 	GetSharedPipeByCode(code string) (models.SharedPipe, error)
 	GetReceivedPipeRecord(pipeId, userId int64) (models.SharedPipeReceiver, error)
 	GetReceivedPipeRecordByCode(code string, userId int64) (models.SharedPipeReceiver, error)
 	AcceptPrivateShare(receiver models.SharedPipeReceiver) (models.SharedPipeReceiver, error)
+	DeclinePrivateShare(receiver models.SharedPipeReceiver) (models.SharedPipeReceiver, error)
+	RenewPrivateShare(receiver models.SharedPipeReceiver, expiresAt time.Time) (models.SharedPipeReceiver, error)
+	GetReceivedShares(userId int64, status string, filter models.Filter) ([]models.ReceivedShare, models.Pagination, error)
+	GetReceivedShare(id, userId int64) (models.SharedPipeReceiver, error)
+	GetPipeAccess(pipeId, userId int64) (models.PipeAccess, error)
+	UpdateReceiverRole(pipeId, receiverId int64, role string) (models.SharedPipeReceiver, error)
+	GetPipeCollaborators(pipeId int64) ([]models.PipeCollaborator, error)
+	DeletePipeReceiver(pipeId, receiverId int64) (models.SharedPipeReceiver, error)
+	UpdateSharedPipeCode(pipeId int64, shareType, code string) (models.SharedPipe, error)
+	DeleteSharedPipe(pipeId int64, shareType string) error
+	UpdateShareLink(share models.SharedPipe) (models.SharedPipe, error)
+	RedeemSharedPipe(sharedPipeId int64) error
+	CreateShareInvitation(invitation models.PipeShareInvitation) (models.PipeShareInvitation, error)
+	AttachShareInvitations(userId int64, email string) ([]models.SharedPipeReceiver, error)
+	RecordShareLinkEvent(event models.ShareLinkEvent) (models.ShareLinkEvent, error)
+	GetShareLinkStats(pipeId int64, from, to time.Time) (models.ShareLinkStats, error)
 }
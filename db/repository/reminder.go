@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+type ReminderRepository interface {
+	CreateReminder(reminder models.Reminder) (models.Reminder, error)
+	GetReminder(reminderID, userID int64) (models.Reminder, error)
+	GetReminders(userID int64) ([]models.Reminder, error)
+	ClaimDueReminders(before time.Time, limit int) ([]models.Reminder, error)
+	ReleaseReminder(reminderID int64) error
+	SnoozeReminder(reminderID, userID int64, until time.Time) (models.Reminder, error)
+	DeleteReminder(reminderID, userID int64) (bool, error)
+	UpdateRediscoverSetting(userID int64, enabled bool) error
+	ClaimRediscoveryUsers(before time.Time, limit int) ([]int64, error)
+	GetRediscoverBookmark(userID int64, createdBefore time.Time) (models.Bookmark, error)
+}
@@ -10,4 +10,11 @@ type Repositories struct {
 	PasswordReset       PasswordResetRepository
 	Tag                 TagRepository
 	Search              SearchRepository
+	Reminder            ReminderRepository
+	Trash               TrashRepository
+	History             HistoryRepository
+	Follow              FollowRepository
+	Activity            ActivityRepository
+	Comment             CommentRepository
+	Moderation          ModerationRepository
 }
@@ -3,8 +3,8 @@ package repository
 import "github.com/mypipeapp/mypipeapi/db/models"
 
 type SearchRepository interface {
-	SearchThroughPipes(name string, userId int64) ([]models.Pipe, error)
-	SearchThroughTags(name string, userId int64) ([]models.Bookmark, error)
-	SearchThroughPlatform(name string, userId int64) ([]models.Bookmark, error)
+	SearchThroughPipes(name string, userId int64, filter models.Filter) ([]models.Pipe, models.Pagination, error)
+	SearchThroughTags(name string, userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error)
+	SearchThroughPlatform(name string, userId int64, filter models.Filter) ([]models.Bookmark, models.Pagination, error)
 	SearchAll(name string, userId int64) ([]interface{}, error)
 }
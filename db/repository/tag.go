@@ -4,7 +4,16 @@ import "github.com/mypipeapp/mypipeapi/db/models"
 
 type TagRepository interface {
 	CreateTag(tag models.Tag) (models.Tag, error)
-	GetTag(tagId int64) (models.Tag, error)
-	GetTagByName(name string) (models.Tag, error)
+	GetTag(tagId, userId int64) (models.Tag, error)
+	GetTagByName(userId int64, name string) (models.Tag, error)
+	GetTags(userId int64, sort string, filter models.Filter) ([]models.Tag, models.Pagination, error)
+	GetTagTree(userId int64) ([]models.Tag, error)
+	RenameTag(tagId, userId int64, name string) (models.Tag, error)
+	MoveTag(tagId, userId int64, parentId *int64) (models.Tag, error)
+	MergeTags(userId, targetId int64, sourceIds []int64) (models.Tag, error)
+	DeleteTag(tagId, userId int64) error
 	AddTagsToBookmark(bmId int64, tags []models.Tag) error
+	AutocompleteTags(userId int64, typed string, limit int) ([]models.Tag, error)
+	GetTagsOfSimilarLinks(userId int64, url, host, section string, limit int) ([]models.Tag, error)
+	GetTagsByKeys(userId int64, keys []string, limit int) ([]models.Tag, error)
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"github.com/mypipeapp/mypipeapi/db/models"
+	"time"
+)
+
+type TrashRepository interface {
+	GetTrashedPipes(userID int64) ([]models.Pipe, error)
+	GetTrashedBookmarks(userID int64) ([]models.Bookmark, error)
+	RestorePipe(pipeID, userID int64) (models.Pipe, error)
+	RestoreBookmark(bmID, userID int64) (models.Bookmark, error)
+	PurgePipe(pipeID, userID int64) (bool, error)
+	PurgeBookmark(bmID, userID int64) (bool, error)
+	EmptyTrash(userID int64) (int64, error)
+	PurgeExpired(before time.Time) (int64, error)
+}
@@ -16,4 +16,7 @@ type UserRepository interface {
 	UpdateUserDeviceTokens(userId int64, deviceTokens []string) ([]string, error)
 	ConnectToTwitter(user models.User, twitterId string) (models.User, error)
 	DisconnectTwitter(user models.User) (models.User, error)
+	GetProfileSettings(userId int64) (models.ProfileSettings, error)
+	UpdateProfileSettings(userId int64, settings models.ProfileSettings) (models.ProfileSettings, error)
+	GetPublicProfile(username string) (models.PublicProfile, error)
 }
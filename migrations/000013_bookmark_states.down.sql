@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS bookmarks_user_id_state_idx;
+
+ALTER TABLE bookmarks
+    DROP COLUMN IF EXISTS is_read,
+    DROP COLUMN IF EXISTS read_at,
+    DROP COLUMN IF EXISTS is_starred,
+    DROP COLUMN IF EXISTS starred_at,
+    DROP COLUMN IF EXISTS is_archived,
+    DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,9 @@
+ALTER TABLE bookmarks
+    ADD COLUMN IF NOT EXISTS is_read BOOLEAN DEFAULT false,
+    ADD COLUMN IF NOT EXISTS read_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS is_starred BOOLEAN DEFAULT false,
+    ADD COLUMN IF NOT EXISTS starred_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS is_archived BOOLEAN DEFAULT false,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS bookmarks_user_id_state_idx ON bookmarks (user_id, is_archived, is_read, is_starred);
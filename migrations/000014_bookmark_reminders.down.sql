@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS rediscover_enabled,
+    DROP COLUMN IF EXISTS last_rediscovered_at;
+
+DROP TABLE IF EXISTS bookmark_reminders;
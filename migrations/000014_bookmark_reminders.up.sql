@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS bookmark_reminders (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    bookmark_id INT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+    note VARCHAR(255) DEFAULT '',
+    remind_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    sent_at TIMESTAMPTZ NULL,
+    snooze_count INT DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    modified_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_reminders_status_remind_at_idx ON bookmark_reminders (status, remind_at);
+
+-- rediscover mode periodically resurfaces an old unread bookmark for the user
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS rediscover_enabled BOOLEAN DEFAULT false,
+    ADD COLUMN IF NOT EXISTS last_rediscovered_at TIMESTAMPTZ NULL;
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS bookmarks_deleted_at_idx;
+DROP INDEX IF EXISTS pipes_deleted_at_idx;
+
+DELETE FROM bookmarks WHERE deleted_at IS NOT NULL;
+DELETE FROM pipes WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS pipes_name_user_id_key;
+ALTER TABLE pipes ADD CONSTRAINT pipes_name_user_id_key UNIQUE (name, user_id);
+
+ALTER TABLE bookmarks
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,13 @@
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+ALTER TABLE bookmarks
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+-- a pipe in the trash should not stop the user from creating
+-- a new pipe with the same name
+ALTER TABLE pipes DROP CONSTRAINT IF EXISTS pipes_name_user_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS pipes_name_user_id_key ON pipes (name, user_id) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS pipes_deleted_at_idx ON pipes (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS bookmarks_deleted_at_idx ON bookmarks (deleted_at) WHERE deleted_at IS NOT NULL;
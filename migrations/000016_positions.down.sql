@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS pipes_user_id_position_idx;
+DROP INDEX IF EXISTS bookmarks_pipe_id_position_idx;
+
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS position;
+
+ALTER TABLE bookmarks
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS position;
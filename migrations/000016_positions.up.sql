@@ -0,0 +1,28 @@
+-- positions are lexicographic ranks compared byte by byte, which is why
+-- they use the "C" collation. Ranks never end with '0' so that there is
+-- always room to insert an item before any other
+ALTER TABLE bookmarks
+    ADD COLUMN IF NOT EXISTS title VARCHAR(255) DEFAULT '',
+    ADD COLUMN IF NOT EXISTS position VARCHAR(255) COLLATE "C" NOT NULL DEFAULT 'i';
+
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS position VARCHAR(255) COLLATE "C" NOT NULL DEFAULT 'i';
+
+UPDATE bookmarks b
+SET position=ranked.position
+FROM (
+    SELECT id, lpad(row_number() OVER (PARTITION BY pipe_id ORDER BY id)::text, 8, '0') || 'i' AS position
+    FROM bookmarks
+) ranked
+WHERE b.id=ranked.id;
+
+UPDATE pipes p
+SET position=ranked.position
+FROM (
+    SELECT id, lpad(row_number() OVER (PARTITION BY user_id ORDER BY id)::text, 8, '0') || 'i' AS position
+    FROM pipes
+) ranked
+WHERE p.id=ranked.id;
+
+CREATE INDEX IF NOT EXISTS bookmarks_pipe_id_position_idx ON bookmarks (pipe_id, position, id);
+CREATE INDEX IF NOT EXISTS pipes_user_id_position_idx ON pipes (user_id, position, id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS history_changes;
+DROP TABLE IF EXISTS history_operations;
@@ -0,0 +1,25 @@
+-- an operation groups every change made by a single request, so that a bulk
+-- change like reordering a pipe can be undone in one go
+CREATE TABLE IF NOT EXISTS history_operations (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    action VARCHAR(50) NOT NULL,
+    undone_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS history_operations_user_id_created_at_idx ON history_operations (user_id, created_at DESC);
+
+-- before and after hold a snapshot of the fields of the pipe or
+-- bookmark that can be changed and restored
+CREATE TABLE IF NOT EXISTS history_changes (
+    id SERIAL PRIMARY KEY,
+    operation_id INT NOT NULL REFERENCES history_operations (id) ON DELETE CASCADE,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id INT NOT NULL,
+    before JSONB NOT NULL,
+    after JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS history_changes_operation_id_idx ON history_changes (operation_id);
+CREATE INDEX IF NOT EXISTS history_changes_entity_idx ON history_changes (entity_type, entity_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS pipes_parent_id_idx;
+
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,5 @@
+-- a pipe nested in another one goes wherever its parent goes
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS parent_id INT NULL REFERENCES pipes (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS pipes_parent_id_idx ON pipes (parent_id);
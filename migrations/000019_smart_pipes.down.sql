@@ -0,0 +1,3 @@
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS query,
+    DROP COLUMN IF EXISTS type;
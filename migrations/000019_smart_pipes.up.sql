@@ -0,0 +1,4 @@
+-- a smart pipe holds no bookmarks of its own, its bookmarks are the ones matching its query
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'standard',
+    ADD COLUMN IF NOT EXISTS query JSONB NULL;
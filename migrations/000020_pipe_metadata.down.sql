@@ -0,0 +1,6 @@
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS visibility,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS icon,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,9 @@
+-- name stays the lowercase key pipes are looked up by, display_name keeps the case the user typed
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS icon VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS color VARCHAR(7) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'private';
+
+UPDATE pipes SET display_name=name WHERE display_name='';
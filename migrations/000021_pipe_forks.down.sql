@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS bookmarks_forked_from_idx;
+DROP INDEX IF EXISTS pipes_forked_from_idx;
+
+ALTER TABLE bookmarks
+    DROP COLUMN IF EXISTS forked_from;
+
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS synced_at,
+    DROP COLUMN IF EXISTS sync_upstream,
+    DROP COLUMN IF EXISTS forked_from;
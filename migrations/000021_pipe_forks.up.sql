@@ -0,0 +1,12 @@
+-- a fork keeps pointing at the pipe it was copied from, and every copied bookmark at its original,
+-- so that the bookmarks added upstream later on can be told apart from the ones already copied
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS forked_from INT NULL REFERENCES pipes (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS sync_upstream BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS synced_at TIMESTAMPTZ NULL;
+
+ALTER TABLE bookmarks
+    ADD COLUMN IF NOT EXISTS forked_from INT NULL REFERENCES bookmarks (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS pipes_forked_from_idx ON pipes (forked_from) WHERE forked_from IS NOT NULL;
+CREATE INDEX IF NOT EXISTS bookmarks_forked_from_idx ON bookmarks (forked_from) WHERE forked_from IS NOT NULL;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS history_operations_actor_id_created_at_idx;
+ALTER TABLE history_operations
+    DROP COLUMN IF EXISTS actor_id;
+
+ALTER TABLE bookmarks
+    DROP COLUMN IF EXISTS added_by;
+
+ALTER TABLE shared_pipe_receivers
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,21 @@
+-- every receiver of a pipe is a collaborator with a role, and receivers who could only view
+-- the pipe so far keep doing so
+ALTER TABLE shared_pipe_receivers
+    ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'viewer';
+
+-- bookmarks stay with the owner of their pipe, but remember the collaborator who added them
+ALTER TABLE bookmarks
+    ADD COLUMN IF NOT EXISTS added_by INT NULL REFERENCES users (id) ON DELETE SET NULL;
+
+UPDATE bookmarks SET added_by=user_id WHERE added_by IS NULL;
+
+-- the history of a pipe edited by a collaborator belongs to its owner, but the changes can only be
+-- undone by the collaborator who made them
+ALTER TABLE history_operations
+    ADD COLUMN IF NOT EXISTS actor_id INT NULL REFERENCES users (id) ON DELETE CASCADE;
+
+UPDATE history_operations SET actor_id=user_id WHERE actor_id IS NULL;
+ALTER TABLE history_operations
+    ALTER COLUMN actor_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS history_operations_actor_id_created_at_idx ON history_operations (actor_id, created_at DESC);
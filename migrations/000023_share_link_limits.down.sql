@@ -0,0 +1,9 @@
+ALTER TABLE shared_pipe_receivers
+    DROP COLUMN IF EXISTS permission;
+
+ALTER TABLE shared_pipes
+    DROP COLUMN IF EXISTS permission,
+    DROP COLUMN IF EXISTS password_hash,
+    DROP COLUMN IF EXISTS redemptions,
+    DROP COLUMN IF EXISTS max_redemptions,
+    DROP COLUMN IF EXISTS expires_at;
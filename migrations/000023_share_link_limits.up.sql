@@ -0,0 +1,13 @@
+-- share links can expire, run out after a number of redemptions, ask for a password
+-- and either let their users add the pipe to their collection or only view it
+ALTER TABLE shared_pipes
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS max_redemptions INT NULL,
+    ADD COLUMN IF NOT EXISTS redemptions INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS password_hash TEXT NULL,
+    ADD COLUMN IF NOT EXISTS permission VARCHAR(20) NOT NULL DEFAULT 'fork';
+
+-- the users who added a pipe through a share link keep the permission of the link, which decides
+-- whether they can fork the pipe
+ALTER TABLE shared_pipe_receivers
+    ADD COLUMN IF NOT EXISTS permission VARCHAR(20) NOT NULL DEFAULT 'fork';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS shared_pipe_receivers_receiver_id_idx;
+
+ALTER TABLE shared_pipe_receivers
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS declined_at;
@@ -0,0 +1,6 @@
+-- private shares are invitations the receiver accepts or declines before they expire
+ALTER TABLE shared_pipe_receivers
+    ADD COLUMN IF NOT EXISTS declined_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS shared_pipe_receivers_receiver_id_idx ON shared_pipe_receivers (receiver_id, created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS pipe_share_invitations_pipe_id_email_idx;
+DROP TABLE IF EXISTS pipe_share_invitations;
@@ -0,0 +1,18 @@
+-- pipes shared by email with people who don't have an account yet. The invitation is attached
+-- to the account that signs up with or verifies the email, becoming a private share
+CREATE TABLE IF NOT EXISTS pipe_share_invitations
+(
+    id SERIAL PRIMARY KEY,
+    sharer_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    pipe_id INT NOT NULL REFERENCES pipes (id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    code VARCHAR(20) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'viewer',
+    expires_at TIMESTAMPTZ NOT NULL,
+    receiver_id INT NULL REFERENCES users (id) ON DELETE SET NULL,
+    attached_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    modified_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pipe_share_invitations_pipe_id_email_idx ON pipe_share_invitations (pipe_id, lower(email));
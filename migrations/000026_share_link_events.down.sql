@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS share_link_events_pipe_id_idx;
+DROP TABLE IF EXISTS share_link_events;
@@ -0,0 +1,17 @@
+-- what happens to the pipes shared with a code: previews, redemptions and the bookmarks opened from
+-- a preview. Only the host of the referrer and a coarse client type are kept, never the address of
+-- the client. Events outlive the share they were recorded for, so pipes keep their stats
+CREATE TABLE IF NOT EXISTS share_link_events
+(
+    id BIGSERIAL PRIMARY KEY,
+    shared_pipe_id INT NULL REFERENCES shared_pipes (id) ON DELETE SET NULL,
+    pipe_id INT NOT NULL REFERENCES pipes (id) ON DELETE CASCADE,
+    code VARCHAR(20) NOT NULL,
+    event VARCHAR(20) NOT NULL,
+    bookmark_id INT NULL REFERENCES bookmarks (id) ON DELETE SET NULL,
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    client_type VARCHAR(20) NOT NULL DEFAULT 'other',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS share_link_events_pipe_id_idx ON share_link_events (pipe_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS pipes_visibility_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS show_in_explore,
+    DROP COLUMN IF EXISTS public_profile;
@@ -0,0 +1,7 @@
+-- profiles are private until their owner opts in. The public pipes of a public profile are listed on
+-- it and, unless the owner opts out, in explore
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS public_profile BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS show_in_explore BOOLEAN NOT NULL DEFAULT true;
+
+CREATE INDEX IF NOT EXISTS pipes_visibility_idx ON pipes (visibility, modified_at) WHERE deleted_at IS NULL;
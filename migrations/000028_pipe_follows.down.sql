@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS pipe_follows_user_id_idx;
+DROP TABLE IF EXISTS pipe_follows;
@@ -0,0 +1,15 @@
+-- users follow public pipes and pipes shared with them to hear about the bookmarks added to them,
+-- either as they are added, in a daily digest or not at all
+CREATE TABLE IF NOT EXISTS pipe_follows
+(
+    id SERIAL PRIMARY KEY,
+    pipe_id INT NOT NULL REFERENCES pipes (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    notify VARCHAR(20) NOT NULL DEFAULT 'instant',
+    digest_sent_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    modified_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pipe_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS pipe_follows_user_id_idx ON pipe_follows (user_id, created_at);
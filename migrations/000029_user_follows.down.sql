@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS activities_actor_id_idx;
+DROP TABLE IF EXISTS activities;
+DROP INDEX IF EXISTS user_blocks_blocked_id_idx;
+DROP TABLE IF EXISTS user_blocks;
+DROP INDEX IF EXISTS user_follows_followee_id_idx;
+DROP TABLE IF EXISTS user_follows;
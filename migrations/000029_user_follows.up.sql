@@ -0,0 +1,40 @@
+-- users follow other users to see what they do on their public pipes, and block users they
+-- don't want to follow them
+CREATE TABLE IF NOT EXISTS user_follows
+(
+    id SERIAL PRIMARY KEY,
+    follower_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    followee_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, followee_id),
+    CHECK (follower_id<>followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_follows_followee_id_idx ON user_follows (followee_id, created_at);
+
+CREATE TABLE IF NOT EXISTS user_blocks
+(
+    id SERIAL PRIMARY KEY,
+    blocker_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (blocker_id, blocked_id),
+    CHECK (blocker_id<>blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+
+-- activities are written once, when a user acts, and the feed of a follower is read from the
+-- activities of the users they follow, so following many people costs nothing until the feed is read
+CREATE TABLE IF NOT EXISTS activities
+(
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    verb VARCHAR(20) NOT NULL,
+    pipe_id INT NOT NULL REFERENCES pipes (id) ON DELETE CASCADE,
+    bookmark_id INT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+    source_pipe_id INT NULL REFERENCES pipes (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS activities_actor_id_idx ON activities (actor_id, created_at DESC, id DESC);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS bookmark_reactions;
+DROP INDEX IF EXISTS bookmark_comments_parent_id_idx;
+DROP INDEX IF EXISTS bookmark_comments_bookmark_id_idx;
+DROP TABLE IF EXISTS bookmark_comments;
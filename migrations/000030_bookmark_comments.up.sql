@@ -0,0 +1,26 @@
+-- everyone with access to a pipe discusses its bookmarks in threaded comments and reacts to them.
+-- Comments are only marked as deleted so that the replies to them stay in place
+CREATE TABLE IF NOT EXISTS bookmark_comments
+(
+    id SERIAL PRIMARY KEY,
+    bookmark_id INT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    parent_id INT NULL REFERENCES bookmark_comments (id) ON DELETE CASCADE,
+    body TEXT NOT NULL DEFAULT '',
+    edited_at TIMESTAMPTZ NULL,
+    deleted_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS bookmark_comments_bookmark_id_idx ON bookmark_comments (bookmark_id, created_at);
+CREATE INDEX IF NOT EXISTS bookmark_comments_parent_id_idx ON bookmark_comments (parent_id);
+
+CREATE TABLE IF NOT EXISTS bookmark_reactions
+(
+    id SERIAL PRIMARY KEY,
+    bookmark_id INT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (bookmark_id, user_id, emoji)
+);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS reports_status_idx;
+DROP INDEX IF EXISTS reports_open_idx;
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE pipes
+    DROP COLUMN IF EXISTS hidden_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS is_admin;
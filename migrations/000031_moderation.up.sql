@@ -0,0 +1,31 @@
+-- admins moderate what users report. Hidden pipes are private and can't be published again by their
+-- owner, while suspended users can't use their account
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ NULL;
+
+ALTER TABLE pipes
+    ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ NULL;
+
+CREATE TABLE IF NOT EXISTS reports
+(
+    id SERIAL PRIMARY KEY,
+    reporter_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    target_type VARCHAR(20) NOT NULL,
+    target_id INT NOT NULL,
+    -- the owner of the reported pipe or bookmark, the author of the reported comment or the reported user
+    target_user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    -- the reported pipe or the pipe holding the reported bookmark or comment
+    pipe_id INT NULL REFERENCES pipes (id) ON DELETE SET NULL,
+    reason VARCHAR(20) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    action VARCHAR(20) NULL,
+    note TEXT NOT NULL DEFAULT '',
+    resolved_by INT NULL REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_idx ON reports (reporter_id, target_type, target_id) WHERE status='open';
+CREATE INDEX IF NOT EXISTS reports_status_idx ON reports (status, created_at);
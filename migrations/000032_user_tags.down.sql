@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS bookmark_tag_tag_id_idx;
+ALTER TABLE bookmark_tag DROP CONSTRAINT IF EXISTS bookmark_tag_bookmark_id_tag_id_key;
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_user_id_name_key;
+ALTER TABLE tags ALTER COLUMN name DROP NOT NULL;
+ALTER TABLE tags DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,31 @@
+-- tags belong to the user whose bookmarks they are on, who can rename, merge and delete them
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users (id) ON DELETE CASCADE;
+
+-- every user gets their own copy of the global tags on their bookmarks
+INSERT INTO tags (user_id, name, created_at, modified_at)
+SELECT b.user_id, t.name, MIN(t.created_at), MIN(t.created_at)
+FROM bookmark_tag bt
+    INNER JOIN tags t ON t.id=bt.tag_id
+    INNER JOIN bookmarks b ON b.id=bt.bookmark_id
+WHERE t.user_id IS NULL AND t.name IS NOT NULL
+GROUP BY b.user_id, t.name;
+
+UPDATE bookmark_tag bt
+SET tag_id=ut.id
+FROM tags t, bookmarks b, tags ut
+WHERE t.id=bt.tag_id AND t.user_id IS NULL AND b.id=bt.bookmark_id
+    AND ut.user_id=b.user_id AND ut.name=t.name;
+
+-- global tags with the same name could be on the same bookmark, which now has the same tag twice
+DELETE FROM bookmark_tag bt
+USING bookmark_tag kept
+WHERE kept.bookmark_id=bt.bookmark_id AND kept.tag_id=bt.tag_id AND kept.id<bt.id;
+
+-- what is left of the global tags is on no bookmark or has no name
+DELETE FROM tags WHERE user_id IS NULL;
+
+ALTER TABLE tags ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE tags ALTER COLUMN name SET NOT NULL;
+ALTER TABLE tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name);
+ALTER TABLE bookmark_tag ADD CONSTRAINT bookmark_tag_bookmark_id_tag_id_key UNIQUE (bookmark_id, tag_id);
+CREATE INDEX IF NOT EXISTS bookmark_tag_tag_id_idx ON bookmark_tag (tag_id);
@@ -0,0 +1,4 @@
+-- this migration can't be reverted: the tags merged by the up migration are gone and their bookmarks
+-- keep the tag they were merged into, and the trimmed names keep their new spelling. Only the index
+-- is dropped, so tags that only differ by case or whitespace can be created again
+DROP INDEX IF EXISTS tags_user_id_key_idx;
@@ -0,0 +1,29 @@
+-- tags whose names only differ by case or whitespace are the same tag. The oldest tag of a user
+-- takes the place of the others with the same key on their bookmarks. The merge is not reverted by
+-- the down migration
+WITH ranked AS (
+    SELECT id, MIN(id) OVER (PARTITION BY user_id, lower(regexp_replace(name, '\s', '', 'g'))) AS kept_id
+    FROM tags
+), moved AS (
+    SELECT MIN(bt.id) AS id, r.kept_id
+    FROM bookmark_tag bt
+        INNER JOIN ranked r ON r.id=bt.tag_id
+    WHERE r.kept_id<>r.id
+        AND NOT EXISTS (SELECT 1 FROM bookmark_tag kt WHERE kt.bookmark_id=bt.bookmark_id AND kt.tag_id=r.kept_id)
+    GROUP BY bt.bookmark_id, r.kept_id
+)
+UPDATE bookmark_tag bt
+SET tag_id=m.kept_id
+FROM moved m
+WHERE bt.id=m.id;
+
+DELETE FROM tags t
+USING tags kept
+WHERE kept.user_id=t.user_id AND kept.id<t.id
+    AND lower(regexp_replace(kept.name, '\s', '', 'g'))=lower(regexp_replace(t.name, '\s', '', 'g'));
+
+UPDATE tags
+SET name=regexp_replace(regexp_replace(name, '\s+', ' ', 'g'), '^ | $', '', 'g')
+WHERE name<>regexp_replace(regexp_replace(name, '\s+', ' ', 'g'), '^ | $', '', 'g');
+
+CREATE UNIQUE INDEX IF NOT EXISTS tags_user_id_key_idx ON tags (user_id, lower(regexp_replace(name, '\s', '', 'g')));
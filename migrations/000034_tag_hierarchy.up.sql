@@ -0,0 +1,52 @@
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS parent_id INT REFERENCES tags (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS tags_parent_id_idx ON tags (parent_id);
+
+-- tags named like paths, such as lang/go, lose the whitespace and empty segments around their slashes.
+-- Names that would then clash with another tag of the user are left as they are
+WITH normalized AS (
+    SELECT id, user_id, trim(both '/' from regexp_replace(regexp_replace(name, '\s*/\s*', '/', 'g'), '/{2,}', '/', 'g')) AS name
+    FROM tags
+    WHERE name ~ '\s/|/\s|//|^/|/$'
+), kept AS (
+    SELECT DISTINCT ON (n.user_id, lower(regexp_replace(n.name, '\s', '', 'g'))) n.id, n.name
+    FROM normalized n
+    WHERE n.name<>'' AND NOT EXISTS (
+        SELECT 1 FROM tags o
+        WHERE o.user_id=n.user_id AND o.id<>n.id
+            AND lower(regexp_replace(o.name, '\s', '', 'g'))=lower(regexp_replace(n.name, '\s', '', 'g'))
+    )
+    ORDER BY n.user_id, lower(regexp_replace(n.name, '\s', '', 'g')), n.id
+)
+UPDATE tags t
+SET name=k.name
+FROM kept k
+WHERE t.id=k.id;
+
+-- every path a tag is under becomes a tag of its own, e.g. lang for lang/go
+INSERT INTO tags (user_id, name)
+SELECT DISTINCT ON (t.user_id, lower(regexp_replace(a.path, '\s', '', 'g'))) t.user_id, a.path
+FROM tags t
+    CROSS JOIN LATERAL generate_series(1, array_length(string_to_array(t.name, '/'), 1) - 1) AS depth
+    CROSS JOIN LATERAL (SELECT array_to_string((string_to_array(t.name, '/'))[1:depth], '/') AS path) a
+WHERE t.name ~ '^[^/]+(/[^/]+)+$'
+ORDER BY t.user_id, lower(regexp_replace(a.path, '\s', '', 'g')), t.id
+ON CONFLICT (user_id, lower(regexp_replace(name, '\s', '', 'g'))) DO NOTHING;
+
+UPDATE tags t
+SET parent_id=p.id
+FROM tags p
+WHERE t.name ~ '^[^/]+(/[^/]+)+$' AND p.user_id=t.user_id
+    AND lower(regexp_replace(p.name, '\s', '', 'g'))=lower(regexp_replace(regexp_replace(t.name, '/[^/]+$', ''), '\s', '', 'g'));
+
+-- the name of a tag is the name of its parent followed by its own, however the parent was spelled
+WITH RECURSIVE paths AS (
+    SELECT id, name::text AS path FROM tags WHERE parent_id IS NULL
+    UNION ALL
+    SELECT c.id, p.path || '/' || substring(c.name from '[^/]+$')
+    FROM tags c
+        INNER JOIN paths p ON c.parent_id=p.id
+)
+UPDATE tags t
+SET name=p.path
+FROM paths p
+WHERE t.id=p.id AND t.name<>p.path;